@@ -5,13 +5,16 @@ import (
 	"errors"
 	"net/http"
 	"strings"
+	"time"
 
+	cryptorand "github.com/cjlapao/common-go-cryptorand"
 	"github.com/cjlapao/common-go-identity-oauth2/oauth2context"
 	"github.com/cjlapao/common-go-identity/api_key_manager"
 	"github.com/cjlapao/common-go-identity/environment"
 	"github.com/cjlapao/common-go-identity/interfaces"
 	"github.com/cjlapao/common-go-identity/jwt_keyvault"
 	"github.com/cjlapao/common-go-identity/models"
+	"github.com/cjlapao/common-go-identity/trusted_issuers"
 	log "github.com/cjlapao/common-go-logger"
 	"github.com/cjlapao/common-go/helper/http_helper"
 	"github.com/cjlapao/common-go/service_provider"
@@ -24,25 +27,32 @@ var (
 )
 
 type AuthorizationContext struct {
-	OauthContext         *oauth2context.Oauth2Context
-	RequestId            string
-	TenantId             string
-	Issuer               string
-	Scope                string
-	Audiences            []string
-	BaseUrl              string
-	Options              *AuthorizationOptions
-	ValidationOptions    *AuthorizationValidationOptions
-	KeyVault             *jwt_keyvault.JwtKeyVaultService
-	ApiKeyManager        *api_key_manager.ApiKeyManager
-	UserDatabaseAdapter  interfaces.UserContextAdapter
-	NotificationCallback func(notification models.OAuthNotification) error
-	IsAuthorized         bool
-	IsMicroService       bool
-	AuthorizationError   *models.OAuthErrorResponse
-	AuthorizedBy         string
-	User                 *UserContext
-	users                []UserContext
+	OauthContext                     *oauth2context.Oauth2Context
+	RequestId                        string
+	TenantId                         string
+	Issuer                           string
+	Scope                            string
+	Audiences                        []string
+	BaseUrl                          string
+	Options                          *AuthorizationOptions
+	ValidationOptions                *AuthorizationValidationOptions
+	KeyVault                         *jwt_keyvault.JwtKeyVaultService
+	ApiKeyManager                    *api_key_manager.ApiKeyManager
+	TrustedIssuers                   *trusted_issuers.TrustedIssuerRegistry
+	TokenExchangePolicies            []TokenExchangePolicy
+	UserDatabaseAdapter              interfaces.UserContextAdapter
+	AuthorizationCodeDatabaseAdapter interfaces.AuthorizationCodeContextAdapter
+	ClientDatabaseAdapter            interfaces.ClientContextAdapter
+	RefreshTokenDatabaseAdapter      interfaces.RefreshTokenContextAdapter
+	RevocationDatabaseAdapter        interfaces.RevocationContextAdapter
+	OpaqueTokenDatabaseAdapter       interfaces.OpaqueTokenContextAdapter
+	NotificationCallback             func(notification models.OAuthNotification) error
+	IsAuthorized                     bool
+	IsMicroService                   bool
+	AuthorizationError               *models.OAuthErrorResponse
+	AuthorizedBy                     string
+	User                             *UserContext
+	users                            []UserContext
 }
 
 var baseAuthorizationCtx *AuthorizationContext
@@ -56,17 +66,24 @@ func NewFromUser(user *UserContext) *AuthorizationContext {
 	}
 
 	newContext := AuthorizationContext{
-		OauthContext:         baseAuthorizationCtx.OauthContext,
-		TenantId:             baseAuthorizationCtx.TenantId,
-		Issuer:               baseAuthorizationCtx.Issuer,
-		Scope:                baseAuthorizationCtx.Scope,
-		Audiences:            make([]string, 0),
-		BaseUrl:              baseAuthorizationCtx.BaseUrl,
-		Options:              baseAuthorizationCtx.Options,
-		ValidationOptions:    baseAuthorizationCtx.ValidationOptions,
-		KeyVault:             baseAuthorizationCtx.KeyVault,
-		UserDatabaseAdapter:  baseAuthorizationCtx.UserDatabaseAdapter,
-		NotificationCallback: baseAuthorizationCtx.NotificationCallback,
+		OauthContext:                     baseAuthorizationCtx.OauthContext,
+		TenantId:                         baseAuthorizationCtx.TenantId,
+		Issuer:                           baseAuthorizationCtx.Issuer,
+		Scope:                            baseAuthorizationCtx.Scope,
+		Audiences:                        make([]string, 0),
+		BaseUrl:                          baseAuthorizationCtx.BaseUrl,
+		Options:                          baseAuthorizationCtx.Options,
+		ValidationOptions:                baseAuthorizationCtx.ValidationOptions,
+		KeyVault:                         baseAuthorizationCtx.KeyVault,
+		TrustedIssuers:                   baseAuthorizationCtx.TrustedIssuers,
+		TokenExchangePolicies:            baseAuthorizationCtx.TokenExchangePolicies,
+		UserDatabaseAdapter:              baseAuthorizationCtx.UserDatabaseAdapter,
+		AuthorizationCodeDatabaseAdapter: baseAuthorizationCtx.AuthorizationCodeDatabaseAdapter,
+		ClientDatabaseAdapter:            baseAuthorizationCtx.ClientDatabaseAdapter,
+		RefreshTokenDatabaseAdapter:      baseAuthorizationCtx.RefreshTokenDatabaseAdapter,
+		RevocationDatabaseAdapter:        baseAuthorizationCtx.RevocationDatabaseAdapter,
+		OpaqueTokenDatabaseAdapter:       baseAuthorizationCtx.OpaqueTokenDatabaseAdapter,
+		NotificationCallback:             baseAuthorizationCtx.NotificationCallback,
 	}
 
 	// Resetting the current context for this user leaving everything else
@@ -97,22 +114,29 @@ func Clone() *AuthorizationContext {
 	}
 
 	newContext := AuthorizationContext{
-		OauthContext:         baseAuthorizationCtx.OauthContext,
-		Issuer:               baseAuthorizationCtx.Issuer,
-		Scope:                baseAuthorizationCtx.Scope,
-		Audiences:            make([]string, 0),
-		BaseUrl:              baseAuthorizationCtx.BaseUrl,
-		Options:              baseAuthorizationCtx.Options,
-		ValidationOptions:    baseAuthorizationCtx.ValidationOptions,
-		KeyVault:             baseAuthorizationCtx.KeyVault,
-		IsAuthorized:         false,
-		RequestId:            "",
-		TenantId:             "",
-		AuthorizationError:   nil,
-		AuthorizedBy:         "",
-		User:                 nil,
-		UserDatabaseAdapter:  baseAuthorizationCtx.UserDatabaseAdapter,
-		NotificationCallback: baseAuthorizationCtx.NotificationCallback,
+		OauthContext:                     baseAuthorizationCtx.OauthContext,
+		Issuer:                           baseAuthorizationCtx.Issuer,
+		Scope:                            baseAuthorizationCtx.Scope,
+		Audiences:                        make([]string, 0),
+		BaseUrl:                          baseAuthorizationCtx.BaseUrl,
+		Options:                          baseAuthorizationCtx.Options,
+		ValidationOptions:                baseAuthorizationCtx.ValidationOptions,
+		KeyVault:                         baseAuthorizationCtx.KeyVault,
+		TrustedIssuers:                   baseAuthorizationCtx.TrustedIssuers,
+		TokenExchangePolicies:            baseAuthorizationCtx.TokenExchangePolicies,
+		IsAuthorized:                     false,
+		RequestId:                        "",
+		TenantId:                         "",
+		AuthorizationError:               nil,
+		AuthorizedBy:                     "",
+		User:                             nil,
+		UserDatabaseAdapter:              baseAuthorizationCtx.UserDatabaseAdapter,
+		AuthorizationCodeDatabaseAdapter: baseAuthorizationCtx.AuthorizationCodeDatabaseAdapter,
+		ClientDatabaseAdapter:            baseAuthorizationCtx.ClientDatabaseAdapter,
+		RefreshTokenDatabaseAdapter:      baseAuthorizationCtx.RefreshTokenDatabaseAdapter,
+		RevocationDatabaseAdapter:        baseAuthorizationCtx.RevocationDatabaseAdapter,
+		OpaqueTokenDatabaseAdapter:       baseAuthorizationCtx.OpaqueTokenDatabaseAdapter,
+		NotificationCallback:             baseAuthorizationCtx.NotificationCallback,
 	}
 
 	// Resetting the current context for this user leaving everything else
@@ -185,26 +209,51 @@ func (a *AuthorizationContext) WithDefaultOptions() *AuthorizationContext {
 
 	// Setting default validate options
 	a.ValidationOptions = &AuthorizationValidationOptions{
-		Audiences:     false,
-		ExpiryDate:    true,
-		Subject:       true,
-		Issuer:        true,
-		VerifiedEmail: false,
-		NotBefore:     false,
-		Tenant:        false,
+		Audiences:       false,
+		ExpiryDate:      true,
+		Subject:         true,
+		Issuer:          true,
+		VerifiedEmail:   false,
+		NotBefore:       false,
+		Tenant:          false,
+		AttemptsToBlock: env.AttemptsToBlock(),
+		BlockDuration:   env.BlockDuration(),
 	}
 
 	// Setting the default durations into the Options object
 	a.Options = &AuthorizationOptions{
-		ControllerPrefix:           env.ControllerPrefix(),
-		TokenDuration:              env.TokenDuration(),
-		RefreshTokenDuration:       env.RefreshTokenDuration(),
-		VerifyEmailTokenDuration:   env.VerifyEmailTokenDuration(),
-		RecoverTokenDuration:       env.RecoverTokenDuration(),
-		OtpSecret:                  env.OtpSecret(),
-		OtpDuration:                env.OtpDefaultDuration(),
-		OtpSkew:                    env.OtpDefaultSkew(),
-		EmailVerificationProcessor: env.VerifyEmailProcessor(),
+		ControllerPrefix:            env.ControllerPrefix(),
+		TokenDuration:               env.TokenDuration(),
+		RefreshTokenDuration:        env.RefreshTokenDuration(),
+		VerifyEmailTokenDuration:    env.VerifyEmailTokenDuration(),
+		RecoverTokenDuration:        env.RecoverTokenDuration(),
+		OtpSecret:                   env.OtpSecret(),
+		OtpDuration:                 env.OtpDefaultDuration(),
+		OtpSkew:                     env.OtpDefaultSkew(),
+		AuthorizationCodeDuration:   env.AuthorizationCodeDuration(),
+		AllowedRedirectUris:         env.AllowedRedirectUris(),
+		LoginUrl:                    env.LoginUrl(),
+		InitialAccessToken:          env.InitialAccessToken(),
+		SelfRegistrationGrantTypes:  env.SelfRegistrationGrantTypes(),
+		SelfRegistrationScopes:      env.SelfRegistrationScopes(),
+		EncryptionKey:               env.EncryptionKey(),
+		MfaTokenDuration:            env.MfaTokenDuration(),
+		TotpIssuer:                  env.TotpIssuer(),
+		WebAuthnRelyingPartyId:      env.WebAuthnRelyingPartyId(),
+		WebAuthnRelyingPartyName:    env.WebAuthnRelyingPartyName(),
+		WebAuthnOrigins:             env.WebAuthnOrigins(),
+		LoginCodeDuration:           env.LoginCodeDuration(),
+		LoginCodeMaxAttempts:        env.LoginCodeMaxAttempts(),
+		LoginCodeRequestInterval:    env.LoginCodeRequestInterval(),
+		JwksCacheDuration:           env.JwksCacheDuration(),
+		KeyRotationInterval:         env.KeyRotationInterval(),
+		KeyRotationPreAnnounce:      env.KeyRotationPreAnnounce(),
+		KeyRotationAlgorithm:        env.KeyRotationAlgorithm(),
+		TrustedIssuers:              env.TrustedIssuers(),
+		TrustedIssuersCacheDuration: env.TrustedIssuersCacheDuration(),
+		AccessTokenFormat:           env.AccessTokenFormat(),
+		OpaqueTokenTenants:          env.OpaqueTokenTenants(),
+		EmailVerificationProcessor:  env.VerifyEmailProcessor(),
 		PasswordRules: PasswordRules{
 			RequiresCapital: env.PasswordValidationRequireCapital(),
 			RequiresSpecial: env.PasswordValidationRequireSpecial(),
@@ -215,6 +264,14 @@ func (a *AuthorizationContext) WithDefaultOptions() *AuthorizationContext {
 		},
 	}
 
+	// Secrets encrypted with a generated key cannot be read after a restart so the key should be configured
+	if a.Options.EncryptionKey == "" {
+		if key, err := cryptorand.GetRandomString(64); err == nil {
+			a.Options.EncryptionKey = key
+			logger.Warn("No encryption key was configured in %v, using a temporary key", environment.ENCRYPTION_KEY_ENV_VAR_NAME)
+		}
+	}
+
 	if a.KeyVault == nil {
 		a.KeyVault = jwt_keyvault.Get()
 	}
@@ -223,6 +280,15 @@ func (a *AuthorizationContext) WithDefaultOptions() *AuthorizationContext {
 		a.ApiKeyManager = api_key_manager.GetApiKeyManager()
 	}
 
+	if a.TrustedIssuers == nil {
+		a.TrustedIssuers = trusted_issuers.NewTrustedIssuerRegistry(trusted_issuers.TrustedIssuerRegistryOptions{
+			CacheDuration: time.Minute * time.Duration(a.Options.TrustedIssuersCacheDuration),
+		})
+	}
+	for _, issuer := range a.Options.TrustedIssuers {
+		a.TrustedIssuers.Add(trusted_issuers.TrustedIssuer{Issuer: issuer})
+	}
+
 	return a
 }
 
@@ -268,6 +334,50 @@ func (a *AuthorizationContext) WithPublicKey(key string) *AuthorizationContext {
 	return a
 }
 
+// WithTrustedIssuer validates the tokens of the issuer with the keys of its discovery document
+func (a *AuthorizationContext) WithTrustedIssuer(issuer trusted_issuers.TrustedIssuer) *AuthorizationContext {
+	a.TrustedIssuers.Add(issuer)
+	return a
+}
+
+// WithOpaqueAccessTokens issues opaque access tokens to the clients of the tenants that do not
+// choose a format, without tenants opaque tokens become the default format of every tenant
+func (a *AuthorizationContext) WithOpaqueAccessTokens(tenantIds ...string) *AuthorizationContext {
+	if len(tenantIds) == 0 {
+		a.Options.AccessTokenFormat = models.OpaqueAccessTokenFormat
+		return a
+	}
+
+	a.Options.OpaqueTokenTenants = append(a.Options.OpaqueTokenTenants, tenantIds...)
+	return a
+}
+
+// GetAccessTokenFormat returns the format of the access tokens issued to the client, the format
+// chosen by the client is used before the one of its tenant
+func (a *AuthorizationContext) GetAccessTokenFormat(client *models.Client) string {
+	tenantId := a.TenantId
+	if client != nil {
+		if client.AccessTokenFormat != "" {
+			return client.AccessTokenFormat
+		}
+		if client.TenantID != "" {
+			tenantId = client.TenantID
+		}
+	}
+
+	for _, tenant := range a.Options.OpaqueTokenTenants {
+		if strings.EqualFold(tenant, tenantId) {
+			return models.OpaqueAccessTokenFormat
+		}
+	}
+
+	if a.Options.AccessTokenFormat == "" {
+		return models.JwtAccessTokenFormat
+	}
+
+	return a.Options.AccessTokenFormat
+}
+
 func (a *AuthorizationContext) WithKeyVault() *AuthorizationContext {
 	a.Options.KeyVaultEnabled = true
 	a.Options.PublicKey = ""
@@ -285,6 +395,23 @@ func (a *AuthorizationContext) GetKeyVault() *jwt_keyvault.JwtKeyVaultService {
 	return a.KeyVault
 }
 
+// GetKeyRotationOptions returns the key rotation options, the rotated keys are kept until the
+// longest lived token they signed has expired plus some clock skew
+func (a *AuthorizationContext) GetKeyRotationOptions() jwt_keyvault.KeyRotationOptions {
+	retention := a.Options.TokenDuration
+	if a.Options.RefreshTokenDuration > retention {
+		retention = a.Options.RefreshTokenDuration
+	}
+
+	return jwt_keyvault.KeyRotationOptions{
+		Algorithm:     a.Options.KeyRotationAlgorithm,
+		Interval:      time.Minute * time.Duration(a.Options.KeyRotationInterval),
+		PreAnnounce:   time.Minute * time.Duration(a.Options.KeyRotationPreAnnounce),
+		Retention:     time.Minute * time.Duration(retention+5),
+		EncryptionKey: a.Options.EncryptionKey,
+	}
+}
+
 func (a *AuthorizationContext) SetRequestIssuer(r *http.Request, tenantId string) string {
 	if a.BaseUrl == "" {
 		a.BaseUrl = service_provider.Get().GetBaseUrl(r)
@@ -336,6 +463,36 @@ func SetUserContext(context interfaces.UserContextAdapter) *AuthorizationContext
 	return baseCtx
 }
 
+func SetAuthorizationCodeContext(context interfaces.AuthorizationCodeContextAdapter) *AuthorizationContext {
+	baseCtx := GetBaseContext()
+	baseCtx.AuthorizationCodeDatabaseAdapter = context
+	return baseCtx
+}
+
+func SetClientContext(context interfaces.ClientContextAdapter) *AuthorizationContext {
+	baseCtx := GetBaseContext()
+	baseCtx.ClientDatabaseAdapter = context
+	return baseCtx
+}
+
+func SetRefreshTokenContext(context interfaces.RefreshTokenContextAdapter) *AuthorizationContext {
+	baseCtx := GetBaseContext()
+	baseCtx.RefreshTokenDatabaseAdapter = context
+	return baseCtx
+}
+
+func SetRevocationContext(context interfaces.RevocationContextAdapter) *AuthorizationContext {
+	baseCtx := GetBaseContext()
+	baseCtx.RevocationDatabaseAdapter = context
+	return baseCtx
+}
+
+func SetOpaqueTokenContext(context interfaces.OpaqueTokenContextAdapter) *AuthorizationContext {
+	baseCtx := GetBaseContext()
+	baseCtx.OpaqueTokenDatabaseAdapter = context
+	return baseCtx
+}
+
 func WithDefaultAuthorization() *AuthorizationContext {
 	return Init()
 }
@@ -5,22 +5,45 @@ import (
 )
 
 type AuthorizationOptions struct {
-	KeyVaultEnabled            bool
-	TokenDuration              int
-	RefreshTokenDuration       int
-	VerifyEmailTokenDuration   int
-	RecoverTokenDuration       int
-	OtpSecret                  string
-	OtpDuration                int
-	OtpSkew                    int
-	EmailVerificationProcessor string
-	SignatureType              encryption.EncryptionKeyType
-	SignatureSize              encryption.EncryptionKeySize
-	PrivateKey                 string
-	PublicKey                  string
-	KeyId                      string
-	ControllerPrefix           string
-	PasswordRules              PasswordRules
+	KeyVaultEnabled             bool
+	TokenDuration               int
+	RefreshTokenDuration        int
+	VerifyEmailTokenDuration    int
+	RecoverTokenDuration        int
+	OtpSecret                   string
+	OtpDuration                 int
+	OtpSkew                     int
+	EmailVerificationProcessor  string
+	SignatureType               encryption.EncryptionKeyType
+	SignatureSize               encryption.EncryptionKeySize
+	PrivateKey                  string
+	PublicKey                   string
+	KeyId                       string
+	ControllerPrefix            string
+	PasswordRules               PasswordRules
+	AuthorizationCodeDuration   int
+	AllowedRedirectUris         []string
+	LoginUrl                    string
+	InitialAccessToken          string
+	SelfRegistrationGrantTypes  []string
+	SelfRegistrationScopes      []string
+	EncryptionKey               string
+	MfaTokenDuration            int
+	TotpIssuer                  string
+	WebAuthnRelyingPartyId      string
+	WebAuthnRelyingPartyName    string
+	WebAuthnOrigins             []string
+	LoginCodeDuration           int
+	LoginCodeMaxAttempts        int
+	LoginCodeRequestInterval    int
+	JwksCacheDuration           int
+	KeyRotationInterval         int
+	KeyRotationPreAnnounce      int
+	KeyRotationAlgorithm        string
+	TrustedIssuers              []string
+	TrustedIssuersCacheDuration int
+	AccessTokenFormat           string
+	OpaqueTokenTenants          []string
 }
 
 type AuthorizationValidationOptions struct {
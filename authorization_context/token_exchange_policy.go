@@ -0,0 +1,92 @@
+package authorization_context
+
+import (
+	"strings"
+
+	"github.com/cjlapao/common-go-identity/constants"
+)
+
+// TokenExchangePolicy allows a client to exchange the tokens of the users with the token exchange
+// grant, clients without a policy cannot exchange any token
+type TokenExchangePolicy struct {
+	ClientID string
+	// Audiences are the audiences and resources the client can request tokens for
+	Audiences []string
+	// Scopes limits the scopes of the issued tokens, without them the subject token scopes are kept
+	Scopes []string
+	// AllowWithoutActor allows the client to exchange the subject token on its own, without it
+	// every exchange needs the actor token of the user acting for the subject
+	AllowWithoutActor bool
+	// ActorRoles are the roles the actor needs to have, any of them is enough, without them only
+	// super users can act for the subject
+	ActorRoles []string
+}
+
+// AllowsAudience returns true if the client can request tokens for the audience or resource
+func (p TokenExchangePolicy) AllowsAudience(audience string) bool {
+	for _, allowedAudience := range p.Audiences {
+		if strings.EqualFold(allowedAudience, audience) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AllowsScope returns true if the issued tokens can carry the scope
+func (p TokenExchangePolicy) AllowsScope(scope string) bool {
+	if len(p.Scopes) == 0 {
+		return true
+	}
+
+	for _, allowedScope := range p.Scopes {
+		if allowedScope == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AllowsActor returns true if the actor has one of the roles of the policy, super users can
+// always act for the subject
+func (p TokenExchangePolicy) AllowsActor(roles []string) bool {
+	for _, role := range roles {
+		if role == constants.SuperUser {
+			return true
+		}
+		for _, allowedRole := range p.ActorRoles {
+			if strings.EqualFold(allowedRole, role) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// WithTokenExchangePolicy allows the client of the policy to exchange tokens, a policy added
+// again for the same client replaces the previous one
+func (a *AuthorizationContext) WithTokenExchangePolicy(policy TokenExchangePolicy) *AuthorizationContext {
+	for idx, existingPolicy := range a.TokenExchangePolicies {
+		if strings.EqualFold(existingPolicy.ClientID, policy.ClientID) {
+			a.TokenExchangePolicies[idx] = policy
+			return a
+		}
+	}
+
+	a.TokenExchangePolicies = append(a.TokenExchangePolicies, policy)
+	return a
+}
+
+// GetTokenExchangePolicy returns the token exchange policy of the client or nil if it cannot
+// exchange tokens
+func (a *AuthorizationContext) GetTokenExchangePolicy(clientId string) *TokenExchangePolicy {
+	for _, policy := range a.TokenExchangePolicies {
+		if strings.EqualFold(policy.ClientID, clientId) {
+			return &policy
+		}
+	}
+
+	return nil
+}
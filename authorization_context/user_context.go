@@ -15,6 +15,8 @@ type UserContext struct {
 	Tenant          string
 	Audiences       []string
 	Issuer          string
+	Scope           string
+	ClientID        string
 	ValidatedClaims []string
 	Roles           []string
 }
@@ -0,0 +1,292 @@
+package identity
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/cjlapao/common-go-identity/authorization_context"
+	"github.com/cjlapao/common-go-identity/models"
+)
+
+const testCodeVerifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk-test-verifier"
+
+func testCodeChallenge(verifier string) string {
+	hash := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+func authorizeForm(overrides map[string]string) url.Values {
+	form := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {"spa"},
+		"redirect_uri":          {testRedirectUri},
+		"state":                 {"af0ifjsldkj"},
+		"nonce":                 {"n-0S6_WzA2Mj"},
+		"code_challenge":        {testCodeChallenge(testCodeVerifier)},
+		"code_challenge_method": {"S256"},
+		"username":              {testAdminUsername},
+		"password":              {testAdminPassword},
+	}
+	for key, value := range overrides {
+		if value == "" {
+			form.Del(key)
+		} else {
+			form.Set(key, value)
+		}
+	}
+
+	return form
+}
+
+func requestAuthorizationCode(t *testing.T, form url.Values) *url.URL {
+	t.Helper()
+	server := getTestServer(t)
+	response, err := newTestClient().PostForm(server.URL+"/auth/authorize", form)
+	if err != nil {
+		t.Fatalf("authorize request failed, %v", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusFound {
+		t.Fatalf("expected status %v, got %v", http.StatusFound, response.StatusCode)
+	}
+
+	location, err := url.Parse(response.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("invalid redirect location, %v", err)
+	}
+
+	return location
+}
+
+func requestToken(t *testing.T, form url.Values) (int, map[string]interface{}) {
+	t.Helper()
+	server := getTestServer(t)
+	response, err := newTestClient().PostForm(server.URL+"/auth/token", form)
+	if err != nil {
+		t.Fatalf("token request failed, %v", err)
+	}
+	defer response.Body.Close()
+
+	var body map[string]interface{}
+	json.NewDecoder(response.Body).Decode(&body)
+	return response.StatusCode, body
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	location := requestAuthorizationCode(t, authorizeForm(nil))
+
+	if !strings.HasPrefix(location.String(), testRedirectUri) {
+		t.Fatalf("expected redirect to %v, got %v", testRedirectUri, location.String())
+	}
+	if got := location.Query().Get("state"); got != "af0ifjsldkj" {
+		t.Errorf("expected state to be echoed, got %v", got)
+	}
+	code := location.Query().Get("code")
+	if code == "" {
+		t.Fatalf("expected a code in the redirect, got %v", location.String())
+	}
+
+	tokenForm := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"client_id":     {"spa"},
+		"redirect_uri":  {testRedirectUri},
+		"code_verifier": {testCodeVerifier},
+	}
+
+	status, body := requestToken(t, tokenForm)
+	if status != http.StatusOK {
+		t.Fatalf("expected status %v, got %v %v", http.StatusOK, status, body)
+	}
+	if body["access_token"] == nil || body["access_token"] == "" {
+		t.Errorf("expected an access token, got %v", body)
+	}
+	if body["token_type"] != "Bearer" {
+		t.Errorf("expected a Bearer token type, got %v", body["token_type"])
+	}
+
+	// codes are single use
+	status, body = requestToken(t, tokenForm)
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected status %v when reusing a code, got %v", http.StatusBadRequest, status)
+	}
+	if body["error"] != models.OAuthInvalidGrant.String() {
+		t.Errorf("expected error %v, got %v", models.OAuthInvalidGrant.String(), body["error"])
+	}
+}
+
+func TestAuthorizationCodeFlowExchangeErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string]string
+	}{
+		{"wrong verifier", map[string]string{"code_verifier": "this-is-not-the-verifier-that-was-used-for-the-challenge"}},
+		{"missing verifier", map[string]string{"code_verifier": ""}},
+		{"wrong redirect uri", map[string]string{"redirect_uri": "https://app.example.com/other"}},
+		{"wrong client", map[string]string{"client_id": "another-app"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			location := requestAuthorizationCode(t, authorizeForm(nil))
+			tokenForm := url.Values{
+				"grant_type":    {"authorization_code"},
+				"code":          {location.Query().Get("code")},
+				"client_id":     {"spa"},
+				"redirect_uri":  {testRedirectUri},
+				"code_verifier": {testCodeVerifier},
+			}
+			for key, value := range tt.overrides {
+				if value == "" {
+					tokenForm.Del(key)
+				} else {
+					tokenForm.Set(key, value)
+				}
+			}
+
+			status, body := requestToken(t, tokenForm)
+			if status != http.StatusBadRequest {
+				t.Fatalf("expected status %v, got %v", http.StatusBadRequest, status)
+			}
+			if body["error"] != models.OAuthInvalidGrant.String() {
+				t.Errorf("expected error %v, got %v", models.OAuthInvalidGrant.String(), body["error"])
+			}
+		})
+	}
+}
+
+func TestAuthorizationCodeFlowDefaultChallengeMethod(t *testing.T) {
+	location := requestAuthorizationCode(t, authorizeForm(map[string]string{"code_challenge_method": ""}))
+
+	status, body := requestToken(t, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {location.Query().Get("code")},
+		"client_id":     {"spa"},
+		"redirect_uri":  {testRedirectUri},
+		"code_verifier": {testCodeVerifier},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("expected status %v, got %v %v", http.StatusOK, status, body)
+	}
+}
+
+func TestAuthorizeRequestErrors(t *testing.T) {
+	server := getTestServer(t)
+	client := newTestClient()
+
+	t.Run("untrusted redirect uri is not followed", func(t *testing.T) {
+		response, err := client.PostForm(server.URL+"/auth/authorize", authorizeForm(map[string]string{"redirect_uri": "https://evil.example.com/callback"}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("expected status %v, got %v", http.StatusBadRequest, response.StatusCode)
+		}
+	})
+
+	t.Run("unregistered client is not redirected to", func(t *testing.T) {
+		response, err := client.PostForm(server.URL+"/auth/authorize", authorizeForm(map[string]string{"client_id": "unregistered-app"}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("expected status %v, got %v", http.StatusBadRequest, response.StatusCode)
+		}
+	})
+
+	tests := []struct {
+		name      string
+		overrides map[string]string
+		error     models.OAuthErrorType
+	}{
+		{"missing challenge", map[string]string{"code_challenge": ""}, models.OAuthInvalidRequestError},
+		{"unsupported challenge method", map[string]string{"code_challenge_method": "S512"}, models.OAuthInvalidRequestError},
+		{"plain challenge method", map[string]string{"code_challenge_method": "plain"}, models.OAuthInvalidRequestError},
+		{"unsupported response type", map[string]string{"response_type": "token"}, models.OAuthUnsupportedResponseType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			location := requestAuthorizationCode(t, authorizeForm(tt.overrides))
+			if got := location.Query().Get("error"); got != tt.error.String() {
+				t.Errorf("expected error %v, got %v", tt.error.String(), got)
+			}
+			if got := location.Query().Get("state"); got != "af0ifjsldkj" {
+				t.Errorf("expected state to be echoed, got %v", got)
+			}
+			if location.Query().Get("code") != "" {
+				t.Errorf("expected no code in the redirect")
+			}
+		})
+	}
+}
+
+func TestAuthorizeLoginPage(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string]string
+		error     string
+	}{
+		{"invalid credentials", map[string]string{"password": "wrong"}, models.OAuthInvalidClientError.String()},
+		{"no credentials", map[string]string{"username": "", "password": ""}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			location := requestAuthorizationCode(t, authorizeForm(tt.overrides))
+			if !strings.HasPrefix(location.String(), testLoginUrl) {
+				t.Fatalf("expected a redirect to the login page, got %v", location.String())
+			}
+			if got := location.Query().Get("error"); got != tt.error {
+				t.Errorf("expected error %v, got %v", tt.error, got)
+			}
+			if got := location.Query().Get("state"); got != "af0ifjsldkj" {
+				t.Errorf("expected state to be kept for the login page, got %v", got)
+			}
+		})
+	}
+
+	// without a login page the clients cannot post the credentials of the users
+	authCtx := authorization_context.GetBaseContext()
+	authCtx.Options.LoginUrl = ""
+	t.Cleanup(func() { authCtx.Options.LoginUrl = testLoginUrl })
+
+	location := requestAuthorizationCode(t, authorizeForm(nil))
+	if got := location.Query().Get("error"); got != models.OAuthInvalidRequestError.String() || location.Query().Get("code") != "" {
+		t.Errorf("expected the posted credentials to be rejected, got %v", location.String())
+	}
+}
+
+func TestAuthorizeWithBearerToken(t *testing.T) {
+	server := getTestServer(t)
+	status, body := requestToken(t, url.Values{
+		"grant_type": {"password"},
+		"username":   {testAdminUsername},
+		"password":   {testAdminPassword},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("expected status %v, got %v %v", http.StatusOK, status, body)
+	}
+
+	form := authorizeForm(map[string]string{"username": "", "password": ""})
+	request, _ := http.NewRequest(http.MethodGet, server.URL+"/auth/authorize?"+form.Encode(), nil)
+	request.Header.Set("Authorization", "Bearer "+body["access_token"].(string))
+	response, err := newTestClient().Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	location, _ := url.Parse(response.Header.Get("Location"))
+	if response.StatusCode != http.StatusFound || location.Query().Get("code") == "" {
+		t.Fatalf("expected a code redirect, got %v %v", response.StatusCode, location)
+	}
+}
@@ -0,0 +1,212 @@
+package identity
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/cjlapao/common-go-identity/authorization_context"
+	"github.com/cjlapao/common-go-identity/jwt"
+	"github.com/cjlapao/common-go-identity/mappers"
+	"github.com/cjlapao/common-go-identity/models"
+	"github.com/cjlapao/common-go/security"
+)
+
+const (
+	testClientId     = "backend-service"
+	testClientSecret = "backend-service-secret"
+)
+
+func addTestClient(t *testing.T, client models.Client) {
+	t.Helper()
+	getTestServer(t)
+	authCtx := authorization_context.GetBaseContext()
+	if err := authCtx.ClientDatabaseAdapter.UpsertClient(mappers.ToClientDTO(client)); err != nil {
+		t.Fatalf("unable to add client %v, %v", client.ID, err)
+	}
+}
+
+func addConfidentialTestClient(t *testing.T) {
+	t.Helper()
+	client := models.NewClient()
+	client.ID = testClientId
+	client.Name = "Backend Service"
+	client.Secret = security.SHA256Encode(testClientSecret)
+	client.GrantTypes = []string{"client_credentials"}
+	client.Scopes = []string{"orders.read", "orders.write"}
+	addTestClient(t, *client)
+}
+
+func requestTokenWithBasicAuth(t *testing.T, form url.Values, clientId string, clientSecret string) (int, map[string]interface{}) {
+	t.Helper()
+	server := getTestServer(t)
+	request, _ := http.NewRequest(http.MethodPost, server.URL+"/auth/token", strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.SetBasicAuth(url.QueryEscape(clientId), url.QueryEscape(clientSecret))
+	response, err := newTestClient().Do(request)
+	if err != nil {
+		t.Fatalf("token request failed, %v", err)
+	}
+	defer response.Body.Close()
+
+	var body map[string]interface{}
+	json.NewDecoder(response.Body).Decode(&body)
+	return response.StatusCode, body
+}
+
+func TestClientCredentialsGrant(t *testing.T) {
+	addConfidentialTestClient(t)
+
+	status, body := requestTokenWithBasicAuth(t, url.Values{
+		"grant_type": {"client_credentials"},
+		"scope":      {"orders.read"},
+	}, testClientId, testClientSecret)
+	if status != http.StatusOK {
+		t.Fatalf("expected status %v, got %v, %v", http.StatusOK, status, body)
+	}
+	if body["scope"] != "orders.read" {
+		t.Errorf("expected granted scope orders.read, got %v", body["scope"])
+	}
+	if refreshToken, ok := body["refresh_token"].(string); ok && refreshToken != "" {
+		t.Errorf("expected no refresh token for the client credentials grant")
+	}
+
+	token, err := jwt.ValidateUserToken(body["access_token"].(string), authorization_context.New())
+	if err != nil {
+		t.Fatalf("expected the client token to be valid, %v", err)
+	}
+	if token.User != testClientId || token.ClientID != testClientId {
+		t.Errorf("expected subject and client id %v, got %v and %v", testClientId, token.User, token.ClientID)
+	}
+	if !jwt.HasScope(token.Scope, "orders.read") || jwt.HasScope(token.Scope, "orders.write") {
+		t.Errorf("unexpected token scope %v", token.Scope)
+	}
+
+	// without a configured scope no token is accepted as an access token
+	if jwt.HasScope(token.Scope, "") {
+		t.Errorf("expected an empty scope not to be contained in %v", token.Scope)
+	}
+	authCtx := authorization_context.New()
+	authCtx.Scope = ""
+	if _, err := jwt.ValidateUserToken(body["access_token"].(string), authCtx); err == nil {
+		t.Errorf("expected the token to be rejected without a configured scope")
+	}
+}
+
+func TestClientCredentialsGrantWithPostAuthentication(t *testing.T) {
+	addConfidentialTestClient(t)
+
+	// the client is registered for client_secret_basic so the post method is rejected
+	status, body := requestToken(t, url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {testClientId},
+		"client_secret": {testClientSecret},
+	})
+	if status != http.StatusUnauthorized || body["error"] != "invalid_client" {
+		t.Fatalf("expected invalid_client, got %v %v", status, body)
+	}
+
+	client := models.NewClient()
+	client.ID = "post-service"
+	client.Secret = security.SHA256Encode(testClientSecret)
+	client.GrantTypes = []string{"client_credentials"}
+	client.Scopes = []string{"orders.read", "orders.write"}
+	client.TokenEndpointAuthMethod = models.ClientSecretPostAuthMethod
+	addTestClient(t, *client)
+
+	status, body = requestToken(t, url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {"post-service"},
+		"client_secret": {testClientSecret},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("expected status %v, got %v, %v", http.StatusOK, status, body)
+	}
+	if body["scope"] != "orders.read orders.write" {
+		t.Errorf("expected all the client scopes to be granted, got %v", body["scope"])
+	}
+}
+
+func TestClientCredentialsGrantErrors(t *testing.T) {
+	addConfidentialTestClient(t)
+
+	publicClient := models.NewClient()
+	publicClient.ID = "public-app"
+	publicClient.GrantTypes = []string{"client_credentials"}
+	publicClient.TokenEndpointAuthMethod = models.NoneAuthMethod
+	addTestClient(t, *publicClient)
+
+	tests := []struct {
+		name          string
+		form          url.Values
+		clientId      string
+		clientSecret  string
+		expectedError string
+		expectedCode  int
+	}{
+		{"wrong secret", url.Values{}, testClientId, "wrong", "invalid_client", http.StatusUnauthorized},
+		{"unknown client", url.Values{}, "unknown", testClientSecret, "invalid_client", http.StatusUnauthorized},
+		{"scope not allowed", url.Values{"scope": {"orders.delete"}}, testClientId, testClientSecret, "invalid_scope", http.StatusBadRequest},
+		{"two authentication methods", url.Values{"client_secret": {testClientSecret}}, testClientId, testClientSecret, "invalid_request", http.StatusBadRequest},
+		{"public client", url.Values{"client_id": {"public-app"}}, "", "", "unauthorized_client", http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.form.Set("grant_type", "client_credentials")
+			var status int
+			var body map[string]interface{}
+			if test.clientId != "" {
+				status, body = requestTokenWithBasicAuth(t, test.form, test.clientId, test.clientSecret)
+			} else {
+				status, body = requestToken(t, test.form)
+			}
+			if status != test.expectedCode || body["error"] != test.expectedError {
+				t.Errorf("expected %v %v, got %v %v", test.expectedCode, test.expectedError, status, body)
+			}
+		})
+	}
+}
+
+func TestPasswordGrantClientAuthentication(t *testing.T) {
+	addConfidentialTestClient(t)
+
+	tests := []struct {
+		name          string
+		form          url.Values
+		expectedError string
+		expectedCode  int
+	}{
+		{"unregistered client", url.Values{"client_id": {"unregistered-app"}}, "invalid_client", http.StatusUnauthorized},
+		{"confidential client without secret", url.Values{"client_id": {testClientId}}, "invalid_client", http.StatusUnauthorized},
+		{"confidential client with wrong secret", url.Values{"client_id": {testClientId}, "client_secret": {"wrong"}}, "invalid_client", http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.form.Set("grant_type", "password")
+			test.form.Set("username", testAdminUsername)
+			test.form.Set("password", testAdminPassword)
+			status, body := requestToken(t, test.form)
+			if status != test.expectedCode || body["error"] != test.expectedError {
+				t.Errorf("expected %v %v, got %v %v", test.expectedCode, test.expectedError, status, body)
+			}
+		})
+	}
+
+	// the token is only issued to a client that authenticated
+	status, body := requestTokenWithBasicAuth(t, url.Values{
+		"grant_type": {"password"},
+		"username":   {testAdminUsername},
+		"password":   {testAdminPassword},
+	}, testClientId, testClientSecret)
+	if status != http.StatusOK {
+		t.Fatalf("expected status %v, got %v, %v", http.StatusOK, status, body)
+	}
+	token, err := jwt.ValidateUserToken(body["access_token"].(string), authorization_context.New())
+	if err != nil || token.ClientID != testClientId {
+		t.Errorf("expected a token for client %v, got %v %v", testClientId, token, err)
+	}
+}
@@ -0,0 +1,178 @@
+package identity
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/cjlapao/common-go-identity/models"
+)
+
+func sendRegistrationRequest(t *testing.T, method string, path string, bearer string, body interface{}) (int, map[string]interface{}) {
+	t.Helper()
+	server := getTestServer(t)
+	var payload bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&payload).Encode(body)
+	}
+
+	request, _ := http.NewRequest(method, server.URL+path, &payload)
+	request.Header.Set("Content-Type", "application/json")
+	if bearer != "" {
+		request.Header.Set("Authorization", "Bearer "+bearer)
+	}
+
+	response, err := newTestClient().Do(request)
+	if err != nil {
+		t.Fatalf("registration request failed, %v", err)
+	}
+	defer response.Body.Close()
+
+	var result map[string]interface{}
+	json.NewDecoder(response.Body).Decode(&result)
+	return response.StatusCode, result
+}
+
+func TestClientRegistration(t *testing.T) {
+	status, body := sendRegistrationRequest(t, http.MethodPost, "/auth/tenant-a/register/clients", testInitialAccessToken, models.OAuthClientRegistrationRequest{
+		ClientName: "Orders Worker",
+		GrantTypes: []string{"client_credentials"},
+		Scope:      "orders.read",
+	})
+	if status != http.StatusCreated {
+		t.Fatalf("expected status %v, got %v %v", http.StatusCreated, status, body)
+	}
+
+	clientId, _ := body["client_id"].(string)
+	clientSecret, _ := body["client_secret"].(string)
+	registrationAccessToken, _ := body["registration_access_token"].(string)
+	if clientId == "" || clientSecret == "" || registrationAccessToken == "" {
+		t.Fatalf("expected client id, secret and registration access token, got %v", body)
+	}
+	if body["token_endpoint_auth_method"] != models.ClientSecretBasicAuthMethod {
+		t.Errorf("expected the default auth method, got %v", body["token_endpoint_auth_method"])
+	}
+
+	// the registered client can get tokens straight away
+	status, body = requestTokenWithBasicAuth(t, url.Values{"grant_type": {"client_credentials"}}, clientId, clientSecret)
+	if status != http.StatusOK || body["scope"] != "orders.read" {
+		t.Fatalf("expected a token for the registered client, got %v %v", status, body)
+	}
+
+	clientPath := "/auth/tenant-a/register/clients/" + clientId
+	status, body = sendRegistrationRequest(t, http.MethodGet, clientPath, registrationAccessToken, nil)
+	if status != http.StatusOK || body["client_id"] != clientId {
+		t.Fatalf("expected the client metadata, got %v %v", status, body)
+	}
+	if _, ok := body["client_secret"]; ok {
+		t.Errorf("expected the secret not to be returned when reading the client")
+	}
+
+	status, body = sendRegistrationRequest(t, http.MethodPut, clientPath, registrationAccessToken, models.OAuthClientRegistrationRequest{
+		ClientID:   clientId,
+		ClientName: "Orders Worker",
+		GrantTypes: []string{"client_credentials"},
+		Scope:      "orders.read orders.write",
+	})
+	if status != http.StatusOK || body["scope"] != "orders.read orders.write" {
+		t.Fatalf("expected the client to be updated, got %v %v", status, body)
+	}
+
+	// the client cannot widen its own grants outside the self registration allow list
+	status, body = sendRegistrationRequest(t, http.MethodPut, clientPath, registrationAccessToken, models.OAuthClientRegistrationRequest{
+		ClientID:   clientId,
+		GrantTypes: []string{"client_credentials", models.OAuthPasswordGrant.String()},
+		Scope:      "orders.read",
+	})
+	if status != http.StatusBadRequest || body["error"] != "invalid_client_metadata" {
+		t.Fatalf("expected a grant outside the allow list to be rejected, got %v %v", status, body)
+	}
+
+	// the client belongs to tenant-a
+	status, _ = sendRegistrationRequest(t, http.MethodGet, "/auth/tenant-b/register/clients/"+clientId, registrationAccessToken, nil)
+	if status != http.StatusNotFound {
+		t.Errorf("expected status %v for another tenant, got %v", http.StatusNotFound, status)
+	}
+
+	status, _ = sendRegistrationRequest(t, http.MethodGet, clientPath, "not-the-registration-token", nil)
+	if status != http.StatusUnauthorized {
+		t.Errorf("expected status %v with an invalid registration token, got %v", http.StatusUnauthorized, status)
+	}
+
+	status, _ = sendRegistrationRequest(t, http.MethodDelete, clientPath, registrationAccessToken, nil)
+	if status != http.StatusNoContent {
+		t.Fatalf("expected status %v when removing the client, got %v", http.StatusNoContent, status)
+	}
+
+	status, body = requestTokenWithBasicAuth(t, url.Values{"grant_type": {"client_credentials"}}, clientId, clientSecret)
+	if status != http.StatusUnauthorized {
+		t.Errorf("expected removed client to be rejected, got %v %v", status, body)
+	}
+}
+
+func TestClientRegistrationErrors(t *testing.T) {
+	tests := []struct {
+		name           string
+		bearer         string
+		request        models.OAuthClientRegistrationRequest
+		expectedStatus int
+		expectedError  string
+	}{
+		{"no credentials", "", models.OAuthClientRegistrationRequest{GrantTypes: []string{"client_credentials"}}, http.StatusUnauthorized, ""},
+		{"wrong initial access token", "wrong-token", models.OAuthClientRegistrationRequest{GrantTypes: []string{"client_credentials"}}, http.StatusUnauthorized, ""},
+		{"missing redirect uris", testInitialAccessToken, models.OAuthClientRegistrationRequest{}, http.StatusBadRequest, "invalid_redirect_uri"},
+		{"redirect uri with fragment", testInitialAccessToken, models.OAuthClientRegistrationRequest{RedirectUris: []string{"https://app.example.com/cb#fragment"}}, http.StatusBadRequest, "invalid_redirect_uri"},
+		{"unsupported grant", testInitialAccessToken, models.OAuthClientRegistrationRequest{GrantTypes: []string{"implicit"}}, http.StatusBadRequest, "invalid_client_metadata"},
+		{"public client credentials", testInitialAccessToken, models.OAuthClientRegistrationRequest{GrantTypes: []string{"client_credentials"}, TokenEndpointAuthMethod: "none"}, http.StatusBadRequest, "invalid_client_metadata"},
+		{"grant outside the self registration grants", testInitialAccessToken, models.OAuthClientRegistrationRequest{GrantTypes: []string{"password"}}, http.StatusBadRequest, "invalid_client_metadata"},
+		{"scope outside the self registration scopes", testInitialAccessToken, models.OAuthClientRegistrationRequest{GrantTypes: []string{"client_credentials"}, Scope: "orders.read users.admin"}, http.StatusBadRequest, "invalid_client_metadata"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status, body := sendRegistrationRequest(t, http.MethodPost, "/auth/register/clients", test.bearer, test.request)
+			if status != test.expectedStatus {
+				t.Fatalf("expected status %v, got %v %v", test.expectedStatus, status, body)
+			}
+			if test.expectedError != "" && body["error"] != test.expectedError {
+				t.Errorf("expected error %v, got %v", test.expectedError, body["error"])
+			}
+		})
+	}
+}
+
+func TestRegisteredClientRedirectUris(t *testing.T) {
+	status, body := sendRegistrationRequest(t, http.MethodPost, "/auth/register/clients", testInitialAccessToken, models.OAuthClientRegistrationRequest{
+		ClientName:              "Single Page App",
+		RedirectUris:            []string{"https://spa.example.com/callback"},
+		TokenEndpointAuthMethod: models.NoneAuthMethod,
+	})
+	if status != http.StatusCreated {
+		t.Fatalf("expected status %v, got %v %v", http.StatusCreated, status, body)
+	}
+	clientId := body["client_id"].(string)
+	if _, ok := body["client_secret"]; ok {
+		t.Errorf("expected no secret for a public client")
+	}
+
+	location := requestAuthorizationCode(t, authorizeForm(map[string]string{
+		"client_id":    clientId,
+		"redirect_uri": "https://spa.example.com/callback",
+	}))
+	if location.Query().Get("code") == "" {
+		t.Fatalf("expected a code for the registered redirect uri, got %v", location.String())
+	}
+
+	// the global redirect uris are not valid for a client with its own redirect uris
+	server := getTestServer(t)
+	response, err := newTestClient().PostForm(server.URL+"/auth/authorize", authorizeForm(map[string]string{"client_id": clientId}))
+	if err != nil {
+		t.Fatalf("authorize request failed, %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status %v, got %v", http.StatusBadRequest, response.StatusCode)
+	}
+}
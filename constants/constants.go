@@ -1,12 +1,48 @@
 package constants
 
 const (
-	IdentityUsersCollection      = "Identity.Users"
-	IdentityUserRolesCollection  = "Identity.UserRoles"
-	IdentityUserClaimsCollection = "Identity.UserClaims"
-	IdentityTenantCollection     = "Identity.Tenants"
-	PasswordScope                = "password"
-	RefreshTokenScope            = "refresh_token"
-	EmailVerificationScope       = "verify_email"
-	PasswordRecoveryScope        = "password_recovery"
+	IdentityUsersCollection               = "Identity.Users"
+	IdentityUserRolesCollection           = "Identity.UserRoles"
+	IdentityUserClaimsCollection          = "Identity.UserClaims"
+	IdentityTenantCollection              = "Identity.Tenants"
+	IdentityClientsCollection             = "Identity.Clients"
+	IdentityUserTotpCollection            = "Identity.UserTotp"
+	IdentityUserRecoveryCodesCollection   = "Identity.UserRecoveryCodes"
+	IdentityWebAuthnCredentialsCollection = "Identity.WebAuthnCredentials"
+	IdentityWebAuthnSessionsCollection    = "Identity.WebAuthnSessions"
+	IdentityUserLoginCodesCollection      = "Identity.UserLoginCodes"
+	IdentitySigningKeysCollection         = "Identity.SigningKeys"
+	IdentityRevokedTokensCollection       = "Identity.RevokedTokens"
+	IdentityUserRevocationsCollection     = "Identity.UserTokenRevocations"
+	PasswordScope                         = "password"
+	RefreshTokenScope                     = "refresh_token"
+	EmailVerificationScope                = "verify_email"
+	PasswordRecoveryScope                 = "password_recovery"
+	MfaScope                              = "mfa"
+	WebAuthnRegistrationScope             = "webauthn_registration"
+	WebAuthnLoginScope                    = "webauthn_login"
+	LoginLinkScope                        = "login_link"
+)
+
+// OpenID Connect scopes
+const (
+	OpenIdScope  = "openid"
+	ProfileScope = "profile"
+	EmailScope   = "email"
+	RolesScope   = "roles"
+)
+
+// UserInfoScopes are the scopes that select the user claims, they are kept in the access token
+// so the userinfo endpoint can release the same claims
+var UserInfoScopes = []string{OpenIdScope, ProfileScope, EmailScope, RolesScope}
+
+// Authentication methods references (RFC 8176) and context classes of the id tokens
+const (
+	AmrPassword     = "pwd"
+	AmrOneTimeCode  = "otp"
+	AmrMultiFactor  = "mfa"
+	AmrHardwareKey  = "hwk"
+	AmrUserPresence = "user"
+	AcrSingleFactor = "1"
+	AcrMultiFactor  = "2"
 )
@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/cjlapao/common-go-identity/constants"
+	"github.com/cjlapao/common-go-identity/jwt"
+	"github.com/cjlapao/common-go-identity/models"
+	"github.com/cjlapao/common-go-identity/oauthflow"
+	"github.com/cjlapao/common-go-restapi/controllers"
+	"github.com/cjlapao/common-go/helper/http_helper"
+)
+
+// Authorize Issues an authorization code to the redirect uri, the user can authenticate
+// by presenting a valid bearer token or by posting its credentials from the configured login page
+func (c *AuthorizationControllers) Authorize() controllers.Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := NewBaseContext(r)
+		r.ParseForm()
+		authorizeRequest := models.OAuthAuthorizeRequest{
+			ResponseType:        r.FormValue("response_type"),
+			ClientID:            r.FormValue("client_id"),
+			RedirectUri:         r.FormValue("redirect_uri"),
+			Scope:               r.FormValue("scope"),
+			State:               r.FormValue("state"),
+			Nonce:               r.FormValue("nonce"),
+			CodeChallenge:       r.FormValue("code_challenge"),
+			CodeChallengeMethod: r.FormValue("code_challenge_method"),
+			Username:            r.PostFormValue("username"),
+		}
+		password := r.PostFormValue("password")
+		otp := r.PostFormValue("otp")
+
+		flow := oauthflow.AuthorizationCodeGrantFlow{}
+		trustedRedirect, errorResponse := flow.ValidateAuthorizeRequest(&authorizeRequest)
+		if errorResponse != nil {
+			ctx.NotifyError(models.AuthorizationRequest, errorResponse, authorizeRequest)
+			if trustedRedirect {
+				redirectAuthorizeError(w, r, authorizeRequest, errorResponse)
+				return
+			}
+
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(*errorResponse)
+			return
+		}
+
+		var user *models.User
+		var amr []string
+		if authorizeRequest.Username != "" {
+			// clients never collect the credentials, only the login page posts them back
+			if ctx.AuthorizationContext.Options.LoginUrl == "" {
+				errorResponse := models.NewOAuthErrorResponse(models.OAuthInvalidRequestError, "Credentials can only be posted by the login page")
+				ctx.NotifyError(models.AuthorizationRequest, &errorResponse, authorizeRequest)
+				redirectAuthorizeError(w, r, authorizeRequest, &errorResponse)
+				return
+			}
+
+			user, errorResponse = oauthflow.PasswordGrantFlow{}.ValidateCredentials(authorizeRequest.Username, password)
+			if errorResponse == nil {
+				errorResponse = oauthflow.ValidateMfa(user, otp)
+			}
+			if errorResponse != nil {
+				ctx.NotifyError(models.AuthorizationRequest, errorResponse, authorizeRequest)
+				redirectToLogin(w, r, ctx.AuthorizationContext.Options.LoginUrl, authorizeRequest, errorResponse)
+				return
+			}
+
+			amr = []string{constants.AmrPassword}
+			if ctx.UserManager.IsTotpEnabled(user.ID) {
+				amr = append(amr, constants.AmrOneTimeCode, constants.AmrMultiFactor)
+			}
+		} else if token, valid := http_helper.GetAuthorizationToken(r.Header); valid {
+			// the subject of an external issuer is not one of our users even if it matches the email of one
+			userToken, err := jwt.ValidateUserToken(token, ctx.AuthorizationContext)
+			if err == nil && userToken != nil && !ctx.AuthorizationContext.TrustedIssuers.IsTrusted(userToken.Issuer) {
+				user = ctx.UserManager.GetUserByEmail(userToken.User)
+			}
+		}
+
+		if user == nil || user.ID == "" {
+			errorResponse := models.NewOAuthErrorResponse(models.OAuthLoginRequired, "User needs to authenticate")
+			ctx.NotifyError(models.AuthorizationRequest, &errorResponse, authorizeRequest)
+			if ctx.AuthorizationContext.Options.LoginUrl != "" {
+				redirectToLogin(w, r, ctx.AuthorizationContext.Options.LoginUrl, authorizeRequest, nil)
+				return
+			}
+
+			redirectAuthorizeError(w, r, authorizeRequest, &errorResponse)
+			return
+		}
+
+		authorizationCode, errorResponse := flow.Authorize(&authorizeRequest, user, ctx.TenantID, amr...)
+		if errorResponse != nil {
+			ctx.NotifyError(models.AuthorizationRequest, errorResponse, authorizeRequest)
+			redirectAuthorizeError(w, r, authorizeRequest, errorResponse)
+			return
+		}
+
+		redirectUri, _ := url.Parse(authorizeRequest.RedirectUri)
+		query := redirectUri.Query()
+		query.Set("code", authorizationCode.Code)
+		if authorizeRequest.State != "" {
+			query.Set("state", authorizeRequest.State)
+		}
+		query.Set("iss", ctx.AuthorizationContext.Issuer)
+		redirectUri.RawQuery = query.Encode()
+
+		ctx.NotifySuccess(models.AuthorizationRequest, authorizeRequest)
+		http.Redirect(w, r, redirectUri.String(), http.StatusFound)
+	}
+}
+
+func redirectAuthorizeError(w http.ResponseWriter, r *http.Request, request models.OAuthAuthorizeRequest, errorResponse *models.OAuthErrorResponse) {
+	redirectUri, err := url.Parse(request.RedirectUri)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(*errorResponse)
+		return
+	}
+
+	query := redirectUri.Query()
+	query.Set("error", errorResponse.Error.String())
+	if errorResponse.ErrorDescription != "" {
+		query.Set("error_description", errorResponse.ErrorDescription)
+	}
+	if request.State != "" {
+		query.Set("state", request.State)
+	}
+	redirectUri.RawQuery = query.Encode()
+
+	http.Redirect(w, r, redirectUri.String(), http.StatusFound)
+}
+
+func redirectToLogin(w http.ResponseWriter, r *http.Request, loginUrl string, request models.OAuthAuthorizeRequest, errorResponse *models.OAuthErrorResponse) {
+	redirectUri, err := url.Parse(loginUrl)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrException)
+		return
+	}
+
+	query := redirectUri.Query()
+	query.Set("response_type", request.ResponseType)
+	query.Set("client_id", request.ClientID)
+	query.Set("redirect_uri", request.RedirectUri)
+	query.Set("code_challenge", request.CodeChallenge)
+	query.Set("code_challenge_method", request.CodeChallengeMethod)
+	if request.Scope != "" {
+		query.Set("scope", request.Scope)
+	}
+	if request.State != "" {
+		query.Set("state", request.State)
+	}
+	if request.Nonce != "" {
+		query.Set("nonce", request.Nonce)
+	}
+	if errorResponse != nil {
+		query.Set("error", errorResponse.Error.String())
+	}
+	redirectUri.RawQuery = query.Encode()
+
+	http.Redirect(w, r, redirectUri.String(), http.StatusFound)
+}
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/cjlapao/common-go-identity/models"
+	"github.com/cjlapao/common-go-identity/oauthflow"
+)
+
+// getClientAuthentication reads the client credentials from the basic authorization header
+// or from the request body and returns the authentication method used by the client
+func getClientAuthentication(r *http.Request, loginRequest *models.OAuthLoginRequest) (string, *models.OAuthErrorResponse) {
+	clientId, clientSecret, hasBasicAuth := r.BasicAuth()
+	if hasBasicAuth {
+		// clients must only use one authentication method per request
+		if loginRequest.ClientSecret != "" {
+			errorResponse := models.NewOAuthErrorResponse(models.OAuthInvalidRequestError, "Client authenticated with more than one method")
+			return "", &errorResponse
+		}
+
+		// client credentials are form url encoded before being added to the header
+		if unescaped, err := url.QueryUnescape(clientId); err == nil {
+			clientId = unescaped
+		}
+		if unescaped, err := url.QueryUnescape(clientSecret); err == nil {
+			clientSecret = unescaped
+		}
+
+		if loginRequest.ClientID != "" && loginRequest.ClientID != clientId {
+			errorResponse := models.NewOAuthErrorResponse(models.OAuthInvalidRequestError, "Client id does not match the authorization header")
+			return "", &errorResponse
+		}
+
+		loginRequest.ClientID = clientId
+		loginRequest.ClientSecret = clientSecret
+		return models.ClientSecretBasicAuthMethod, nil
+	}
+
+	if loginRequest.ClientSecret != "" {
+		return models.ClientSecretPostAuthMethod, nil
+	}
+
+	return models.NoneAuthMethod, nil
+}
+
+// authenticateGrantClient authenticates the client of a user grant with the credentials of the
+// request, the grants issue tokens to the client so unregistered clients are rejected
+func authenticateGrantClient(r *http.Request, loginRequest *models.OAuthLoginRequest) *models.OAuthErrorResponse {
+	authenticationMethod, errorResponse := getClientAuthentication(r, loginRequest)
+	if errorResponse != nil {
+		return errorResponse
+	}
+
+	_, errorResponse = oauthflow.AuthenticateRegisteredClient(loginRequest.ClientID, loginRequest.ClientSecret, authenticationMethod)
+	return errorResponse
+}
+
+func hasBasicAuth(r *http.Request) bool {
+	_, _, ok := r.BasicAuth()
+	return ok
+}
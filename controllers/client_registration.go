@@ -0,0 +1,359 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	cryptorand "github.com/cjlapao/common-go-cryptorand"
+	"github.com/cjlapao/common-go-identity/mappers"
+	"github.com/cjlapao/common-go-identity/models"
+	"github.com/cjlapao/common-go-restapi/controllers"
+	"github.com/cjlapao/common-go/constants"
+	"github.com/cjlapao/common-go/helper/http_helper"
+	"github.com/cjlapao/common-go/security"
+	"github.com/gorilla/mux"
+)
+
+var supportedClientGrantTypes = []string{
+	models.OAuthPasswordGrant.String(),
+	models.OAuthAuthorizationCodeGrant.String(),
+	models.OAuthClientCredentialsGrant.String(),
+	models.OAuthRefreshTokenGrant.String(),
+	models.OAuthMfaOtpGrant.String(),
+	models.OAuthWebAuthnGrant.String(),
+	models.OAuthLoginCodeGrant.String(),
+	models.OAuthTokenExchangeGrant.String(),
+}
+
+var supportedClientAuthMethods = []string{
+	models.ClientSecretBasicAuthMethod,
+	models.ClientSecretPostAuthMethod,
+	models.NoneAuthMethod,
+}
+
+var supportedAccessTokenFormats = []string{
+	models.JwtAccessTokenFormat,
+	models.OpaqueAccessTokenFormat,
+}
+
+// RegisterClient Registers a new OAuth client in the tenant as defined in RFC 7591
+func (c *AuthorizationControllers) RegisterClient() controllers.Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := NewBaseContext(r)
+		var registrationRequest models.OAuthClientRegistrationRequest
+		ctx.MapRequestBody(&registrationRequest)
+
+		client := models.NewClient()
+		client.TenantID = ctx.TenantID
+		client.IssuedAt = time.Now()
+		errorResponse := applyClientMetadata(client, registrationRequest)
+		if errorResponse == nil {
+			errorResponse = validateSelfRegistration(ctx, *client)
+		}
+		if errorResponse != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			ctx.NotifyError(models.ClientRegistrationRequest, errorResponse, registrationRequest)
+			json.NewEncoder(w).Encode(*errorResponse)
+			return
+		}
+
+		clientId, err := cryptorand.GetRandomString(32)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			ctx.NotifyError(models.ClientRegistrationRequest, &ErrException, registrationRequest)
+			json.NewEncoder(w).Encode(ErrException)
+			return
+		}
+		client.ID = clientId
+
+		clientSecret := ""
+		if client.IsConfidential() {
+			clientSecret, err = cryptorand.GetRandomString(constants.ID_SIZE)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				ctx.NotifyError(models.ClientRegistrationRequest, &ErrException, registrationRequest)
+				json.NewEncoder(w).Encode(ErrException)
+				return
+			}
+			client.Secret = security.SHA256Encode(clientSecret)
+		}
+
+		registrationAccessToken, err := cryptorand.GetRandomString(constants.ID_SIZE)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			ctx.NotifyError(models.ClientRegistrationRequest, &ErrException, registrationRequest)
+			json.NewEncoder(w).Encode(ErrException)
+			return
+		}
+		client.RegistrationAccessToken = security.SHA256Encode(registrationAccessToken)
+
+		if err := ctx.AuthorizationContext.ClientDatabaseAdapter.UpsertClient(mappers.ToClientDTO(*client)); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			ctx.Logger.Exception(err, "There was an error registering client %v", client.ID)
+			ctx.NotifyError(models.ClientRegistrationRequest, &ErrException, registrationRequest)
+			json.NewEncoder(w).Encode(ErrException)
+			return
+		}
+
+		response := newClientRegistrationResponse(ctx, r, *client)
+		response.ClientSecret = clientSecret
+		response.RegistrationAccessToken = registrationAccessToken
+
+		ctx.Logger.Success("Client %v was registered successfully in tenant %v", client.ID, client.TenantID)
+		ctx.NotifySuccess(models.ClientRegistrationRequest, response.ClientID)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// GetRegisteredClient Reads the metadata of a registered client as defined in RFC 7592
+func (c *AuthorizationControllers) GetRegisteredClient() controllers.Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := NewBaseContext(r)
+		client := getRegisteredClient(ctx, r)
+		if client == nil {
+			w.WriteHeader(http.StatusNotFound)
+			ctx.NotifyError(models.ClientManagementRequest, &ErrClientNotFound, mux.Vars(r)["clientId"])
+			json.NewEncoder(w).Encode(ErrClientNotFound)
+			return
+		}
+
+		ctx.NotifySuccess(models.ClientManagementRequest, client.ID)
+		json.NewEncoder(w).Encode(newClientRegistrationResponse(ctx, r, *client))
+	}
+}
+
+// UpdateRegisteredClient Replaces the metadata of a registered client as defined in RFC 7592
+func (c *AuthorizationControllers) UpdateRegisteredClient() controllers.Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := NewBaseContext(r)
+		client := getRegisteredClient(ctx, r)
+		if client == nil {
+			w.WriteHeader(http.StatusNotFound)
+			ctx.NotifyError(models.ClientManagementRequest, &ErrClientNotFound, mux.Vars(r)["clientId"])
+			json.NewEncoder(w).Encode(ErrClientNotFound)
+			return
+		}
+
+		var registrationRequest models.OAuthClientRegistrationRequest
+		ctx.MapRequestBody(&registrationRequest)
+
+		if registrationRequest.ClientID != client.ID {
+			w.WriteHeader(http.StatusBadRequest)
+			errorResponse := models.NewOAuthErrorResponse(models.OAuthInvalidRequestError, "client_id does not match the registered client")
+			ctx.NotifyError(models.ClientManagementRequest, &errorResponse, registrationRequest)
+			json.NewEncoder(w).Encode(errorResponse)
+			return
+		}
+
+		// the secret is optional but if sent it needs to be the current one
+		if registrationRequest.ClientSecret != "" && subtle.ConstantTimeCompare([]byte(security.SHA256Encode(registrationRequest.ClientSecret)), []byte(client.Secret)) != 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			errorResponse := models.NewOAuthErrorResponse(models.OAuthInvalidClientMetadata, "client_secret does not match the registered client")
+			ctx.NotifyError(models.ClientManagementRequest, &errorResponse, client.ID)
+			json.NewEncoder(w).Encode(errorResponse)
+			return
+		}
+
+		wasConfidential := client.IsConfidential()
+		errorResponse := applyClientMetadata(client, registrationRequest)
+		if errorResponse == nil {
+			errorResponse = validateSelfRegistration(ctx, *client)
+		}
+		if errorResponse != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			ctx.NotifyError(models.ClientManagementRequest, errorResponse, registrationRequest)
+			json.NewEncoder(w).Encode(*errorResponse)
+			return
+		}
+
+		// a public client that becomes confidential needs a secret
+		clientSecret := ""
+		if client.IsConfidential() && !wasConfidential {
+			var err error
+			clientSecret, err = cryptorand.GetRandomString(constants.ID_SIZE)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				ctx.NotifyError(models.ClientManagementRequest, &ErrException, client.ID)
+				json.NewEncoder(w).Encode(ErrException)
+				return
+			}
+			client.Secret = security.SHA256Encode(clientSecret)
+		} else if !client.IsConfidential() {
+			client.Secret = ""
+		}
+
+		if err := ctx.AuthorizationContext.ClientDatabaseAdapter.UpsertClient(mappers.ToClientDTO(*client)); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			ctx.Logger.Exception(err, "There was an error updating client %v", client.ID)
+			ctx.NotifyError(models.ClientManagementRequest, &ErrException, client.ID)
+			json.NewEncoder(w).Encode(ErrException)
+			return
+		}
+
+		response := newClientRegistrationResponse(ctx, r, *client)
+		response.ClientSecret = clientSecret
+
+		ctx.Logger.Success("Client %v was updated successfully", client.ID)
+		ctx.NotifySuccess(models.ClientManagementRequest, client.ID)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// RemoveRegisteredClient Removes a registered client as defined in RFC 7592
+func (c *AuthorizationControllers) RemoveRegisteredClient() controllers.Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := NewBaseContext(r)
+		client := getRegisteredClient(ctx, r)
+		if client == nil || !ctx.AuthorizationContext.ClientDatabaseAdapter.RemoveClient(client.ID) {
+			w.WriteHeader(http.StatusNotFound)
+			ctx.NotifyError(models.ClientManagementRequest, &ErrClientNotFound, mux.Vars(r)["clientId"])
+			json.NewEncoder(w).Encode(ErrClientNotFound)
+			return
+		}
+
+		ctx.Logger.Success("Client %v was removed successfully", client.ID)
+		ctx.NotifySuccess(models.ClientManagementRequest, client.ID)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// getRegisteredClient returns the client in the route if it belongs to the request tenant
+func getRegisteredClient(ctx *BaseControllerContext, r *http.Request) *models.Client {
+	clientId := mux.Vars(r)["clientId"]
+	if clientId == "" || ctx.AuthorizationContext.ClientDatabaseAdapter == nil {
+		return nil
+	}
+
+	dbClient := ctx.AuthorizationContext.ClientDatabaseAdapter.GetClientById(clientId)
+	if dbClient == nil || !strings.EqualFold(dbClient.TenantID, ctx.TenantID) {
+		return nil
+	}
+
+	client := mappers.ToClient(*dbClient)
+	return &client
+}
+
+// applyClientMetadata validates the requested metadata and sets it in the client
+// using the RFC 7591 defaults for anything that was not requested
+func applyClientMetadata(client *models.Client, request models.OAuthClientRegistrationRequest) *models.OAuthErrorResponse {
+	grantTypes := request.GrantTypes
+	if len(grantTypes) == 0 {
+		grantTypes = []string{models.OAuthAuthorizationCodeGrant.String()}
+	}
+	for _, grantType := range grantTypes {
+		if !containsString(supportedClientGrantTypes, grantType) {
+			errorResponse := models.NewOAuthErrorResponse(models.OAuthInvalidClientMetadata, fmt.Sprintf("grant_type %v is not supported", grantType))
+			return &errorResponse
+		}
+	}
+
+	authMethod := request.TokenEndpointAuthMethod
+	if authMethod == "" {
+		authMethod = models.ClientSecretBasicAuthMethod
+	}
+	if !containsString(supportedClientAuthMethods, authMethod) {
+		errorResponse := models.NewOAuthErrorResponse(models.OAuthInvalidClientMetadata, fmt.Sprintf("token_endpoint_auth_method %v is not supported", authMethod))
+		return &errorResponse
+	}
+
+	if authMethod == models.NoneAuthMethod && containsString(grantTypes, models.OAuthClientCredentialsGrant.String()) {
+		errorResponse := models.NewOAuthErrorResponse(models.OAuthInvalidClientMetadata, "public clients cannot use the client_credentials grant")
+		return &errorResponse
+	}
+
+	for _, responseType := range request.ResponseTypes {
+		if responseType != "code" {
+			errorResponse := models.NewOAuthErrorResponse(models.OAuthInvalidClientMetadata, fmt.Sprintf("response_type %v is not supported", responseType))
+			return &errorResponse
+		}
+	}
+
+	if containsString(grantTypes, models.OAuthAuthorizationCodeGrant.String()) && len(request.RedirectUris) == 0 {
+		errorResponse := models.NewOAuthErrorResponse(models.OAuthInvalidRedirectUri, "redirect_uris are required for the authorization_code grant")
+		return &errorResponse
+	}
+
+	// without a format the client uses the access token format of its tenant
+	if request.AccessTokenFormat != "" && request.AccessTokenFormat != models.JwtAccessTokenFormat && request.AccessTokenFormat != models.OpaqueAccessTokenFormat {
+		errorResponse := models.NewOAuthErrorResponse(models.OAuthInvalidClientMetadata, fmt.Sprintf("access_token_format %v is not supported", request.AccessTokenFormat))
+		return &errorResponse
+	}
+
+	for _, redirectUri := range request.RedirectUris {
+		parsedUri, err := url.Parse(redirectUri)
+		if err != nil || !parsedUri.IsAbs() || parsedUri.Fragment != "" {
+			errorResponse := models.NewOAuthErrorResponse(models.OAuthInvalidRedirectUri, fmt.Sprintf("redirect_uri %v must be an absolute uri without a fragment", redirectUri))
+			return &errorResponse
+		}
+	}
+
+	client.Name = request.ClientName
+	client.GrantTypes = grantTypes
+	client.TokenEndpointAuthMethod = authMethod
+	client.RedirectUris = append(make([]string, 0), request.RedirectUris...)
+	client.Scopes = strings.Fields(request.Scope)
+	client.AccessTokenFormat = request.AccessTokenFormat
+
+	return nil
+}
+
+// validateSelfRegistration limits the grant types and scopes of the clients that register or
+// update themselves with the initial or registration access tokens, admins are not limited
+func validateSelfRegistration(ctx *BaseControllerContext, client models.Client) *models.OAuthErrorResponse {
+	if ctx.AuthorizationContext.AuthorizedBy != "InitialAccessTokenAuthorization" && ctx.AuthorizationContext.AuthorizedBy != "RegistrationAccessTokenAuthorization" {
+		return nil
+	}
+
+	for _, grantType := range client.GrantTypes {
+		if !containsString(ctx.AuthorizationContext.Options.SelfRegistrationGrantTypes, grantType) {
+			errorResponse := models.NewOAuthErrorResponse(models.OAuthInvalidClientMetadata, fmt.Sprintf("grant_type %v is not allowed for self registered clients", grantType))
+			return &errorResponse
+		}
+	}
+
+	for _, scope := range client.Scopes {
+		if !containsString(ctx.AuthorizationContext.Options.SelfRegistrationScopes, scope) {
+			errorResponse := models.NewOAuthErrorResponse(models.OAuthInvalidClientMetadata, fmt.Sprintf("scope %v is not allowed for self registered clients", scope))
+			return &errorResponse
+		}
+	}
+
+	return nil
+}
+
+func newClientRegistrationResponse(ctx *BaseControllerContext, r *http.Request, client models.Client) models.OAuthClientRegistrationResponse {
+	responseTypes := make([]string, 0)
+	if client.AllowsGrantType(models.OAuthAuthorizationCodeGrant.String()) {
+		responseTypes = append(responseTypes, "code")
+	}
+
+	return models.OAuthClientRegistrationResponse{
+		ClientID:                client.ID,
+		ClientIdIssuedAt:        client.IssuedAt.Unix(),
+		ClientSecretExpiresAt:   0,
+		RegistrationClientUri:   strings.TrimSuffix(ctx.AuthorizationContext.GetBaseUrl(r), "/") + http_helper.JoinUrl(ctx.AuthorizationContext.Options.ControllerPrefix, ctx.TenantID, "register", "clients", client.ID),
+		ClientName:              client.Name,
+		RedirectUris:            client.RedirectUris,
+		GrantTypes:              client.GrantTypes,
+		ResponseTypes:           responseTypes,
+		Scope:                   strings.Join(client.Scopes, " "),
+		TokenEndpointAuthMethod: client.TokenEndpointAuthMethod,
+		AccessTokenFormat:       client.AccessTokenFormat,
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
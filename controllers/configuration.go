@@ -3,40 +3,129 @@ package controllers
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 
+	identity_constants "github.com/cjlapao/common-go-identity/constants"
 	"github.com/cjlapao/common-go-identity/models"
 	"github.com/cjlapao/common-go-restapi/controllers"
 	"github.com/cjlapao/common-go/helper/http_helper"
 	"github.com/cjlapao/common-go/service_provider"
+	"github.com/gorilla/mux"
 )
 
+// claimsSupported are the claims the server can release in the id tokens and the userinfo
+var claimsSupported = []string{
+	"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "acr", "amr", "azp", "at_hash", "c_hash", "tid",
+	"name", "given_name", "family_name", "preferred_username", "email", "email_verified", "roles", "claims",
+}
+
 // Configuration Returns the OpenID Oauth configuration endpoint
 func (c *AuthorizationControllers) Configuration() controllers.Controller {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := NewBaseContext(r)
+		c.writeConfiguration(w, ctx, c.getConfiguration(ctx, r))
+	}
+}
 
-		baseurl := service_provider.Get().GetBaseUrl(r)
+// AuthorizationServerMetadata Returns the RFC 8414 OAuth authorization server metadata, it is the
+// OpenID configuration without the OpenID Connect only values
+func (c *AuthorizationControllers) AuthorizationServerMetadata() controllers.Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := NewBaseContext(r)
+		response := c.getConfiguration(ctx, r)
+		response.UserinfoEndpoint = ""
+		response.ClaimsSupported = nil
+		response.SubjectTypesSupported = nil
+		response.IDTokenSigningAlgValuesSupported = nil
+		response.UserinfoSigningAlgValuesSupported = nil
 
-		response := models.OAuthConfigurationResponse{
-			Issuer:                baseurl + http_helper.JoinUrl(c.AuthorizationContext.Options.ControllerPrefix, c.AuthorizationContext.TenantId),
-			JwksURI:               baseurl + http_helper.JoinUrl(c.AuthorizationContext.Options.ControllerPrefix, c.AuthorizationContext.TenantId, ".well-known", "openid-configuration", "jwks"),
-			AuthorizationEndpoint: baseurl + http_helper.JoinUrl(c.AuthorizationContext.Options.ControllerPrefix, c.AuthorizationContext.TenantId, "authorize"),
-			TokenEndpoint:         baseurl + http_helper.JoinUrl(c.AuthorizationContext.Options.ControllerPrefix, c.AuthorizationContext.TenantId, "token"),
-			UserinfoEndpoint:      baseurl + http_helper.JoinUrl(c.AuthorizationContext.Options.ControllerPrefix, c.AuthorizationContext.TenantId, "userinfo"),
-			IntrospectionEndpoint: baseurl + http_helper.JoinUrl(c.AuthorizationContext.Options.ControllerPrefix, c.AuthorizationContext.TenantId, "introspection"),
-		}
+		c.writeConfiguration(w, ctx, response)
+	}
+}
 
-		if err := ctx.NotifySuccess(models.ConfigurationRequest, response); err != nil {
-			w.WriteHeader(http.StatusUnauthorized)
-			ctx.Logger.Exception(err, "error calling back the notification callback for %s", models.ConfigurationRequest.String())
-			responseErr := models.OAuthErrorResponse{
-				Error:            models.UnknownError,
-				ErrorDescription: "there was unknown error",
-			}
-			json.NewEncoder(w).Encode(responseErr)
-			return
+func (c *AuthorizationControllers) writeConfiguration(w http.ResponseWriter, ctx *BaseControllerContext, response models.OAuthConfigurationResponse) {
+	if err := ctx.NotifySuccess(models.ConfigurationRequest, response); err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		ctx.Logger.Exception(err, "error calling back the notification callback for %s", models.ConfigurationRequest.String())
+		responseErr := models.OAuthErrorResponse{
+			Error:            models.UnknownError,
+			ErrorDescription: "there was unknown error",
 		}
+		json.NewEncoder(w).Encode(responseErr)
+		return
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// getConfiguration builds the discovery document from the routes registered in the router, the
+// keys in the key vault and the issuer of the request tenant
+func (c *AuthorizationControllers) getConfiguration(ctx *BaseControllerContext, r *http.Request) models.OAuthConfigurationResponse {
+	baseUrl := service_provider.Get().GetBaseUrl(r)
+	tenantId := mux.Vars(r)["tenantId"]
+	algorithms := ctx.AuthorizationContext.KeyVault.SigningAlgorithms()
+
+	scopes := make([]string, 0)
+	if ctx.AuthorizationContext.Scope != "" {
+		scopes = append(scopes, ctx.AuthorizationContext.Scope)
+	}
+	scopes = append(scopes, identity_constants.UserInfoScopes...)
+
+	response := models.OAuthConfigurationResponse{
+		Issuer:                            ctx.AuthorizationContext.Issuer,
+		JwksURI:                           c.getEndpoint(ctx, baseUrl, tenantId, ".well-known", "openid-configuration", "jwks"),
+		AuthorizationEndpoint:             c.getEndpoint(ctx, baseUrl, tenantId, "authorize"),
+		TokenEndpoint:                     c.getEndpoint(ctx, baseUrl, tenantId, "token"),
+		UserinfoEndpoint:                  c.getEndpoint(ctx, baseUrl, tenantId, "userinfo"),
+		RegistrationEndpoint:              c.getEndpoint(ctx, baseUrl, tenantId, "register", "clients"),
+		RevocationEndpoint:                c.getEndpoint(ctx, baseUrl, tenantId, "revoke"),
+		IntrospectionEndpoint:             c.getEndpoint(ctx, baseUrl, tenantId, "token", "introspect"),
+		ScopesSupported:                   scopes,
+		ClaimsSupported:                   claimsSupported,
+		GrantTypesSupported:               supportedClientGrantTypes,
+		ResponseTypesSupported:            []string{"code"},
+		ResponseModesSupported:            []string{"query"},
+		TokenEndpointAuthMethodsSupported: supportedClientAuthMethods,
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  algorithms,
+		UserinfoSigningAlgValuesSupported: algorithms,
+		CodeChallengeMethodsSupported:     []string{"S256"},
+	}
+
+	// Resource servers introspect tokens as confidential clients
+	if response.IntrospectionEndpoint != "" {
+		response.IntrospectionEndpointAuthMethods = []string{models.ClientSecretBasicAuthMethod, models.ClientSecretPostAuthMethod}
+		response.IntrospectionSigningAlgValues = algorithms
+	}
+
+	return response
+}
 
-		json.NewEncoder(w).Encode(response)
+// getEndpoint returns the url of the endpoint in the request tenant, it is empty if the endpoint
+// route was not registered
+func (c *AuthorizationControllers) getEndpoint(ctx *BaseControllerContext, baseUrl string, tenantId string, path ...string) string {
+	prefix := ctx.AuthorizationContext.Options.ControllerPrefix
+	if !c.isRouteRegistered(http_helper.JoinUrl(append([]string{prefix}, path...)...)) {
+		return ""
 	}
+
+	return baseUrl + http_helper.JoinUrl(append([]string{prefix, tenantId}, path...)...)
+}
+
+// isRouteRegistered returns true if the router has a route for the path, the controllers that
+// are not attached to a router assume it is
+func (c *AuthorizationControllers) isRouteRegistered(path string) bool {
+	if c.Router == nil {
+		return true
+	}
+
+	registered := false
+	c.Router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		if template, err := route.GetPathTemplate(); err == nil && strings.HasSuffix(template, path) {
+			registered = true
+		}
+		return nil
+	})
+
+	return registered
 }
@@ -10,6 +10,7 @@ import (
 	"github.com/cjlapao/common-go-identity/environment"
 	"github.com/cjlapao/common-go-identity/jwt"
 	"github.com/cjlapao/common-go-identity/models"
+	"github.com/cjlapao/common-go-identity/oauthflow"
 	"github.com/cjlapao/common-go-identity/user_manager"
 	"github.com/cjlapao/common-go-restapi/controllers"
 )
@@ -162,6 +163,11 @@ func (c *AuthorizationControllers) VerifyEmail() controllers.Controller {
 				return
 			}
 
+			if err := oauthflow.RegisterRefreshToken(token.RefreshToken, *user, "", ""); err != nil {
+				ctx.Logger.Exception(err, "There was an error storing the refresh token for user %v", user.ID)
+				token.RefreshToken = ""
+			}
+
 			authCtx := authorization_context.Clone()
 			expiresIn := authCtx.Options.TokenDuration * 60
 			response := models.OAuthVerifyEmailResponse{
@@ -21,6 +21,9 @@ var (
 	// ErrUserAlreadyExists The user already exists in the database
 	ErrUserAlreadyExists = models.NewOAuthErrorResponse(models.OAuthInvalidClientError, "User already exists.")
 
+	// ErrClientNotFound Client was not found in the database context
+	ErrClientNotFound = models.NewOAuthErrorResponse(models.OAuthInvalidClientError, "Client not found.")
+
 	// ErrEmptyToken No User database context found error response
 	ErrEmptyToken = models.NewOAuthErrorResponse(models.OAuthInvalidRequestError, "JWT token is nil or empty.")
 	// ErrTokenNotFound No User database context found error response
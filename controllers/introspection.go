@@ -4,49 +4,103 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/cjlapao/common-go-identity/jwt"
 	"github.com/cjlapao/common-go-identity/models"
+	"github.com/cjlapao/common-go-identity/oauthflow"
 	"github.com/cjlapao/common-go-restapi/controllers"
 )
 
-// Introspection Validates a token in the context returning an openid oauth introspect response
+// Introspection returns the state of a token as defined in RFC 7662, the resource server needs to
+// authenticate as a confidential client or with an api key. Callers that accept
+// application/token-introspection+jwt get the response signed as defined in RFC 9701
 func (c *AuthorizationControllers) Introspection() controllers.Controller {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := NewBaseContext(r)
-		token := r.FormValue("token")
+		var introspectRequest models.OAuthIntrospectRequest
+		ctx.MapRequestBody(&introspectRequest)
 
-		if token == "" {
-			w.WriteHeader(http.StatusUnauthorized)
+		clientId, authenticationMethod, errorResponse := authenticateResourceServer(ctx, r, &introspectRequest)
+		if errorResponse != nil {
+			if authenticationMethod == models.ClientSecretBasicAuthMethod {
+				w.Header().Set("WWW-Authenticate", "Basic")
+			}
+			if errorResponse.Error == models.OAuthInvalidClientError {
+				w.WriteHeader(http.StatusUnauthorized)
+			} else {
+				w.WriteHeader(http.StatusBadRequest)
+			}
+
+			ctx.NotifyError(models.TokenIntrospection, errorResponse, introspectRequest.ClientID)
+			json.NewEncoder(w).Encode(*errorResponse)
+			return
+		}
+
+		if introspectRequest.Token == "" {
+			w.WriteHeader(http.StatusBadRequest)
 			ErrEmptyToken.Log()
+			ctx.NotifyError(models.TokenIntrospection, &ErrEmptyToken, clientId)
 			json.NewEncoder(w).Encode(ErrEmptyToken)
 			return
 		}
 
-		userToken, err := jwt.ValidateUserToken(token, ctx.AuthorizationContext)
+		response := oauthflow.IntrospectToken(ctx.AuthorizationContext, introspectRequest.Token, introspectRequest.TokenTypeHint)
+		if response.Active {
+			ctx.Logger.Success("Token %v was introspected by %v", response.ID, clientId)
+		} else {
+			ctx.Logger.Info("An inactive token was introspected by %v", clientId)
+		}
 
-		if err != nil {
-			response := models.OAuthIntrospectResponse{
-				Active: false,
+		w.Header().Set("Cache-Control", "no-store")
+		if strings.Contains(r.Header.Get("Accept"), "application/"+jwt.IntrospectionTokenType) {
+			token, err := jwt.GenerateIntrospectionToken("", response, clientId)
+			if err != nil {
+				ctx.Logger.Exception(err, "error signing the introspection response")
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(ErrException)
+				return
 			}
 
-			ErrInvalidToken.Log()
-			c.Logger.Error("Token for user %v is not valid, %v", userToken.DisplayName, err.Error())
-			json.NewEncoder(w).Encode(response)
+			w.Header().Set("Content-Type", "application/"+jwt.IntrospectionTokenType)
+			w.Write([]byte(token))
 			return
 		}
 
-		response := models.OAuthIntrospectResponse{
-			Active:    true,
-			ID:        userToken.ID,
-			TokenType: userToken.Scope,
-			Subject:   userToken.User,
-			ExpiresAt: fmt.Sprintf("%v", userToken.ExpiresAt.Unix()),
-			IssuedAt:  fmt.Sprintf("%v", userToken.IssuedAt.Unix()),
-			Issuer:    userToken.Issuer,
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// authenticateResourceServer returns the client id of the caller, callers already authorized by
+// the api key layer are trusted, anyone else needs to be a registered confidential client
+func authenticateResourceServer(ctx *BaseControllerContext, r *http.Request, introspectRequest *models.OAuthIntrospectRequest) (string, string, *models.OAuthErrorResponse) {
+	clientRequest := models.OAuthLoginRequest{
+		ClientID:     introspectRequest.ClientID,
+		ClientSecret: introspectRequest.ClientSecret,
+	}
+	authenticationMethod, errorResponse := getClientAuthentication(r, &clientRequest)
+	if errorResponse != nil {
+		return "", authenticationMethod, errorResponse
+	}
+	introspectRequest.ClientID = clientRequest.ClientID
+
+	if clientRequest.ClientID == "" && ctx.AuthorizationContext.IsAuthorized && ctx.AuthorizationContext.AuthorizedBy == "ApiKeyAuthorization" {
+		return "", authenticationMethod, nil
+	}
+
+	if authenticationMethod == models.NoneAuthMethod {
+		response := models.NewOAuthErrorResponse(models.OAuthInvalidClientError, "Client authentication is required")
+		if clientRequest.ClientID != "" {
+			response.ErrorDescription = fmt.Sprintf("Client %v is a public client and cannot introspect tokens", clientRequest.ClientID)
 		}
+		return "", authenticationMethod, &response
+	}
 
-		ctx.Logger.Success("Token for user %v was validated successfully", userToken.DisplayName)
-		json.NewEncoder(w).Encode(response)
+	client, errorResponse := oauthflow.AuthenticateClient(clientRequest.ClientID, clientRequest.ClientSecret, authenticationMethod)
+	if errorResponse != nil {
+		return "", authenticationMethod, errorResponse
 	}
+
+	return client.ID, authenticationMethod, nil
 }
@@ -1,30 +1,63 @@
 package controllers
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/cjlapao/common-go-identity/models"
 	"github.com/cjlapao/common-go-restapi/controllers"
-	"github.com/cjlapao/common-go/automapper"
 )
 
-// Jwks Returns the public keys for the openid oauth configuration endpoint for validation
+// Jwks Returns the public keys for the openid oauth configuration endpoint for validation, every
+// key that is not retired is published so tokens signed before a rotation can still be verified
 func (c *AuthorizationControllers) Jwks() controllers.Controller {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := NewBaseContext(r)
 		response := models.OAuthJwksResponse{
 			Keys: make([]models.OAuthJwksKey, 0),
 		}
-		key := models.OAuthJwksKey{}
-		defaultKey := ctx.AuthorizationContext.KeyVault.GetDefaultKey()
-		if len(defaultKey.JWK.Keys) >= 1 {
-			automapper.Map(defaultKey.JWK.Keys[0], &key)
+
+		for _, vaultKey := range ctx.AuthorizationContext.KeyVault.GetVerificationKeys() {
+			jsonWebKey := vaultKey.GetJsonWebKey()
+			response.Keys = append(response.Keys, models.OAuthJwksKey{
+				ID:        vaultKey.ID,
+				KeyType:   jsonWebKey.KeyType,
+				Algorithm: jsonWebKey.Algorithm,
+				Use:       jsonWebKey.Use,
+				X5C:       jsonWebKey.X5C,
+				X5T:       jsonWebKey.X5T,
+				X5TS256:   jsonWebKey.X5TS256,
+				Exponent:  jsonWebKey.Exponent,
+				Modulus:   jsonWebKey.Modulus,
+				Curve:     jsonWebKey.Curve,
+				X:         jsonWebKey.X,
+				Y:         jsonWebKey.Y,
+			})
+		}
+
+		content, err := json.Marshal(response)
+		if err != nil {
+			ctx.Logger.Exception(err, "error encoding the json web keys")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(ErrException)
+			return
 		}
 
-		key.ID = defaultKey.ID
-		response.Keys = append(response.Keys, key)
+		// The gateways poll the keys, the etag lets them skip the body when nothing changed
+		hash := sha256.Sum256(content)
+		etag := fmt.Sprintf(`"%v"`, base64.RawURLEncoding.EncodeToString(hash[:]))
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%v", ctx.AuthorizationContext.Options.JwksCacheDuration*60))
+		if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch == "*" || strings.Contains(ifNoneMatch, etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
 
-		json.NewEncoder(w).Encode(response)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(content)
 	}
 }
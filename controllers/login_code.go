@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/cjlapao/common-go-identity/models"
+	"github.com/cjlapao/common-go-identity/user_manager"
+	"github.com/cjlapao/common-go-restapi/controllers"
+)
+
+// RequestLoginCode sends a passwordless login code or link to the user through the notification
+// callback, it always accepts the request so it cannot be used to find which users exist
+func (c *AuthorizationControllers) RequestLoginCode() controllers.Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := NewBaseContext(r)
+		var loginCodeRequest models.OAuthLoginCodeRequest
+		ctx.MapRequestBody(&loginCodeRequest)
+
+		usr := ctx.UserManager.GetUserByUsername(loginCodeRequest.Username)
+		if usr == nil || usr.ID == "" {
+			responseErr := models.NewOAuthErrorResponse(models.OAuthInvalidRequestError, "user not found")
+			ctx.NotifyError(models.LoginCodeRequest, &responseErr, loginCodeRequest)
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		ctx.UserID = usr.ID
+		if usr.Blocked || usr.IsLockedOut() {
+			responseErr := models.NewOAuthErrorResponse(models.OAuthUserBlocked, "user is blocked")
+			ctx.NotifyError(models.LoginCodeRequest, &responseErr, loginCodeRequest)
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		code, err := ctx.UserManager.GenerateLoginCode(*usr, loginCodeRequest.ClientID)
+		if err != nil {
+			err.Log()
+			responseErr := models.NewOAuthErrorResponse(models.UnknownError, "there was an error generating the login code")
+			if err.Error == user_manager.LoginCodeRateLimitedError {
+				responseErr = models.NewOAuthErrorResponse(models.OAuthInvalidRequestError, "a login code was requested too recently")
+			}
+			ctx.NotifyError(models.LoginCodeRequest, &responseErr, loginCodeRequest)
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		notifyData := models.OAuthLoginCodeNotification{
+			UserID:      usr.ID,
+			Email:       usr.Email,
+			DisplayName: usr.DisplayName,
+			FirstName:   usr.FirstName,
+			LastName:    usr.LastName,
+			ClientID:    loginCodeRequest.ClientID,
+			LoginCode:   code,
+			ExpiresIn:   ctx.AuthorizationContext.Options.LoginCodeDuration * 60,
+		}
+
+		if err := ctx.NotifySuccess(models.LoginCodeRequest, notifyData); err != nil {
+			ctx.Logger.Exception(err, "error calling back the notification callback for %s", models.LoginCodeRequest.String())
+		}
+
+		ctx.Logger.Info("User %v requested a login code successfully", ctx.UserID)
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/cjlapao/common-go-identity/interfaces"
 	log "github.com/cjlapao/common-go-logger"
 	"github.com/cjlapao/common-go/execution_context"
+	"github.com/gorilla/mux"
 )
 
 // AuthorizationControllers
@@ -13,6 +14,7 @@ type AuthorizationControllers struct {
 	Logger               *log.LoggerService
 	Context              *execution_context.Context
 	AuthorizationContext *authorization_context.AuthorizationContext
+	Router               *mux.Router
 }
 
 func NewDefaultAuthorizationControllers() *AuthorizationControllers {
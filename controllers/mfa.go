@@ -0,0 +1,214 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+
+	"github.com/cjlapao/common-go-identity/models"
+	"github.com/cjlapao/common-go-identity/user_manager"
+	"github.com/cjlapao/common-go-restapi/controllers"
+)
+
+// EnrollTotp Starts the totp enrollment of the authenticated user, the response contains the
+// otpauth uri and its QR code to add the account to an authenticator app
+func (c *AuthorizationControllers) EnrollTotp() controllers.Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := NewBaseContext(r)
+		user := getAuthenticatedUser(ctx)
+		if user == nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			ctx.NotifyError(models.MfaEnrollment, &ErrUserNotFound, nil)
+			json.NewEncoder(w).Encode(ErrUserNotFound)
+			return
+		}
+
+		key, err := ctx.UserManager.EnrollTotp(user.ID)
+		if err != nil {
+			err.Log()
+			responseErr := toMfaErrorResponse(err)
+			w.WriteHeader(http.StatusBadRequest)
+			ctx.NotifyError(models.MfaEnrollment, &responseErr, user.ID)
+			json.NewEncoder(w).Encode(responseErr)
+			return
+		}
+
+		png, pngErr := key.Png()
+		if pngErr != nil {
+			ctx.Logger.Exception(pngErr, "error generating the totp QR code")
+			w.WriteHeader(http.StatusInternalServerError)
+			ctx.NotifyError(models.MfaEnrollment, &ErrException, user.ID)
+			json.NewEncoder(w).Encode(ErrException)
+			return
+		}
+
+		response := models.OAuthTotpEnrollmentResponse{
+			Secret:     key.Secret(),
+			OtpAuthUri: key.String(),
+			QrCode:     "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+		}
+
+		ctx.NotifySuccess(models.MfaEnrollment, user.ID)
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// ConfirmTotp Enables the pending totp enrollment of the authenticated user with a first code,
+// the response contains the recovery codes of the user
+func (c *AuthorizationControllers) ConfirmTotp() controllers.Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := NewBaseContext(r)
+		var codeRequest models.OAuthTotpCodeRequest
+		ctx.MapRequestBody(&codeRequest)
+
+		user := getAuthenticatedUser(ctx)
+		if user == nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			ctx.NotifyError(models.MfaEnrollment, &ErrUserNotFound, nil)
+			json.NewEncoder(w).Encode(ErrUserNotFound)
+			return
+		}
+
+		recoveryCodes, err := ctx.UserManager.ConfirmTotp(user.ID, codeRequest.Code)
+		if err != nil {
+			err.Log()
+			responseErr := toMfaErrorResponse(err)
+			w.WriteHeader(http.StatusBadRequest)
+			ctx.NotifyError(models.MfaEnrollment, &responseErr, user.ID)
+			json.NewEncoder(w).Encode(responseErr)
+			return
+		}
+
+		ctx.NotifySuccess(models.MfaEnrollment, user.ID)
+		ctx.NotifySuccess(models.MfaRecoveryCodesGenerated, user.ID)
+		ctx.Logger.Info("User %v enabled totp successfully", user.ID)
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(models.OAuthRecoveryCodesResponse{
+			RecoveryCodes: recoveryCodes,
+			Remaining:     len(recoveryCodes),
+		})
+	}
+}
+
+// DisableTotp Removes the totp of the authenticated user, a valid code is required
+func (c *AuthorizationControllers) DisableTotp() controllers.Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := NewBaseContext(r)
+		var codeRequest models.OAuthTotpCodeRequest
+		ctx.MapRequestBody(&codeRequest)
+
+		user := getAuthenticatedUser(ctx)
+		if user == nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			ctx.NotifyError(models.MfaRemoval, &ErrUserNotFound, nil)
+			json.NewEncoder(w).Encode(ErrUserNotFound)
+			return
+		}
+
+		if err := ctx.UserManager.DisableTotp(user.ID, codeRequest.Code); err != nil {
+			err.Log()
+			responseErr := toMfaErrorResponse(err)
+			w.WriteHeader(http.StatusBadRequest)
+			ctx.NotifyError(models.MfaRemoval, &responseErr, user.ID)
+			json.NewEncoder(w).Encode(responseErr)
+			return
+		}
+
+		ctx.NotifySuccess(models.MfaRemoval, user.ID)
+		ctx.Logger.Info("User %v disabled totp successfully", user.ID)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// RegenerateRecoveryCodes Replaces the recovery codes of the authenticated user, a valid totp
+// or recovery code is required
+func (c *AuthorizationControllers) RegenerateRecoveryCodes() controllers.Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := NewBaseContext(r)
+		var codeRequest models.OAuthTotpCodeRequest
+		ctx.MapRequestBody(&codeRequest)
+
+		user := getAuthenticatedUser(ctx)
+		if user == nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			ctx.NotifyError(models.MfaRecoveryCodesGenerated, &ErrUserNotFound, nil)
+			json.NewEncoder(w).Encode(ErrUserNotFound)
+			return
+		}
+
+		recoveryCodes, err := ctx.UserManager.RegenerateRecoveryCodes(user.ID, codeRequest.Code)
+		if err != nil {
+			err.Log()
+			responseErr := toMfaErrorResponse(err)
+			w.WriteHeader(http.StatusBadRequest)
+			ctx.NotifyError(models.MfaRecoveryCodesGenerated, &responseErr, user.ID)
+			json.NewEncoder(w).Encode(responseErr)
+			return
+		}
+
+		ctx.NotifySuccess(models.MfaRecoveryCodesGenerated, user.ID)
+		ctx.Logger.Info("User %v regenerated the recovery codes successfully", user.ID)
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(models.OAuthRecoveryCodesResponse{
+			RecoveryCodes: recoveryCodes,
+			Remaining:     len(recoveryCodes),
+		})
+	}
+}
+
+// GetRecoveryCodes Returns how many recovery codes the authenticated user has left
+func (c *AuthorizationControllers) GetRecoveryCodes() controllers.Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := NewBaseContext(r)
+		user := getAuthenticatedUser(ctx)
+		if user == nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(ErrUserNotFound)
+			return
+		}
+
+		if !ctx.UserManager.IsTotpEnabled(user.ID) {
+			responseErr := models.NewOAuthErrorResponse(models.OAuthInvalidRequestError, "Multi-factor authentication is not enrolled")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(responseErr)
+			return
+		}
+
+		json.NewEncoder(w).Encode(models.OAuthRecoveryCodesResponse{
+			Remaining: ctx.UserManager.CountRecoveryCodes(user.ID),
+		})
+	}
+}
+
+// getAuthenticatedUser returns the user of the bearer token that authorized the request
+func getAuthenticatedUser(ctx *BaseControllerContext) *models.User {
+	if ctx.AuthorizationContext == nil || ctx.AuthorizationContext.User == nil || ctx.AuthorizationContext.User.ID == "" {
+		return nil
+	}
+
+	// tokens of external issuers never act on behalf of a local user
+	if ctx.AuthorizationContext.TrustedIssuers.IsTrusted(ctx.AuthorizationContext.User.Issuer) {
+		return nil
+	}
+
+	user := ctx.UserManager.GetUserById(ctx.AuthorizationContext.User.ID)
+	if user == nil || user.ID == "" {
+		return nil
+	}
+
+	return user
+}
+
+func toMfaErrorResponse(err *user_manager.UserManagerError) models.OAuthErrorResponse {
+	switch err.Error {
+	case user_manager.InvalidMfaCodeError:
+		return models.NewOAuthErrorResponse(models.OAuthInvalidMfaCode, "The one time password or recovery code is not valid")
+	case user_manager.MfaNotEnrolledError:
+		return models.NewOAuthErrorResponse(models.OAuthInvalidRequestError, "Multi-factor authentication is not enrolled")
+	case user_manager.MfaAlreadyEnabledError:
+		return models.NewOAuthErrorResponse(models.OAuthInvalidRequestError, "Multi-factor authentication is already enabled")
+	default:
+		return models.NewOAuthErrorResponse(models.UnknownError, "There was an error processing the multi-factor authentication request")
+	}
+}
@@ -15,11 +15,20 @@ func (c *AuthorizationControllers) OtpForEmailValidation() controllers.Controlle
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := NewBaseContext(r)
 
+		// The per user multi-factor secrets are never used here, only the configured otp secret
+		otpSecret := ctx.AuthorizationContext.Options.OtpSecret
+		if otpSecret == "" {
+			w.WriteHeader(http.StatusNotFound)
+			responseErr := models.NewOAuthErrorResponse(models.OAuthInvalidRequestError, "no otp secret is configured")
+			json.NewEncoder(w).Encode(responseErr)
+			return
+		}
+
 		options := totp.TotpOptions{
 			Period: 5 * 60,
 		}
 
-		code, err := totp.GenerateCode("JBSWY3DPEHPK3PXP", time.Now().UTC(), &options)
+		code, err := totp.GenerateCode(otpSecret, time.Now().UTC(), &options)
 		if err != nil {
 			ctx.Logger.Exception(err, "error generating the totp code")
 			w.WriteHeader(http.StatusInternalServerError)
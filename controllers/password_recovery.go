@@ -3,7 +3,6 @@ package controllers
 import (
 	"encoding/json"
 	"net/http"
-	"strings"
 
 	"github.com/cjlapao/common-go-identity/constants"
 	"github.com/cjlapao/common-go-identity/models"
@@ -219,8 +218,7 @@ func (c *AuthorizationControllers) ChangePassword() controllers.Controller {
 
 		ctx.UserID = usr.ID
 
-		oldPassword := usr.HashPassword(changePassword.OldPassword)
-		if !strings.EqualFold(oldPassword, usr.Password) {
+		if !usr.VerifyPassword(changePassword.OldPassword) {
 			w.WriteHeader(http.StatusUnauthorized)
 			responseErr := models.OAuthErrorResponse{
 				Error:            models.OAuthPasswordMismatch,
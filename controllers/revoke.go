@@ -5,68 +5,60 @@ import (
 	"net/http"
 
 	"github.com/cjlapao/common-go-identity/models"
+	"github.com/cjlapao/common-go-identity/oauthflow"
 	"github.com/cjlapao/common-go-restapi/controllers"
 )
 
-// TODO: Implement UserManager
-// Revoke Revokes a user or a user refresh tenant, when revoking a user
-// this will remove the user from the database deleting it
+// Revoke revokes an access or a refresh token as defined in RFC 7009, the client needs to
+// authenticate and can only revoke its own tokens. Any token, even an invalid one, gets a 200
+// back so the response does not tell anything about the token
 func (c *AuthorizationControllers) Revoke() controllers.Controller {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := NewBaseContext(r)
 		var revokeRequest models.OAuthRevokeRequest
-
 		ctx.MapRequestBody(&revokeRequest)
 
-		if revokeRequest.ClientID == "" {
-			w.WriteHeader(http.StatusBadRequest)
-			ErrEmptyUserID.Log()
+		clientRequest := models.OAuthLoginRequest{
+			ClientID:     revokeRequest.ClientID,
+			ClientSecret: revokeRequest.ClientSecret,
+		}
+		authenticationMethod, errorResponse := getClientAuthentication(r, &clientRequest)
+		if errorResponse == nil && clientRequest.ClientID == "" {
+			response := models.NewOAuthErrorResponse(models.OAuthInvalidClientError, "Client id is required")
+			errorResponse = &response
+		}
+		if errorResponse == nil {
+			_, errorResponse = oauthflow.AuthenticateRegisteredClient(clientRequest.ClientID, clientRequest.ClientSecret, authenticationMethod)
+		}
+		if errorResponse != nil {
+			switch errorResponse.Error {
+			case models.OAuthInvalidClientError:
+				if authenticationMethod == models.ClientSecretBasicAuthMethod {
+					w.Header().Set("WWW-Authenticate", "Basic")
+				}
+				w.WriteHeader(http.StatusUnauthorized)
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+			}
 
-			ctx.NotifyError(models.TokenRevoked, &ErrEmptyUserID, revokeRequest)
-			json.NewEncoder(w).Encode(ErrEmptyUserID)
+			ctx.NotifyError(models.TokenRevoked, errorResponse, clientRequest.ClientID)
+			json.NewEncoder(w).Encode(*errorResponse)
 			return
 		}
 
-		usr := ctx.UserManager.GetUserById(revokeRequest.ClientID)
-
-		if usr == nil || usr.ID == "" {
+		if revokeRequest.Token == "" {
+			errorResponse := models.NewOAuthErrorResponse(models.OAuthInvalidRequestError, "Token is required")
 			w.WriteHeader(http.StatusBadRequest)
-			ErrUserNotFound.Log()
-
-			ctx.NotifyError(models.TokenRevoked, &ErrUserNotFound, revokeRequest)
-			json.NewEncoder(w).Encode(ErrUserNotFound)
+			ctx.NotifyError(models.TokenRevoked, &errorResponse, clientRequest.ClientID)
+			json.NewEncoder(w).Encode(errorResponse)
 			return
 		}
 
-		// There is no revoke token filled in so we will be removing the user
-		// otherwise we will only revoke the refresh token
-		switch revokeRequest.GrantType {
-		case "revoke_user":
-			removeResult := ctx.UserManager.RemoveUser(usr.ID)
-
-			if !removeResult {
-				w.WriteHeader(http.StatusBadRequest)
-				ErrUserNotRemoved.Log()
-				ctx.NotifyError(models.TokenRevoked, &ErrUserNotRemoved, usr)
-				json.NewEncoder(w).Encode(ErrUserNotRemoved)
-				return
-			}
-		case "revoke_token":
-			if !ctx.UserManager.UpdateUserRefreshToken(usr.ID, "") {
-				w.WriteHeader(http.StatusBadRequest)
-				ErrTokenNotFound.Log()
-				ctx.NotifyError(models.TokenRevoked, &ErrTokenNotFound, usr)
-				json.NewEncoder(w).Encode(ErrUserNotRemoved)
-				return
-			}
-		default:
-			w.WriteHeader(http.StatusBadRequest)
-			ErrGrantNotSupported.Log()
-			ctx.NotifyError(models.TokenRevoked, &ErrGrantNotSupported, usr)
-			json.NewEncoder(w).Encode(ErrGrantNotSupported)
-			return
+		if oauthflow.RevokeToken(ctx.AuthorizationContext, revokeRequest.Token, clientRequest.ClientID) {
+			ctx.NotifySuccess(models.TokenRevoked, clientRequest.ClientID)
 		}
 
-		w.WriteHeader(http.StatusAccepted)
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
 	}
 }
@@ -18,11 +18,17 @@ func (c *AuthorizationControllers) Token() controllers.Controller {
 
 		switch loginRequest.GrantType {
 		case "password":
-			response, errorResponse := oauthflow.PasswordGrantFlow{}.Authenticate(&loginRequest)
+			var response *models.OAuthLoginResponse
+			errorResponse := authenticateGrantClient(r, &loginRequest)
+			if errorResponse == nil {
+				response, errorResponse = oauthflow.PasswordGrantFlow{}.Authenticate(&loginRequest)
+			}
 			if errorResponse != nil {
 				switch errorResponse.Error {
 				case models.OAuthInvalidClientError:
 					w.WriteHeader(http.StatusUnauthorized)
+				case models.OAuthMfaRequired:
+					w.WriteHeader(http.StatusForbidden)
 				default:
 					w.WriteHeader(http.StatusBadRequest)
 				}
@@ -35,8 +41,148 @@ func (c *AuthorizationControllers) Token() controllers.Controller {
 			ctx.NotifySuccess(models.TokenRequest, loginRequest)
 			json.NewEncoder(w).Encode(*response)
 			return
+		case "mfa_otp":
+			var response *models.OAuthLoginResponse
+			errorResponse := authenticateGrantClient(r, &loginRequest)
+			if errorResponse == nil {
+				response, errorResponse = oauthflow.MfaOtpGrantFlow{}.Authenticate(&loginRequest)
+			}
+			if errorResponse != nil {
+				switch errorResponse.Error {
+				case models.OAuthInvalidClientError:
+					w.WriteHeader(http.StatusUnauthorized)
+				default:
+					w.WriteHeader(http.StatusBadRequest)
+				}
+
+				ctx.NotifyError(models.TokenRequest, errorResponse, loginRequest)
+				json.NewEncoder(w).Encode(*errorResponse)
+				return
+			}
+
+			ctx.NotifySuccess(models.TokenRequest, loginRequest)
+			json.NewEncoder(w).Encode(*response)
+			return
+		case "webauthn":
+			var response *models.OAuthLoginResponse
+			errorResponse := authenticateGrantClient(r, &loginRequest)
+			if errorResponse == nil {
+				response, errorResponse = oauthflow.WebAuthnGrantFlow{}.Authenticate(&loginRequest)
+			}
+			if errorResponse != nil {
+				switch errorResponse.Error {
+				case models.OAuthInvalidClientError:
+					w.WriteHeader(http.StatusUnauthorized)
+				default:
+					w.WriteHeader(http.StatusBadRequest)
+				}
+
+				ctx.NotifyError(models.TokenRequest, errorResponse, loginRequest)
+				json.NewEncoder(w).Encode(*errorResponse)
+				return
+			}
+
+			ctx.NotifySuccess(models.TokenRequest, loginRequest)
+			json.NewEncoder(w).Encode(*response)
+			return
+		case "login_code":
+			var response *models.OAuthLoginResponse
+			errorResponse := authenticateGrantClient(r, &loginRequest)
+			if errorResponse == nil {
+				response, errorResponse = oauthflow.LoginCodeGrantFlow{}.Authenticate(&loginRequest)
+			}
+			if errorResponse != nil {
+				switch errorResponse.Error {
+				case models.OAuthInvalidClientError:
+					w.WriteHeader(http.StatusUnauthorized)
+				case models.OAuthMfaRequired:
+					w.WriteHeader(http.StatusForbidden)
+				default:
+					w.WriteHeader(http.StatusBadRequest)
+				}
+
+				ctx.NotifyError(models.TokenRequest, errorResponse, loginRequest)
+				json.NewEncoder(w).Encode(*errorResponse)
+				return
+			}
+
+			ctx.NotifySuccess(models.TokenRequest, loginRequest)
+			json.NewEncoder(w).Encode(*response)
+			return
+		case "authorization_code":
+			authenticationMethod, errorResponse := getClientAuthentication(r, &loginRequest)
+			var response *models.OAuthLoginResponse
+			if errorResponse == nil {
+				response, errorResponse = oauthflow.AuthorizationCodeGrantFlow{AuthenticationMethod: authenticationMethod}.Authenticate(&loginRequest)
+			}
+			if errorResponse != nil {
+				switch errorResponse.Error {
+				case models.OAuthInvalidClientError:
+					w.WriteHeader(http.StatusUnauthorized)
+				default:
+					w.WriteHeader(http.StatusBadRequest)
+				}
+
+				ctx.NotifyError(models.TokenRequest, errorResponse, loginRequest)
+				json.NewEncoder(w).Encode(*errorResponse)
+				return
+			}
+
+			ctx.NotifySuccess(models.TokenRequest, loginRequest)
+			json.NewEncoder(w).Encode(*response)
+			return
+		case "client_credentials":
+			authenticationMethod, errorResponse := getClientAuthentication(r, &loginRequest)
+			if errorResponse == nil {
+				var response *models.OAuthLoginResponse
+				response, errorResponse = oauthflow.ClientCredentialsGrantFlow{AuthenticationMethod: authenticationMethod}.Authenticate(&loginRequest)
+				if errorResponse == nil {
+					ctx.NotifySuccess(models.TokenRequest, loginRequest)
+					json.NewEncoder(w).Encode(*response)
+					return
+				}
+			}
+
+			switch errorResponse.Error {
+			case models.OAuthInvalidClientError:
+				if authenticationMethod == models.ClientSecretBasicAuthMethod {
+					w.Header().Set("WWW-Authenticate", "Basic")
+				}
+				w.WriteHeader(http.StatusUnauthorized)
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+			}
+
+			ctx.NotifyError(models.TokenRequest, errorResponse, loginRequest)
+			json.NewEncoder(w).Encode(*errorResponse)
+			return
+		case "urn:ietf:params:oauth:grant-type:token-exchange":
+			authenticationMethod, errorResponse := getClientAuthentication(r, &loginRequest)
+			if errorResponse == nil {
+				var response *models.OAuthLoginResponse
+				response, errorResponse = oauthflow.TokenExchangeGrantFlow{AuthenticationMethod: authenticationMethod}.Authenticate(&loginRequest)
+				if errorResponse == nil {
+					ctx.NotifySuccess(models.TokenRequest, loginRequest)
+					json.NewEncoder(w).Encode(*response)
+					return
+				}
+			}
+
+			switch errorResponse.Error {
+			case models.OAuthInvalidClientError:
+				if authenticationMethod == models.ClientSecretBasicAuthMethod {
+					w.Header().Set("WWW-Authenticate", "Basic")
+				}
+				w.WriteHeader(http.StatusUnauthorized)
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+			}
+
+			ctx.NotifyError(models.TokenRequest, errorResponse, loginRequest)
+			json.NewEncoder(w).Encode(*errorResponse)
+			return
 		case "refresh_token":
-			if loginRequest.Username != "" {
+			if loginRequest.Username != "" && loginRequest.ClientID == "" && !hasBasicAuth(r) {
 				response, errorResponse := oauthflow.PasswordGrantFlow{}.RefreshToken(&loginRequest)
 				if errorResponse != nil {
 					switch errorResponse.Error {
@@ -54,13 +200,27 @@ func (c *AuthorizationControllers) Token() controllers.Controller {
 				ctx.NotifySuccess(models.TokenRequest, loginRequest)
 				json.NewEncoder(w).Encode(*response)
 				return
-			} else if loginRequest.ClientID != "" {
-				// TODO: Implement client id validations
-				w.WriteHeader(http.StatusBadRequest)
-				ErrGrantNotSupported.Log()
+			} else if loginRequest.ClientID != "" || hasBasicAuth(r) {
+				errorResponse := authenticateGrantClient(r, &loginRequest)
+				if errorResponse == nil {
+					var response *models.OAuthLoginResponse
+					response, errorResponse = oauthflow.PasswordGrantFlow{}.RefreshToken(&loginRequest)
+					if errorResponse == nil {
+						ctx.NotifySuccess(models.TokenRequest, loginRequest)
+						json.NewEncoder(w).Encode(*response)
+						return
+					}
+				}
 
-				ctx.NotifyError(models.TokenRequest, &ErrGrantNotSupported, loginRequest)
-				json.NewEncoder(w).Encode(ErrGrantNotSupported)
+				switch errorResponse.Error {
+				case models.OAuthInvalidClientError:
+					w.WriteHeader(http.StatusUnauthorized)
+				default:
+					w.WriteHeader(http.StatusBadRequest)
+				}
+
+				ctx.NotifyError(models.TokenRequest, errorResponse, loginRequest)
+				json.NewEncoder(w).Encode(*errorResponse)
 				return
 			} else {
 				w.WriteHeader(http.StatusBadRequest)
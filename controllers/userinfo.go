@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	identity_constants "github.com/cjlapao/common-go-identity/constants"
+	"github.com/cjlapao/common-go-identity/jwt"
+	"github.com/cjlapao/common-go-identity/models"
+	"github.com/cjlapao/common-go-restapi/controllers"
+)
+
+// UserInfo returns the OpenID Connect claims of the user of the bearer token, the claims are
+// selected by the scopes granted in the token, clients that accept application/jwt get them
+// signed by the server
+func (c *AuthorizationControllers) UserInfo() controllers.Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := NewBaseContext(r)
+		user := getAuthenticatedUser(ctx)
+		if user == nil {
+			responseErr := models.NewOAuthErrorResponse(models.OAuthUnauthorizedClient, "The access token does not belong to a user")
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(responseErr)
+			return
+		}
+
+		scope := ctx.AuthorizationContext.User.Scope
+		if !jwt.HasScope(scope, identity_constants.OpenIdScope) {
+			responseErr := models.NewOAuthErrorResponse(models.OAuthInsufficientScope, "The access token was not granted the openid scope")
+			w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="openid"`)
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(responseErr)
+			return
+		}
+
+		userInfo := jwt.GetUserInfoClaims(*user, scope)
+		w.Header().Set("Cache-Control", "no-store")
+
+		if strings.Contains(r.Header.Get("Accept"), "application/jwt") {
+			token, err := jwt.GenerateUserInfoToken("", userInfo, ctx.AuthorizationContext.User.ClientID)
+			if err != nil {
+				ctx.Logger.Exception(err, "error signing the userinfo response")
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(ErrException)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/jwt")
+			w.Write([]byte(token))
+			return
+		}
+
+		json.NewEncoder(w).Encode(userInfo)
+	}
+}
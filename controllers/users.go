@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/cjlapao/common-go-identity/models"
+	"github.com/cjlapao/common-go-identity/oauthflow"
+	"github.com/cjlapao/common-go-restapi/controllers"
+)
+
+// RemoveUser deletes the user in the route, the tokens of the user are revoked first so no
+// session outlives it
+func (c *AuthorizationControllers) RemoveUser() controllers.Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := NewBaseContext(r)
+		usr := getRouteUser(ctx, w, models.UserRemoved)
+		if usr == nil {
+			return
+		}
+
+		revokeUserSessions(ctx, usr.ID)
+		if !ctx.UserManager.RemoveUser(usr.ID) {
+			w.WriteHeader(http.StatusBadRequest)
+			ErrUserNotRemoved.Log()
+			ctx.NotifyError(models.UserRemoved, &ErrUserNotRemoved, usr.ID)
+			json.NewEncoder(w).Encode(ErrUserNotRemoved)
+			return
+		}
+
+		ctx.Logger.Success("User %v was removed successfully", usr.ID)
+		ctx.NotifySuccess(models.UserRemoved, usr.ID)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// RevokeUserSessions revokes every refresh token of the user in the route and the access tokens
+// issued until now
+func (c *AuthorizationControllers) RevokeUserSessions() controllers.Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := NewBaseContext(r)
+		usr := getRouteUser(ctx, w, models.UserSessionsRevoked)
+		if usr == nil {
+			return
+		}
+
+		if !revokeUserSessions(ctx, usr.ID) {
+			w.WriteHeader(http.StatusInternalServerError)
+			ctx.NotifyError(models.UserSessionsRevoked, &ErrException, usr.ID)
+			json.NewEncoder(w).Encode(ErrException)
+			return
+		}
+
+		ctx.Logger.Success("Sessions of user %v were revoked successfully", usr.ID)
+		ctx.NotifySuccess(models.UserSessionsRevoked, usr.ID)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// getRouteUser returns the user in the route or writes the error response if it was not found
+func getRouteUser(ctx *BaseControllerContext, w http.ResponseWriter, notification models.OAuthNotificationType) *models.User {
+	if ctx.UserID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		ErrEmptyUserID.Log()
+		ctx.NotifyError(notification, &ErrEmptyUserID, ctx.UserID)
+		json.NewEncoder(w).Encode(ErrEmptyUserID)
+		return nil
+	}
+
+	usr := ctx.UserManager.GetUserById(ctx.UserID)
+	if usr == nil || usr.ID == "" {
+		w.WriteHeader(http.StatusNotFound)
+		ErrUserNotFound.Log()
+		ctx.NotifyError(notification, &ErrUserNotFound, ctx.UserID)
+		json.NewEncoder(w).Encode(ErrUserNotFound)
+		return nil
+	}
+
+	return usr
+}
+
+func revokeUserSessions(ctx *BaseControllerContext, userId string) bool {
+	if ctx.AuthorizationContext.RefreshTokenDatabaseAdapter != nil {
+		if err := ctx.AuthorizationContext.RefreshTokenDatabaseAdapter.RevokeUserRefreshTokens(userId); err != nil {
+			ctx.Logger.Exception(err, "There was an error revoking the refresh tokens of user %v", userId)
+			return false
+		}
+	}
+
+	ctx.UserManager.UpdateUserRefreshToken(userId, "")
+
+	if err := oauthflow.RevokeUserTokens(ctx.AuthorizationContext, userId); err != nil {
+		ctx.Logger.Exception(err, "There was an error revoking the access tokens of user %v", userId)
+		return false
+	}
+
+	return true
+}
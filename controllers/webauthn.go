@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/cjlapao/common-go-identity/models"
+	"github.com/cjlapao/common-go-identity/user_manager"
+	"github.com/cjlapao/common-go-restapi/controllers"
+	"github.com/gorilla/mux"
+)
+
+// BeginWebAuthnRegistration Returns the options for navigator.credentials.create to register a
+// new passkey for the authenticated user
+func (c *AuthorizationControllers) BeginWebAuthnRegistration() controllers.Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := NewBaseContext(r)
+		user := getAuthenticatedUser(ctx)
+		if user == nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			ctx.NotifyError(models.WebAuthnRegistration, &ErrUserNotFound, nil)
+			json.NewEncoder(w).Encode(ErrUserNotFound)
+			return
+		}
+
+		options, session, err := ctx.UserManager.BeginWebAuthnRegistration(user.ID)
+		if err != nil {
+			err.Log()
+			responseErr := toWebAuthnErrorResponse(err)
+			w.WriteHeader(http.StatusBadRequest)
+			ctx.NotifyError(models.WebAuthnRegistration, &responseErr, user.ID)
+			json.NewEncoder(w).Encode(responseErr)
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(models.OAuthWebAuthnBeginResponse{
+			Session:   session,
+			PublicKey: options,
+		})
+	}
+}
+
+// FinishWebAuthnRegistration Verifies the credential created by the authenticator and stores it
+// for the authenticated user
+func (c *AuthorizationControllers) FinishWebAuthnRegistration() controllers.Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := NewBaseContext(r)
+		var registrationRequest models.OAuthWebAuthnRegistrationRequest
+		if err := json.NewDecoder(r.Body).Decode(&registrationRequest); err != nil {
+			responseErr := models.NewOAuthErrorResponse(models.OAuthInvalidRequestError, "The webauthn registration request is not valid")
+			w.WriteHeader(http.StatusBadRequest)
+			ctx.NotifyError(models.WebAuthnRegistration, &responseErr, nil)
+			json.NewEncoder(w).Encode(responseErr)
+			return
+		}
+
+		user := getAuthenticatedUser(ctx)
+		if user == nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			ctx.NotifyError(models.WebAuthnRegistration, &ErrUserNotFound, nil)
+			json.NewEncoder(w).Encode(ErrUserNotFound)
+			return
+		}
+
+		credential, err := ctx.UserManager.FinishWebAuthnRegistration(user.ID, registrationRequest.Session, registrationRequest.Name, registrationRequest.Credential)
+		if err != nil {
+			err.Log()
+			responseErr := toWebAuthnErrorResponse(err)
+			w.WriteHeader(http.StatusBadRequest)
+			ctx.NotifyError(models.WebAuthnRegistration, &responseErr, user.ID)
+			json.NewEncoder(w).Encode(responseErr)
+			return
+		}
+
+		ctx.NotifySuccess(models.WebAuthnRegistration, user.ID)
+		ctx.Logger.Info("User %v registered the webauthn credential %v", user.ID, credential.ID)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(credential)
+	}
+}
+
+// GetWebAuthnCredentials Returns the passkeys registered by the authenticated user
+func (c *AuthorizationControllers) GetWebAuthnCredentials() controllers.Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := NewBaseContext(r)
+		user := getAuthenticatedUser(ctx)
+		if user == nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(ErrUserNotFound)
+			return
+		}
+
+		json.NewEncoder(w).Encode(ctx.UserManager.GetWebAuthnCredentials(user.ID))
+	}
+}
+
+// RemoveWebAuthnCredential Removes a passkey of the authenticated user
+func (c *AuthorizationControllers) RemoveWebAuthnCredential() controllers.Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := NewBaseContext(r)
+		credentialId := mux.Vars(r)["credentialId"]
+		user := getAuthenticatedUser(ctx)
+		if user == nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			ctx.NotifyError(models.WebAuthnRemoval, &ErrUserNotFound, nil)
+			json.NewEncoder(w).Encode(ErrUserNotFound)
+			return
+		}
+
+		if err := ctx.UserManager.RemoveWebAuthnCredential(user.ID, credentialId); err != nil {
+			err.Log()
+			responseErr := toWebAuthnErrorResponse(err)
+			w.WriteHeader(http.StatusNotFound)
+			ctx.NotifyError(models.WebAuthnRemoval, &responseErr, user.ID)
+			json.NewEncoder(w).Encode(responseErr)
+			return
+		}
+
+		ctx.NotifySuccess(models.WebAuthnRemoval, user.ID)
+		ctx.Logger.Info("User %v removed the webauthn credential %v", user.ID, credentialId)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// BeginWebAuthnLogin Returns the options for navigator.credentials.get to sign in with a
+// passkey, the assertion is then exchanged for a token with the webauthn grant
+func (c *AuthorizationControllers) BeginWebAuthnLogin() controllers.Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := NewBaseContext(r)
+		var loginRequest models.OAuthWebAuthnLoginRequest
+		ctx.MapRequestBody(&loginRequest)
+
+		options, session, err := ctx.UserManager.BeginWebAuthnLogin(loginRequest.Username, loginRequest.ClientID)
+		if err != nil {
+			err.Log()
+			responseErr := toWebAuthnErrorResponse(err)
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(responseErr)
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(models.OAuthWebAuthnBeginResponse{
+			Session:   session,
+			PublicKey: options,
+		})
+	}
+}
+
+func toWebAuthnErrorResponse(err *user_manager.UserManagerError) models.OAuthErrorResponse {
+	switch err.Error {
+	case user_manager.InvalidTokenError:
+		return models.NewOAuthErrorResponse(models.OAuthInvalidRequestError, "The webauthn session is not valid")
+	case user_manager.InvalidWebAuthnResponseError:
+		return models.NewOAuthErrorResponse(models.OAuthInvalidRequestError, "The webauthn credential is not valid")
+	case user_manager.WebAuthnCredentialNotFoundError:
+		return models.NewOAuthErrorResponse(models.OAuthInvalidRequestError, "The webauthn credential was not found")
+	default:
+		return models.NewOAuthErrorResponse(models.UnknownError, "There was an error processing the webauthn request")
+	}
+}
@@ -0,0 +1,90 @@
+package data_protection
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+const protectedPrefix = "v1:"
+
+var (
+	ErrEmptyKey             = errors.New("the encryption key cannot be empty")
+	ErrInvalidProtectedData = errors.New("the protected data is not valid")
+)
+
+// Protector encrypts data at rest with AES-256-GCM, the key is derived from the configured
+// encryption key so any length of key can be used
+type Protector struct {
+	aead cipher.AEAD
+}
+
+func NewProtector(key string) (*Protector, error) {
+	if key == "" {
+		return nil, ErrEmptyKey
+	}
+
+	derivedKey := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(derivedKey[:])
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Protector{
+		aead: aead,
+	}, nil
+}
+
+// Protect encrypts the data with a random nonce and returns it as a versioned base64 string
+func (p *Protector) Protect(data []byte) (string, error) {
+	nonce := make([]byte, p.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := p.aead.Seal(nonce, nonce, data, nil)
+	return protectedPrefix + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Unprotect decrypts data returned by Protect, it fails if the data was changed or was
+// encrypted with another key
+func (p *Protector) Unprotect(protected string) ([]byte, error) {
+	if !strings.HasPrefix(protected, protectedPrefix) {
+		return nil, ErrInvalidProtectedData
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(protected, protectedPrefix))
+	if err != nil || len(sealed) < p.aead.NonceSize() {
+		return nil, ErrInvalidProtectedData
+	}
+
+	nonce := sealed[:p.aead.NonceSize()]
+	data, err := p.aead.Open(nil, nonce, sealed[p.aead.NonceSize():], nil)
+	if err != nil {
+		return nil, ErrInvalidProtectedData
+	}
+
+	return data, nil
+}
+
+func (p *Protector) ProtectString(value string) (string, error) {
+	return p.Protect([]byte(value))
+}
+
+func (p *Protector) UnprotectString(protected string) (string, error) {
+	data, err := p.Unprotect(protected)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
@@ -0,0 +1,56 @@
+package data_protection
+
+import (
+	"testing"
+)
+
+func TestProtectRoundTrip(t *testing.T) {
+	protector, err := NewProtector("a-key-used-only-by-the-tests")
+	if err != nil {
+		t.Fatalf("failed to create the protector, %v", err)
+	}
+
+	protected, err := protector.ProtectString("JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatalf("failed to protect the value, %v", err)
+	}
+	if protected == "JBSWY3DPEHPK3PXP" {
+		t.Fatalf("expected the value to be encrypted")
+	}
+
+	other, _ := protector.ProtectString("JBSWY3DPEHPK3PXP")
+	if protected == other {
+		t.Errorf("expected every encryption to use a new nonce")
+	}
+
+	value, err := protector.UnprotectString(protected)
+	if err != nil || value != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("expected the original value, got %v %v", value, err)
+	}
+}
+
+func TestUnprotectRejectsTamperedData(t *testing.T) {
+	protector, _ := NewProtector("a-key-used-only-by-the-tests")
+	otherProtector, _ := NewProtector("another-key")
+	protected, _ := protector.ProtectString("JBSWY3DPEHPK3PXP")
+
+	if _, err := otherProtector.UnprotectString(protected); err == nil {
+		t.Errorf("expected data encrypted with another key to be rejected")
+	}
+
+	tampered := protected[:len(protected)-2] + "AA"
+	if tampered == protected {
+		tampered = protected[:len(protected)-2] + "BB"
+	}
+	if _, err := protector.UnprotectString(tampered); err == nil {
+		t.Errorf("expected tampered data to be rejected")
+	}
+
+	if _, err := protector.UnprotectString("JBSWY3DPEHPK3PXP"); err == nil {
+		t.Errorf("expected plain text to be rejected")
+	}
+
+	if _, err := NewProtector(""); err == nil {
+		t.Errorf("expected an empty key to be rejected")
+	}
+}
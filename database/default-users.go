@@ -4,9 +4,9 @@ import (
 	"fmt"
 
 	"github.com/cjlapao/common-go-identity/database/dto"
+	"github.com/cjlapao/common-go-identity/password_hasher"
 	"github.com/cjlapao/common-go/configuration"
 	"github.com/cjlapao/common-go/helper/reflect_helper"
-	"github.com/cjlapao/common-go/security"
 )
 
 func GetDefaultUsers() []dto.UserDTO {
@@ -25,7 +25,6 @@ func GetDefaultUsers() []dto.UserDTO {
 			FirstName: "Administrator",
 			LastName:  "User",
 			Username:  "admin@localhost.com",
-			Password:  "a075d17f3d453073853f813838c15b8023b8c487038436354fe599c3942e1f95",
 		}
 	} else {
 		adminUser = dto.UserDTO{
@@ -38,10 +37,9 @@ func GetDefaultUsers() []dto.UserDTO {
 	}
 
 	if reflect_helper.IsNilOrEmpty(adminPassword) {
-		security.SHA256Encode("p@ssw0rd")
-	} else {
-		adminUser.Password = security.SHA256Encode(adminPassword)
+		adminPassword = "p@ssw0rd"
 	}
+	adminUser.Password = hashDefaultPassword(adminPassword)
 
 	// adminUser.Roles = append(adminUser.Roles, mappers.ToUserRoleDTO(constants.AdminRole), mappers.ToUserRoleDTO(constants.RegularUserRole))
 	// adminUser.Claims = append(adminUser.Claims, mappers.ToUserClaimDTO(constants.ReadClaim), mappers.ToUserClaimDTO(constants.ReadWriteClaim), mappers.ToUserClaimDTO(constants.RemoveClaim))
@@ -56,7 +54,6 @@ func GetDefaultUsers() []dto.UserDTO {
 			FirstName: "Demo",
 			LastName:  "User",
 			Username:  "demo@localhost.com",
-			Password:  "2a97516c354b68848cdbd8f54a226a0a55b21ed138e207ad6c5cbb9c00aa5aea",
 		}
 	} else {
 		demoUser = dto.UserDTO{
@@ -69,10 +66,9 @@ func GetDefaultUsers() []dto.UserDTO {
 	}
 
 	if reflect_helper.IsNilOrEmpty(demoPassword) {
-		security.SHA256Encode("demo")
-	} else {
-		demoUser.Password = security.SHA256Encode(demoPassword)
+		demoPassword = "demo"
 	}
+	demoUser.Password = hashDefaultPassword(demoPassword)
 
 	// demoUser.Roles = append(demoUser.Roles, mappers.ToUserRoleDTO(constants.RegularUserRole))
 	// demoUser.Claims = append(demoUser.Claims, mappers.ToUserClaimDTO(constants.ReadClaim))
@@ -82,3 +78,14 @@ func GetDefaultUsers() []dto.UserDTO {
 
 	return users
 }
+
+// hashDefaultPassword hashes the seeded passwords with the default password hasher
+func hashDefaultPassword(password string) string {
+	hashedPassword, err := password_hasher.Hash(password)
+	if err != nil {
+		logger.Exception(err, "There was an error hashing the default user password")
+		return ""
+	}
+
+	return hashedPassword
+}
@@ -0,0 +1,18 @@
+package dto
+
+import "time"
+
+type AuthorizationCodeDTO struct {
+	ID                  string    `json:"id" bson:"_id"`
+	ClientID            string    `json:"clientId" bson:"clientId"`
+	UserID              string    `json:"userId" bson:"userId"`
+	TenantID            string    `json:"tenantId" bson:"tenantId"`
+	RedirectUri         string    `json:"redirectUri" bson:"redirectUri"`
+	Scope               string    `json:"scope" bson:"scope"`
+	Nonce               string    `json:"nonce" bson:"nonce"`
+	CodeChallenge       string    `json:"codeChallenge" bson:"codeChallenge"`
+	CodeChallengeMethod string    `json:"codeChallengeMethod" bson:"codeChallengeMethod"`
+	AuthTime            time.Time `json:"authTime" bson:"authTime"`
+	Amr                 string    `json:"amr" bson:"amr"`
+	ExpiresAt           time.Time `json:"expiresAt" bson:"expiresAt"`
+}
@@ -0,0 +1,18 @@
+package dto
+
+import "time"
+
+type ClientDTO struct {
+	ID                      string    `json:"id" bson:"_id"`
+	Name                    string    `json:"name" bson:"name"`
+	Secret                  string    `json:"secret" bson:"secret"`
+	TenantID                string    `json:"tenantId" bson:"tenantId"`
+	RedirectUris            []string  `json:"redirectUris" bson:"redirectUris"`
+	GrantTypes              []string  `json:"grantTypes" bson:"grantTypes"`
+	Scopes                  []string  `json:"scopes" bson:"scopes"`
+	TokenEndpointAuthMethod string    `json:"tokenEndpointAuthMethod" bson:"tokenEndpointAuthMethod"`
+	AccessTokenFormat       string    `json:"accessTokenFormat" bson:"accessTokenFormat"`
+	Blocked                 bool      `json:"blocked" bson:"blocked"`
+	RegistrationAccessToken string    `json:"registrationAccessToken" bson:"registrationAccessToken"`
+	IssuedAt                time.Time `json:"issuedAt" bson:"issuedAt"`
+}
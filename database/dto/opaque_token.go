@@ -0,0 +1,14 @@
+package dto
+
+import "time"
+
+// OpaqueTokenDTO keeps the claims of an opaque access token, the id is the hash of the handle
+// given to the client so the handles cannot be read from the store
+type OpaqueTokenDTO struct {
+	ID        string    `json:"id" bson:"_id"`
+	TokenID   string    `json:"tokenId" bson:"tokenId"`
+	ClientID  string    `json:"clientId" bson:"clientId"`
+	UserID    string    `json:"userId" bson:"userId"`
+	Claims    string    `json:"claims" bson:"claims"`
+	ExpiresAt time.Time `json:"expiresAt" bson:"expiresAt"`
+}
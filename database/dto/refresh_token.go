@@ -0,0 +1,16 @@
+package dto
+
+import "time"
+
+type RefreshTokenDTO struct {
+	ID        string    `json:"id" bson:"_id"`
+	FamilyID  string    `json:"familyId" bson:"familyId"`
+	UserID    string    `json:"userId" bson:"userId"`
+	ClientID  string    `json:"clientId" bson:"clientId"`
+	TenantID  string    `json:"tenantId" bson:"tenantId"`
+	Scope     string    `json:"scope" bson:"scope"`
+	Rotated   bool      `json:"rotated" bson:"rotated"`
+	Revoked   bool      `json:"revoked" bson:"revoked"`
+	IssuedAt  time.Time `json:"issuedAt" bson:"issuedAt"`
+	ExpiresAt time.Time `json:"expiresAt" bson:"expiresAt"`
+}
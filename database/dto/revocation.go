@@ -0,0 +1,15 @@
+package dto
+
+import "time"
+
+type RevokedTokenDTO struct {
+	ID        string    `json:"id" bson:"_id"`
+	ExpiresAt time.Time `json:"expiresAt" bson:"expiresAt"`
+}
+
+// UserTokenRevocationDTO revokes every access token of the user issued before the time
+type UserTokenRevocationDTO struct {
+	UserID        string    `json:"userId" bson:"_id"`
+	RevokedBefore time.Time `json:"revokedBefore" bson:"revokedBefore"`
+	ExpiresAt     time.Time `json:"expiresAt" bson:"expiresAt"`
+}
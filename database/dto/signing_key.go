@@ -0,0 +1,15 @@
+package dto
+
+import "time"
+
+// SigningKeyDTO is a signing key of the key vault, the private key is the PKCS#8 encoding of
+// the rsa and ecdsa keys or the hmac secret, encrypted with the configured encryption key
+type SigningKeyDTO struct {
+	ID          string    `json:"id" bson:"_id"`
+	Algorithm   string    `json:"algorithm" bson:"algorithm"`
+	PrivateKey  string    `json:"privateKey" bson:"privateKey"`
+	CreatedAt   time.Time `json:"createdAt" bson:"createdAt"`
+	ActivatesAt time.Time `json:"activatesAt" bson:"activatesAt"`
+	ExpiresAt   time.Time `json:"expiresAt" bson:"expiresAt"`
+	Retired     bool      `json:"retired" bson:"retired"`
+}
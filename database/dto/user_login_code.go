@@ -0,0 +1,12 @@
+package dto
+
+import "time"
+
+type UserLoginCodeDTO struct {
+	UserID    string    `json:"userId" bson:"_id"`
+	Code      string    `json:"code" bson:"code"`
+	ClientID  string    `json:"clientId" bson:"clientId"`
+	Attempts  int       `json:"attempts" bson:"attempts"`
+	ExpiresAt time.Time `json:"expiresAt" bson:"expiresAt"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+}
@@ -0,0 +1,10 @@
+package dto
+
+import "time"
+
+type UserRecoveryCodeDTO struct {
+	ID        string    `json:"id" bson:"_id"`
+	UserID    string    `json:"userId" bson:"userId"`
+	Used      bool      `json:"used" bson:"used"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+}
@@ -0,0 +1,11 @@
+package dto
+
+import "time"
+
+type UserTotpDTO struct {
+	UserID       string    `json:"userId" bson:"_id"`
+	Secret       string    `json:"secret" bson:"secret"`
+	Enabled      bool      `json:"enabled" bson:"enabled"`
+	LastUsedStep int64     `json:"lastUsedStep" bson:"lastUsedStep"`
+	CreatedAt    time.Time `json:"createdAt" bson:"createdAt"`
+}
@@ -0,0 +1,15 @@
+package dto
+
+import "time"
+
+type WebAuthnCredentialDTO struct {
+	ID              string    `json:"id" bson:"_id"`
+	UserID          string    `json:"userId" bson:"userId"`
+	Name            string    `json:"name" bson:"name"`
+	PublicKey       string    `json:"publicKey" bson:"publicKey"`
+	AttestationType string    `json:"attestationType" bson:"attestationType"`
+	AAGUID          string    `json:"aaguid" bson:"aaguid"`
+	SignCount       int64     `json:"signCount" bson:"signCount"`
+	CreatedAt       time.Time `json:"createdAt" bson:"createdAt"`
+	LastUsedAt      time.Time `json:"lastUsedAt" bson:"lastUsedAt"`
+}
@@ -0,0 +1,10 @@
+package dto
+
+import "time"
+
+// WebAuthnSessionDTO is a pending webauthn ceremony, the id is the hash of its challenge
+type WebAuthnSessionDTO struct {
+	ID        string    `json:"id" bson:"_id"`
+	UserID    string    `json:"userId" bson:"userId"`
+	ExpiresAt time.Time `json:"expiresAt" bson:"expiresAt"`
+}
@@ -0,0 +1,139 @@
+package file
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/cjlapao/common-go-identity/database/dto"
+)
+
+// FileKeyStoreAdapter keeps the signing keys in a json file, the file is replaced on every
+// change so a reader never sees a partially written file
+type FileKeyStoreAdapter struct {
+	mutex sync.Mutex
+	Path  string
+}
+
+func NewFileKeyStoreAdapter(path string) *FileKeyStoreAdapter {
+	return &FileKeyStoreAdapter{
+		Path: path,
+	}
+}
+
+func (c *FileKeyStoreAdapter) GetKeys() []dto.SigningKeyDTO {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	keys, err := c.read()
+	if err != nil {
+		return make([]dto.SigningKeyDTO, 0)
+	}
+
+	return keys
+}
+
+func (c *FileKeyStoreAdapter) GetKey(id string) *dto.SigningKeyDTO {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	keys, err := c.read()
+	if err != nil {
+		return nil
+	}
+
+	for _, key := range keys {
+		if key.ID == id {
+			return &key
+		}
+	}
+
+	return nil
+}
+
+func (c *FileKeyStoreAdapter) AddKey(key dto.SigningKeyDTO) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	keys, err := c.read()
+	if err != nil {
+		return false
+	}
+
+	for _, existing := range keys {
+		if existing.ID == key.ID {
+			return false
+		}
+	}
+
+	keys = append(keys, key)
+	return c.write(keys) == nil
+}
+
+func (c *FileKeyStoreAdapter) RetireKey(id string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	keys, err := c.read()
+	if err != nil {
+		return err
+	}
+
+	for i := range keys {
+		if keys[i].ID == id {
+			keys[i].Retired = true
+			return c.write(keys)
+		}
+	}
+
+	return nil
+}
+
+func (c *FileKeyStoreAdapter) read() ([]dto.SigningKeyDTO, error) {
+	keys := make([]dto.SigningKeyDTO, 0)
+	content, err := os.ReadFile(c.Path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return keys, nil
+		}
+		return nil, err
+	}
+
+	if len(content) > 0 {
+		if err := json.Unmarshal(content, &keys); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].ActivatesAt.Before(keys[j].ActivatesAt)
+	})
+
+	return keys, nil
+}
+
+func (c *FileKeyStoreAdapter) write(keys []dto.SigningKeyDTO) error {
+	content, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(c.Path), filepath.Base(c.Path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(content); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), c.Path)
+}
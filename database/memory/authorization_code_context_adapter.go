@@ -0,0 +1,51 @@
+package memory
+
+import (
+	"sync"
+
+	"github.com/cjlapao/common-go-identity/database/dto"
+)
+
+type MemoryAuthorizationCodeContextAdapter struct {
+	mutex sync.Mutex
+	Codes map[string]dto.AuthorizationCodeDTO
+}
+
+func NewMemoryAuthorizationCodeAdapter() *MemoryAuthorizationCodeContextAdapter {
+	context := MemoryAuthorizationCodeContextAdapter{}
+	context.Codes = make(map[string]dto.AuthorizationCodeDTO)
+
+	return &context
+}
+
+func (c *MemoryAuthorizationCodeContextAdapter) GetAuthorizationCode(id string) *dto.AuthorizationCodeDTO {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	code, ok := c.Codes[id]
+	if !ok {
+		return nil
+	}
+
+	return &code
+}
+
+func (c *MemoryAuthorizationCodeContextAdapter) UpsertAuthorizationCode(code dto.AuthorizationCodeDTO) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.Codes[code.ID] = code
+	return nil
+}
+
+func (c *MemoryAuthorizationCodeContextAdapter) RemoveAuthorizationCode(id string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, ok := c.Codes[id]; !ok {
+		return false
+	}
+
+	delete(c.Codes, id)
+	return true
+}
@@ -0,0 +1,51 @@
+package memory
+
+import (
+	"sync"
+
+	"github.com/cjlapao/common-go-identity/database/dto"
+)
+
+type MemoryClientContextAdapter struct {
+	mutex   sync.Mutex
+	Clients map[string]dto.ClientDTO
+}
+
+func NewMemoryClientAdapter() *MemoryClientContextAdapter {
+	context := MemoryClientContextAdapter{}
+	context.Clients = make(map[string]dto.ClientDTO)
+
+	return &context
+}
+
+func (c *MemoryClientContextAdapter) GetClientById(id string) *dto.ClientDTO {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	client, ok := c.Clients[id]
+	if !ok {
+		return nil
+	}
+
+	return &client
+}
+
+func (c *MemoryClientContextAdapter) UpsertClient(client dto.ClientDTO) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.Clients[client.ID] = client
+	return nil
+}
+
+func (c *MemoryClientContextAdapter) RemoveClient(id string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, ok := c.Clients[id]; !ok {
+		return false
+	}
+
+	delete(c.Clients, id)
+	return true
+}
@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/cjlapao/common-go-identity/database/dto"
+)
+
+type MemoryKeyStoreAdapter struct {
+	mutex sync.Mutex
+	Keys  map[string]dto.SigningKeyDTO
+}
+
+func NewMemoryKeyStoreAdapter() *MemoryKeyStoreAdapter {
+	context := MemoryKeyStoreAdapter{}
+	context.Keys = make(map[string]dto.SigningKeyDTO)
+
+	return &context
+}
+
+func (c *MemoryKeyStoreAdapter) GetKeys() []dto.SigningKeyDTO {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	keys := make([]dto.SigningKeyDTO, 0)
+	for _, key := range c.Keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ActivatesAt.Before(keys[j].ActivatesAt)
+	})
+
+	return keys
+}
+
+func (c *MemoryKeyStoreAdapter) GetKey(id string) *dto.SigningKeyDTO {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	key, ok := c.Keys[id]
+	if !ok {
+		return nil
+	}
+
+	return &key
+}
+
+func (c *MemoryKeyStoreAdapter) AddKey(key dto.SigningKeyDTO) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, exists := c.Keys[key.ID]; exists {
+		return false
+	}
+
+	c.Keys[key.ID] = key
+	return true
+}
+
+func (c *MemoryKeyStoreAdapter) RetireKey(id string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if key, ok := c.Keys[id]; ok {
+		key.Retired = true
+		c.Keys[id] = key
+	}
+
+	return nil
+}
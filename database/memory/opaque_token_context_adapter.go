@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cjlapao/common-go-identity/database/dto"
+)
+
+type MemoryOpaqueTokenContextAdapter struct {
+	mutex  sync.Mutex
+	Tokens map[string]dto.OpaqueTokenDTO
+}
+
+func NewMemoryOpaqueTokenAdapter() *MemoryOpaqueTokenContextAdapter {
+	context := MemoryOpaqueTokenContextAdapter{}
+	context.Tokens = make(map[string]dto.OpaqueTokenDTO)
+
+	return &context
+}
+
+func (c *MemoryOpaqueTokenContextAdapter) GetOpaqueToken(id string) *dto.OpaqueTokenDTO {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	token, ok := c.Tokens[id]
+	if !ok || token.ExpiresAt.Before(time.Now()) {
+		return nil
+	}
+
+	return &token
+}
+
+func (c *MemoryOpaqueTokenContextAdapter) AddOpaqueToken(token dto.OpaqueTokenDTO) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Removing the expired tokens so the store does not grow forever
+	now := time.Now()
+	for id, storedToken := range c.Tokens {
+		if storedToken.ExpiresAt.Before(now) {
+			delete(c.Tokens, id)
+		}
+	}
+
+	c.Tokens[token.ID] = token
+	return nil
+}
+
+func (c *MemoryOpaqueTokenContextAdapter) RemoveOpaqueToken(id string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.Tokens, id)
+	return nil
+}
@@ -0,0 +1,90 @@
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cjlapao/common-go-identity/database/dto"
+)
+
+type MemoryRefreshTokenContextAdapter struct {
+	mutex  sync.Mutex
+	Tokens map[string]dto.RefreshTokenDTO
+}
+
+func NewMemoryRefreshTokenAdapter() *MemoryRefreshTokenContextAdapter {
+	context := MemoryRefreshTokenContextAdapter{}
+	context.Tokens = make(map[string]dto.RefreshTokenDTO)
+
+	return &context
+}
+
+func (c *MemoryRefreshTokenContextAdapter) GetRefreshToken(id string) *dto.RefreshTokenDTO {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	token, ok := c.Tokens[id]
+	if !ok {
+		return nil
+	}
+
+	return &token
+}
+
+func (c *MemoryRefreshTokenContextAdapter) UpsertRefreshToken(token dto.RefreshTokenDTO) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Removing the expired tokens so the store does not grow forever
+	now := time.Now()
+	for id, storedToken := range c.Tokens {
+		if storedToken.ExpiresAt.Before(now) {
+			delete(c.Tokens, id)
+		}
+	}
+
+	c.Tokens[token.ID] = token
+	return nil
+}
+
+func (c *MemoryRefreshTokenContextAdapter) RotateRefreshToken(id string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	token, ok := c.Tokens[id]
+	if !ok || token.Rotated || token.Revoked {
+		return false
+	}
+
+	token.Rotated = true
+	c.Tokens[id] = token
+	return true
+}
+
+func (c *MemoryRefreshTokenContextAdapter) RevokeRefreshTokenFamily(familyId string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for id, token := range c.Tokens {
+		if token.FamilyID == familyId {
+			token.Revoked = true
+			c.Tokens[id] = token
+		}
+	}
+
+	return nil
+}
+
+func (c *MemoryRefreshTokenContextAdapter) RevokeUserRefreshTokens(userId string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for id, token := range c.Tokens {
+		if token.UserID == userId {
+			token.Revoked = true
+			c.Tokens[id] = token
+		}
+	}
+
+	return nil
+}
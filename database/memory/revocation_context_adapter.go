@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cjlapao/common-go-identity/database/dto"
+)
+
+type MemoryRevocationContextAdapter struct {
+	mutex  sync.Mutex
+	Tokens map[string]time.Time
+	Users  map[string]dto.UserTokenRevocationDTO
+}
+
+func NewMemoryRevocationAdapter() *MemoryRevocationContextAdapter {
+	context := MemoryRevocationContextAdapter{}
+	context.Tokens = make(map[string]time.Time)
+	context.Users = make(map[string]dto.UserTokenRevocationDTO)
+
+	return &context
+}
+
+func (c *MemoryRevocationContextAdapter) RevokeToken(id string, expiresAt time.Time) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.removeExpired()
+	c.Tokens[id] = expiresAt
+	return nil
+}
+
+func (c *MemoryRevocationContextAdapter) IsTokenRevoked(id string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	expiresAt, ok := c.Tokens[id]
+	return ok && expiresAt.After(time.Now())
+}
+
+func (c *MemoryRevocationContextAdapter) RevokeUserTokens(revocation dto.UserTokenRevocationDTO) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.removeExpired()
+	c.Users[revocation.UserID] = revocation
+	return nil
+}
+
+func (c *MemoryRevocationContextAdapter) GetUserTokenRevocation(userId string) *dto.UserTokenRevocationDTO {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	revocation, ok := c.Users[userId]
+	if !ok || revocation.ExpiresAt.Before(time.Now()) {
+		return nil
+	}
+
+	return &revocation
+}
+
+// removeExpired removes the entries of the tokens that already expired so the store does not
+// grow forever
+func (c *MemoryRevocationContextAdapter) removeExpired() {
+	now := time.Now()
+	for tokenId, expiresAt := range c.Tokens {
+		if expiresAt.Before(now) {
+			delete(c.Tokens, tokenId)
+		}
+	}
+	for userId, revocation := range c.Users {
+		if revocation.ExpiresAt.Before(now) {
+			delete(c.Users, userId)
+		}
+	}
+}
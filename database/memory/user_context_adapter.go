@@ -1,108 +1,152 @@
 package memory
 
 import (
+	"errors"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/cjlapao/common-go-identity/database"
 	"github.com/cjlapao/common-go-identity/database/dto"
 )
 
 type MemoryUserContextAdapter struct {
-	Users []dto.UserDTO
+	usersMutex          sync.RWMutex
+	totpMutex           sync.Mutex
+	webAuthnMutex       sync.Mutex
+	loginCodesMutex     sync.Mutex
+	Users               []dto.UserDTO
+	Totp                map[string]dto.UserTotpDTO
+	RecoveryCodes       map[string][]dto.UserRecoveryCodeDTO
+	WebAuthnCredentials map[string]dto.WebAuthnCredentialDTO
+	WebAuthnSessions    map[string]dto.WebAuthnSessionDTO
+	LoginCodes          map[string]dto.UserLoginCodeDTO
 }
 
 func NewMemoryUserAdapter() *MemoryUserContextAdapter {
 	context := MemoryUserContextAdapter{}
 	context.Users = database.GetDefaultUsers()
+	context.Totp = make(map[string]dto.UserTotpDTO)
+	context.RecoveryCodes = make(map[string][]dto.UserRecoveryCodeDTO)
+	context.WebAuthnCredentials = make(map[string]dto.WebAuthnCredentialDTO)
+	context.WebAuthnSessions = make(map[string]dto.WebAuthnSessionDTO)
+	context.LoginCodes = make(map[string]dto.UserLoginCodeDTO)
 
 	return &context
 }
 
-func (c *MemoryUserContextAdapter) GetUserById(id string) *dto.UserDTO {
-	users := database.GetDefaultUsers()
-	var user dto.UserDTO
-	found := false
-	for _, usr := range users {
-		if strings.EqualFold(id, usr.ID) {
-			user = usr
-			found = true
-			break
+// findUser returns a copy of the first user that matches, the users can be changed by other
+// requests so a pointer into the store is never returned
+func (c *MemoryUserContextAdapter) findUser(match func(user dto.UserDTO) bool) *dto.UserDTO {
+	c.usersMutex.RLock()
+	defer c.usersMutex.RUnlock()
+
+	for _, user := range c.Users {
+		if match(user) {
+			return &user
 		}
 	}
 
-	if found {
-		return &user
-	}
 	return nil
 }
 
-func (c *MemoryUserContextAdapter) GetUserByEmail(email string) *dto.UserDTO {
-	users := database.GetDefaultUsers()
-	var user dto.UserDTO
-	found := false
-	for _, usr := range users {
-		if strings.EqualFold(email, usr.Email) {
-			user = usr
-			found = true
-			break
+// updateUser changes the user with the id while holding the store lock
+func (c *MemoryUserContextAdapter) updateUser(id string, update func(user *dto.UserDTO)) bool {
+	c.usersMutex.Lock()
+	defer c.usersMutex.Unlock()
+
+	for i := range c.Users {
+		if strings.EqualFold(id, c.Users[i].ID) {
+			update(&c.Users[i])
+			return true
 		}
 	}
 
-	if found {
-		return &user
-	}
-	return nil
+	return false
+}
+
+func (c *MemoryUserContextAdapter) GetUserById(id string) *dto.UserDTO {
+	return c.findUser(func(user dto.UserDTO) bool {
+		return strings.EqualFold(id, user.ID)
+	})
+}
+
+func (c *MemoryUserContextAdapter) GetUserByEmail(email string) *dto.UserDTO {
+	return c.findUser(func(user dto.UserDTO) bool {
+		return strings.EqualFold(email, user.Email)
+	})
 }
 
 func (c *MemoryUserContextAdapter) GetUserByUsername(username string) *dto.UserDTO {
-	users := database.GetDefaultUsers()
-	var user dto.UserDTO
-	found := false
-	for _, usr := range users {
-		if strings.EqualFold(username, usr.Username) {
-			user = usr
-			found = true
-			break
+	return c.findUser(func(user dto.UserDTO) bool {
+		return strings.EqualFold(username, user.Username)
+	})
+}
+
+func (c *MemoryUserContextAdapter) GetUser(id string) *dto.UserDTO {
+	return c.findUser(func(user dto.UserDTO) bool {
+		return strings.EqualFold(id, user.ID) || strings.EqualFold(id, user.Email) || strings.EqualFold(id, user.Username)
+	})
+}
+
+func (c *MemoryUserContextAdapter) UpsertUser(user dto.UserDTO) error {
+	c.usersMutex.Lock()
+	defer c.usersMutex.Unlock()
+
+	for i := range c.Users {
+		if strings.EqualFold(user.ID, c.Users[i].ID) {
+			c.Users[i] = user
+			return nil
 		}
 	}
 
-	if found {
-		return &user
-	}
+	c.Users = append(c.Users, user)
 	return nil
 }
 
-func (c *MemoryUserContextAdapter) GetUser(id string) *dto.UserDTO {
-	users := database.GetDefaultUsers()
-	var user dto.UserDTO
-	found := false
-	for _, usr := range users {
-		if strings.EqualFold(id, usr.ID) || strings.EqualFold(id, usr.Email) || strings.EqualFold(id, usr.Username) {
-			user = usr
-			found = true
-			break
+func (c *MemoryUserContextAdapter) RemoveUser(id string) bool {
+	c.usersMutex.Lock()
+	defer c.usersMutex.Unlock()
+
+	for i := range c.Users {
+		if strings.EqualFold(id, c.Users[i].ID) {
+			c.Users = append(c.Users[:i], c.Users[i+1:]...)
+			return true
 		}
 	}
 
-	if found {
-		return &user
-	}
-	return nil
+	return false
 }
 
-func (c *MemoryUserContextAdapter) UpsertUser(user dto.UserDTO) error {
-	c.Users = append(c.Users, user)
+func (c *MemoryUserContextAdapter) UpdateUserInvalidAttempts(id string, attempts int, blockedUntil string) error {
+	found := c.updateUser(id, func(user *dto.UserDTO) {
+		user.InvalidAttempts = attempts
+		user.BlockedUntil = &blockedUntil
+	})
+	if !found {
+		return errors.New("user not found")
+	}
+
 	return nil
 }
 
-func (u MemoryUserContextAdapter) RemoveUser(id string) bool {
-	return true
+func (c *MemoryUserContextAdapter) IncrementUserInvalidAttempts(id string) (int, error) {
+	attempts := 0
+	found := c.updateUser(id, func(user *dto.UserDTO) {
+		user.InvalidAttempts++
+		attempts = user.InvalidAttempts
+	})
+	if !found {
+		return 0, errors.New("user not found")
+	}
+
+	return attempts, nil
 }
 
 func (c *MemoryUserContextAdapter) GetUserRefreshToken(id string) *string {
 	user := c.GetUserById(id)
 	token := ""
-	if user != nil {
+	if user != nil && user.RefreshToken != nil {
 		token = *user.RefreshToken
 	}
 
@@ -110,18 +154,15 @@ func (c *MemoryUserContextAdapter) GetUserRefreshToken(id string) *string {
 }
 
 func (c *MemoryUserContextAdapter) UpdateUserRefreshToken(id string, token string) bool {
-	user := c.GetUserById(id)
-	if user != nil {
+	return c.updateUser(id, func(user *dto.UserDTO) {
 		user.RefreshToken = &token
-		return true
-	}
-	return false
+	})
 }
 
 func (c *MemoryUserContextAdapter) GetUserEmailVerificationToken(id string) *string {
 	user := c.GetUserById(id)
 	token := ""
-	if user != nil {
+	if user != nil && user.EmailVerifyToken != nil {
 		token = *user.EmailVerifyToken
 	}
 
@@ -129,65 +170,291 @@ func (c *MemoryUserContextAdapter) GetUserEmailVerificationToken(id string) *str
 }
 
 func (c *MemoryUserContextAdapter) UpdateUserEmailVerificationToken(id string, token string) bool {
-	user := c.GetUserById(id)
-	if user != nil {
+	return c.updateUser(id, func(user *dto.UserDTO) {
 		user.EmailVerifyToken = &token
-		return true
-	}
-
-	return false
+	})
 }
 
 // TODO: Implement MemoryUser GetUserClaimsById
-func (u MemoryUserContextAdapter) GetUserClaimsById(id string) []dto.UserClaimDTO {
+func (u *MemoryUserContextAdapter) GetUserClaimsById(id string) []dto.UserClaimDTO {
 	result := make([]dto.UserClaimDTO, 0)
 
 	return result
 }
 
 // TODO: Implement MemoryUser UpsertUserClaims
-func (u MemoryUserContextAdapter) UpsertUserClaims(user dto.UserDTO) error {
+func (u *MemoryUserContextAdapter) UpsertUserClaims(user dto.UserDTO) error {
 	return nil
 }
 
 // TODO: Implement MemoryUser GetUserRolesById
-func (u MemoryUserContextAdapter) GetUserRolesById(id string) []dto.UserRoleDTO {
+func (u *MemoryUserContextAdapter) GetUserRolesById(id string) []dto.UserRoleDTO {
 	result := make([]dto.UserRoleDTO, 0)
 	return result
 }
 
 // TODO: Implement MemoryUser UpsertUserRoles
-func (u MemoryUserContextAdapter) UpsertUserRoles(user dto.UserDTO) error {
+func (u *MemoryUserContextAdapter) UpsertUserRoles(user dto.UserDTO) error {
 	return nil
 }
 
 // TODO: Implement MemoryUser CleanUserRecoveryToken
-func (u MemoryUserContextAdapter) CleanUserRecoveryToken(id string) error {
+func (u *MemoryUserContextAdapter) CleanUserRecoveryToken(id string) error {
 	return nil
 }
 
 // TODO: Implement MemoryUser UpdateUserRecoverToken
-func (u MemoryUserContextAdapter) UpdateUserRecoveryToken(id string, token string) bool {
+func (u *MemoryUserContextAdapter) UpdateUserRecoveryToken(id string, token string) bool {
 	return false
 }
 
 // TODO: Implement MemoryUser GetUserRecoveryToken
-func (u MemoryUserContextAdapter) GetUserRecoveryToken(id string) *string {
+func (u *MemoryUserContextAdapter) GetUserRecoveryToken(id string) *string {
 	result := ""
 	return &result
 }
 
-// TODO: Implement MongoDB UpdateUserPassword
-func (u MemoryUserContextAdapter) UpdateUserPassword(id string, password string) error {
+func (c *MemoryUserContextAdapter) UpdateUserPassword(id string, password string) error {
+	found := c.updateUser(id, func(user *dto.UserDTO) {
+		user.Password = password
+	})
+	if !found {
+		return errors.New("user not found")
+	}
+
 	return nil
 }
 
 // TODO: Implement MemoryUser CleanUserEmailVerificationToken
-func (u MemoryUserContextAdapter) CleanUserEmailVerificationToken(id string) error {
+func (u *MemoryUserContextAdapter) CleanUserEmailVerificationToken(id string) error {
 	return nil
 }
 
 // TODO: Implement MemoryUser UpdateVerifyUserEmail
-func (u MemoryUserContextAdapter) SetEmailVerificationState(id string, state bool) bool {
+func (u *MemoryUserContextAdapter) SetEmailVerificationState(id string, state bool) bool {
 	return false
 }
+
+func (c *MemoryUserContextAdapter) GetUserTotp(id string) *dto.UserTotpDTO {
+	c.totpMutex.Lock()
+	defer c.totpMutex.Unlock()
+
+	totp, ok := c.Totp[strings.ToLower(id)]
+	if !ok {
+		return nil
+	}
+
+	return &totp
+}
+
+func (c *MemoryUserContextAdapter) UpsertUserTotp(totp dto.UserTotpDTO) error {
+	c.totpMutex.Lock()
+	defer c.totpMutex.Unlock()
+
+	c.Totp[strings.ToLower(totp.UserID)] = totp
+	return nil
+}
+
+func (c *MemoryUserContextAdapter) RemoveUserTotp(id string) error {
+	c.totpMutex.Lock()
+	defer c.totpMutex.Unlock()
+
+	delete(c.Totp, strings.ToLower(id))
+	return nil
+}
+
+func (c *MemoryUserContextAdapter) UpdateUserTotpLastUsedStep(id string, step int64) bool {
+	c.totpMutex.Lock()
+	defer c.totpMutex.Unlock()
+
+	totp, ok := c.Totp[strings.ToLower(id)]
+	if !ok || totp.LastUsedStep >= step {
+		return false
+	}
+
+	totp.LastUsedStep = step
+	c.Totp[strings.ToLower(id)] = totp
+	return true
+}
+
+func (c *MemoryUserContextAdapter) CountUserRecoveryCodes(userId string) int {
+	c.totpMutex.Lock()
+	defer c.totpMutex.Unlock()
+
+	count := 0
+	for _, code := range c.RecoveryCodes[strings.ToLower(userId)] {
+		if !code.Used {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (c *MemoryUserContextAdapter) ReplaceUserRecoveryCodes(userId string, codes []dto.UserRecoveryCodeDTO) error {
+	c.totpMutex.Lock()
+	defer c.totpMutex.Unlock()
+
+	c.RecoveryCodes[strings.ToLower(userId)] = codes
+	return nil
+}
+
+func (c *MemoryUserContextAdapter) RemoveUserRecoveryCodes(userId string) error {
+	c.totpMutex.Lock()
+	defer c.totpMutex.Unlock()
+
+	delete(c.RecoveryCodes, strings.ToLower(userId))
+	return nil
+}
+
+func (c *MemoryUserContextAdapter) UseUserRecoveryCode(userId string, id string) bool {
+	c.totpMutex.Lock()
+	defer c.totpMutex.Unlock()
+
+	codes := c.RecoveryCodes[strings.ToLower(userId)]
+	for i := range codes {
+		if codes[i].ID == id && !codes[i].Used {
+			codes[i].Used = true
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *MemoryUserContextAdapter) GetUserWebAuthnCredentials(userId string) []dto.WebAuthnCredentialDTO {
+	c.webAuthnMutex.Lock()
+	defer c.webAuthnMutex.Unlock()
+
+	result := make([]dto.WebAuthnCredentialDTO, 0)
+	for _, credential := range c.WebAuthnCredentials {
+		if strings.EqualFold(credential.UserID, userId) {
+			result = append(result, credential)
+		}
+	}
+
+	return result
+}
+
+func (c *MemoryUserContextAdapter) GetWebAuthnCredential(id string) *dto.WebAuthnCredentialDTO {
+	c.webAuthnMutex.Lock()
+	defer c.webAuthnMutex.Unlock()
+
+	// credential ids are case sensitive
+	credential, ok := c.WebAuthnCredentials[id]
+	if !ok {
+		return nil
+	}
+
+	return &credential
+}
+
+func (c *MemoryUserContextAdapter) UpsertWebAuthnCredential(credential dto.WebAuthnCredentialDTO) error {
+	c.webAuthnMutex.Lock()
+	defer c.webAuthnMutex.Unlock()
+
+	c.WebAuthnCredentials[credential.ID] = credential
+	return nil
+}
+
+func (c *MemoryUserContextAdapter) RemoveWebAuthnCredential(userId string, id string) error {
+	c.webAuthnMutex.Lock()
+	defer c.webAuthnMutex.Unlock()
+
+	credential, ok := c.WebAuthnCredentials[id]
+	if !ok || !strings.EqualFold(credential.UserID, userId) {
+		return errors.New("credential not found")
+	}
+
+	delete(c.WebAuthnCredentials, id)
+	return nil
+}
+
+func (c *MemoryUserContextAdapter) UpdateWebAuthnCredentialSignCount(id string, signCount int64, lastUsedAt time.Time) bool {
+	c.webAuthnMutex.Lock()
+	defer c.webAuthnMutex.Unlock()
+
+	credential, ok := c.WebAuthnCredentials[id]
+	if !ok || (signCount != 0 && credential.SignCount >= signCount) {
+		return false
+	}
+
+	credential.SignCount = signCount
+	credential.LastUsedAt = lastUsedAt
+	c.WebAuthnCredentials[id] = credential
+	return true
+}
+
+func (c *MemoryUserContextAdapter) AddWebAuthnSession(session dto.WebAuthnSessionDTO) error {
+	c.webAuthnMutex.Lock()
+	defer c.webAuthnMutex.Unlock()
+
+	for id, existing := range c.WebAuthnSessions {
+		if existing.ExpiresAt.Before(time.Now()) {
+			delete(c.WebAuthnSessions, id)
+		}
+	}
+
+	c.WebAuthnSessions[session.ID] = session
+	return nil
+}
+
+func (c *MemoryUserContextAdapter) RemoveWebAuthnSession(id string) bool {
+	c.webAuthnMutex.Lock()
+	defer c.webAuthnMutex.Unlock()
+
+	session, ok := c.WebAuthnSessions[id]
+	if !ok {
+		return false
+	}
+
+	delete(c.WebAuthnSessions, id)
+	return session.ExpiresAt.After(time.Now())
+}
+
+func (c *MemoryUserContextAdapter) GetUserLoginCode(userId string) *dto.UserLoginCodeDTO {
+	c.loginCodesMutex.Lock()
+	defer c.loginCodesMutex.Unlock()
+
+	loginCode, ok := c.LoginCodes[strings.ToLower(userId)]
+	if !ok {
+		return nil
+	}
+
+	return &loginCode
+}
+
+func (c *MemoryUserContextAdapter) UpsertUserLoginCode(loginCode dto.UserLoginCodeDTO) error {
+	c.loginCodesMutex.Lock()
+	defer c.loginCodesMutex.Unlock()
+
+	c.LoginCodes[strings.ToLower(loginCode.UserID)] = loginCode
+	return nil
+}
+
+func (c *MemoryUserContextAdapter) UpdateUserLoginCodeAttempts(userId string, attempts int) bool {
+	c.loginCodesMutex.Lock()
+	defer c.loginCodesMutex.Unlock()
+
+	loginCode, ok := c.LoginCodes[strings.ToLower(userId)]
+	if !ok || loginCode.Attempts != attempts-1 {
+		return false
+	}
+
+	loginCode.Attempts = attempts
+	c.LoginCodes[strings.ToLower(userId)] = loginCode
+	return true
+}
+
+func (c *MemoryUserContextAdapter) RemoveUserLoginCode(userId string, code string) bool {
+	c.loginCodesMutex.Lock()
+	defer c.loginCodesMutex.Unlock()
+
+	loginCode, ok := c.LoginCodes[strings.ToLower(userId)]
+	if !ok || loginCode.Code != code {
+		return false
+	}
+
+	delete(c.LoginCodes, strings.ToLower(userId))
+	return true
+}
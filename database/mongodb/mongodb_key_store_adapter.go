@@ -0,0 +1,76 @@
+package mongodb
+
+import (
+	"fmt"
+
+	"github.com/cjlapao/common-go-database/mongodb"
+	"github.com/cjlapao/common-go-identity/constants"
+	"github.com/cjlapao/common-go-identity/database/dto"
+)
+
+type MongoDBKeyStoreAdapter struct{}
+
+func (u MongoDBKeyStoreAdapter) GetKeys() []dto.SigningKeyDTO {
+	result := make([]dto.SigningKeyDTO, 0)
+	repo := u.getMongoDBTenantRepository()
+	cursor, err := repo.FindBy("")
+	if err != nil {
+		return result
+	}
+
+	if err := cursor.DecodeAll(&result); err != nil {
+		return make([]dto.SigningKeyDTO, 0)
+	}
+
+	return result
+}
+
+func (u MongoDBKeyStoreAdapter) GetKey(id string) *dto.SigningKeyDTO {
+	var result dto.SigningKeyDTO
+	repo := u.getMongoDBTenantRepository()
+	dbKey := repo.FindOne(fmt.Sprintf("_id eq '%v'", id))
+	dbKey.Decode(&result)
+	if result.ID == "" {
+		return nil
+	}
+
+	return &result
+}
+
+func (u MongoDBKeyStoreAdapter) AddKey(key dto.SigningKeyDTO) bool {
+	repo := u.getMongoDBTenantRepository()
+
+	// The key id is the document id so only one replica can add it
+	if _, err := repo.InsertOne(key); err != nil {
+		logger.Info("Signing key %v was not added, %v", key.ID, err.Error())
+		return false
+	}
+
+	return true
+}
+
+func (u MongoDBKeyStoreAdapter) RetireKey(id string) error {
+	key := u.GetKey(id)
+	if key == nil {
+		return nil
+	}
+
+	key.Retired = true
+	repo := u.getMongoDBTenantRepository()
+	builder, err := mongodb.NewUpdateOneModelBuilder().FilterBy("_id", mongodb.Equal, id).Encode(key).Build()
+	if err != nil {
+		return err
+	}
+
+	if _, err := repo.UpdateOne(builder); err != nil {
+		logger.Exception(err, "There was an error retiring the signing key %v", id)
+		return err
+	}
+
+	return nil
+}
+
+func (u MongoDBKeyStoreAdapter) getMongoDBTenantRepository() mongodb.MongoRepository {
+	mongodbSvc := mongodb.Get()
+	return mongodbSvc.TenantDatabase().NewRepository(constants.IdentitySigningKeysCollection)
+}
@@ -0,0 +1,89 @@
+package mongodb
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cjlapao/common-go-database/mongodb"
+	"github.com/cjlapao/common-go-identity/constants"
+	"github.com/cjlapao/common-go-identity/database/dto"
+)
+
+type MongoDBRevocationContextAdapter struct{}
+
+func (u MongoDBRevocationContextAdapter) RevokeToken(id string, expiresAt time.Time) error {
+	repo := u.getMongoDBTenantRepository(constants.IdentityRevokedTokensCollection)
+	u.removeExpired(repo)
+
+	token := dto.RevokedTokenDTO{
+		ID:        id,
+		ExpiresAt: expiresAt,
+	}
+	builder, err := mongodb.NewUpdateOneModelBuilder().FilterBy("_id", mongodb.Equal, id).Encode(token).Build()
+	if err != nil {
+		return err
+	}
+
+	if _, err := repo.UpsertOne(builder); err != nil {
+		logger.Exception(err, "There was an error revoking the access token %v", id)
+		return err
+	}
+
+	return nil
+}
+
+func (u MongoDBRevocationContextAdapter) IsTokenRevoked(id string) bool {
+	var result dto.RevokedTokenDTO
+	repo := u.getMongoDBTenantRepository(constants.IdentityRevokedTokensCollection)
+	dbToken := repo.FindOne(fmt.Sprintf("_id eq '%v'", id))
+	dbToken.Decode(&result)
+
+	return result.ID != "" && result.ExpiresAt.After(time.Now())
+}
+
+func (u MongoDBRevocationContextAdapter) RevokeUserTokens(revocation dto.UserTokenRevocationDTO) error {
+	repo := u.getMongoDBTenantRepository(constants.IdentityUserRevocationsCollection)
+	u.removeExpired(repo)
+
+	builder, err := mongodb.NewUpdateOneModelBuilder().FilterBy("_id", mongodb.Equal, revocation.UserID).Encode(revocation).Build()
+	if err != nil {
+		return err
+	}
+
+	if _, err := repo.UpsertOne(builder); err != nil {
+		logger.Exception(err, "There was an error revoking the tokens of user %v", revocation.UserID)
+		return err
+	}
+
+	return nil
+}
+
+func (u MongoDBRevocationContextAdapter) GetUserTokenRevocation(userId string) *dto.UserTokenRevocationDTO {
+	var result dto.UserTokenRevocationDTO
+	repo := u.getMongoDBTenantRepository(constants.IdentityUserRevocationsCollection)
+	dbRevocation := repo.FindOne(fmt.Sprintf("_id eq '%v'", userId))
+	dbRevocation.Decode(&result)
+	if result.UserID == "" || result.ExpiresAt.Before(time.Now()) {
+		return nil
+	}
+
+	return &result
+}
+
+// removeExpired removes the entries of the tokens that already expired so the collection does
+// not grow forever
+func (u MongoDBRevocationContextAdapter) removeExpired(repo mongodb.MongoRepository) {
+	filter, err := mongodb.NewDeleteOneBuilder().FilterBy("expiresAt", mongodb.LowerThan, time.Now()).Build()
+	if err != nil {
+		return
+	}
+
+	if _, err := repo.DeleteMany(filter.Filter); err != nil {
+		logger.Exception(err, "There was an error removing the expired revocations")
+	}
+}
+
+func (u MongoDBRevocationContextAdapter) getMongoDBTenantRepository(collection string) mongodb.MongoRepository {
+	mongodbSvc := mongodb.Get()
+	return mongodbSvc.TenantDatabase().NewRepository(collection)
+}
@@ -1,14 +1,16 @@
 package mongodb
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/cjlapao/common-go-database/mongodb"
 	"github.com/cjlapao/common-go-identity/constants"
 	"github.com/cjlapao/common-go-identity/database"
 	"github.com/cjlapao/common-go-identity/database/dto"
 	log "github.com/cjlapao/common-go-logger"
-	"github.com/cjlapao/common-go/security"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 var logger = log.Get()
@@ -58,7 +60,6 @@ func (u MongoDBUserContextAdapter) GetUser(id string) *dto.UserDTO {
 }
 
 func (u MongoDBUserContextAdapter) UpsertUser(user dto.UserDTO) error {
-	user.Password = security.SHA256Encode(user.Password)
 	repo := u.getMongoDBTenantRepository()
 	logger.Info("Upserting user %v into database %v", u.currentDatabase)
 	builder, err := mongodb.NewUpdateOneModelBuilder().FilterBy("_id", mongodb.Equal, user.ID).Encode(user).Build()
@@ -100,6 +101,56 @@ func (u MongoDBUserContextAdapter) RemoveUser(id string) bool {
 	return true
 }
 
+func (u MongoDBUserContextAdapter) UpdateUserInvalidAttempts(id string, attempts int, blockedUntil string) error {
+	user := u.GetUserById(id)
+	if user == nil {
+		return errors.New("user not found")
+	}
+
+	user.InvalidAttempts = attempts
+	user.BlockedUntil = &blockedUntil
+	repo := u.getMongoDBTenantRepository()
+	builder, err := mongodb.NewUpdateOneModelBuilder().FilterBy("_id", mongodb.Equal, id).Encode(user).Build()
+	if err != nil {
+		return err
+	}
+
+	if _, err := repo.UpsertOne(builder); err != nil {
+		logger.Exception(err, "There was an error updating the invalid attempts for user with id %v", id)
+		return err
+	}
+
+	return nil
+}
+
+// IncrementUserInvalidAttempts increments the counter with $inc so concurrent failed logins are
+// all counted, the repository has no find and update so the new count is read afterwards and can
+// include the attempts of other requests
+func (u MongoDBUserContextAdapter) IncrementUserInvalidAttempts(id string) (int, error) {
+	repo := u.getMongoDBTenantRepository()
+	model, err := mongodb.NewUpdateOneModelBuilder().FilterBy("_id", mongodb.Equal, id).Set("invalidAttempts", 0).Build()
+	if err != nil {
+		return 0, err
+	}
+	model.Update = bson.M{"$inc": bson.M{"invalidAttempts": 1}}
+
+	result, err := repo.UpdateOne(model)
+	if err != nil {
+		logger.Exception(err, "There was an error incrementing the invalid attempts for user with id %v", id)
+		return 0, err
+	}
+	if result.MatchedCount == 0 {
+		return 0, errors.New("user not found")
+	}
+
+	user := u.GetUserById(id)
+	if user == nil || user.ID == "" {
+		return 0, errors.New("user not found")
+	}
+
+	return user.InvalidAttempts, nil
+}
+
 func (u MongoDBUserContextAdapter) GetUserRefreshToken(id string) *string {
 	user := u.GetUserById(id)
 	if user != nil {
@@ -209,8 +260,24 @@ func (u MongoDBUserContextAdapter) GetUserRecoveryToken(id string) *string {
 	return &result
 }
 
-// TODO: Implement MongoDB GetUserRecoveryToken
 func (u MongoDBUserContextAdapter) UpdateUserPassword(id string, password string) error {
+	user := u.GetUserById(id)
+	if user == nil || user.ID == "" {
+		return errors.New("user not found")
+	}
+
+	user.Password = password
+	repo := u.getMongoDBTenantRepository()
+	builder, err := mongodb.NewUpdateOneModelBuilder().FilterBy("_id", mongodb.Equal, id).Encode(user).Build()
+	if err != nil {
+		return err
+	}
+
+	if _, err := repo.UpsertOne(builder); err != nil {
+		logger.Exception(err, "There was an error updating the password for user with id %v", id)
+		return err
+	}
+
 	return nil
 }
 
@@ -239,3 +306,354 @@ func SeedMongoDbUsers(factory *mongodb.MongoFactory, databaseName string) {
 		repo.UpsertOne(model)
 	}
 }
+
+func (u MongoDBUserContextAdapter) GetUserTotp(id string) *dto.UserTotpDTO {
+	var result dto.UserTotpDTO
+	repo := u.getMongoDBTotpRepository()
+	dbTotp := repo.FindOne(fmt.Sprintf("_id eq '%v'", id))
+	dbTotp.Decode(&result)
+	if result.UserID == "" {
+		return nil
+	}
+
+	return &result
+}
+
+func (u MongoDBUserContextAdapter) UpsertUserTotp(totp dto.UserTotpDTO) error {
+	repo := u.getMongoDBTotpRepository()
+	builder, err := mongodb.NewUpdateOneModelBuilder().FilterBy("_id", mongodb.Equal, totp.UserID).Encode(totp).Build()
+	if err != nil {
+		return err
+	}
+
+	if _, err := repo.UpsertOne(builder); err != nil {
+		logger.Exception(err, "There was an error upserting the totp for user with id %v", totp.UserID)
+		return err
+	}
+
+	return nil
+}
+
+func (u MongoDBUserContextAdapter) RemoveUserTotp(id string) error {
+	repo := u.getMongoDBTotpRepository()
+	builder, err := mongodb.NewDeleteOneBuilder().FilterBy("_id", mongodb.Equal, id).Build()
+	if err != nil {
+		return err
+	}
+
+	if _, err := repo.DeleteOne(builder); err != nil {
+		logger.Exception(err, "There was an error removing the totp for user with id %v", id)
+		return err
+	}
+
+	return nil
+}
+
+func (u MongoDBUserContextAdapter) UpdateUserTotpLastUsedStep(id string, step int64) bool {
+	repo := u.getMongoDBTotpRepository()
+	builder, err := mongodb.NewUpdateOneModelBuilder().
+		FilterBy("_id", mongodb.Equal, id).
+		FilterBy("lastUsedStep", mongodb.LowerThan, step).
+		Set("lastUsedStep", step).
+		Build()
+	if err != nil {
+		return false
+	}
+
+	result, err := repo.UpdateOne(builder)
+	if err != nil {
+		logger.Exception(err, "There was an error updating the totp last used step for user with id %v", id)
+		return false
+	}
+
+	return result.ModifiedCount == 1
+}
+
+func (u MongoDBUserContextAdapter) getMongoDBTotpRepository() mongodb.MongoRepository {
+	mongodbSvc := mongodb.Get()
+	return mongodbSvc.TenantDatabase().NewRepository(constants.IdentityUserTotpCollection)
+}
+
+func (u MongoDBUserContextAdapter) CountUserRecoveryCodes(userId string) int {
+	var codes []dto.UserRecoveryCodeDTO
+	repo := u.getMongoDBRecoveryCodesRepository()
+	cursor, err := repo.FindBy(fmt.Sprintf("userId eq '%v'", userId))
+	if err != nil {
+		return 0
+	}
+
+	if err := cursor.DecodeAll(&codes); err != nil {
+		return 0
+	}
+
+	count := 0
+	for _, code := range codes {
+		if !code.Used {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (u MongoDBUserContextAdapter) ReplaceUserRecoveryCodes(userId string, codes []dto.UserRecoveryCodeDTO) error {
+	if err := u.RemoveUserRecoveryCodes(userId); err != nil {
+		return err
+	}
+
+	if len(codes) == 0 {
+		return nil
+	}
+
+	repo := u.getMongoDBRecoveryCodesRepository()
+	elements := make([]interface{}, 0)
+	for _, code := range codes {
+		code.UserID = userId
+		elements = append(elements, code)
+	}
+
+	if _, err := repo.InsertMany(elements...); err != nil {
+		logger.Exception(err, "There was an error inserting the recovery codes for user with id %v", userId)
+		return err
+	}
+
+	return nil
+}
+
+func (u MongoDBUserContextAdapter) RemoveUserRecoveryCodes(userId string) error {
+	repo := u.getMongoDBRecoveryCodesRepository()
+	if _, err := repo.DeleteMany(fmt.Sprintf("userId eq '%v'", userId)); err != nil {
+		logger.Exception(err, "There was an error removing the recovery codes for user with id %v", userId)
+		return err
+	}
+
+	return nil
+}
+
+func (u MongoDBUserContextAdapter) UseUserRecoveryCode(userId string, id string) bool {
+	repo := u.getMongoDBRecoveryCodesRepository()
+	builder, err := mongodb.NewUpdateOneModelBuilder().
+		FilterBy("_id", mongodb.Equal, id).
+		FilterBy("userId", mongodb.Equal, userId).
+		FilterBy("used", mongodb.Equal, false).
+		Set("used", true).
+		Build()
+	if err != nil {
+		return false
+	}
+
+	result, err := repo.UpdateOne(builder)
+	if err != nil {
+		logger.Exception(err, "There was an error using a recovery code for user with id %v", userId)
+		return false
+	}
+
+	return result.ModifiedCount == 1
+}
+
+func (u MongoDBUserContextAdapter) getMongoDBRecoveryCodesRepository() mongodb.MongoRepository {
+	mongodbSvc := mongodb.Get()
+	return mongodbSvc.TenantDatabase().NewRepository(constants.IdentityUserRecoveryCodesCollection)
+}
+
+func (u MongoDBUserContextAdapter) GetUserWebAuthnCredentials(userId string) []dto.WebAuthnCredentialDTO {
+	result := make([]dto.WebAuthnCredentialDTO, 0)
+	repo := u.getMongoDBWebAuthnRepository()
+	cursor, err := repo.FindBy(fmt.Sprintf("userId eq '%v'", userId))
+	if err != nil {
+		return result
+	}
+
+	if err := cursor.DecodeAll(&result); err != nil {
+		return make([]dto.WebAuthnCredentialDTO, 0)
+	}
+
+	return result
+}
+
+func (u MongoDBUserContextAdapter) GetWebAuthnCredential(id string) *dto.WebAuthnCredentialDTO {
+	var result dto.WebAuthnCredentialDTO
+	repo := u.getMongoDBWebAuthnRepository()
+	dbCredential := repo.FindOne(fmt.Sprintf("_id eq '%v'", id))
+	dbCredential.Decode(&result)
+	if result.ID == "" {
+		return nil
+	}
+
+	return &result
+}
+
+func (u MongoDBUserContextAdapter) UpsertWebAuthnCredential(credential dto.WebAuthnCredentialDTO) error {
+	repo := u.getMongoDBWebAuthnRepository()
+	builder, err := mongodb.NewUpdateOneModelBuilder().FilterBy("_id", mongodb.Equal, credential.ID).Encode(credential).Build()
+	if err != nil {
+		return err
+	}
+
+	if _, err := repo.UpsertOne(builder); err != nil {
+		logger.Exception(err, "There was an error upserting the webauthn credential for user with id %v", credential.UserID)
+		return err
+	}
+
+	return nil
+}
+
+func (u MongoDBUserContextAdapter) RemoveWebAuthnCredential(userId string, id string) error {
+	repo := u.getMongoDBWebAuthnRepository()
+	builder, err := mongodb.NewDeleteOneBuilder().FilterBy("_id", mongodb.Equal, id).FilterBy("userId", mongodb.Equal, userId).Build()
+	if err != nil {
+		return err
+	}
+
+	result, err := repo.DeleteOne(builder)
+	if err != nil {
+		logger.Exception(err, "There was an error removing the webauthn credential for user with id %v", userId)
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return errors.New("credential not found")
+	}
+
+	return nil
+}
+
+func (u MongoDBUserContextAdapter) UpdateWebAuthnCredentialSignCount(id string, signCount int64, lastUsedAt time.Time) bool {
+	repo := u.getMongoDBWebAuthnRepository()
+	builder := mongodb.NewUpdateOneModelBuilder().FilterBy("_id", mongodb.Equal, id)
+	// authenticators without a counter always send zero
+	if signCount != 0 {
+		builder = builder.FilterBy("signCount", mongodb.LowerThan, signCount)
+	}
+
+	model, err := builder.Set("signCount", signCount).Set("lastUsedAt", lastUsedAt).Build()
+	if err != nil {
+		return false
+	}
+
+	result, err := repo.UpdateOne(model)
+	if err != nil {
+		logger.Exception(err, "There was an error updating the signature counter of the webauthn credential %v", id)
+		return false
+	}
+
+	return result.MatchedCount == 1
+}
+
+func (u MongoDBUserContextAdapter) getMongoDBWebAuthnRepository() mongodb.MongoRepository {
+	mongodbSvc := mongodb.Get()
+	return mongodbSvc.TenantDatabase().NewRepository(constants.IdentityWebAuthnCredentialsCollection)
+}
+
+func (u MongoDBUserContextAdapter) AddWebAuthnSession(session dto.WebAuthnSessionDTO) error {
+	repo := u.getMongoDBWebAuthnSessionsRepository()
+
+	// Removing the expired sessions so the collection does not grow forever
+	if filter, err := mongodb.NewDeleteOneBuilder().FilterBy("expiresAt", mongodb.LowerThan, time.Now()).Build(); err == nil {
+		if _, err := repo.DeleteMany(filter.Filter); err != nil {
+			logger.Exception(err, "There was an error removing the expired webauthn sessions")
+		}
+	}
+
+	if _, err := repo.InsertOne(session); err != nil {
+		logger.Exception(err, "There was an error adding the webauthn session for user with id %v", session.UserID)
+		return err
+	}
+
+	return nil
+}
+
+func (u MongoDBUserContextAdapter) RemoveWebAuthnSession(id string) bool {
+	repo := u.getMongoDBWebAuthnSessionsRepository()
+	builder, err := mongodb.NewDeleteOneBuilder().
+		FilterBy("_id", mongodb.Equal, id).
+		FilterBy("expiresAt", mongodb.GreaterThan, time.Now()).
+		Build()
+	if err != nil {
+		return false
+	}
+
+	result, err := repo.DeleteOne(builder)
+	if err != nil {
+		logger.Exception(err, "There was an error removing the webauthn session %v", id)
+		return false
+	}
+
+	return result.DeletedCount == 1
+}
+
+func (u MongoDBUserContextAdapter) getMongoDBWebAuthnSessionsRepository() mongodb.MongoRepository {
+	mongodbSvc := mongodb.Get()
+	return mongodbSvc.TenantDatabase().NewRepository(constants.IdentityWebAuthnSessionsCollection)
+}
+
+func (u MongoDBUserContextAdapter) GetUserLoginCode(userId string) *dto.UserLoginCodeDTO {
+	var result dto.UserLoginCodeDTO
+	repo := u.getMongoDBLoginCodesRepository()
+	dbLoginCode := repo.FindOne(fmt.Sprintf("_id eq '%v'", userId))
+	dbLoginCode.Decode(&result)
+	if result.UserID == "" {
+		return nil
+	}
+
+	return &result
+}
+
+func (u MongoDBUserContextAdapter) UpsertUserLoginCode(loginCode dto.UserLoginCodeDTO) error {
+	repo := u.getMongoDBLoginCodesRepository()
+	builder, err := mongodb.NewUpdateOneModelBuilder().FilterBy("_id", mongodb.Equal, loginCode.UserID).Encode(loginCode).Build()
+	if err != nil {
+		return err
+	}
+
+	if _, err := repo.UpsertOne(builder); err != nil {
+		logger.Exception(err, "There was an error upserting the login code for user with id %v", loginCode.UserID)
+		return err
+	}
+
+	return nil
+}
+
+func (u MongoDBUserContextAdapter) UpdateUserLoginCodeAttempts(userId string, attempts int) bool {
+	repo := u.getMongoDBLoginCodesRepository()
+	builder, err := mongodb.NewUpdateOneModelBuilder().
+		FilterBy("_id", mongodb.Equal, userId).
+		FilterBy("attempts", mongodb.Equal, attempts-1).
+		Set("attempts", attempts).
+		Build()
+	if err != nil {
+		return false
+	}
+
+	result, err := repo.UpdateOne(builder)
+	if err != nil {
+		logger.Exception(err, "There was an error updating the login code attempts for user with id %v", userId)
+		return false
+	}
+
+	return result.ModifiedCount == 1
+}
+
+func (u MongoDBUserContextAdapter) RemoveUserLoginCode(userId string, code string) bool {
+	repo := u.getMongoDBLoginCodesRepository()
+	builder, err := mongodb.NewDeleteOneBuilder().
+		FilterBy("_id", mongodb.Equal, userId).
+		FilterBy("code", mongodb.Equal, code).
+		Build()
+	if err != nil {
+		return false
+	}
+
+	result, err := repo.DeleteOne(builder)
+	if err != nil {
+		logger.Exception(err, "There was an error removing the login code for user with id %v", userId)
+		return false
+	}
+
+	return result.DeletedCount == 1
+}
+
+func (u MongoDBUserContextAdapter) getMongoDBLoginCodesRepository() mongodb.MongoRepository {
+	mongodbSvc := mongodb.Get()
+	return mongodbSvc.TenantDatabase().NewRepository(constants.IdentityUserLoginCodesCollection)
+}
@@ -0,0 +1,121 @@
+package sql
+
+import (
+	"time"
+
+	"github.com/cjlapao/common-go-database/migrations"
+	"github.com/cjlapao/common-go-database/sql"
+	"github.com/cjlapao/common-go-identity/database/dto"
+	"github.com/cjlapao/common-go-identity/database/sql/sql_migrations"
+)
+
+type SqlDBAuthorizationCodeContextAdapter struct{}
+
+func (u SqlDBAuthorizationCodeContextAdapter) ApplyMigrations() error {
+	sqlRepo := sql.NewSqlMigrationRepo()
+	migrationService := migrations.NewMigrationService(sqlRepo)
+
+	migrationService.Register(sql_migrations.AuthorizationCodesTableMigration{})
+	migrationService.Register(sql_migrations.AuthorizationCodesAmrColumnMigration{})
+
+	return migrationService.Run()
+}
+
+func (u SqlDBAuthorizationCodeContextAdapter) GetAuthorizationCode(id string) *dto.AuthorizationCodeDTO {
+	var result dto.AuthorizationCodeDTO
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	row := db.QueryRowContext(`
+SELECT
+  id, clientId, userId, tenantId, redirectUri,
+  scope, nonce, codeChallenge, codeChallengeMethod,
+  authTime, amr, expiresAt
+FROM
+  identity_authorization_codes
+WHERE
+  id = ?
+`, id)
+
+	if row.Err() != nil {
+		return nil
+	}
+
+	row.Scan(
+		&result.ID,
+		&result.ClientID,
+		&result.UserID,
+		&result.TenantID,
+		&result.RedirectUri,
+		&result.Scope,
+		&result.Nonce,
+		&result.CodeChallenge,
+		&result.CodeChallengeMethod,
+		&result.AuthTime,
+		&result.Amr,
+		&result.ExpiresAt,
+	)
+
+	if result.ID == "" {
+		return nil
+	}
+
+	return &result
+}
+
+func (u SqlDBAuthorizationCodeContextAdapter) UpsertAuthorizationCode(code dto.AuthorizationCodeDTO) error {
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	_, err := db.ExecContext(`
+INSERT INTO
+identity_authorization_codes(
+  id,
+  clientId,
+  userId,
+  tenantId,
+  redirectUri,
+  scope,
+  nonce,
+  codeChallenge,
+  codeChallengeMethod,
+  authTime,
+  amr,
+  expiresAt,
+  create_time)
+VALUES
+(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`,
+		code.ID, code.ClientID, code.UserID, code.TenantID, code.RedirectUri,
+		code.Scope, code.Nonce, code.CodeChallenge, code.CodeChallengeMethod,
+		code.AuthTime, code.Amr, code.ExpiresAt, time.Now())
+
+	return err
+}
+
+func (u SqlDBAuthorizationCodeContextAdapter) RemoveAuthorizationCode(id string) bool {
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	result, err := db.ExecContext(`
+DELETE
+FROM
+  identity_authorization_codes
+WHERE
+  id = ?
+`, id)
+
+	if err != nil {
+		return false
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false
+	}
+
+	return affected == 1
+}
+
+func (u SqlDBAuthorizationCodeContextAdapter) getTenantRepository() *sql.SqlFactory {
+	return sql.Get().TenantDatabase()
+}
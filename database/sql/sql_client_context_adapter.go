@@ -0,0 +1,144 @@
+package sql
+
+import (
+	"strings"
+	"time"
+
+	"github.com/cjlapao/common-go-database/migrations"
+	"github.com/cjlapao/common-go-database/sql"
+	"github.com/cjlapao/common-go-identity/database/dto"
+	"github.com/cjlapao/common-go-identity/database/sql/sql_migrations"
+)
+
+type SqlDBClientContextAdapter struct{}
+
+func (u SqlDBClientContextAdapter) ApplyMigrations() error {
+	sqlRepo := sql.NewSqlMigrationRepo()
+	migrationService := migrations.NewMigrationService(sqlRepo)
+
+	migrationService.Register(sql_migrations.ClientsTableMigration{})
+	migrationService.Register(sql_migrations.ClientsAccessTokenFormatColumnMigration{})
+
+	return migrationService.Run()
+}
+
+func (u SqlDBClientContextAdapter) GetClientById(id string) *dto.ClientDTO {
+	var result dto.ClientDTO
+	var redirectUris, grantTypes, scopes string
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	row := db.QueryRowContext(`
+SELECT
+  id, name, secret, tenantId, redirectUris,
+  grantTypes, scopes, tokenEndpointAuthMethod, blocked,
+  registrationAccessToken, issuedAt, COALESCE(accessTokenFormat, '')
+FROM
+  identity_clients
+WHERE
+  id = ?
+`, id)
+
+	if row.Err() != nil {
+		return nil
+	}
+
+	row.Scan(
+		&result.ID,
+		&result.Name,
+		&result.Secret,
+		&result.TenantID,
+		&redirectUris,
+		&grantTypes,
+		&scopes,
+		&result.TokenEndpointAuthMethod,
+		&result.Blocked,
+		&result.RegistrationAccessToken,
+		&result.IssuedAt,
+		&result.AccessTokenFormat,
+	)
+
+	if result.ID == "" {
+		return nil
+	}
+
+	result.RedirectUris = strings.Fields(redirectUris)
+	result.GrantTypes = strings.Fields(grantTypes)
+	result.Scopes = strings.Fields(scopes)
+
+	return &result
+}
+
+func (u SqlDBClientContextAdapter) UpsertClient(client dto.ClientDTO) error {
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	now := time.Now()
+	_, err := db.ExecContext(`
+INSERT INTO
+identity_clients(
+  id,
+  name,
+  secret,
+  tenantId,
+  redirectUris,
+  grantTypes,
+  scopes,
+  tokenEndpointAuthMethod,
+  accessTokenFormat,
+  blocked,
+  registrationAccessToken,
+  issuedAt,
+  create_time,
+  update_time)
+VALUES
+(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON DUPLICATE KEY UPDATE
+  name = VALUES(name),
+  secret = VALUES(secret),
+  tenantId = VALUES(tenantId),
+  redirectUris = VALUES(redirectUris),
+  grantTypes = VALUES(grantTypes),
+  scopes = VALUES(scopes),
+  tokenEndpointAuthMethod = VALUES(tokenEndpointAuthMethod),
+  accessTokenFormat = VALUES(accessTokenFormat),
+  blocked = VALUES(blocked),
+  registrationAccessToken = VALUES(registrationAccessToken),
+  update_time = VALUES(update_time);`,
+		client.ID, client.Name, client.Secret, client.TenantID,
+		strings.Join(client.RedirectUris, " "),
+		strings.Join(client.GrantTypes, " "),
+		strings.Join(client.Scopes, " "),
+		client.TokenEndpointAuthMethod, client.AccessTokenFormat, client.Blocked,
+		client.RegistrationAccessToken, client.IssuedAt, now, now)
+
+	return err
+}
+
+func (u SqlDBClientContextAdapter) RemoveClient(id string) bool {
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	result, err := db.ExecContext(`
+DELETE
+FROM
+  identity_clients
+WHERE
+  id = ?
+`, id)
+
+	if err != nil {
+		return false
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false
+	}
+
+	return affected == 1
+}
+
+func (u SqlDBClientContextAdapter) getTenantRepository() *sql.SqlFactory {
+	return sql.Get().TenantDatabase()
+}
@@ -0,0 +1,141 @@
+package sql
+
+import (
+	"github.com/cjlapao/common-go-database/migrations"
+	"github.com/cjlapao/common-go-database/sql"
+	"github.com/cjlapao/common-go-identity/database/dto"
+	"github.com/cjlapao/common-go-identity/database/sql/sql_migrations"
+)
+
+type SqlDBKeyStoreAdapter struct{}
+
+func (u SqlDBKeyStoreAdapter) ApplyMigrations() error {
+	sqlRepo := sql.NewSqlMigrationRepo()
+	migrationService := migrations.NewMigrationService(sqlRepo)
+
+	migrationService.Register(sql_migrations.SigningKeysTableMigration{})
+
+	return migrationService.Run()
+}
+
+func (u SqlDBKeyStoreAdapter) GetKeys() []dto.SigningKeyDTO {
+	result := make([]dto.SigningKeyDTO, 0)
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	rows, err := db.QueryContext(`
+SELECT
+  id, algorithm, privateKey, createdAt, activatesAt, expiresAt, retired
+FROM
+  identity_signing_keys
+ORDER BY
+  activatesAt
+`)
+
+	if err != nil {
+		return result
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var key dto.SigningKeyDTO
+		rows.Scan(
+			&key.ID,
+			&key.Algorithm,
+			&key.PrivateKey,
+			&key.CreatedAt,
+			&key.ActivatesAt,
+			&key.ExpiresAt,
+			&key.Retired,
+		)
+		result = append(result, key)
+	}
+
+	return result
+}
+
+func (u SqlDBKeyStoreAdapter) GetKey(id string) *dto.SigningKeyDTO {
+	var result dto.SigningKeyDTO
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	row := db.QueryRowContext(`
+SELECT
+  id, algorithm, privateKey, createdAt, activatesAt, expiresAt, retired
+FROM
+  identity_signing_keys
+WHERE
+  id = ?
+`, id)
+
+	if row.Err() != nil {
+		return nil
+	}
+
+	row.Scan(
+		&result.ID,
+		&result.Algorithm,
+		&result.PrivateKey,
+		&result.CreatedAt,
+		&result.ActivatesAt,
+		&result.ExpiresAt,
+		&result.Retired,
+	)
+
+	if result.ID == "" {
+		return nil
+	}
+
+	return &result
+}
+
+func (u SqlDBKeyStoreAdapter) AddKey(key dto.SigningKeyDTO) bool {
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	// The primary key makes sure only one replica adds a key with the same id
+	result, err := db.ExecContext(`
+INSERT IGNORE INTO
+identity_signing_keys(
+  id,
+  algorithm,
+  privateKey,
+  createdAt,
+  activatesAt,
+  expiresAt,
+  retired)
+VALUES
+(?, ?, ?, ?, ?, ?, ?)`,
+		key.ID, key.Algorithm, key.PrivateKey, key.CreatedAt, key.ActivatesAt, key.ExpiresAt, key.Retired)
+
+	if err != nil {
+		return false
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false
+	}
+
+	return affected == 1
+}
+
+func (u SqlDBKeyStoreAdapter) RetireKey(id string) error {
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	_, err := db.ExecContext(`
+UPDATE
+  identity_signing_keys
+SET
+  retired = TRUE
+WHERE
+  id = ?
+`, id)
+
+	return err
+}
+
+func (u SqlDBKeyStoreAdapter) getTenantRepository() *sql.SqlFactory {
+	return sql.Get().TenantDatabase()
+}
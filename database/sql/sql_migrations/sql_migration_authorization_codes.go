@@ -0,0 +1,78 @@
+package sql_migrations
+
+import (
+	"github.com/cjlapao/common-go-database/sql"
+	log "github.com/cjlapao/common-go-logger"
+)
+
+type AuthorizationCodesTableMigration struct{}
+
+func (m AuthorizationCodesTableMigration) Name() string {
+	return "Create Identity Authorization Codes Table"
+}
+
+func (m AuthorizationCodesTableMigration) Order() int {
+	return 6
+}
+
+func (m AuthorizationCodesTableMigration) Up() bool {
+	logger := log.Get()
+	dbService := sql.Get()
+
+	tenantDb := dbService.TenantDatabase().Connect()
+
+	if tenantDb == nil {
+		logger.Error("Error connecting to apply  %v", m.Name())
+		return false
+	}
+
+	defer tenantDb.Close()
+
+	_, err := tenantDb.Query(`
+  CREATE TABLE IF NOT EXISTS identity_authorization_codes(
+    id CHAR(64) NOT NULL COMMENT 'Primary Key, hash of the code',
+    clientId CHAR(100) NOT NULL COMMENT 'Client Id',
+    userId CHAR(50) NOT NULL COMMENT 'User Id',
+    tenantId CHAR(50) COMMENT 'Tenant Id',
+    redirectUri TEXT NOT NULL COMMENT 'Redirect Uri',
+    scope TEXT COMMENT 'Requested Scope',
+    nonce CHAR(255) COMMENT 'Request Nonce',
+    codeChallenge CHAR(128) COMMENT 'PKCE Code Challenge',
+    codeChallengeMethod CHAR(10) COMMENT 'PKCE Code Challenge Method',
+    authTime DATETIME COMMENT 'User Authentication Time',
+    expiresAt DATETIME NOT NULL COMMENT 'Code Expiry Time',
+    create_time DATETIME COMMENT 'Create Time',
+    PRIMARY KEY (id)
+) DEFAULT CHARSET UTF8 COMMENT '';
+`)
+
+	if err != nil {
+		logger.Exception(err, "Error applying Up to  %v", m.Name())
+		return false
+	}
+	return true
+}
+
+func (m AuthorizationCodesTableMigration) Down() bool {
+	logger := log.Get()
+	dbService := sql.Get()
+
+	globalDb := dbService.GlobalDatabase()
+
+	if globalDb == nil {
+		logger.Error("Error connecting to apply  %v", m.Name())
+		return false
+	}
+
+	defer globalDb.Database.Close()
+
+	_, err := globalDb.Database.Query(`
+  DROP TABLE IF EXISTS identity_authorization_codes;
+`)
+
+	if err != nil {
+		logger.Exception(err, "Error applying Down to  %v", m.Name())
+		return false
+	}
+	return true
+}
@@ -0,0 +1,66 @@
+package sql_migrations
+
+import (
+	"github.com/cjlapao/common-go-database/sql"
+	log "github.com/cjlapao/common-go-logger"
+)
+
+type AuthorizationCodesAmrColumnMigration struct{}
+
+func (m AuthorizationCodesAmrColumnMigration) Name() string {
+	return "Add Amr To Identity Authorization Codes Table"
+}
+
+func (m AuthorizationCodesAmrColumnMigration) Order() int {
+	return 13
+}
+
+func (m AuthorizationCodesAmrColumnMigration) Up() bool {
+	logger := log.Get()
+	dbService := sql.Get()
+
+	tenantDb := dbService.TenantDatabase().Connect()
+
+	if tenantDb == nil {
+		logger.Error("Error connecting to apply  %v", m.Name())
+		return false
+	}
+
+	defer tenantDb.Close()
+
+	_, err := tenantDb.Query(`
+  ALTER TABLE identity_authorization_codes
+    ADD COLUMN amr VARCHAR(255) COMMENT 'Authentication Methods' AFTER authTime;
+`)
+
+	if err != nil {
+		logger.Exception(err, "Error applying Up to  %v", m.Name())
+		return false
+	}
+	return true
+}
+
+func (m AuthorizationCodesAmrColumnMigration) Down() bool {
+	logger := log.Get()
+	dbService := sql.Get()
+
+	globalDb := dbService.GlobalDatabase()
+
+	if globalDb == nil {
+		logger.Error("Error connecting to apply  %v", m.Name())
+		return false
+	}
+
+	defer globalDb.Database.Close()
+
+	_, err := globalDb.Database.Query(`
+  ALTER TABLE identity_authorization_codes
+    DROP COLUMN amr;
+`)
+
+	if err != nil {
+		logger.Exception(err, "Error applying Down to  %v", m.Name())
+		return false
+	}
+	return true
+}
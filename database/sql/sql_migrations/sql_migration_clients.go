@@ -0,0 +1,79 @@
+package sql_migrations
+
+import (
+	"github.com/cjlapao/common-go-database/sql"
+	log "github.com/cjlapao/common-go-logger"
+)
+
+type ClientsTableMigration struct{}
+
+func (m ClientsTableMigration) Name() string {
+	return "Create Identity Clients Table"
+}
+
+func (m ClientsTableMigration) Order() int {
+	return 7
+}
+
+func (m ClientsTableMigration) Up() bool {
+	logger := log.Get()
+	dbService := sql.Get()
+
+	tenantDb := dbService.TenantDatabase().Connect()
+
+	if tenantDb == nil {
+		logger.Error("Error connecting to apply  %v", m.Name())
+		return false
+	}
+
+	defer tenantDb.Close()
+
+	_, err := tenantDb.Query(`
+  CREATE TABLE IF NOT EXISTS identity_clients(
+    id CHAR(100) NOT NULL COMMENT 'Primary Key, client id',
+    name CHAR(255) COMMENT 'Client Name',
+    secret CHAR(255) COMMENT 'Hashed Client Secret',
+    tenantId CHAR(50) COMMENT 'Tenant Id',
+    redirectUris TEXT COMMENT 'Space separated Redirect Uris',
+    grantTypes TEXT COMMENT 'Space separated Grant Types',
+    scopes TEXT COMMENT 'Space separated Scopes',
+    tokenEndpointAuthMethod CHAR(50) NOT NULL COMMENT 'Token Endpoint Authentication Method',
+    blocked BOOLEAN DEFAULT FALSE COMMENT 'Client is blocked',
+    registrationAccessToken CHAR(64) COMMENT 'Hashed Registration Access Token',
+    issuedAt DATETIME COMMENT 'Client Id Issue Time',
+    create_time DATETIME COMMENT 'Create Time',
+    update_time DATETIME COMMENT 'Update Time',
+    PRIMARY KEY (id)
+) DEFAULT CHARSET UTF8 COMMENT '';
+`)
+
+	if err != nil {
+		logger.Exception(err, "Error applying Up to  %v", m.Name())
+		return false
+	}
+	return true
+}
+
+func (m ClientsTableMigration) Down() bool {
+	logger := log.Get()
+	dbService := sql.Get()
+
+	globalDb := dbService.GlobalDatabase()
+
+	if globalDb == nil {
+		logger.Error("Error connecting to apply  %v", m.Name())
+		return false
+	}
+
+	defer globalDb.Database.Close()
+
+	_, err := globalDb.Database.Query(`
+  DROP TABLE IF EXISTS identity_clients;
+`)
+
+	if err != nil {
+		logger.Exception(err, "Error applying Down to  %v", m.Name())
+		return false
+	}
+	return true
+}
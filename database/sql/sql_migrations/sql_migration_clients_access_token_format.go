@@ -0,0 +1,66 @@
+package sql_migrations
+
+import (
+	"github.com/cjlapao/common-go-database/sql"
+	log "github.com/cjlapao/common-go-logger"
+)
+
+type ClientsAccessTokenFormatColumnMigration struct{}
+
+func (m ClientsAccessTokenFormatColumnMigration) Name() string {
+	return "Add Access Token Format To Identity Clients Table"
+}
+
+func (m ClientsAccessTokenFormatColumnMigration) Order() int {
+	return 19
+}
+
+func (m ClientsAccessTokenFormatColumnMigration) Up() bool {
+	logger := log.Get()
+	dbService := sql.Get()
+
+	tenantDb := dbService.TenantDatabase().Connect()
+
+	if tenantDb == nil {
+		logger.Error("Error connecting to apply  %v", m.Name())
+		return false
+	}
+
+	defer tenantDb.Close()
+
+	_, err := tenantDb.Query(`
+  ALTER TABLE identity_clients
+    ADD COLUMN accessTokenFormat VARCHAR(50) COMMENT 'Format of the Access Tokens' AFTER tokenEndpointAuthMethod;
+`)
+
+	if err != nil {
+		logger.Exception(err, "Error applying Up to  %v", m.Name())
+		return false
+	}
+	return true
+}
+
+func (m ClientsAccessTokenFormatColumnMigration) Down() bool {
+	logger := log.Get()
+	dbService := sql.Get()
+
+	globalDb := dbService.GlobalDatabase()
+
+	if globalDb == nil {
+		logger.Error("Error connecting to apply  %v", m.Name())
+		return false
+	}
+
+	defer globalDb.Database.Close()
+
+	_, err := globalDb.Database.Query(`
+  ALTER TABLE identity_clients
+    DROP COLUMN accessTokenFormat;
+`)
+
+	if err != nil {
+		logger.Exception(err, "Error applying Down to  %v", m.Name())
+		return false
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package sql_migrations
+
+import (
+	"github.com/cjlapao/common-go-database/sql"
+	log "github.com/cjlapao/common-go-logger"
+)
+
+type OpaqueTokensTableMigration struct{}
+
+func (m OpaqueTokensTableMigration) Name() string {
+	return "Create Identity Opaque Tokens Table"
+}
+
+func (m OpaqueTokensTableMigration) Order() int {
+	return 18
+}
+
+func (m OpaqueTokensTableMigration) Up() bool {
+	logger := log.Get()
+	dbService := sql.Get()
+
+	tenantDb := dbService.TenantDatabase().Connect()
+
+	if tenantDb == nil {
+		logger.Error("Error connecting to apply  %v", m.Name())
+		return false
+	}
+
+	defer tenantDb.Close()
+
+	_, err := tenantDb.Query(`
+  CREATE TABLE IF NOT EXISTS identity_opaque_tokens(
+    id CHAR(64) NOT NULL COMMENT 'Primary Key, Hash of the Token Handle',
+    tokenId VARCHAR(255) NOT NULL COMMENT 'Access Token Id',
+    clientId VARCHAR(255) COMMENT 'Client the token was issued to',
+    userId VARCHAR(255) COMMENT 'User the token was issued to',
+    claims TEXT NOT NULL COMMENT 'Claims of the token',
+    expiresAt DATETIME NOT NULL COMMENT 'Expiry Time of the token',
+    PRIMARY KEY (id),
+    INDEX (expiresAt)
+) DEFAULT CHARSET UTF8 COMMENT '';
+`)
+
+	if err != nil {
+		logger.Exception(err, "Error applying Up to  %v", m.Name())
+		return false
+	}
+	return true
+}
+
+func (m OpaqueTokensTableMigration) Down() bool {
+	logger := log.Get()
+	dbService := sql.Get()
+
+	globalDb := dbService.GlobalDatabase()
+
+	if globalDb == nil {
+		logger.Error("Error connecting to apply  %v", m.Name())
+		return false
+	}
+
+	defer globalDb.Database.Close()
+
+	_, err := globalDb.Database.Query(`
+  DROP TABLE IF EXISTS identity_opaque_tokens;
+`)
+
+	if err != nil {
+		logger.Exception(err, "Error applying Down to  %v", m.Name())
+		return false
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package sql_migrations
+
+import (
+	"github.com/cjlapao/common-go-database/sql"
+	log "github.com/cjlapao/common-go-logger"
+)
+
+type RefreshTokensTableMigration struct{}
+
+func (m RefreshTokensTableMigration) Name() string {
+	return "Create Identity Refresh Tokens Table"
+}
+
+func (m RefreshTokensTableMigration) Order() int {
+	return 8
+}
+
+func (m RefreshTokensTableMigration) Up() bool {
+	logger := log.Get()
+	dbService := sql.Get()
+
+	tenantDb := dbService.TenantDatabase().Connect()
+
+	if tenantDb == nil {
+		logger.Error("Error connecting to apply  %v", m.Name())
+		return false
+	}
+
+	defer tenantDb.Close()
+
+	_, err := tenantDb.Query(`
+  CREATE TABLE IF NOT EXISTS identity_refresh_tokens(
+    id CHAR(64) NOT NULL COMMENT 'Primary Key, hash of the refresh token',
+    familyId CHAR(64) NOT NULL COMMENT 'Token Family Id',
+    userId CHAR(50) NOT NULL COMMENT 'User Id',
+    clientId CHAR(100) COMMENT 'Client Id',
+    tenantId CHAR(50) COMMENT 'Tenant Id',
+    rotated BOOLEAN DEFAULT FALSE COMMENT 'Token was already used',
+    revoked BOOLEAN DEFAULT FALSE COMMENT 'Token was revoked',
+    issuedAt DATETIME NOT NULL COMMENT 'Issue Time',
+    expiresAt DATETIME NOT NULL COMMENT 'Expiry Time',
+    PRIMARY KEY (id),
+    INDEX (familyId),
+    INDEX (userId)
+) DEFAULT CHARSET UTF8 COMMENT '';
+`)
+
+	if err != nil {
+		logger.Exception(err, "Error applying Up to  %v", m.Name())
+		return false
+	}
+	return true
+}
+
+func (m RefreshTokensTableMigration) Down() bool {
+	logger := log.Get()
+	dbService := sql.Get()
+
+	globalDb := dbService.GlobalDatabase()
+
+	if globalDb == nil {
+		logger.Error("Error connecting to apply  %v", m.Name())
+		return false
+	}
+
+	defer globalDb.Database.Close()
+
+	_, err := globalDb.Database.Query(`
+  DROP TABLE IF EXISTS identity_refresh_tokens;
+`)
+
+	if err != nil {
+		logger.Exception(err, "Error applying Down to  %v", m.Name())
+		return false
+	}
+	return true
+}
@@ -0,0 +1,66 @@
+package sql_migrations
+
+import (
+	"github.com/cjlapao/common-go-database/sql"
+	log "github.com/cjlapao/common-go-logger"
+)
+
+type RefreshTokensScopeColumnMigration struct{}
+
+func (m RefreshTokensScopeColumnMigration) Name() string {
+	return "Add Scope To Identity Refresh Tokens Table"
+}
+
+func (m RefreshTokensScopeColumnMigration) Order() int {
+	return 14
+}
+
+func (m RefreshTokensScopeColumnMigration) Up() bool {
+	logger := log.Get()
+	dbService := sql.Get()
+
+	tenantDb := dbService.TenantDatabase().Connect()
+
+	if tenantDb == nil {
+		logger.Error("Error connecting to apply  %v", m.Name())
+		return false
+	}
+
+	defer tenantDb.Close()
+
+	_, err := tenantDb.Query(`
+  ALTER TABLE identity_refresh_tokens
+    ADD COLUMN scope VARCHAR(1024) COMMENT 'Granted Scopes' AFTER tenantId;
+`)
+
+	if err != nil {
+		logger.Exception(err, "Error applying Up to  %v", m.Name())
+		return false
+	}
+	return true
+}
+
+func (m RefreshTokensScopeColumnMigration) Down() bool {
+	logger := log.Get()
+	dbService := sql.Get()
+
+	globalDb := dbService.GlobalDatabase()
+
+	if globalDb == nil {
+		logger.Error("Error connecting to apply  %v", m.Name())
+		return false
+	}
+
+	defer globalDb.Database.Close()
+
+	_, err := globalDb.Database.Query(`
+  ALTER TABLE identity_refresh_tokens
+    DROP COLUMN scope;
+`)
+
+	if err != nil {
+		logger.Exception(err, "Error applying Down to  %v", m.Name())
+		return false
+	}
+	return true
+}
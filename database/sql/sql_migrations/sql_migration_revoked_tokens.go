@@ -0,0 +1,69 @@
+package sql_migrations
+
+import (
+	"github.com/cjlapao/common-go-database/sql"
+	log "github.com/cjlapao/common-go-logger"
+)
+
+type RevokedTokensTableMigration struct{}
+
+func (m RevokedTokensTableMigration) Name() string {
+	return "Create Identity Revoked Tokens Table"
+}
+
+func (m RevokedTokensTableMigration) Order() int {
+	return 16
+}
+
+func (m RevokedTokensTableMigration) Up() bool {
+	logger := log.Get()
+	dbService := sql.Get()
+
+	tenantDb := dbService.TenantDatabase().Connect()
+
+	if tenantDb == nil {
+		logger.Error("Error connecting to apply  %v", m.Name())
+		return false
+	}
+
+	defer tenantDb.Close()
+
+	_, err := tenantDb.Query(`
+  CREATE TABLE IF NOT EXISTS identity_revoked_tokens(
+    id CHAR(64) NOT NULL COMMENT 'Primary Key, Access Token Id',
+    expiresAt DATETIME NOT NULL COMMENT 'Expiry Time of the token',
+    PRIMARY KEY (id),
+    INDEX (expiresAt)
+) DEFAULT CHARSET UTF8 COMMENT '';
+`)
+
+	if err != nil {
+		logger.Exception(err, "Error applying Up to  %v", m.Name())
+		return false
+	}
+	return true
+}
+
+func (m RevokedTokensTableMigration) Down() bool {
+	logger := log.Get()
+	dbService := sql.Get()
+
+	globalDb := dbService.GlobalDatabase()
+
+	if globalDb == nil {
+		logger.Error("Error connecting to apply  %v", m.Name())
+		return false
+	}
+
+	defer globalDb.Database.Close()
+
+	_, err := globalDb.Database.Query(`
+  DROP TABLE IF EXISTS identity_revoked_tokens;
+`)
+
+	if err != nil {
+		logger.Exception(err, "Error applying Down to  %v", m.Name())
+		return false
+	}
+	return true
+}
@@ -0,0 +1,74 @@
+package sql_migrations
+
+import (
+	"github.com/cjlapao/common-go-database/sql"
+	log "github.com/cjlapao/common-go-logger"
+)
+
+type SigningKeysTableMigration struct{}
+
+func (m SigningKeysTableMigration) Name() string {
+	return "Create Identity Signing Keys Table"
+}
+
+func (m SigningKeysTableMigration) Order() int {
+	return 15
+}
+
+func (m SigningKeysTableMigration) Up() bool {
+	logger := log.Get()
+	dbService := sql.Get()
+
+	tenantDb := dbService.TenantDatabase().Connect()
+
+	if tenantDb == nil {
+		logger.Error("Error connecting to apply  %v", m.Name())
+		return false
+	}
+
+	defer tenantDb.Close()
+
+	_, err := tenantDb.Query(`
+  CREATE TABLE IF NOT EXISTS identity_signing_keys(
+    id CHAR(50) NOT NULL COMMENT 'Primary Key, Key Id',
+    algorithm CHAR(10) NOT NULL COMMENT 'Signing Algorithm',
+    privateKey TEXT NOT NULL COMMENT 'Encrypted Private Key',
+    createdAt DATETIME NOT NULL COMMENT 'Creation Time',
+    activatesAt DATETIME NOT NULL COMMENT 'Time the key starts signing',
+    expiresAt DATETIME NOT NULL COMMENT 'Time the key stops signing',
+    retired BOOLEAN DEFAULT FALSE COMMENT 'Key no longer verifies tokens',
+    PRIMARY KEY (id),
+    INDEX (activatesAt)
+) DEFAULT CHARSET UTF8 COMMENT '';
+`)
+
+	if err != nil {
+		logger.Exception(err, "Error applying Up to  %v", m.Name())
+		return false
+	}
+	return true
+}
+
+func (m SigningKeysTableMigration) Down() bool {
+	logger := log.Get()
+	dbService := sql.Get()
+
+	globalDb := dbService.GlobalDatabase()
+
+	if globalDb == nil {
+		logger.Error("Error connecting to apply  %v", m.Name())
+		return false
+	}
+
+	defer globalDb.Database.Close()
+
+	_, err := globalDb.Database.Query(`
+  DROP TABLE IF EXISTS identity_signing_keys;
+`)
+
+	if err != nil {
+		logger.Exception(err, "Error applying Down to  %v", m.Name())
+		return false
+	}
+	return true
+}
@@ -0,0 +1,72 @@
+package sql_migrations
+
+import (
+	"github.com/cjlapao/common-go-database/sql"
+	log "github.com/cjlapao/common-go-logger"
+)
+
+type UserLoginCodesTableMigration struct{}
+
+func (m UserLoginCodesTableMigration) Name() string {
+	return "Create Identity User Login Codes Table"
+}
+
+func (m UserLoginCodesTableMigration) Order() int {
+	return 12
+}
+
+func (m UserLoginCodesTableMigration) Up() bool {
+	logger := log.Get()
+	dbService := sql.Get()
+
+	tenantDb := dbService.TenantDatabase().Connect()
+
+	if tenantDb == nil {
+		logger.Error("Error connecting to apply  %v", m.Name())
+		return false
+	}
+
+	defer tenantDb.Close()
+
+	_, err := tenantDb.Query(`
+  CREATE TABLE IF NOT EXISTS identity_user_login_codes(
+    userId CHAR(50) NOT NULL COMMENT 'Primary Key, User Id',
+    code CHAR(64) NOT NULL COMMENT 'Sha256 Login Code Hash',
+    clientId VARCHAR(255) COMMENT 'Requesting Client Id',
+    attempts INT DEFAULT 0 COMMENT 'Attempts Made',
+    expiresAt DATETIME NOT NULL COMMENT 'Expiry Time',
+    createdAt DATETIME NOT NULL COMMENT 'Creation Time',
+    PRIMARY KEY (userId)
+) DEFAULT CHARSET UTF8 COMMENT '';
+`)
+
+	if err != nil {
+		logger.Exception(err, "Error applying Up to  %v", m.Name())
+		return false
+	}
+	return true
+}
+
+func (m UserLoginCodesTableMigration) Down() bool {
+	logger := log.Get()
+	dbService := sql.Get()
+
+	globalDb := dbService.GlobalDatabase()
+
+	if globalDb == nil {
+		logger.Error("Error connecting to apply  %v", m.Name())
+		return false
+	}
+
+	defer globalDb.Database.Close()
+
+	_, err := globalDb.Database.Query(`
+  DROP TABLE IF EXISTS identity_user_login_codes;
+`)
+
+	if err != nil {
+		logger.Exception(err, "Error applying Down to  %v", m.Name())
+		return false
+	}
+	return true
+}
@@ -0,0 +1,71 @@
+package sql_migrations
+
+import (
+	"github.com/cjlapao/common-go-database/sql"
+	log "github.com/cjlapao/common-go-logger"
+)
+
+type UserRecoveryCodesTableMigration struct{}
+
+func (m UserRecoveryCodesTableMigration) Name() string {
+	return "Create Identity User Recovery Codes Table"
+}
+
+func (m UserRecoveryCodesTableMigration) Order() int {
+	return 10
+}
+
+func (m UserRecoveryCodesTableMigration) Up() bool {
+	logger := log.Get()
+	dbService := sql.Get()
+
+	tenantDb := dbService.TenantDatabase().Connect()
+
+	if tenantDb == nil {
+		logger.Error("Error connecting to apply  %v", m.Name())
+		return false
+	}
+
+	defer tenantDb.Close()
+
+	_, err := tenantDb.Query(`
+  CREATE TABLE IF NOT EXISTS identity_user_recovery_codes(
+    id CHAR(64) NOT NULL COMMENT 'Primary Key, Recovery Code Hash',
+    userId CHAR(50) NOT NULL COMMENT 'User Id',
+    used BOOLEAN DEFAULT FALSE COMMENT 'Code was already used',
+    createdAt DATETIME NOT NULL COMMENT 'Generation Time',
+    PRIMARY KEY (id),
+    INDEX (userId)
+) DEFAULT CHARSET UTF8 COMMENT '';
+`)
+
+	if err != nil {
+		logger.Exception(err, "Error applying Up to  %v", m.Name())
+		return false
+	}
+	return true
+}
+
+func (m UserRecoveryCodesTableMigration) Down() bool {
+	logger := log.Get()
+	dbService := sql.Get()
+
+	globalDb := dbService.GlobalDatabase()
+
+	if globalDb == nil {
+		logger.Error("Error connecting to apply  %v", m.Name())
+		return false
+	}
+
+	defer globalDb.Database.Close()
+
+	_, err := globalDb.Database.Query(`
+  DROP TABLE IF EXISTS identity_user_recovery_codes;
+`)
+
+	if err != nil {
+		logger.Exception(err, "Error applying Down to  %v", m.Name())
+		return false
+	}
+	return true
+}
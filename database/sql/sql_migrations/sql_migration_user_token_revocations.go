@@ -0,0 +1,70 @@
+package sql_migrations
+
+import (
+	"github.com/cjlapao/common-go-database/sql"
+	log "github.com/cjlapao/common-go-logger"
+)
+
+type UserTokenRevocationsTableMigration struct{}
+
+func (m UserTokenRevocationsTableMigration) Name() string {
+	return "Create Identity User Token Revocations Table"
+}
+
+func (m UserTokenRevocationsTableMigration) Order() int {
+	return 17
+}
+
+func (m UserTokenRevocationsTableMigration) Up() bool {
+	logger := log.Get()
+	dbService := sql.Get()
+
+	tenantDb := dbService.TenantDatabase().Connect()
+
+	if tenantDb == nil {
+		logger.Error("Error connecting to apply  %v", m.Name())
+		return false
+	}
+
+	defer tenantDb.Close()
+
+	_, err := tenantDb.Query(`
+  CREATE TABLE IF NOT EXISTS identity_user_token_revocations(
+    userId CHAR(50) NOT NULL COMMENT 'Primary Key, User Id',
+    revokedBefore DATETIME NOT NULL COMMENT 'Tokens issued before are revoked',
+    expiresAt DATETIME NOT NULL COMMENT 'Expiry Time of the last revoked token',
+    PRIMARY KEY (userId),
+    INDEX (expiresAt)
+) DEFAULT CHARSET UTF8 COMMENT '';
+`)
+
+	if err != nil {
+		logger.Exception(err, "Error applying Up to  %v", m.Name())
+		return false
+	}
+	return true
+}
+
+func (m UserTokenRevocationsTableMigration) Down() bool {
+	logger := log.Get()
+	dbService := sql.Get()
+
+	globalDb := dbService.GlobalDatabase()
+
+	if globalDb == nil {
+		logger.Error("Error connecting to apply  %v", m.Name())
+		return false
+	}
+
+	defer globalDb.Database.Close()
+
+	_, err := globalDb.Database.Query(`
+  DROP TABLE IF EXISTS identity_user_token_revocations;
+`)
+
+	if err != nil {
+		logger.Exception(err, "Error applying Down to  %v", m.Name())
+		return false
+	}
+	return true
+}
@@ -0,0 +1,71 @@
+package sql_migrations
+
+import (
+	"github.com/cjlapao/common-go-database/sql"
+	log "github.com/cjlapao/common-go-logger"
+)
+
+type UserTotpTableMigration struct{}
+
+func (m UserTotpTableMigration) Name() string {
+	return "Create Identity User Totp Table"
+}
+
+func (m UserTotpTableMigration) Order() int {
+	return 9
+}
+
+func (m UserTotpTableMigration) Up() bool {
+	logger := log.Get()
+	dbService := sql.Get()
+
+	tenantDb := dbService.TenantDatabase().Connect()
+
+	if tenantDb == nil {
+		logger.Error("Error connecting to apply  %v", m.Name())
+		return false
+	}
+
+	defer tenantDb.Close()
+
+	_, err := tenantDb.Query(`
+  CREATE TABLE IF NOT EXISTS identity_user_totp(
+    userId CHAR(50) NOT NULL COMMENT 'Primary Key, User Id',
+    secret VARCHAR(255) NOT NULL COMMENT 'Encrypted Totp Secret',
+    enabled BOOLEAN DEFAULT FALSE COMMENT 'Enrollment was confirmed',
+    lastUsedStep BIGINT DEFAULT 0 COMMENT 'Time step of the last accepted code',
+    createdAt DATETIME NOT NULL COMMENT 'Enrollment Time',
+    PRIMARY KEY (userId)
+) DEFAULT CHARSET UTF8 COMMENT '';
+`)
+
+	if err != nil {
+		logger.Exception(err, "Error applying Up to  %v", m.Name())
+		return false
+	}
+	return true
+}
+
+func (m UserTotpTableMigration) Down() bool {
+	logger := log.Get()
+	dbService := sql.Get()
+
+	globalDb := dbService.GlobalDatabase()
+
+	if globalDb == nil {
+		logger.Error("Error connecting to apply  %v", m.Name())
+		return false
+	}
+
+	defer globalDb.Database.Close()
+
+	_, err := globalDb.Database.Query(`
+  DROP TABLE IF EXISTS identity_user_totp;
+`)
+
+	if err != nil {
+		logger.Exception(err, "Error applying Down to  %v", m.Name())
+		return false
+	}
+	return true
+}
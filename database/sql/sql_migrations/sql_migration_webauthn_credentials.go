@@ -0,0 +1,76 @@
+package sql_migrations
+
+import (
+	"github.com/cjlapao/common-go-database/sql"
+	log "github.com/cjlapao/common-go-logger"
+)
+
+type WebAuthnCredentialsTableMigration struct{}
+
+func (m WebAuthnCredentialsTableMigration) Name() string {
+	return "Create Identity WebAuthn Credentials Table"
+}
+
+func (m WebAuthnCredentialsTableMigration) Order() int {
+	return 11
+}
+
+func (m WebAuthnCredentialsTableMigration) Up() bool {
+	logger := log.Get()
+	dbService := sql.Get()
+
+	tenantDb := dbService.TenantDatabase().Connect()
+
+	if tenantDb == nil {
+		logger.Error("Error connecting to apply  %v", m.Name())
+		return false
+	}
+
+	defer tenantDb.Close()
+
+	_, err := tenantDb.Query(`
+  CREATE TABLE IF NOT EXISTS identity_webauthn_credentials(
+    id VARCHAR(1400) NOT NULL COMMENT 'Primary Key, Base64Url Credential Id',
+    userId CHAR(50) NOT NULL COMMENT 'User Id',
+    name VARCHAR(255) COMMENT 'Credential Display Name',
+    publicKey TEXT NOT NULL COMMENT 'Base64Url COSE Public Key',
+    attestationType VARCHAR(20) COMMENT 'Attestation Type',
+    aaguid CHAR(32) COMMENT 'Authenticator Model',
+    signCount BIGINT DEFAULT 0 COMMENT 'Last Signature Counter',
+    createdAt DATETIME NOT NULL COMMENT 'Registration Time',
+    lastUsedAt DATETIME NOT NULL COMMENT 'Last Login Time',
+    PRIMARY KEY (id(255)),
+    INDEX (userId)
+) DEFAULT CHARSET UTF8 COMMENT '';
+`)
+
+	if err != nil {
+		logger.Exception(err, "Error applying Up to  %v", m.Name())
+		return false
+	}
+	return true
+}
+
+func (m WebAuthnCredentialsTableMigration) Down() bool {
+	logger := log.Get()
+	dbService := sql.Get()
+
+	globalDb := dbService.GlobalDatabase()
+
+	if globalDb == nil {
+		logger.Error("Error connecting to apply  %v", m.Name())
+		return false
+	}
+
+	defer globalDb.Database.Close()
+
+	_, err := globalDb.Database.Query(`
+  DROP TABLE IF EXISTS identity_webauthn_credentials;
+`)
+
+	if err != nil {
+		logger.Exception(err, "Error applying Down to  %v", m.Name())
+		return false
+	}
+	return true
+}
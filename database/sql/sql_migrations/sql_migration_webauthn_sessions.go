@@ -0,0 +1,69 @@
+package sql_migrations
+
+import (
+	"github.com/cjlapao/common-go-database/sql"
+	log "github.com/cjlapao/common-go-logger"
+)
+
+type WebAuthnSessionsTableMigration struct{}
+
+func (m WebAuthnSessionsTableMigration) Name() string {
+	return "Create Identity WebAuthn Sessions Table"
+}
+
+func (m WebAuthnSessionsTableMigration) Order() int {
+	return 20
+}
+
+func (m WebAuthnSessionsTableMigration) Up() bool {
+	logger := log.Get()
+	dbService := sql.Get()
+
+	tenantDb := dbService.TenantDatabase().Connect()
+
+	if tenantDb == nil {
+		logger.Error("Error connecting to apply  %v", m.Name())
+		return false
+	}
+
+	defer tenantDb.Close()
+
+	_, err := tenantDb.Query(`
+  CREATE TABLE IF NOT EXISTS identity_webauthn_sessions(
+    id CHAR(64) NOT NULL COMMENT 'Primary Key, Sha256 Challenge Hash',
+    userId CHAR(50) COMMENT 'User Id',
+    expiresAt DATETIME NOT NULL COMMENT 'Expiry Time',
+    PRIMARY KEY (id)
+) DEFAULT CHARSET UTF8 COMMENT '';
+`)
+
+	if err != nil {
+		logger.Exception(err, "Error applying Up to  %v", m.Name())
+		return false
+	}
+	return true
+}
+
+func (m WebAuthnSessionsTableMigration) Down() bool {
+	logger := log.Get()
+	dbService := sql.Get()
+
+	globalDb := dbService.GlobalDatabase()
+
+	if globalDb == nil {
+		logger.Error("Error connecting to apply  %v", m.Name())
+		return false
+	}
+
+	defer globalDb.Database.Close()
+
+	_, err := globalDb.Database.Query(`
+  DROP TABLE IF EXISTS identity_webauthn_sessions;
+`)
+
+	if err != nil {
+		logger.Exception(err, "Error applying Down to  %v", m.Name())
+		return false
+	}
+	return true
+}
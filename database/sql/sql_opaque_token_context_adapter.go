@@ -0,0 +1,103 @@
+package sql
+
+import (
+	"time"
+
+	"github.com/cjlapao/common-go-database/migrations"
+	"github.com/cjlapao/common-go-database/sql"
+	"github.com/cjlapao/common-go-identity/database/dto"
+	"github.com/cjlapao/common-go-identity/database/sql/sql_migrations"
+)
+
+type SqlDBOpaqueTokenContextAdapter struct{}
+
+func (u SqlDBOpaqueTokenContextAdapter) ApplyMigrations() error {
+	sqlRepo := sql.NewSqlMigrationRepo()
+	migrationService := migrations.NewMigrationService(sqlRepo)
+
+	migrationService.Register(sql_migrations.OpaqueTokensTableMigration{})
+
+	return migrationService.Run()
+}
+
+func (u SqlDBOpaqueTokenContextAdapter) GetOpaqueToken(id string) *dto.OpaqueTokenDTO {
+	var result dto.OpaqueTokenDTO
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	row := db.QueryRowContext(`
+SELECT
+  id, tokenId, clientId, userId, claims, expiresAt
+FROM
+  identity_opaque_tokens
+WHERE
+  id = ? AND expiresAt >= ?
+`, id, time.Now())
+
+	if row.Err() != nil {
+		return nil
+	}
+
+	row.Scan(
+		&result.ID,
+		&result.TokenID,
+		&result.ClientID,
+		&result.UserID,
+		&result.Claims,
+		&result.ExpiresAt,
+	)
+
+	if result.ID == "" {
+		return nil
+	}
+
+	return &result
+}
+
+func (u SqlDBOpaqueTokenContextAdapter) AddOpaqueToken(token dto.OpaqueTokenDTO) error {
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	// Removing the expired tokens so the table does not grow forever
+	if _, err := db.ExecContext(`
+DELETE FROM
+  identity_opaque_tokens
+WHERE
+  expiresAt < ?
+`, time.Now()); err != nil {
+		return err
+	}
+
+	_, err := db.ExecContext(`
+INSERT INTO
+identity_opaque_tokens(
+  id,
+  tokenId,
+  clientId,
+  userId,
+  claims,
+  expiresAt)
+VALUES
+(?, ?, ?, ?, ?, ?);`,
+		token.ID, token.TokenID, token.ClientID, token.UserID, token.Claims, token.ExpiresAt)
+
+	return err
+}
+
+func (u SqlDBOpaqueTokenContextAdapter) RemoveOpaqueToken(id string) error {
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	_, err := db.ExecContext(`
+DELETE FROM
+  identity_opaque_tokens
+WHERE
+  id = ?
+`, id)
+
+	return err
+}
+
+func (u SqlDBOpaqueTokenContextAdapter) getTenantRepository() *sql.SqlFactory {
+	return sql.Get().TenantDatabase()
+}
@@ -0,0 +1,149 @@
+package sql
+
+import (
+	"github.com/cjlapao/common-go-database/migrations"
+	"github.com/cjlapao/common-go-database/sql"
+	"github.com/cjlapao/common-go-identity/database/dto"
+	"github.com/cjlapao/common-go-identity/database/sql/sql_migrations"
+)
+
+type SqlDBRefreshTokenContextAdapter struct{}
+
+func (u SqlDBRefreshTokenContextAdapter) ApplyMigrations() error {
+	sqlRepo := sql.NewSqlMigrationRepo()
+	migrationService := migrations.NewMigrationService(sqlRepo)
+
+	migrationService.Register(sql_migrations.RefreshTokensTableMigration{})
+	migrationService.Register(sql_migrations.RefreshTokensScopeColumnMigration{})
+
+	return migrationService.Run()
+}
+
+func (u SqlDBRefreshTokenContextAdapter) GetRefreshToken(id string) *dto.RefreshTokenDTO {
+	var result dto.RefreshTokenDTO
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	row := db.QueryRowContext(`
+SELECT
+  id, familyId, userId, clientId, tenantId, scope,
+  rotated, revoked, issuedAt, expiresAt
+FROM
+  identity_refresh_tokens
+WHERE
+  id = ?
+`, id)
+
+	if row.Err() != nil {
+		return nil
+	}
+
+	row.Scan(
+		&result.ID,
+		&result.FamilyID,
+		&result.UserID,
+		&result.ClientID,
+		&result.TenantID,
+		&result.Scope,
+		&result.Rotated,
+		&result.Revoked,
+		&result.IssuedAt,
+		&result.ExpiresAt,
+	)
+
+	if result.ID == "" {
+		return nil
+	}
+
+	return &result
+}
+
+func (u SqlDBRefreshTokenContextAdapter) UpsertRefreshToken(token dto.RefreshTokenDTO) error {
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	_, err := db.ExecContext(`
+INSERT INTO
+identity_refresh_tokens(
+  id,
+  familyId,
+  userId,
+  clientId,
+  tenantId,
+  scope,
+  rotated,
+  revoked,
+  issuedAt,
+  expiresAt)
+VALUES
+(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON DUPLICATE KEY UPDATE
+  rotated = VALUES(rotated),
+  revoked = VALUES(revoked);`,
+		token.ID, token.FamilyID, token.UserID, token.ClientID, token.TenantID, token.Scope,
+		token.Rotated, token.Revoked, token.IssuedAt, token.ExpiresAt)
+
+	return err
+}
+
+func (u SqlDBRefreshTokenContextAdapter) RotateRefreshToken(id string) bool {
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	// Only one caller can flip the flag, this is what detects a replayed token
+	result, err := db.ExecContext(`
+UPDATE
+  identity_refresh_tokens
+SET
+  rotated = TRUE
+WHERE
+  id = ? AND rotated = FALSE AND revoked = FALSE
+`, id)
+
+	if err != nil {
+		return false
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false
+	}
+
+	return affected == 1
+}
+
+func (u SqlDBRefreshTokenContextAdapter) RevokeRefreshTokenFamily(familyId string) error {
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	_, err := db.ExecContext(`
+UPDATE
+  identity_refresh_tokens
+SET
+  revoked = TRUE
+WHERE
+  familyId = ?
+`, familyId)
+
+	return err
+}
+
+func (u SqlDBRefreshTokenContextAdapter) RevokeUserRefreshTokens(userId string) error {
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	_, err := db.ExecContext(`
+UPDATE
+  identity_refresh_tokens
+SET
+  revoked = TRUE
+WHERE
+  userId = ?
+`, userId)
+
+	return err
+}
+
+func (u SqlDBRefreshTokenContextAdapter) getTenantRepository() *sql.SqlFactory {
+	return sql.Get().TenantDatabase()
+}
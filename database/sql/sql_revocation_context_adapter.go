@@ -0,0 +1,136 @@
+package sql
+
+import (
+	"time"
+
+	"github.com/cjlapao/common-go-database/migrations"
+	"github.com/cjlapao/common-go-database/sql"
+	"github.com/cjlapao/common-go-identity/database/dto"
+	"github.com/cjlapao/common-go-identity/database/sql/sql_migrations"
+)
+
+type SqlDBRevocationContextAdapter struct{}
+
+func (u SqlDBRevocationContextAdapter) ApplyMigrations() error {
+	sqlRepo := sql.NewSqlMigrationRepo()
+	migrationService := migrations.NewMigrationService(sqlRepo)
+
+	migrationService.Register(sql_migrations.RevokedTokensTableMigration{})
+	migrationService.Register(sql_migrations.UserTokenRevocationsTableMigration{})
+
+	return migrationService.Run()
+}
+
+func (u SqlDBRevocationContextAdapter) RevokeToken(id string, expiresAt time.Time) error {
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	// Removing the expired tokens so the table does not grow forever
+	if _, err := db.ExecContext(`
+DELETE FROM
+  identity_revoked_tokens
+WHERE
+  expiresAt < ?
+`, time.Now()); err != nil {
+		return err
+	}
+
+	_, err := db.ExecContext(`
+INSERT INTO
+identity_revoked_tokens(
+  id,
+  expiresAt)
+VALUES
+(?, ?)
+ON DUPLICATE KEY UPDATE
+  expiresAt = VALUES(expiresAt);`,
+		id, expiresAt)
+
+	return err
+}
+
+func (u SqlDBRevocationContextAdapter) IsTokenRevoked(id string) bool {
+	var result string
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	row := db.QueryRowContext(`
+SELECT
+  id
+FROM
+  identity_revoked_tokens
+WHERE
+  id = ? AND expiresAt >= ?
+`, id, time.Now())
+
+	if row.Err() != nil {
+		return false
+	}
+
+	row.Scan(&result)
+	return result != ""
+}
+
+func (u SqlDBRevocationContextAdapter) RevokeUserTokens(revocation dto.UserTokenRevocationDTO) error {
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	if _, err := db.ExecContext(`
+DELETE FROM
+  identity_user_token_revocations
+WHERE
+  expiresAt < ?
+`, time.Now()); err != nil {
+		return err
+	}
+
+	_, err := db.ExecContext(`
+INSERT INTO
+identity_user_token_revocations(
+  userId,
+  revokedBefore,
+  expiresAt)
+VALUES
+(?, ?, ?)
+ON DUPLICATE KEY UPDATE
+  revokedBefore = VALUES(revokedBefore),
+  expiresAt = VALUES(expiresAt);`,
+		revocation.UserID, revocation.RevokedBefore, revocation.ExpiresAt)
+
+	return err
+}
+
+func (u SqlDBRevocationContextAdapter) GetUserTokenRevocation(userId string) *dto.UserTokenRevocationDTO {
+	var result dto.UserTokenRevocationDTO
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	row := db.QueryRowContext(`
+SELECT
+  userId, revokedBefore, expiresAt
+FROM
+  identity_user_token_revocations
+WHERE
+  userId = ? AND expiresAt >= ?
+`, userId, time.Now())
+
+	if row.Err() != nil {
+		return nil
+	}
+
+	row.Scan(
+		&result.UserID,
+		&result.RevokedBefore,
+		&result.ExpiresAt,
+	)
+
+	if result.UserID == "" {
+		return nil
+	}
+
+	return &result
+}
+
+func (u SqlDBRevocationContextAdapter) getTenantRepository() *sql.SqlFactory {
+	return sql.Get().TenantDatabase()
+}
@@ -1,6 +1,7 @@
 package sql
 
 import (
+	"errors"
 	"strings"
 	"time"
 
@@ -21,6 +22,11 @@ func (u SqlDBUserContextAdapter) ApplyMigrations() error {
 	migrationService.Register(sql_migrations.UserRolesTableMigration{})
 	migrationService.Register(sql_migrations.ClaimsTableMigration{})
 	migrationService.Register(sql_migrations.UserClaimsTableMigration{})
+	migrationService.Register(sql_migrations.UserTotpTableMigration{})
+	migrationService.Register(sql_migrations.UserRecoveryCodesTableMigration{})
+	migrationService.Register(sql_migrations.WebAuthnCredentialsTableMigration{})
+	migrationService.Register(sql_migrations.UserLoginCodesTableMigration{})
+	migrationService.Register(sql_migrations.WebAuthnSessionsTableMigration{})
 
 	return migrationService.Run()
 }
@@ -347,6 +353,62 @@ WHERE
 	return row.Err()
 }
 
+func (u SqlDBUserContextAdapter) UpdateUserInvalidAttempts(id string, attempts int, blockedUntil string) error {
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	_, err := db.ExecContext(`
+UPDATE
+  identity_users
+SET
+  invalidAttempts = ?,
+  blockedUntil = ?,
+  update_time = ?
+WHERE
+  id = ?
+`, attempts, blockedUntil, time.Now(), id)
+
+	return err
+}
+
+// IncrementUserInvalidAttempts increments the counter in the database so concurrent failed
+// logins are all counted, the returned count can include the attempts of other requests
+func (u SqlDBUserContextAdapter) IncrementUserInvalidAttempts(id string) (int, error) {
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	result, err := db.ExecContext(`
+UPDATE
+  identity_users
+SET
+  invalidAttempts = invalidAttempts + 1,
+  update_time = ?
+WHERE
+  id = ?
+`, time.Now(), id)
+	if err != nil {
+		return 0, err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return 0, errors.New("user not found")
+	}
+
+	attempts := 0
+	row := db.QueryRowContext(`
+SELECT
+  invalidAttempts
+FROM
+  identity_users
+WHERE
+  id = ?
+`, id)
+	if err := row.Scan(&attempts); err != nil {
+		return 0, err
+	}
+
+	return attempts, nil
+}
+
 func (u SqlDBUserContextAdapter) GetUserRefreshToken(id string) *string {
 	var result dto.UserDTO
 	db := u.getTenantRepository().Connect()
@@ -798,3 +860,501 @@ VALUES(?,?);
 func (u SqlDBUserContextAdapter) getTenantRepository() *sql.SqlFactory {
 	return sql.Get().TenantDatabase()
 }
+
+func (u SqlDBUserContextAdapter) GetUserTotp(id string) *dto.UserTotpDTO {
+	var result dto.UserTotpDTO
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	row := db.QueryRowContext(`
+SELECT
+  userId, secret, enabled, lastUsedStep, createdAt
+FROM
+  identity_user_totp
+WHERE
+  userId = ?
+`, id)
+
+	if row.Err() != nil {
+		return nil
+	}
+
+	row.Scan(
+		&result.UserID,
+		&result.Secret,
+		&result.Enabled,
+		&result.LastUsedStep,
+		&result.CreatedAt,
+	)
+
+	if result.UserID == "" {
+		return nil
+	}
+
+	return &result
+}
+
+func (u SqlDBUserContextAdapter) UpsertUserTotp(totp dto.UserTotpDTO) error {
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	_, err := db.ExecContext(`
+INSERT INTO
+identity_user_totp(
+  userId,
+  secret,
+  enabled,
+  lastUsedStep,
+  createdAt)
+VALUES
+(?, ?, ?, ?, ?)
+ON DUPLICATE KEY UPDATE
+  secret = VALUES(secret),
+  enabled = VALUES(enabled),
+  lastUsedStep = VALUES(lastUsedStep),
+  createdAt = VALUES(createdAt);`,
+		totp.UserID, totp.Secret, totp.Enabled, totp.LastUsedStep, totp.CreatedAt)
+
+	return err
+}
+
+func (u SqlDBUserContextAdapter) RemoveUserTotp(id string) error {
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	_, err := db.ExecContext(`
+DELETE FROM
+  identity_user_totp
+WHERE
+  userId = ?
+`, id)
+
+	return err
+}
+
+func (u SqlDBUserContextAdapter) UpdateUserTotpLastUsedStep(id string, step int64) bool {
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	result, err := db.ExecContext(`
+UPDATE
+  identity_user_totp
+SET
+  lastUsedStep = ?
+WHERE
+  userId = ? AND lastUsedStep < ?
+`, step, id, step)
+
+	if err != nil {
+		return false
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false
+	}
+
+	return affected == 1
+}
+
+func (u SqlDBUserContextAdapter) CountUserRecoveryCodes(userId string) int {
+	var result int
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	row := db.QueryRowContext(`
+SELECT
+  COUNT(*)
+FROM
+  identity_user_recovery_codes
+WHERE
+  userId = ? AND used = FALSE
+`, userId)
+
+	if row.Err() != nil {
+		return 0
+	}
+
+	row.Scan(&result)
+	return result
+}
+
+func (u SqlDBUserContextAdapter) ReplaceUserRecoveryCodes(userId string, codes []dto.UserRecoveryCodeDTO) error {
+	if err := u.RemoveUserRecoveryCodes(userId); err != nil {
+		return err
+	}
+
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	for _, code := range codes {
+		_, err := db.ExecContext(`
+INSERT INTO
+identity_user_recovery_codes(
+  id,
+  userId,
+  used,
+  createdAt)
+VALUES
+(?, ?, ?, ?)`,
+			code.ID, userId, code.Used, code.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (u SqlDBUserContextAdapter) RemoveUserRecoveryCodes(userId string) error {
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	_, err := db.ExecContext(`
+DELETE FROM
+  identity_user_recovery_codes
+WHERE
+  userId = ?
+`, userId)
+
+	return err
+}
+
+func (u SqlDBUserContextAdapter) UseUserRecoveryCode(userId string, id string) bool {
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	result, err := db.ExecContext(`
+UPDATE
+  identity_user_recovery_codes
+SET
+  used = TRUE
+WHERE
+  id = ? AND userId = ? AND used = FALSE
+`, id, userId)
+
+	if err != nil {
+		return false
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false
+	}
+
+	return affected == 1
+}
+
+func (u SqlDBUserContextAdapter) GetUserWebAuthnCredentials(userId string) []dto.WebAuthnCredentialDTO {
+	result := make([]dto.WebAuthnCredentialDTO, 0)
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	rows, err := db.QueryContext(`
+SELECT
+  id, userId, name, publicKey, attestationType,
+  aaguid, signCount, createdAt, lastUsedAt
+FROM
+  identity_webauthn_credentials
+WHERE
+  userId = ?
+`, userId)
+
+	if err != nil {
+		return result
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var credential dto.WebAuthnCredentialDTO
+		if err := rows.Scan(
+			&credential.ID,
+			&credential.UserID,
+			&credential.Name,
+			&credential.PublicKey,
+			&credential.AttestationType,
+			&credential.AAGUID,
+			&credential.SignCount,
+			&credential.CreatedAt,
+			&credential.LastUsedAt,
+		); err != nil {
+			return result
+		}
+
+		result = append(result, credential)
+	}
+
+	return result
+}
+
+func (u SqlDBUserContextAdapter) GetWebAuthnCredential(id string) *dto.WebAuthnCredentialDTO {
+	var result dto.WebAuthnCredentialDTO
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	row := db.QueryRowContext(`
+SELECT
+  id, userId, name, publicKey, attestationType,
+  aaguid, signCount, createdAt, lastUsedAt
+FROM
+  identity_webauthn_credentials
+WHERE
+  id = ?
+`, id)
+
+	if row.Err() != nil {
+		return nil
+	}
+
+	row.Scan(
+		&result.ID,
+		&result.UserID,
+		&result.Name,
+		&result.PublicKey,
+		&result.AttestationType,
+		&result.AAGUID,
+		&result.SignCount,
+		&result.CreatedAt,
+		&result.LastUsedAt,
+	)
+
+	if result.ID == "" {
+		return nil
+	}
+
+	return &result
+}
+
+func (u SqlDBUserContextAdapter) UpsertWebAuthnCredential(credential dto.WebAuthnCredentialDTO) error {
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	_, err := db.ExecContext(`
+INSERT INTO
+identity_webauthn_credentials(
+  id,
+  userId,
+  name,
+  publicKey,
+  attestationType,
+  aaguid,
+  signCount,
+  createdAt,
+  lastUsedAt)
+VALUES
+(?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON DUPLICATE KEY UPDATE
+  name = VALUES(name),
+  signCount = VALUES(signCount),
+  lastUsedAt = VALUES(lastUsedAt);`,
+		credential.ID, credential.UserID, credential.Name, credential.PublicKey, credential.AttestationType,
+		credential.AAGUID, credential.SignCount, credential.CreatedAt, credential.LastUsedAt)
+
+	return err
+}
+
+func (u SqlDBUserContextAdapter) RemoveWebAuthnCredential(userId string, id string) error {
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	result, err := db.ExecContext(`
+DELETE FROM
+  identity_webauthn_credentials
+WHERE
+  id = ? AND userId = ?
+`, id, userId)
+
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return errors.New("credential not found")
+	}
+
+	return nil
+}
+
+func (u SqlDBUserContextAdapter) UpdateWebAuthnCredentialSignCount(id string, signCount int64, lastUsedAt time.Time) bool {
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	result, err := db.ExecContext(`
+UPDATE
+  identity_webauthn_credentials
+SET
+  signCount = ?,
+  lastUsedAt = ?
+WHERE
+  id = ? AND (signCount < ? OR ? = 0)
+`, signCount, lastUsedAt, id, signCount, signCount)
+
+	if err != nil {
+		return false
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false
+	}
+
+	return affected == 1
+}
+
+func (u SqlDBUserContextAdapter) AddWebAuthnSession(session dto.WebAuthnSessionDTO) error {
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	// Removing the expired sessions so the table does not grow forever
+	if _, err := db.ExecContext(`
+DELETE FROM
+  identity_webauthn_sessions
+WHERE
+  expiresAt < ?
+`, time.Now()); err != nil {
+		return err
+	}
+
+	_, err := db.ExecContext(`
+INSERT INTO
+identity_webauthn_sessions(
+  id,
+  userId,
+  expiresAt)
+VALUES
+(?, ?, ?)`,
+		session.ID, session.UserID, session.ExpiresAt)
+
+	return err
+}
+
+func (u SqlDBUserContextAdapter) RemoveWebAuthnSession(id string) bool {
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	result, err := db.ExecContext(`
+DELETE FROM
+  identity_webauthn_sessions
+WHERE
+  id = ? AND expiresAt > ?
+`, id, time.Now())
+
+	if err != nil {
+		return false
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false
+	}
+
+	return affected == 1
+}
+
+func (u SqlDBUserContextAdapter) GetUserLoginCode(userId string) *dto.UserLoginCodeDTO {
+	var result dto.UserLoginCodeDTO
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	row := db.QueryRowContext(`
+SELECT
+  userId, code, clientId, attempts, expiresAt, createdAt
+FROM
+  identity_user_login_codes
+WHERE
+  userId = ?
+`, userId)
+
+	if row.Err() != nil {
+		return nil
+	}
+
+	row.Scan(
+		&result.UserID,
+		&result.Code,
+		&result.ClientID,
+		&result.Attempts,
+		&result.ExpiresAt,
+		&result.CreatedAt,
+	)
+
+	if result.UserID == "" {
+		return nil
+	}
+
+	return &result
+}
+
+func (u SqlDBUserContextAdapter) UpsertUserLoginCode(loginCode dto.UserLoginCodeDTO) error {
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	_, err := db.ExecContext(`
+INSERT INTO
+identity_user_login_codes(
+  userId,
+  code,
+  clientId,
+  attempts,
+  expiresAt,
+  createdAt)
+VALUES
+(?, ?, ?, ?, ?, ?)
+ON DUPLICATE KEY UPDATE
+  code = VALUES(code),
+  clientId = VALUES(clientId),
+  attempts = VALUES(attempts),
+  expiresAt = VALUES(expiresAt),
+  createdAt = VALUES(createdAt);`,
+		loginCode.UserID, loginCode.Code, loginCode.ClientID, loginCode.Attempts, loginCode.ExpiresAt, loginCode.CreatedAt)
+
+	return err
+}
+
+func (u SqlDBUserContextAdapter) UpdateUserLoginCodeAttempts(userId string, attempts int) bool {
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	result, err := db.ExecContext(`
+UPDATE
+  identity_user_login_codes
+SET
+  attempts = ?
+WHERE
+  userId = ? AND attempts = ?
+`, attempts, userId, attempts-1)
+
+	if err != nil {
+		return false
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false
+	}
+
+	return affected == 1
+}
+
+func (u SqlDBUserContextAdapter) RemoveUserLoginCode(userId string, code string) bool {
+	db := u.getTenantRepository().Connect()
+	defer db.Close()
+
+	result, err := db.ExecContext(`
+DELETE FROM
+  identity_user_login_codes
+WHERE
+  userId = ? AND code = ?
+`, userId, code)
+
+	if err != nil {
+		return false
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false
+	}
+
+	return affected == 1
+}
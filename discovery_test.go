@@ -0,0 +1,91 @@
+package identity
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestOpenIdConfiguration(t *testing.T) {
+	var configuration map[string]interface{}
+	if status := sendJsonRequest(t, http.MethodGet, ".well-known/openid-configuration", "", nil, &configuration); status != http.StatusOK {
+		t.Fatalf("expected status %v, got %v", http.StatusOK, status)
+	}
+
+	if endpoint, _ := configuration["introspection_endpoint"].(string); !strings.HasSuffix(endpoint, "/auth/token/introspect") {
+		t.Errorf("expected the registered introspection route, got %v", configuration["introspection_endpoint"])
+	}
+	if methods := toStrings(configuration["introspection_endpoint_auth_methods_supported"]); slices.Contains(methods, "none") || !slices.Contains(methods, "client_secret_basic") {
+		t.Errorf("expected the introspection endpoint to require client authentication, got %v", methods)
+	}
+	if endpoint, _ := configuration["userinfo_endpoint"].(string); !strings.HasSuffix(endpoint, "/auth/userinfo") {
+		t.Errorf("expected the userinfo endpoint, got %v", configuration["userinfo_endpoint"])
+	}
+	if configuration["end_session_endpoint"] != nil {
+		t.Errorf("expected no end session endpoint as it is not registered, got %v", configuration["end_session_endpoint"])
+	}
+	if grants := toStrings(configuration["grant_types_supported"]); !slices.Contains(grants, "authorization_code") || !slices.Contains(grants, "refresh_token") {
+		t.Errorf("expected the supported grants, got %v", grants)
+	}
+	if algorithms := toStrings(configuration["id_token_signing_alg_values_supported"]); len(algorithms) == 0 || algorithms[0] != "HS256" {
+		t.Errorf("expected the algorithm of the test key, got %v", algorithms)
+	}
+	if methods := toStrings(configuration["code_challenge_methods_supported"]); !slices.Contains(methods, "S256") {
+		t.Errorf("expected the pkce methods, got %v", methods)
+	}
+	if scopes := toStrings(configuration["scopes_supported"]); !slices.Contains(scopes, "openid") {
+		t.Errorf("expected the openid scope, got %v", scopes)
+	}
+
+	// the issuer is the one in the tokens
+	_, body := requestToken(t, url.Values{
+		"grant_type": {"password"},
+		"client_id":  {"spa"},
+		"username":   {testAdminUsername},
+		"password":   {testAdminPassword},
+	})
+	if claims := decodeTestToken(t, body["access_token"].(string)); claims["iss"] != configuration["issuer"] {
+		t.Errorf("expected the issuer %v, got %v", claims["iss"], configuration["issuer"])
+	}
+}
+
+func TestOpenIdConfigurationTenant(t *testing.T) {
+	var configuration map[string]interface{}
+	if status := sendJsonRequest(t, http.MethodGet, "contoso/.well-known/openid-configuration", "", nil, &configuration); status != http.StatusOK {
+		t.Fatalf("expected status %v, got %v", http.StatusOK, status)
+	}
+
+	if configuration["issuer"] == nil || configuration["issuer"] == "" {
+		t.Errorf("expected the tenant issuer, got %v", configuration["issuer"])
+	}
+	if endpoint, _ := configuration["token_endpoint"].(string); !strings.HasSuffix(endpoint, "/auth/contoso/token") {
+		t.Errorf("expected the tenant token endpoint, got %v", configuration["token_endpoint"])
+	}
+}
+
+func TestAuthorizationServerMetadata(t *testing.T) {
+	server := getTestServer(t)
+	for _, path := range []string{"/auth/.well-known/oauth-authorization-server", "/.well-known/oauth-authorization-server/auth"} {
+		response, err := newTestClient().Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("request failed, %v", err)
+		}
+		response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("expected status %v on %v, got %v", http.StatusOK, path, response.StatusCode)
+		}
+	}
+
+	var metadata map[string]interface{}
+	if status := sendJsonRequest(t, http.MethodGet, ".well-known/oauth-authorization-server", "", nil, &metadata); status != http.StatusOK {
+		t.Fatalf("expected status %v, got %v", http.StatusOK, status)
+	}
+	if metadata["issuer"] == nil || metadata["token_endpoint"] == nil || metadata["response_types_supported"] == nil {
+		t.Errorf("expected the required metadata, got %v", metadata)
+	}
+	if metadata["userinfo_endpoint"] != nil || metadata["id_token_signing_alg_values_supported"] != nil {
+		t.Errorf("expected no OpenID Connect only metadata, got %v", metadata)
+	}
+}
@@ -32,6 +32,31 @@ const (
 	OTP_DEFAULT_SKEW_ENV_VAR_NAME                           = "identity__otp_default_skew"
 	OTP_SECRET_ENV_VAR_NAME                                 = "identity__otp_secret"
 	GENERATE_EMAIL_VERIFICATION_RESPONSE_TOKEN_ENV_VAR_NAME = "identity__generate_email_verification_response_token"
+	AUTHORIZATION_CODE_DURATION_ENV_VAR_NAME                = "identity__authorization_code_duration"
+	ALLOWED_REDIRECT_URIS_ENV_VAR_NAME                      = "identity__allowed_redirect_uris"
+	LOGIN_URL_ENV_VAR_NAME                                  = "identity__login_url"
+	INITIAL_ACCESS_TOKEN_ENV_VAR_NAME                       = "identity__initial_access_token"
+	SELF_REGISTRATION_GRANT_TYPES_ENV_VAR_NAME              = "identity__self_registration_grant_types"
+	SELF_REGISTRATION_SCOPES_ENV_VAR_NAME                   = "identity__self_registration_scopes"
+	VALIDATION_ATTEMPTS_TO_BLOCK_ENV_VAR_NAME               = "identity__validation__attempts_to_block"
+	VALIDATION_BLOCK_DURATION_ENV_VAR_NAME                  = "identity__validation__block_duration"
+	ENCRYPTION_KEY_ENV_VAR_NAME                             = "identity__encryption_key"
+	MFA_TOKEN_DURATION_ENV_VAR_NAME                         = "identity__mfa_token_duration"
+	TOTP_ISSUER_ENV_VAR_NAME                                = "identity__totp_issuer"
+	WEBAUTHN_RP_ID_ENV_VAR_NAME                             = "identity__webauthn__rp_id"
+	WEBAUTHN_RP_NAME_ENV_VAR_NAME                           = "identity__webauthn__rp_name"
+	WEBAUTHN_ORIGINS_ENV_VAR_NAME                           = "identity__webauthn__origins"
+	LOGIN_CODE_DURATION_ENV_VAR_NAME                        = "identity__login_code_duration"
+	LOGIN_CODE_MAX_ATTEMPTS_ENV_VAR_NAME                    = "identity__login_code_max_attempts"
+	LOGIN_CODE_REQUEST_INTERVAL_ENV_VAR_NAME                = "identity__login_code_request_interval"
+	JWKS_CACHE_DURATION_ENV_VAR_NAME                        = "identity__jwks_cache_duration"
+	KEY_ROTATION_INTERVAL_ENV_VAR_NAME                      = "identity__key_rotation_interval"
+	KEY_ROTATION_PRE_ANNOUNCE_ENV_VAR_NAME                  = "identity__key_rotation_pre_announce"
+	KEY_ROTATION_ALGORITHM_ENV_VAR_NAME                     = "identity__key_rotation_algorithm"
+	TRUSTED_ISSUERS_ENV_VAR_NAME                            = "identity__trusted_issuers"
+	TRUSTED_ISSUERS_CACHE_DURATION_ENV_VAR_NAME             = "identity__trusted_issuers_cache_duration"
+	ACCESS_TOKEN_FORMAT_ENV_VAR_NAME                        = "identity__access_token_format"
+	OPAQUE_TOKEN_TENANTS_ENV_VAR_NAME                       = "identity__opaque_token_tenants"
 )
 
 var currentEnv *Environment
@@ -59,6 +84,31 @@ type Environment struct {
 	passwordValidationAllowSpaces          bool
 	passwordValidationAllowedSpecials      string
 	generateEmailVerificationResponseToken bool
+	authorizationCodeDuration              int
+	allowedRedirectUris                    string
+	loginUrl                               string
+	initialAccessToken                     string
+	selfRegistrationGrantTypes             string
+	selfRegistrationScopes                 string
+	attemptsToBlock                        int
+	blockDuration                          int
+	encryptionKey                          string
+	mfaTokenDuration                       int
+	totpIssuer                             string
+	webAuthnRelyingPartyId                 string
+	webAuthnRelyingPartyName               string
+	webAuthnOrigins                        string
+	loginCodeDuration                      int
+	loginCodeMaxAttempts                   int
+	loginCodeRequestInterval               int
+	jwksCacheDuration                      int
+	keyRotationInterval                    int
+	keyRotationPreAnnounce                 int
+	keyRotationAlgorithm                   string
+	trustedIssuers                         string
+	trustedIssuersCacheDuration            int
+	accessTokenFormat                      string
+	opaqueTokenTenants                     string
 }
 
 func New() *Environment {
@@ -80,6 +130,31 @@ func New() *Environment {
 		otpDefaultDuration:                     config.GetInt(OTP_DEFAULT_DURATION_ENV_VAR_NAME),
 		otpDefaultSkew:                         config.GetInt(OTP_DEFAULT_SKEW_ENV_VAR_NAME),
 		generateEmailVerificationResponseToken: config.GetBool(GENERATE_EMAIL_VERIFICATION_RESPONSE_TOKEN_ENV_VAR_NAME),
+		authorizationCodeDuration:              config.GetInt(AUTHORIZATION_CODE_DURATION_ENV_VAR_NAME),
+		allowedRedirectUris:                    config.GetString(ALLOWED_REDIRECT_URIS_ENV_VAR_NAME),
+		loginUrl:                               config.GetString(LOGIN_URL_ENV_VAR_NAME),
+		initialAccessToken:                     config.GetString(INITIAL_ACCESS_TOKEN_ENV_VAR_NAME),
+		selfRegistrationGrantTypes:             config.GetString(SELF_REGISTRATION_GRANT_TYPES_ENV_VAR_NAME),
+		selfRegistrationScopes:                 config.GetString(SELF_REGISTRATION_SCOPES_ENV_VAR_NAME),
+		attemptsToBlock:                        config.GetInt(VALIDATION_ATTEMPTS_TO_BLOCK_ENV_VAR_NAME),
+		blockDuration:                          config.GetInt(VALIDATION_BLOCK_DURATION_ENV_VAR_NAME),
+		encryptionKey:                          config.GetString(ENCRYPTION_KEY_ENV_VAR_NAME),
+		mfaTokenDuration:                       config.GetInt(MFA_TOKEN_DURATION_ENV_VAR_NAME),
+		totpIssuer:                             config.GetString(TOTP_ISSUER_ENV_VAR_NAME),
+		webAuthnRelyingPartyId:                 config.GetString(WEBAUTHN_RP_ID_ENV_VAR_NAME),
+		webAuthnRelyingPartyName:               config.GetString(WEBAUTHN_RP_NAME_ENV_VAR_NAME),
+		webAuthnOrigins:                        config.GetString(WEBAUTHN_ORIGINS_ENV_VAR_NAME),
+		loginCodeDuration:                      config.GetInt(LOGIN_CODE_DURATION_ENV_VAR_NAME),
+		loginCodeMaxAttempts:                   config.GetInt(LOGIN_CODE_MAX_ATTEMPTS_ENV_VAR_NAME),
+		loginCodeRequestInterval:               config.GetInt(LOGIN_CODE_REQUEST_INTERVAL_ENV_VAR_NAME),
+		jwksCacheDuration:                      config.GetInt(JWKS_CACHE_DURATION_ENV_VAR_NAME),
+		keyRotationInterval:                    config.GetInt(KEY_ROTATION_INTERVAL_ENV_VAR_NAME),
+		keyRotationPreAnnounce:                 config.GetInt(KEY_ROTATION_PRE_ANNOUNCE_ENV_VAR_NAME),
+		keyRotationAlgorithm:                   config.GetString(KEY_ROTATION_ALGORITHM_ENV_VAR_NAME),
+		trustedIssuers:                         config.GetString(TRUSTED_ISSUERS_ENV_VAR_NAME),
+		trustedIssuersCacheDuration:            config.GetInt(TRUSTED_ISSUERS_CACHE_DURATION_ENV_VAR_NAME),
+		accessTokenFormat:                      config.GetString(ACCESS_TOKEN_FORMAT_ENV_VAR_NAME),
+		opaqueTokenTenants:                     config.GetString(OPAQUE_TOKEN_TENANTS_ENV_VAR_NAME),
 	}
 
 	// password default config
@@ -303,3 +378,236 @@ func (env *Environment) OtpSecret() string {
 func (env *Environment) GenerateEmailVerificationResponseToken() bool {
 	return env.generateEmailVerificationResponseToken
 }
+
+// AuthorizationCodeDuration returns the lifetime of an authorization code in seconds
+func (env *Environment) AuthorizationCodeDuration() int {
+	if env.authorizationCodeDuration <= 0 {
+		env.authorizationCodeDuration = 60
+	}
+
+	return env.authorizationCodeDuration
+}
+
+func (env *Environment) AllowedRedirectUris() []string {
+	result := make([]string, 0)
+	for _, uri := range strings.Split(env.allowedRedirectUris, ",") {
+		uri = strings.TrimSpace(uri)
+		if uri != "" {
+			result = append(result, uri)
+		}
+	}
+
+	return result
+}
+
+func (env *Environment) LoginUrl() string {
+	return env.loginUrl
+}
+
+// InitialAccessToken returns the token that allows clients to register without an admin user
+func (env *Environment) InitialAccessToken() string {
+	return env.initialAccessToken
+}
+
+// SelfRegistrationGrantTypes returns the comma separated grant types the clients registered with
+// the initial access token can use, by default only the authorization code and refresh token grants
+func (env *Environment) SelfRegistrationGrantTypes() []string {
+	result := make([]string, 0)
+	for _, grantType := range strings.Split(env.selfRegistrationGrantTypes, ",") {
+		grantType = strings.TrimSpace(grantType)
+		if grantType != "" {
+			result = append(result, grantType)
+		}
+	}
+
+	if len(result) == 0 {
+		result = append(result, "authorization_code", "refresh_token")
+	}
+
+	return result
+}
+
+// SelfRegistrationScopes returns the comma separated scopes the clients registered with the
+// initial access token can request, by default they cannot request any scope
+func (env *Environment) SelfRegistrationScopes() []string {
+	result := make([]string, 0)
+	for _, scope := range strings.Split(env.selfRegistrationScopes, ",") {
+		scope = strings.TrimSpace(scope)
+		if scope != "" {
+			result = append(result, scope)
+		}
+	}
+
+	return result
+}
+
+// AttemptsToBlock returns the number of failed logins before a user is locked out
+func (env *Environment) AttemptsToBlock() int {
+	if env.attemptsToBlock <= 0 {
+		env.attemptsToBlock = 5
+	}
+
+	return env.attemptsToBlock
+}
+
+// BlockDuration returns how long a user stays locked out in minutes
+func (env *Environment) BlockDuration() int {
+	if env.blockDuration <= 0 {
+		env.blockDuration = 15
+	}
+
+	return env.blockDuration
+}
+
+// EncryptionKey returns the key used to encrypt the secrets stored in the database
+func (env *Environment) EncryptionKey() string {
+	return env.encryptionKey
+}
+
+// MfaTokenDuration returns how long the token to complete a multi-factor login is valid in minutes
+func (env *Environment) MfaTokenDuration() int {
+	if env.mfaTokenDuration <= 0 {
+		env.mfaTokenDuration = 5
+	}
+
+	return env.mfaTokenDuration
+}
+
+// TotpIssuer returns the issuer shown by the authenticator apps, defaults to the issuer host
+func (env *Environment) TotpIssuer() string {
+	return env.totpIssuer
+}
+
+// WebAuthnRelyingPartyId returns the domain the passkeys are scoped to, defaults to the issuer host
+func (env *Environment) WebAuthnRelyingPartyId() string {
+	return env.webAuthnRelyingPartyId
+}
+
+// WebAuthnRelyingPartyName returns the name shown by the authenticators, defaults to the relying party id
+func (env *Environment) WebAuthnRelyingPartyName() string {
+	return env.webAuthnRelyingPartyName
+}
+
+// WebAuthnOrigins returns the origins allowed to run the webauthn ceremonies, defaults to the issuer
+func (env *Environment) WebAuthnOrigins() []string {
+	result := make([]string, 0)
+	for _, origin := range strings.Split(env.webAuthnOrigins, ",") {
+		origin = strings.TrimSpace(origin)
+		if origin != "" {
+			result = append(result, origin)
+		}
+	}
+
+	return result
+}
+
+// LoginCodeDuration returns how long a passwordless login code or link is valid in minutes
+func (env *Environment) LoginCodeDuration() int {
+	if env.loginCodeDuration <= 0 {
+		env.loginCodeDuration = 10
+	}
+
+	return env.loginCodeDuration
+}
+
+// LoginCodeMaxAttempts returns how many times a login code can be tried before it is discarded
+func (env *Environment) LoginCodeMaxAttempts() int {
+	if env.loginCodeMaxAttempts <= 0 {
+		env.loginCodeMaxAttempts = 3
+	}
+
+	return env.loginCodeMaxAttempts
+}
+
+// LoginCodeRequestInterval returns how many seconds a user waits before requesting another login code
+func (env *Environment) LoginCodeRequestInterval() int {
+	if env.loginCodeRequestInterval <= 0 {
+		env.loginCodeRequestInterval = 60
+	}
+
+	return env.loginCodeRequestInterval
+}
+
+// JwksCacheDuration returns how long the clients can cache the published keys in minutes, new
+// keys need to be published for longer than this before they sign tokens
+func (env *Environment) JwksCacheDuration() int {
+	if env.jwksCacheDuration <= 0 {
+		env.jwksCacheDuration = 60
+	}
+
+	return env.jwksCacheDuration
+}
+
+// KeyRotationInterval returns how often a new signing key is generated in minutes
+func (env *Environment) KeyRotationInterval() int {
+	if env.keyRotationInterval <= 0 {
+		env.keyRotationInterval = 43200
+	}
+
+	return env.keyRotationInterval
+}
+
+// KeyRotationPreAnnounce returns how long a new key is published in the jwks before it signs
+// tokens in minutes, by default twice the jwks cache duration
+func (env *Environment) KeyRotationPreAnnounce() int {
+	if env.keyRotationPreAnnounce <= 0 {
+		env.keyRotationPreAnnounce = env.JwksCacheDuration() * 2
+	}
+
+	return env.keyRotationPreAnnounce
+}
+
+// KeyRotationAlgorithm returns the jws algorithm of the generated signing keys
+func (env *Environment) KeyRotationAlgorithm() string {
+	if env.keyRotationAlgorithm == "" {
+		env.keyRotationAlgorithm = "RS256"
+	}
+
+	return env.keyRotationAlgorithm
+}
+
+// TrustedIssuers returns the comma separated issuers whose tokens are validated with the keys of
+// their discovery document
+func (env *Environment) TrustedIssuers() []string {
+	result := make([]string, 0)
+	for _, issuer := range strings.Split(env.trustedIssuers, ",") {
+		issuer = strings.TrimSpace(issuer)
+		if issuer != "" {
+			result = append(result, issuer)
+		}
+	}
+
+	return result
+}
+
+// TrustedIssuersCacheDuration returns how long the keys of the trusted issuers are cached in minutes
+func (env *Environment) TrustedIssuersCacheDuration() int {
+	if env.trustedIssuersCacheDuration <= 0 {
+		env.trustedIssuersCacheDuration = 60
+	}
+
+	return env.trustedIssuersCacheDuration
+}
+
+// AccessTokenFormat returns the format of the access tokens of the clients and tenants that do not
+// choose one, jwt or opaque
+func (env *Environment) AccessTokenFormat() string {
+	if env.accessTokenFormat == "" {
+		env.accessTokenFormat = "jwt"
+	}
+
+	return env.accessTokenFormat
+}
+
+// OpaqueTokenTenants returns the comma separated tenants that issue opaque access tokens
+func (env *Environment) OpaqueTokenTenants() []string {
+	result := make([]string, 0)
+	for _, tenant := range strings.Split(env.opaqueTokenTenants, ",") {
+		tenant = strings.TrimSpace(tenant)
+		if tenant != "" {
+			result = append(result, tenant)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,134 @@
+package identity
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const testAdminUserId = "592D8E5C-6F5D-40A0-9348-80131B083715"
+
+// decodeTestToken returns the claims of a jwt without checking its signature
+func decodeTestToken(t *testing.T, token string) map[string]interface{} {
+	t.Helper()
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("expected a jwt, got %v", token)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatalf("invalid jwt payload, %v", err)
+	}
+
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatalf("invalid jwt claims, %v", err)
+	}
+
+	return claims
+}
+
+// testTokenHash is the at_hash and c_hash of a value for the HS256 test key
+func testTokenHash(value string) string {
+	hash := sha256.Sum256([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(hash[:len(hash)/2])
+}
+
+func TestIdTokenAuthorizationCodeFlow(t *testing.T) {
+	location := requestAuthorizationCode(t, authorizeForm(map[string]string{"scope": "openid profile"}))
+	code := location.Query().Get("code")
+
+	status, body := requestToken(t, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"client_id":     {"spa"},
+		"redirect_uri":  {testRedirectUri},
+		"code_verifier": {testCodeVerifier},
+	})
+	if status != http.StatusOK || body["id_token"] == nil {
+		t.Fatalf("expected an id token, got %v %v", status, body)
+	}
+
+	claims := decodeTestToken(t, body["id_token"].(string))
+	if claims["sub"] != testAdminUserId {
+		t.Errorf("expected the user id as subject, got %v", claims["sub"])
+	}
+	if aud := toStrings(claims["aud"]); len(aud) != 1 || aud[0] != "spa" {
+		t.Errorf("expected the client as audience, got %v", claims["aud"])
+	}
+	if claims["nonce"] != "n-0S6_WzA2Mj" {
+		t.Errorf("expected the nonce to be echoed, got %v", claims["nonce"])
+	}
+	if claims["auth_time"] == nil {
+		t.Errorf("expected the authentication time")
+	}
+	if amr := toStrings(claims["amr"]); len(amr) != 1 || amr[0] != "pwd" || claims["acr"] != "1" {
+		t.Errorf("expected a password authentication, got %v %v", claims["amr"], claims["acr"])
+	}
+	if claims["at_hash"] != testTokenHash(body["access_token"].(string)) {
+		t.Errorf("expected the access token hash, got %v", claims["at_hash"])
+	}
+	if claims["c_hash"] != testTokenHash(code) {
+		t.Errorf("expected the code hash, got %v", claims["c_hash"])
+	}
+	if claims["given_name"] == nil || claims["preferred_username"] == nil {
+		t.Errorf("expected the profile claims, got %v", claims)
+	}
+	if claims["email"] != nil {
+		t.Errorf("expected no email claims without the email scope, got %v", claims["email"])
+	}
+}
+
+func TestIdTokenPasswordGrant(t *testing.T) {
+	status, body := requestToken(t, url.Values{
+		"grant_type": {"password"},
+		"client_id":  {"spa"},
+		"username":   {testAdminUsername},
+		"password":   {testAdminPassword},
+		"scope":      {"openid email"},
+		"nonce":      {"password-nonce"},
+	})
+	if status != http.StatusOK || body["id_token"] == nil {
+		t.Fatalf("expected an id token, got %v %v", status, body)
+	}
+
+	claims := decodeTestToken(t, body["id_token"].(string))
+	if claims["nonce"] != "password-nonce" {
+		t.Errorf("expected the nonce to be echoed, got %v", claims["nonce"])
+	}
+	if claims["email"] != testAdminUsername || claims["email_verified"] == nil {
+		t.Errorf("expected the email claims, got %v", claims)
+	}
+	if claims["given_name"] != nil {
+		t.Errorf("expected no profile claims without the profile scope, got %v", claims["given_name"])
+	}
+
+	// without the openid scope it is a plain oauth login
+	status, body = requestToken(t, url.Values{
+		"grant_type": {"password"},
+		"client_id":  {"spa"},
+		"username":   {testAdminUsername},
+		"password":   {testAdminPassword},
+	})
+	if status != http.StatusOK || body["id_token"] != nil {
+		t.Fatalf("expected no id token, got %v %v", status, body)
+	}
+}
+
+// toStrings converts a json array claim into a string slice
+func toStrings(value interface{}) []string {
+	result := make([]string, 0)
+	values, _ := value.([]interface{})
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			result = append(result, s)
+		}
+	}
+
+	return result
+}
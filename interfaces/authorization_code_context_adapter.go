@@ -0,0 +1,12 @@
+package interfaces
+
+import "github.com/cjlapao/common-go-identity/database/dto"
+
+// AuthorizationCodeContextAdapter stores the issued authorization codes by
+// their hash, RemoveAuthorizationCode must only return true for the caller
+// that actually removed the code so it can be used to enforce single use
+type AuthorizationCodeContextAdapter interface {
+	GetAuthorizationCode(id string) *dto.AuthorizationCodeDTO
+	UpsertAuthorizationCode(code dto.AuthorizationCodeDTO) error
+	RemoveAuthorizationCode(id string) bool
+}
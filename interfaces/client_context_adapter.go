@@ -0,0 +1,9 @@
+package interfaces
+
+import "github.com/cjlapao/common-go-identity/database/dto"
+
+type ClientContextAdapter interface {
+	GetClientById(id string) *dto.ClientDTO
+	UpsertClient(client dto.ClientDTO) error
+	RemoveClient(id string) bool
+}
@@ -0,0 +1,14 @@
+package interfaces
+
+import "github.com/cjlapao/common-go-identity/database/dto"
+
+// KeyStoreAdapter keeps the signing keys of the key vault so every replica signs with the same
+// key, the private keys are already encrypted with the configured encryption key when stored
+type KeyStoreAdapter interface {
+	GetKeys() []dto.SigningKeyDTO
+	GetKey(id string) *dto.SigningKeyDTO
+	// AddKey stores a new key, it only returns true for the caller that added it so the
+	// replicas rotating at the same time agree on a single key
+	AddKey(key dto.SigningKeyDTO) bool
+	RetireKey(id string) error
+}
@@ -0,0 +1,11 @@
+package interfaces
+
+import "github.com/cjlapao/common-go-identity/database/dto"
+
+// OpaqueTokenContextAdapter keeps the claims of the opaque access tokens by the hash of their
+// handle, the entries only need to be kept until the tokens expire
+type OpaqueTokenContextAdapter interface {
+	GetOpaqueToken(id string) *dto.OpaqueTokenDTO
+	AddOpaqueToken(token dto.OpaqueTokenDTO) error
+	RemoveOpaqueToken(id string) error
+}
@@ -0,0 +1,13 @@
+package interfaces
+
+import "github.com/cjlapao/common-go-identity/database/dto"
+
+type RefreshTokenContextAdapter interface {
+	GetRefreshToken(id string) *dto.RefreshTokenDTO
+	UpsertRefreshToken(token dto.RefreshTokenDTO) error
+	// RotateRefreshToken marks the token as used, it only returns true for the caller
+	// that rotated it, a false means the token was already used or revoked
+	RotateRefreshToken(id string) bool
+	RevokeRefreshTokenFamily(familyId string) error
+	RevokeUserRefreshTokens(userId string) error
+}
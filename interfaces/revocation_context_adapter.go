@@ -0,0 +1,17 @@
+package interfaces
+
+import (
+	"time"
+
+	"github.com/cjlapao/common-go-identity/database/dto"
+)
+
+// RevocationContextAdapter keeps the ids of the revoked access tokens and the time before which
+// all the tokens of a user are revoked, the entries only need to be kept until the tokens expire
+// as they are rejected after that anyway
+type RevocationContextAdapter interface {
+	RevokeToken(id string, expiresAt time.Time) error
+	IsTokenRevoked(id string) bool
+	RevokeUserTokens(revocation dto.UserTokenRevocationDTO) error
+	GetUserTokenRevocation(userId string) *dto.UserTokenRevocationDTO
+}
@@ -1,6 +1,10 @@
 package interfaces
 
-import "github.com/cjlapao/common-go-identity/database/dto"
+import (
+	"time"
+
+	"github.com/cjlapao/common-go-identity/database/dto"
+)
 
 type UserContextAdapter interface {
 	GetUserById(id string) *dto.UserDTO
@@ -10,6 +14,8 @@ type UserContextAdapter interface {
 	UpsertUser(user dto.UserDTO) error
 	RemoveUser(id string) bool
 	UpdateUserPassword(id string, password string) error
+	UpdateUserInvalidAttempts(id string, attempts int, blockedUntil string) error
+	IncrementUserInvalidAttempts(id string) (int, error)
 	GetUserRefreshToken(id string) *string
 	UpdateUserRefreshToken(id string, token string) bool
 
@@ -23,6 +29,44 @@ type UserContextAdapter interface {
 	UpdateUserEmailVerificationToken(id string, token string) bool
 	SetEmailVerificationState(id string, state bool) bool
 
+	// Totp, the secret is already encrypted when it reaches the adapter
+	GetUserTotp(id string) *dto.UserTotpDTO
+	UpsertUserTotp(totp dto.UserTotpDTO) error
+	RemoveUserTotp(id string) error
+	// UpdateUserTotpLastUsedStep stores the time step of the last accepted code, it must only
+	// succeed if the step is newer than the stored one so a code cannot be used twice
+	UpdateUserTotpLastUsedStep(id string, step int64) bool
+
+	// Recovery codes, the id of each code is its hash so the code itself is never stored
+	CountUserRecoveryCodes(userId string) int
+	ReplaceUserRecoveryCodes(userId string, codes []dto.UserRecoveryCodeDTO) error
+	RemoveUserRecoveryCodes(userId string) error
+	// UseUserRecoveryCode marks the code as used, it must only succeed once for each code
+	UseUserRecoveryCode(userId string, id string) bool
+
+	// WebAuthn credentials, the id is the base64url encoded credential id
+	GetUserWebAuthnCredentials(userId string) []dto.WebAuthnCredentialDTO
+	GetWebAuthnCredential(id string) *dto.WebAuthnCredentialDTO
+	UpsertWebAuthnCredential(credential dto.WebAuthnCredentialDTO) error
+	RemoveWebAuthnCredential(userId string, id string) error
+	// UpdateWebAuthnCredentialSignCount stores the counter of the last assertion, it must only
+	// succeed if the counter is higher than the stored one or the authenticator has no counter
+	UpdateWebAuthnCredentialSignCount(id string, signCount int64, lastUsedAt time.Time) bool
+	// WebAuthn sessions, the id is the hash of the ceremony challenge
+	AddWebAuthnSession(session dto.WebAuthnSessionDTO) error
+	// RemoveWebAuthnSession deletes the pending session, it must only succeed once and never
+	// for an expired session
+	RemoveWebAuthnSession(id string) bool
+
+	// Login codes, each user has at most one pending code and only its hash is stored
+	GetUserLoginCode(userId string) *dto.UserLoginCodeDTO
+	UpsertUserLoginCode(loginCode dto.UserLoginCodeDTO) error
+	// UpdateUserLoginCodeAttempts stores the attempts made against the code, it must only
+	// succeed if the stored counter is still the one before this attempt
+	UpdateUserLoginCodeAttempts(userId string, attempts int) bool
+	// RemoveUserLoginCode deletes the pending code if it matches, it must only succeed once
+	RemoveUserLoginCode(userId string, code string) bool
+
 	GetUserRolesById(id string) []dto.UserRoleDTO
 	UpsertUserRoles(user dto.UserDTO) error
 	GetUserClaimsById(id string) []dto.UserClaimDTO
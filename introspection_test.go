@@ -0,0 +1,156 @@
+package identity
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/cjlapao/common-go-identity/models"
+)
+
+// introspectToken posts the form to the introspection endpoint authenticated as the confidential
+// test client, an empty secret sends the request without client authentication
+func introspectToken(t *testing.T, form url.Values, clientSecret string, accept string) (*http.Response, []byte) {
+	t.Helper()
+	server := getTestServer(t)
+	addConfidentialTestClient(t)
+	request, _ := http.NewRequest(http.MethodPost, server.URL+"/auth/token/introspect", strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if clientSecret != "" {
+		request.SetBasicAuth(testClientId, clientSecret)
+	}
+	if accept != "" {
+		request.Header.Set("Accept", accept)
+	}
+	response, err := newTestClient().Do(request)
+	if err != nil {
+		t.Fatalf("introspection request failed, %v", err)
+	}
+	defer response.Body.Close()
+
+	body, _ := io.ReadAll(response.Body)
+	return response, body
+}
+
+func introspectTokenState(t *testing.T, form url.Values) map[string]interface{} {
+	t.Helper()
+	response, body := introspectToken(t, form, testClientSecret, "")
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %v, got %v %s", http.StatusOK, response.StatusCode, body)
+	}
+
+	var state map[string]interface{}
+	if err := json.Unmarshal(body, &state); err != nil {
+		t.Fatalf("invalid introspection response, %v", err)
+	}
+
+	return state
+}
+
+func TestIntrospectAccessToken(t *testing.T) {
+	_, accessTokenLogin := passwordLogin(t, testAdminUsername, testAdminPassword, url.Values{"scope": {"openid"}})
+	accessToken := accessTokenLogin["access_token"].(string)
+
+	state := introspectTokenState(t, url.Values{"token": {accessToken}})
+	if state["active"] != true || state["token_type"] != "Bearer" || state["client_id"] != "spa" || state["username"] != testAdminUsername {
+		t.Fatalf("expected an active access token, got %v", state)
+	}
+	if !strings.Contains(state["scope"].(string), "openid") {
+		t.Errorf("expected the granted scopes, got %v", state["scope"])
+	}
+	for _, claim := range []string{"sub", "exp", "iat", "iss", "jti"} {
+		if _, ok := state[claim]; !ok {
+			t.Errorf("expected the %v claim, got %v", claim, state)
+		}
+	}
+
+	// the revocation state is consulted
+	revokeToken(t, url.Values{"token": {accessToken}, "client_id": {"spa"}})
+	state = introspectTokenState(t, url.Values{"token": {accessToken}, "token_type_hint": {models.AccessTokenTypeHint}})
+	if state["active"] != false || len(state) != 1 {
+		t.Errorf("expected a revoked token to only be inactive, got %v", state)
+	}
+}
+
+func TestIntrospectTrustedIssuerToken(t *testing.T) {
+	issuerUrl, signTokenFor := addTestTrustedIssuer(t, "api.read")
+	addTestUser(t, "introspection-external-user", testRegularUserPassword)
+	token := signTokenFor(issuerUrl, "introspection-external-user@localhost.com", "api.read", map[string]interface{}{"uid": "introspection-external-user"})
+
+	// the external tokens are not ours to describe even if their subject is one of our users
+	if state := introspectTokenState(t, url.Values{"token": {token}}); state["active"] != false || len(state) != 1 {
+		t.Errorf("expected the token of an external issuer to be inactive, got %v", state)
+	}
+}
+
+func TestIntrospectRefreshToken(t *testing.T) {
+	token := loginWithPassword(t)
+
+	for _, hint := range []string{models.RefreshTokenTypeHint, models.AccessTokenTypeHint, ""} {
+		state := introspectTokenState(t, url.Values{"token": {token}, "token_type_hint": {hint}})
+		if state["active"] != true || state["client_id"] != "spa" || state["username"] != testAdminUsername {
+			t.Errorf("expected an active refresh token with the %q hint, got %v", hint, state)
+		}
+		if _, ok := state["token_type"]; ok {
+			t.Errorf("expected a refresh token not to be a bearer token, got %v", state)
+		}
+	}
+
+	// a rotated token cannot be used anymore
+	if status, body := refreshToken(t, token); status != http.StatusOK {
+		t.Fatalf("expected the token to be refreshed, got %v %v", status, body)
+	}
+	if state := introspectTokenState(t, url.Values{"token": {token}}); state["active"] != false {
+		t.Errorf("expected a rotated refresh token to be inactive, got %v", state)
+	}
+
+	if state := introspectTokenState(t, url.Values{"token": {"not-a-token"}}); state["active"] != false {
+		t.Errorf("expected an unknown token to be inactive, got %v", state)
+	}
+}
+
+func TestIntrospectRequiresClientAuthentication(t *testing.T) {
+	token := loginWithPassword(t)
+
+	response, body := introspectToken(t, url.Values{"token": {token}}, "", "")
+	if response.StatusCode != http.StatusUnauthorized || !strings.Contains(string(body), models.OAuthInvalidClientError.String()) {
+		t.Errorf("expected an unauthenticated request to be rejected, got %v %s", response.StatusCode, body)
+	}
+
+	response, body = introspectToken(t, url.Values{"token": {token}, "client_id": {"spa"}}, "", "")
+	if response.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected a public client to be rejected, got %v %s", response.StatusCode, body)
+	}
+
+	response, body = introspectToken(t, url.Values{"token": {token}}, "not-the-secret", "")
+	if response.StatusCode != http.StatusUnauthorized || response.Header.Get("WWW-Authenticate") != "Basic" {
+		t.Errorf("expected an invalid secret to be rejected, got %v %v %s", response.StatusCode, response.Header, body)
+	}
+
+	response, body = introspectToken(t, url.Values{}, testClientSecret, "")
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected a request without a token to be rejected, got %v %s", response.StatusCode, body)
+	}
+}
+
+func TestIntrospectSignedResponse(t *testing.T) {
+	token := loginWithPassword(t)
+
+	response, body := introspectToken(t, url.Values{"token": {token}}, testClientSecret, "application/token-introspection+jwt")
+	if response.StatusCode != http.StatusOK || response.Header.Get("Content-Type") != "application/token-introspection+jwt" {
+		t.Fatalf("expected a signed response, got %v %v %s", response.StatusCode, response.Header, body)
+	}
+
+	if header := decodeTestTokenHeader(t, string(body)); header["typ"] != "token-introspection+jwt" {
+		t.Errorf("expected the introspection token type, got %v", header)
+	}
+	claims := decodeTestToken(t, string(body))
+	state, _ := claims["token_introspection"].(map[string]interface{})
+	audiences, _ := claims["aud"].([]interface{})
+	if len(audiences) != 1 || audiences[0] != testClientId || claims["iss"] == nil || state["active"] != true || state["client_id"] != "spa" {
+		t.Errorf("expected the introspection claims for the client, got %v", claims)
+	}
+}
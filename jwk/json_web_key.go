@@ -2,92 +2,138 @@ package jwk
 
 import (
 	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
-	"fmt"
 	"math/big"
+	"strings"
 
 	"github.com/cjlapao/common-go/guard"
-	"github.com/cjlapao/common-go/security/encryption"
 )
 
+// JsonWebKey is the public part of a signing key as defined in RFC 7517, the thumbprint is the
+// RFC 7638 one and it is used as the key id when none is given
 type JsonWebKey struct {
-	ID              string                       `json:"kid"`
-	Algorithm       encryption.EncryptionKey     `json:"alg"`
-	AlgorithmFamily encryption.EncryptionKeyType `json:"kty"`
-	Use             string                       `json:"use"`
-	X5C             []string                     `json:"x5c"`
-	Exponent        string                       `json:"e,omitempty"`
-	Modulus         string                       `json:"n,omitempty"`
-	Curve           string                       `json:"curve,omitempty"`
-	X               string                       `json:"x,omitempty"`
-	Y               string                       `json:"y,omitempty"`
-	Thumbprint      string                       `json:"x5t"`
+	ID         string   `json:"kid"`
+	KeyType    string   `json:"kty"`
+	Algorithm  string   `json:"alg"`
+	Use        string   `json:"use"`
+	X5C        []string `json:"x5c,omitempty"`
+	X5T        string   `json:"x5t,omitempty"`
+	X5TS256    string   `json:"x5t#S256,omitempty"`
+	Exponent   string   `json:"e,omitempty"`
+	Modulus    string   `json:"n,omitempty"`
+	Curve      string   `json:"crv,omitempty"`
+	X          string   `json:"x,omitempty"`
+	Y          string   `json:"y,omitempty"`
+	Thumbprint string   `json:"-"`
 }
 
-func NewKey(privateKey interface{}) *JsonWebKey {
-	return NewKeyWithId("", privateKey)
+func NewKey(key interface{}) *JsonWebKey {
+	return NewKeyWithId("", key)
 }
 
-func NewKeyWithId(id string, privateKey interface{}) *JsonWebKey {
-	guard.FatalEmptyOrNil(privateKey)
+// NewKeyWithId returns the json web key of a rsa, ecdsa or ed25519 key, private keys only publish
+// their public part
+func NewKeyWithId(id string, key interface{}) *JsonWebKey {
+	guard.FatalEmptyOrNil(key)
 
-	key := JsonWebKey{
+	result := JsonWebKey{
+		ID:  id,
 		Use: "sig",
-		X5C: make([]string, 0),
 	}
 
-	fingerprint := encryption.GetSHA1KeyFingerprint(privateKey)
-	key.Thumbprint = base64.URLEncoding.EncodeToString(fingerprint[:])
-	if id == "" {
-		key.ID = key.Thumbprint
+	switch kt := publicKeyOf(key).(type) {
+	case *rsa.PublicKey:
+		result.KeyType = "RSA"
+		result.Algorithm = rsaAlgorithm(kt)
+		result.Exponent = encode(big.NewInt(int64(kt.E)).Bytes())
+		result.Modulus = encode(kt.N.Bytes())
+	case *ecdsa.PublicKey:
+		size := (kt.Curve.Params().BitSize + 7) / 8
+		result.KeyType = "EC"
+		result.Algorithm = ecdsaAlgorithm(kt)
+		result.Curve = kt.Curve.Params().Name
+		result.X = encode(kt.X.FillBytes(make([]byte, size)))
+		result.Y = encode(kt.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		result.KeyType = "OKP"
+		result.Algorithm = "EdDSA"
+		result.Curve = "Ed25519"
+		result.X = encode(kt)
+	default:
+		logger.Error("Unsupported key type for a json web key")
+		return nil
 	}
 
-	switch kt := privateKey.(type) {
-	case *rsa.PrivateKey:
-		publicKey := kt.PublicKey
-		key.Algorithm = key.Algorithm.FromString("RS" + fmt.Sprintf("%v", kt.Size()))
-		key.AlgorithmFamily = key.Algorithm.GetFamily()
-		exponentBytes := new(big.Int).SetInt64(int64(publicKey.E))
-		key.Exponent = base64.URLEncoding.EncodeToString(exponentBytes.Bytes())
-		key.Modulus = base64.URLEncoding.EncodeToString(publicKey.N.Bytes())
-		encodedPublicKey := base64.StdEncoding.EncodeToString(x509.MarshalPKCS1PublicKey(&publicKey))
-		key.X5C = append(key.X5C, encodedPublicKey)
-	case *ecdsa.PrivateKey:
-		publicKey := kt.PublicKey
-		key.Algorithm = key.Algorithm.FromString("ES" + fmt.Sprintf("%v", publicKey.Params().BitSize))
-		key.AlgorithmFamily = key.Algorithm.GetFamily()
-		key.Curve = publicKey.Params().Name
-		key.X = base64.URLEncoding.EncodeToString(publicKey.X.Bytes())
-		key.Y = base64.URLEncoding.EncodeToString(publicKey.Y.Bytes())
-		genericKey, _ := x509.MarshalPKIXPublicKey(&publicKey)
-		encodedPublicKey := base64.StdEncoding.EncodeToString(genericKey)
-		key.X5C = append(key.X5C, encodedPublicKey)
+	result.Thumbprint = result.GetThumbprint()
+	if result.ID == "" {
+		result.ID = result.Thumbprint
 	}
 
-	return &key
+	return &result
+}
+
+// WithCertificate publishes the certificate of the key in the x5c, x5t and x5t#S256 members
+func (k *JsonWebKey) WithCertificate(certificate *x509.Certificate) *JsonWebKey {
+	if certificate == nil {
+		return k
+	}
+
+	return k.WithCertificateChain([]*x509.Certificate{certificate})
+}
+
+// WithCertificateChain publishes the certificate chain in the x5c, the first certificate is the
+// one of the key and the thumbprints are taken from it
+func (k *JsonWebKey) WithCertificateChain(chain []*x509.Certificate) *JsonWebKey {
+	if len(chain) == 0 || chain[0] == nil {
+		return k
+	}
+
+	k.X5C = make([]string, 0)
+	for _, certificate := range chain {
+		k.X5C = append(k.X5C, base64.StdEncoding.EncodeToString(certificate.Raw))
+	}
+
+	thumbprint := sha1.Sum(chain[0].Raw)
+	sha256Thumbprint := sha256.Sum256(chain[0].Raw)
+	k.X5T = encode(thumbprint[:])
+	k.X5TS256 = encode(sha256Thumbprint[:])
+	return k
+}
+
+// GetThumbprint returns the RFC 7638 thumbprint, the sha256 of the required members of the key
+// in lexicographic order
+func (k JsonWebKey) GetThumbprint() string {
+	var members string
+	switch k.KeyType {
+	case "RSA":
+		members = `{"e":"` + k.Exponent + `","kty":"RSA","n":"` + k.Modulus + `"}`
+	case "EC":
+		members = `{"crv":"` + k.Curve + `","kty":"EC","x":"` + k.X + `","y":"` + k.Y + `"}`
+	case "OKP":
+		members = `{"crv":"` + k.Curve + `","kty":"OKP","x":"` + k.X + `"}`
+	default:
+		return ""
+	}
+
+	hash := sha256.Sum256([]byte(members))
+	return encode(hash[:])
 }
 
 func (k JsonWebKey) Validate(key interface{}) bool {
-	switch kt := key.(type) {
-	case rsa.PublicKey:
-		return k.validateRsa(kt)
+	switch kt := publicKeyOf(key).(type) {
 	case *rsa.PublicKey:
 		return k.validateRsa(*kt)
-	case rsa.PrivateKey:
-		return k.validateRsa(kt.PublicKey)
-	case *rsa.PrivateKey:
-		return k.validateRsa(kt.PublicKey)
-	case ecdsa.PublicKey:
-		return k.validateEcdsa(kt)
 	case *ecdsa.PublicKey:
 		return k.validateEcdsa(*kt)
-	case ecdsa.PrivateKey:
-		return k.validateEcdsa(kt.PublicKey)
-	case *ecdsa.PrivateKey:
-		return k.validateEcdsa(kt.PublicKey)
+	case ed25519.PublicKey:
+		return k.KeyType == "OKP" && kt.Equal(ed25519.PublicKey(decode(k.X)))
 	default:
 		return false
 	}
@@ -100,70 +146,70 @@ func Decode(jwk string) (*JsonWebKey, error) {
 		return nil, err
 	}
 
+	decodedKey.Thumbprint = decodedKey.GetThumbprint()
 	return &decodedKey, nil
 }
 
+// GetKey returns the public key of the json web key
 func (k JsonWebKey) GetKey() interface{} {
-	return k.GetPublicKeyAt(0)
-}
-
-func (k JsonWebKey) GetPublicKeyAt(index int) interface{} {
-	if len(k.X5C) == 0 || len(k.X5C) < index {
-		return nil
-	}
-
-	stringkey := k.X5C[index]
-	key, err := base64.StdEncoding.DecodeString(stringkey)
-	if err != nil {
-		return nil
-	}
-
-	var publicKey interface{}
-	switch k.AlgorithmFamily {
-	case encryption.RSA:
-		publicKey, err = x509.ParsePKCS1PublicKey(key)
-		if err != nil {
+	switch k.KeyType {
+	case "RSA":
+		exponent, modulus := decode(k.Exponent), decode(k.Modulus)
+		if exponent == nil || modulus == nil {
 			return nil
 		}
-		if !k.verifyKeyParameters(publicKey) {
-			return nil
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(modulus),
+			E: int(new(big.Int).SetBytes(exponent).Int64()),
 		}
-		return publicKey
-	case encryption.ECDSA:
-		generickPublicKey, err := x509.ParsePKIXPublicKey(key)
-		publicKey = generickPublicKey.(*ecdsa.PublicKey)
-		if err != nil {
+	case "EC":
+		curve := getCurve(k.Curve)
+		x, y := decode(k.X), decode(k.Y)
+		if curve == nil || x == nil || y == nil {
 			return nil
 		}
-		if !k.verifyKeyParameters(publicKey) {
+		return &ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+	case "OKP":
+		x := decode(k.X)
+		if k.Curve != "Ed25519" || len(x) != ed25519.PublicKeySize {
 			return nil
 		}
-		return publicKey
+		return ed25519.PublicKey(x)
 	}
 
 	return nil
 }
 
-func (k JsonWebKey) verifyKeyParameters(key interface{}) bool {
-	switch kt := key.(type) {
-	case rsa.PublicKey:
-		return k.validateRsa(kt)
-	case *rsa.PublicKey:
-		return k.validateRsa(*kt)
-	case ecdsa.PublicKey:
-		return k.validateEcdsa(kt)
-	case *ecdsa.PublicKey:
-		return k.validateEcdsa(*kt)
-	default:
-		return false
+// GetPublicKeyAt returns the public key of the certificate in the x5c chain position
+func (k JsonWebKey) GetPublicKeyAt(index int) interface{} {
+	if index < 0 || index >= len(k.X5C) {
+		return nil
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(k.X5C[index])
+	if err != nil {
+		return nil
+	}
+
+	certificate, err := x509.ParseCertificate(raw)
+	if err != nil {
+		return nil
+	}
+
+	if !k.Validate(certificate.PublicKey) {
+		return nil
 	}
+
+	return certificate.PublicKey
 }
 
 func (k JsonWebKey) validateRsa(publicKey rsa.PublicKey) bool {
-	decodedExponent, _ := base64.URLEncoding.DecodeString(k.Exponent)
-	decodedModulus, _ := base64.URLEncoding.DecodeString(k.Modulus)
-	exponent := new(big.Int).SetBytes(decodedExponent)
-	modulus := new(big.Int).SetBytes(decodedModulus)
+	exponent := new(big.Int).SetBytes(decode(k.Exponent))
+	modulus := new(big.Int).SetBytes(decode(k.Modulus))
 	if publicKey.E == int(exponent.Int64()) && publicKey.N.Cmp(modulus) == 0 {
 		return true
 	}
@@ -172,10 +218,8 @@ func (k JsonWebKey) validateRsa(publicKey rsa.PublicKey) bool {
 }
 
 func (k JsonWebKey) validateEcdsa(publicKey ecdsa.PublicKey) bool {
-	decodedX, _ := base64.URLEncoding.DecodeString(k.X)
-	decodedY, _ := base64.URLEncoding.DecodeString(k.Y)
-	x := new(big.Int).SetBytes(decodedX)
-	y := new(big.Int).SetBytes(decodedY)
+	x := new(big.Int).SetBytes(decode(k.X))
+	y := new(big.Int).SetBytes(decode(k.Y))
 
 	if publicKey.X.Cmp(x) == 0 && publicKey.Y.Cmp(y) == 0 {
 		return true
@@ -183,3 +227,82 @@ func (k JsonWebKey) validateEcdsa(publicKey ecdsa.PublicKey) bool {
 
 	return false
 }
+
+// publicKeyOf returns the public key pointer of a rsa or ecdsa key, ed25519 keys are slices and
+// are returned as they are
+func publicKeyOf(key interface{}) interface{} {
+	switch kt := key.(type) {
+	case rsa.PublicKey:
+		return &kt
+	case *rsa.PublicKey:
+		return kt
+	case rsa.PrivateKey:
+		return &kt.PublicKey
+	case *rsa.PrivateKey:
+		return &kt.PublicKey
+	case ecdsa.PublicKey:
+		return &kt
+	case *ecdsa.PublicKey:
+		return kt
+	case ecdsa.PrivateKey:
+		return &kt.PublicKey
+	case *ecdsa.PrivateKey:
+		return &kt.PublicKey
+	case ed25519.PublicKey:
+		return kt
+	case ed25519.PrivateKey:
+		return kt.Public().(ed25519.PublicKey)
+	default:
+		return nil
+	}
+}
+
+// rsaAlgorithm follows the key vault naming where the hash grows with the key size
+func rsaAlgorithm(publicKey *rsa.PublicKey) string {
+	switch {
+	case publicKey.Size() >= 512:
+		return "RS512"
+	case publicKey.Size() >= 384:
+		return "RS384"
+	default:
+		return "RS256"
+	}
+}
+
+func ecdsaAlgorithm(publicKey *ecdsa.PublicKey) string {
+	switch publicKey.Curve.Params().BitSize {
+	case 521:
+		return "ES512"
+	case 384:
+		return "ES384"
+	default:
+		return "ES256"
+	}
+}
+
+func getCurve(name string) elliptic.Curve {
+	switch name {
+	case "P-256":
+		return elliptic.P256()
+	case "P-384":
+		return elliptic.P384()
+	case "P-521":
+		return elliptic.P521()
+	default:
+		return nil
+	}
+}
+
+// encode is the base64url encoding without padding used by all the json web key members
+func encode(value []byte) string {
+	return base64.RawURLEncoding.EncodeToString(value)
+}
+
+func decode(value string) []byte {
+	result, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil {
+		return nil
+	}
+
+	return result
+}
@@ -0,0 +1,57 @@
+package jwk
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+)
+
+func TestThumbprint(t *testing.T) {
+	// RFC 7638 section 3.1 example
+	key := JsonWebKey{
+		KeyType:  "RSA",
+		Exponent: "AQAB",
+		Modulus:  "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+	}
+
+	if thumbprint := key.GetThumbprint(); thumbprint != "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs" {
+		t.Errorf("expected the RFC 7638 thumbprint, got %v", thumbprint)
+	}
+
+	// RFC 8037 appendix A.3 example
+	okpKey := JsonWebKey{
+		KeyType: "OKP",
+		Curve:   "Ed25519",
+		X:       "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo",
+	}
+
+	if thumbprint := okpKey.GetThumbprint(); thumbprint != "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k" {
+		t.Errorf("expected the RFC 8037 thumbprint, got %v", thumbprint)
+	}
+}
+
+func TestNewKeyRoundTrip(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecdsaKey, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	_, ed25519Key, _ := ed25519.GenerateKey(rand.Reader)
+
+	for _, privateKey := range []interface{}{rsaKey, ecdsaKey, ed25519Key} {
+		key := NewKey(privateKey)
+		if key == nil || key.ID != key.Thumbprint {
+			t.Fatalf("expected the thumbprint as key id, got %v", key)
+		}
+		if !key.Validate(key.GetKey()) || !key.Validate(privateKey) {
+			t.Errorf("expected the %v public key to be rebuilt from the members", key.KeyType)
+		}
+	}
+
+	if key := NewKey(ecdsaKey); key.Curve != "P-384" || key.Algorithm != "ES384" || len(key.X) != 64 {
+		t.Errorf("expected a P-384 key with padded coordinates, got %v", key)
+	}
+	if key := NewKey(ed25519Key); key.KeyType != "OKP" || key.Curve != "Ed25519" || key.Algorithm != "EdDSA" || key.Y != "" {
+		t.Errorf("expected an Ed25519 octet key pair, got %v", key)
+	}
+}
@@ -12,6 +12,7 @@ func New() *JsonWebKeys {
 }
 
 func (jk *JsonWebKeys) Add(id string, privateKey interface{}) {
-	key := NewKeyWithId(id, privateKey)
-	jk.Keys = append(jk.Keys, key)
+	if key := NewKeyWithId(id, privateKey); key != nil {
+		jk.Keys = append(jk.Keys, key)
+	}
 }
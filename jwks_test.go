@@ -0,0 +1,124 @@
+package identity
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cjlapao/common-go-identity/authorization_context"
+)
+
+type testJwks struct {
+	Keys []map[string]interface{} `json:"keys"`
+}
+
+func getTestJwks(t *testing.T, etag string) (*http.Response, testJwks) {
+	t.Helper()
+	var jwks testJwks
+	request, _ := http.NewRequest(http.MethodGet, getTestServer(t).URL+"/auth/.well-known/openid-configuration/jwks", nil)
+	if etag != "" {
+		request.Header.Set("If-None-Match", etag)
+	}
+	response, err := newTestClient().Do(request)
+	if err != nil {
+		t.Fatalf("request failed, %v", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusOK {
+		json.NewDecoder(response.Body).Decode(&jwks)
+	}
+	return response, jwks
+}
+
+func findTestJwk(jwks testJwks, kid string) map[string]interface{} {
+	for _, key := range jwks.Keys {
+		if key["kid"] == kid {
+			return key
+		}
+	}
+
+	return nil
+}
+
+func TestJwks(t *testing.T) {
+	getTestServer(t)
+	keyVault := authorization_context.GetBaseContext().KeyVault
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecdsaKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	keyVault.WithRsaKey("jwks-rsa", rsaKey)
+	keyVault.WithEcdsaKey("jwks-ecdsa", ecdsaKey)
+	t.Cleanup(func() {
+		keyVault.RetireKey("jwks-rsa")
+		keyVault.RetireKey("jwks-ecdsa")
+	})
+
+	response, jwks := getTestJwks(t, "")
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %v, got %v", http.StatusOK, response.StatusCode)
+	}
+	if findTestJwk(jwks, "test") != nil {
+		t.Errorf("expected the hmac key to be kept secret")
+	}
+
+	rsaJwk := findTestJwk(jwks, "jwks-rsa")
+	if rsaJwk == nil || rsaJwk["kty"] != "RSA" || rsaJwk["alg"] != "RS256" || rsaJwk["use"] != "sig" || rsaJwk["e"] != "AQAB" {
+		t.Fatalf("expected the rsa key, got %v", rsaJwk)
+	}
+	ecdsaJwk := findTestJwk(jwks, "jwks-ecdsa")
+	if ecdsaJwk == nil || ecdsaJwk["kty"] != "EC" || ecdsaJwk["crv"] != "P-256" || ecdsaJwk["alg"] != "ES256" {
+		t.Fatalf("expected the ecdsa key, got %v", ecdsaJwk)
+	}
+	for _, member := range []interface{}{rsaJwk["n"], ecdsaJwk["x"], ecdsaJwk["y"]} {
+		if value, _ := member.(string); value == "" || strings.ContainsAny(value, "=+/") {
+			t.Errorf("expected base64url without padding, got %v", member)
+		}
+	}
+	if ecdsaJwk["x5c"] != nil {
+		t.Errorf("expected no certificate chain for a bare key, got %v", ecdsaJwk["x5c"])
+	}
+
+	etag := response.Header.Get("ETag")
+	if etag == "" || !strings.Contains(response.Header.Get("Cache-Control"), "max-age") {
+		t.Fatalf("expected the cache headers, got %v", response.Header)
+	}
+	if response, _ := getTestJwks(t, etag); response.StatusCode != http.StatusNotModified {
+		t.Errorf("expected status %v for the same keys, got %v", http.StatusNotModified, response.StatusCode)
+	}
+
+	// retired keys are no longer published and the etag changes
+	keyVault.RetireKey("jwks-rsa")
+	response, jwks = getTestJwks(t, etag)
+	if response.StatusCode != http.StatusOK || findTestJwk(jwks, "jwks-rsa") != nil || findTestJwk(jwks, "jwks-ecdsa") == nil {
+		t.Errorf("expected only the active keys, got %v %v", response.StatusCode, jwks)
+	}
+}
+
+func TestJwksCertificate(t *testing.T) {
+	getTestServer(t)
+	keyVault := authorization_context.GetBaseContext().KeyVault
+	if err := keyVault.WithPkcs12File("jwks-certificate", filepath.Join("jwt_keyvault", "testdata", "signing.p12"), "test"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		keyVault.RetireKey("jwks-certificate")
+	})
+
+	_, jwks := getTestJwks(t, "")
+	certificateJwk := findTestJwk(jwks, "jwks-certificate")
+	if certificateJwk == nil {
+		t.Fatal("expected the certificate key to be published")
+	}
+	if chain, _ := certificateJwk["x5c"].([]interface{}); len(chain) != 2 {
+		t.Errorf("expected the certificate chain, got %v", certificateJwk["x5c"])
+	}
+	if certificateJwk["x5t"] == nil || certificateJwk["x5t#S256"] == nil {
+		t.Errorf("expected the certificate thumbprints, got %v", certificateJwk)
+	}
+}
@@ -0,0 +1,123 @@
+package jwt
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	cryptorand "github.com/cjlapao/common-go-cryptorand"
+	"github.com/cjlapao/common-go-identity/authorization_context"
+	identity_constants "github.com/cjlapao/common-go-identity/constants"
+	"github.com/cjlapao/common-go-identity/models"
+	"github.com/cjlapao/common-go/constants"
+	"github.com/cjlapao/common-go/security/encryption"
+	"github.com/pascaldekloe/jwt"
+)
+
+// IdTokenOptions describes the authentication the id token is issued for, the access token
+// and the authorization code are only used to add their hashes
+type IdTokenOptions struct {
+	ClientID    string
+	Scope       string
+	Nonce       string
+	AuthTime    time.Time
+	Amr         []string
+	AccessToken string
+	Code        string
+}
+
+// GenerateIdToken generates an OpenID Connect id token for the client, the user claims are
+// only added if their scopes were requested
+func GenerateIdToken(keyId string, user models.User, options IdTokenOptions) (string, error) {
+	var idTokenClaims jwt.Claims
+	authCtx := authorization_context.New()
+	now := time.Now().Round(time.Second)
+	validUntil := now.Add(time.Minute * time.Duration(authCtx.Options.TokenDuration))
+
+	if options.ClientID == "" {
+		return "", errors.New("id tokens need a client id for the audience")
+	}
+
+	idTokenClaims.Subject = user.ID
+	idTokenClaims.Issuer = authCtx.Issuer
+	idTokenClaims.Audiences = []string{options.ClientID}
+	idTokenClaims.Issued = jwt.NewNumericTime(now)
+	idTokenClaims.Expires = jwt.NewNumericTime(validUntil)
+	id, idErr := cryptorand.GetRandomString(constants.ID_SIZE)
+	if idErr != nil {
+		return "", idErr
+	}
+	idTokenClaims.ID = id
+
+	signKey := authCtx.KeyVault.GetKey(keyId)
+	if keyId == "" {
+		signKey = authCtx.KeyVault.GetDefaultKey()
+	}
+	if signKey == nil {
+		return "", errors.New("signing key was not found")
+	}
+
+	// Custom Claims
+	customClaims := make(map[string]interface{})
+	customClaims["azp"] = options.ClientID
+	if options.Nonce != "" {
+		customClaims["nonce"] = options.Nonce
+	}
+	if !options.AuthTime.IsZero() {
+		customClaims["auth_time"] = options.AuthTime.Unix()
+	}
+	if len(options.Amr) > 0 {
+		customClaims["amr"] = options.Amr
+		customClaims["acr"] = identity_constants.AcrSingleFactor
+		for _, method := range options.Amr {
+			if method == identity_constants.AmrMultiFactor {
+				customClaims["acr"] = identity_constants.AcrMultiFactor
+			}
+		}
+	}
+	if options.AccessToken != "" {
+		customClaims["at_hash"] = tokenHash(options.AccessToken, signKey.Size)
+	}
+	if options.Code != "" {
+		customClaims["c_hash"] = tokenHash(options.Code, signKey.Size)
+	}
+	for name, value := range GetUserInfoClaims(user, options.Scope) {
+		if name != "sub" {
+			customClaims[name] = value
+		}
+	}
+	if authCtx.TenantId != "" {
+		customClaims["tid"] = authCtx.TenantId
+	}
+
+	idTokenClaims.KeyID = authCtx.Options.KeyId
+	idTokenClaims.Set = customClaims
+	idToken, err := signToken(keyId, idTokenClaims)
+	if err != nil {
+		logger.Error("There was an error signing the id token for user %v with key id %v", user.Username, keyId)
+		return "", err
+	}
+
+	return idToken, nil
+}
+
+// tokenHash is the left half of the hash of the value with the hash size of the signing
+// algorithm, base64url encoded as used by the at_hash and c_hash claims
+func tokenHash(value string, size encryption.EncryptionKeySize) string {
+	var sum []byte
+	switch size {
+	case encryption.Bit384:
+		hash := sha512.Sum384([]byte(value))
+		sum = hash[:]
+	case encryption.Bit512:
+		hash := sha512.Sum512([]byte(value))
+		sum = hash[:]
+	default:
+		hash := sha256.Sum256([]byte(value))
+		sum = hash[:]
+	}
+
+	return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2])
+}
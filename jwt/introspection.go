@@ -0,0 +1,38 @@
+package jwt
+
+import (
+	"time"
+
+	"github.com/cjlapao/common-go-identity/authorization_context"
+	"github.com/cjlapao/common-go-identity/models"
+	"github.com/pascaldekloe/jwt"
+)
+
+// IntrospectionTokenType is the media type of the signed introspection responses of RFC 9701
+const IntrospectionTokenType = "token-introspection+jwt"
+
+// GenerateIntrospectionToken signs the introspection response for the resource server that
+// requested it as defined in RFC 9701, the audience is left out when the caller is not a client
+func GenerateIntrospectionToken(keyId string, introspection models.OAuthIntrospectResponse, clientId string) (string, error) {
+	var introspectionClaims jwt.Claims
+	authCtx := authorization_context.New()
+	now := time.Now().Round(time.Second)
+
+	introspectionClaims.Issuer = authCtx.Issuer
+	if clientId != "" {
+		introspectionClaims.Audiences = []string{clientId}
+	}
+	introspectionClaims.Issued = jwt.NewNumericTime(now)
+	introspectionClaims.KeyID = authCtx.Options.KeyId
+	introspectionClaims.Set = map[string]interface{}{
+		"token_introspection": introspection,
+	}
+
+	introspectionToken, err := signTokenWithType(keyId, introspectionClaims, IntrospectionTokenType)
+	if err != nil {
+		logger.Error("There was an error signing the introspection response for client %v with key id %v", clientId, keyId)
+		return "", err
+	}
+
+	return introspectionToken, nil
+}
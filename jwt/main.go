@@ -3,6 +3,7 @@ package jwt
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	log "github.com/cjlapao/common-go-logger"
 	"github.com/pascaldekloe/jwt"
@@ -29,5 +30,20 @@ func GetTokenClaim(token string, claim string) string {
 		return ""
 	}
 
-	return fmt.Sprintf("%v", tokenMap[claim])
+	value, ok := tokenMap[claim]
+	if !ok || value == nil {
+		return ""
+	}
+
+	return fmt.Sprintf("%v", value)
+}
+
+// GetTokenExpiry returns the expiry time of a token without validating it
+func GetTokenExpiry(token string) time.Time {
+	jwtToken, err := jwt.ParseWithoutCheck([]byte(token))
+	if err != nil || jwtToken.Expires == nil {
+		return time.Time{}
+	}
+
+	return jwtToken.Expires.Time()
 }
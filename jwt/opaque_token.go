@@ -0,0 +1,86 @@
+package jwt
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/cjlapao/common-go-identity/authorization_context"
+	"github.com/cjlapao/common-go-identity/database/dto"
+	"github.com/cjlapao/common-go-identity/models"
+	"github.com/cjlapao/common-go/security"
+	"github.com/pascaldekloe/jwt"
+)
+
+var (
+	ErrOpaqueTokensNotSupported = errors.New("no opaque token store was configured")
+	ErrOpaqueTokenNotFound      = errors.New("opaque token was not found")
+)
+
+// opaqueTokenSize is the number of random bytes of an opaque token handle
+const opaqueTokenSize = 32
+
+// IsOpaqueToken returns true if the token is an opaque handle instead of a jwt, the handles are
+// base64url encoded so they never contain the dots that separate the parts of a jwt
+func IsOpaqueToken(token string) bool {
+	return token != "" && !strings.Contains(token, ".")
+}
+
+// ToOpaqueToken replaces the signed access token with a random handle, the token claims are kept
+// in the opaque token store under the hash of the handle so they are never sent to the client
+func ToOpaqueToken(userToken *models.UserToken, authorizationContext *authorization_context.AuthorizationContext) error {
+	if authorizationContext.OpaqueTokenDatabaseAdapter == nil {
+		return ErrOpaqueTokensNotSupported
+	}
+
+	claims, err := jwt.ParseWithoutCheck([]byte(userToken.Token))
+	if err != nil {
+		return err
+	}
+
+	handleBytes := make([]byte, opaqueTokenSize)
+	if _, err := rand.Read(handleBytes); err != nil {
+		return err
+	}
+	handle := base64.RawURLEncoding.EncodeToString(handleBytes)
+
+	record := dto.OpaqueTokenDTO{
+		ID:        security.SHA256Encode(handle),
+		TokenID:   claims.ID,
+		Claims:    string(claims.Raw),
+		ExpiresAt: userToken.ExpiresAt,
+	}
+	record.ClientID, _ = claims.String("client_id")
+	record.UserID, _ = claims.String("uid")
+	if err := authorizationContext.OpaqueTokenDatabaseAdapter.AddOpaqueToken(record); err != nil {
+		return err
+	}
+
+	userToken.Token = handle
+	return nil
+}
+
+// RemoveOpaqueToken removes the claims of an opaque token from the store, the handle cannot be
+// resolved after that
+func RemoveOpaqueToken(token string, authorizationContext *authorization_context.AuthorizationContext) error {
+	if authorizationContext.OpaqueTokenDatabaseAdapter == nil {
+		return ErrOpaqueTokensNotSupported
+	}
+
+	return authorizationContext.OpaqueTokenDatabaseAdapter.RemoveOpaqueToken(security.SHA256Encode(token))
+}
+
+// resolveOpaqueToken returns the json claims kept for the opaque token handle
+func resolveOpaqueToken(token string, authorizationContext *authorization_context.AuthorizationContext) ([]byte, error) {
+	if authorizationContext.OpaqueTokenDatabaseAdapter == nil {
+		return nil, ErrOpaqueTokensNotSupported
+	}
+
+	record := authorizationContext.OpaqueTokenDatabaseAdapter.GetOpaqueToken(security.SHA256Encode(token))
+	if record == nil {
+		return nil, ErrOpaqueTokenNotFound
+	}
+
+	return []byte(record.Claims), nil
+}
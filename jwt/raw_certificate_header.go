@@ -2,6 +2,8 @@ package jwt
 
 type RawCertificateHeader struct {
 	Algorithm string `json:"alg,omitempty"`
+	Type      string `json:"typ,omitempty"`
 	KeyId     string `json:"kid,omitempty"`
 	X5T       string `json:"x5t,omitempty"`
+	X5TS256   string `json:"x5t#S256,omitempty"`
 }
@@ -0,0 +1,133 @@
+package jwt
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/cjlapao/common-go-identity/authorization_context"
+	"github.com/cjlapao/common-go-identity/database/dto"
+	"github.com/cjlapao/common-go-identity/models"
+)
+
+var ErrTokenRevoked = errors.New("token was revoked")
+
+// maxRevocationCacheSize limits the revocations kept in memory, the cache is cleared when it is
+// full as the store still has all of them
+const maxRevocationCacheSize = 10000
+
+// revocationCache keeps the revocations already found in the store, a revocation is never undone
+// so it can be kept until the token expires. Tokens that are not known to be revoked are always
+// checked in the store so a revocation done by another replica applies to the next request
+type revocationCache struct {
+	mutex  sync.Mutex
+	tokens map[string]time.Time
+	users  map[string]dto.UserTokenRevocationDTO
+}
+
+var revocations = &revocationCache{
+	tokens: make(map[string]time.Time),
+	users:  make(map[string]dto.UserTokenRevocationDTO),
+}
+
+// IsTokenRevoked returns true if the token id was revoked or the token was issued before all
+// the tokens of its user were revoked
+func IsTokenRevoked(userToken *models.UserToken, authorizationContext *authorization_context.AuthorizationContext) bool {
+	if userToken == nil || authorizationContext.RevocationDatabaseAdapter == nil {
+		return false
+	}
+
+	if userToken.ID != "" && revocations.isTokenRevoked(userToken, authorizationContext) {
+		return true
+	}
+
+	return userToken.UserID != "" && revocations.isUserTokenRevoked(userToken, authorizationContext)
+}
+
+// CacheTokenRevocation adds a revocation done by this replica to the cache
+func CacheTokenRevocation(id string, expiresAt time.Time) {
+	revocations.addToken(id, expiresAt)
+}
+
+// CacheUserTokenRevocation adds a revocation of the tokens of a user done by this replica to
+// the cache
+func CacheUserTokenRevocation(revocation dto.UserTokenRevocationDTO) {
+	revocations.addUser(revocation)
+}
+
+func (c *revocationCache) isTokenRevoked(userToken *models.UserToken, authorizationContext *authorization_context.AuthorizationContext) bool {
+	c.mutex.Lock()
+	_, found := c.tokens[userToken.ID]
+	c.mutex.Unlock()
+	if found {
+		return true
+	}
+
+	if !authorizationContext.RevocationDatabaseAdapter.IsTokenRevoked(userToken.ID) {
+		return false
+	}
+
+	c.addToken(userToken.ID, userToken.ExpiresAt)
+	return true
+}
+
+func (c *revocationCache) isUserTokenRevoked(userToken *models.UserToken, authorizationContext *authorization_context.AuthorizationContext) bool {
+	c.mutex.Lock()
+	cached, found := c.users[userToken.UserID]
+	c.mutex.Unlock()
+	if found && !userToken.IssuedAt.After(cached.RevokedBefore) {
+		return true
+	}
+
+	// a newer revocation can exist in the store
+	revocation := authorizationContext.RevocationDatabaseAdapter.GetUserTokenRevocation(userToken.UserID)
+	if revocation == nil {
+		return false
+	}
+
+	c.addUser(*revocation)
+	return !userToken.IssuedAt.After(revocation.RevokedBefore)
+}
+
+func (c *revocationCache) addToken(id string, expiresAt time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.makeRoom()
+	c.tokens[id] = expiresAt
+}
+
+func (c *revocationCache) addUser(revocation dto.UserTokenRevocationDTO) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if cached, found := c.users[revocation.UserID]; found && cached.RevokedBefore.After(revocation.RevokedBefore) {
+		return
+	}
+
+	c.makeRoom()
+	c.users[revocation.UserID] = revocation
+}
+
+func (c *revocationCache) makeRoom() {
+	if len(c.tokens)+len(c.users) < maxRevocationCacheSize {
+		return
+	}
+
+	now := time.Now()
+	for id, expiresAt := range c.tokens {
+		if expiresAt.Before(now) {
+			delete(c.tokens, id)
+		}
+	}
+	for id, revocation := range c.users {
+		if revocation.ExpiresAt.Before(now) {
+			delete(c.users, id)
+		}
+	}
+
+	if len(c.tokens)+len(c.users) >= maxRevocationCacheSize {
+		c.tokens = make(map[string]time.Time)
+		c.users = make(map[string]dto.UserTokenRevocationDTO)
+	}
+}
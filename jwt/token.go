@@ -3,9 +3,12 @@ package jwt
 
 import (
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"strings"
 	"time"
@@ -16,6 +19,7 @@ import (
 	identity_constants "github.com/cjlapao/common-go-identity/constants"
 	"github.com/cjlapao/common-go-identity/jwt_keyvault"
 	"github.com/cjlapao/common-go-identity/models"
+	"github.com/cjlapao/common-go-identity/trusted_issuers"
 	"github.com/cjlapao/common-go/constants"
 	"github.com/cjlapao/common-go/security/encryption"
 	"github.com/pascaldekloe/jwt"
@@ -43,18 +47,38 @@ func GenerateUserTokenForAudiences(keyId string, user models.User, audiences ...
 }
 
 func GenerateUserTokenForKeyAndAudiences(keyId string, user models.User, audiences ...string) (*models.UserToken, error) {
+	return GenerateUserTokenWithOptions(keyId, user, UserTokenOptions{}, audiences...)
+}
+
+// UserTokenOptions describes the client the user token is issued to and the user info scopes
+// it was granted, they are added after the context scope. Exchanged tokens carry the actor and
+// never expire after the token they were exchanged for
+type UserTokenOptions struct {
+	ClientID  string
+	Scopes    []string
+	Actor     *models.TokenActor
+	ExpiresAt time.Time
+}
+
+// GenerateUserTokenWithOptions generates a jwt user token issued to a client with the granted
+// scopes, the userinfo endpoint releases the user claims using these scopes
+func GenerateUserTokenWithOptions(keyId string, user models.User, options UserTokenOptions, audiences ...string) (*models.UserToken, error) {
 	var userToken models.UserToken
 	var userTokenClaims jwt.Claims
 	ctx := execution_context.Get()
 	authCtx := authorization_context.New()
-	now := time.Now().Round(time.Second)
+	// truncated so a token never looks issued after a revocation done before it
+	now := time.Now().Truncate(time.Second)
 	nowSkew := now.Add((time.Minute * 2))
 	nowNegativeSkew := now.Add((time.Minute * 2) * -1)
 	validUntil := nowSkew.Add(time.Minute * time.Duration(authCtx.Options.TokenDuration))
+	if !options.ExpiresAt.IsZero() && options.ExpiresAt.Before(validUntil) {
+		validUntil = options.ExpiresAt
+	}
 
 	userTokenClaims.Subject = user.Email
 	userTokenClaims.Issuer = authCtx.Issuer
-	userTokenClaims.Issued = jwt.NewNumericTime(nowSkew)
+	userTokenClaims.Issued = jwt.NewNumericTime(now)
 	if authCtx.ValidationOptions.NotBefore {
 		userTokenClaims.NotBefore = jwt.NewNumericTime(nowNegativeSkew)
 	}
@@ -69,8 +93,14 @@ func GenerateUserTokenForKeyAndAudiences(keyId string, user models.User, audienc
 
 	// Adding Custom Claims to the token
 	userClaims := make(map[string]interface{})
-	userClaims["scope"] = authCtx.Scope
+	userClaims["scope"] = strings.TrimSpace(authCtx.Scope + " " + strings.Join(options.Scopes, " "))
 	userClaims["uid"] = user.ID
+	if options.ClientID != "" {
+		userClaims["client_id"] = options.ClientID
+	}
+	if options.Actor != nil {
+		userClaims["act"] = options.Actor
+	}
 	userClaims["name"] = user.DisplayName
 	userClaims["given_name"] = user.FirstName
 	userClaims["family_name"] = user.LastName
@@ -117,6 +147,8 @@ func GenerateUserTokenForKeyAndAudiences(keyId string, user models.User, audienc
 
 	userToken = models.UserToken{
 		Token:     token,
+		Scope:     userClaims["scope"].(string),
+		ClientID:  options.ClientID,
 		ExpiresAt: validUntil,
 		NotBefore: nowNegativeSkew,
 		Audiences: audiences,
@@ -132,6 +164,85 @@ func GenerateUserTokenForKeyAndAudiences(keyId string, user models.User, audienc
 	return &userToken, nil
 }
 
+// GenerateClientToken generates a jwt access token for a client application, the subject
+// is the client id and the scope claim carries the context scope followed by the granted scopes
+func GenerateClientToken(keyId string, client models.Client, scopes []string, audiences ...string) (*models.UserToken, error) {
+	var clientTokenClaims jwt.Claims
+	authCtx := authorization_context.New()
+	now := time.Now().Truncate(time.Second)
+	nowNegativeSkew := now.Add((time.Minute * 2) * -1)
+	validUntil := now.Add(time.Minute * time.Duration(authCtx.Options.TokenDuration))
+
+	clientTokenClaims.Subject = client.ID
+	clientTokenClaims.Issuer = authCtx.Issuer
+	clientTokenClaims.Issued = jwt.NewNumericTime(now)
+	if authCtx.ValidationOptions.NotBefore {
+		clientTokenClaims.NotBefore = jwt.NewNumericTime(nowNegativeSkew)
+	}
+
+	id, idErr := cryptorand.GetRandomString(constants.ID_SIZE)
+	if idErr != nil {
+		return nil, idErr
+	}
+
+	clientTokenClaims.Expires = jwt.NewNumericTime(validUntil)
+	clientTokenClaims.ID = id
+
+	// Custom Claims
+	customClaims := make(map[string]interface{})
+	customClaims["scope"] = strings.TrimSpace(authCtx.Scope + " " + strings.Join(scopes, " "))
+	customClaims["client_id"] = client.ID
+	if client.Name != "" {
+		customClaims["name"] = client.Name
+	}
+	if authCtx.TenantId != "" {
+		customClaims["tid"] = authCtx.TenantId
+	}
+	clientTokenClaims.KeyID = authCtx.Options.KeyId
+
+	if len(audiences) > 0 {
+		clientTokenClaims.Audiences = audiences
+	}
+
+	clientTokenClaims.Set = customClaims
+
+	token, err := signToken(keyId, clientTokenClaims)
+	if err != nil {
+		logger.Error("There was an error generating a jwt token for client %v with key id %v", client.ID, keyId)
+		return nil, err
+	}
+
+	clientToken := models.UserToken{
+		Token:     token,
+		Scope:     customClaims["scope"].(string),
+		User:      client.ID,
+		ClientID:  client.ID,
+		ExpiresAt: validUntil,
+		NotBefore: nowNegativeSkew,
+		Audiences: audiences,
+		Issuer:    clientTokenClaims.Issuer,
+		UsedKeyID: keyId,
+	}
+
+	return &clientToken, nil
+}
+
+// HasScope returns true if the space delimited token scope contains the requested scope, an
+// empty scope is never contained so callers always need to configure the scope they require
+func HasScope(tokenScope string, scope string) bool {
+	if scope == "" {
+		return false
+	}
+
+	for _, value := range strings.Fields(tokenScope) {
+		if strings.EqualFold(value, scope) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // GenerateRefreshToken generates a refresh token for the user with a
 func GenerateRefreshToken(keyId string, user models.User) (string, error) {
 	var refreshTokenClaims jwt.Claims
@@ -262,101 +373,166 @@ func GenerateRecoverToken(keyId string, user models.User) string {
 	return recoveryToken
 }
 
+// GenerateMfaToken issues the short lived token that proves the user already passed the
+// first step of a login, it can only be exchanged for tokens together with a second factor,
+// the amr keeps the methods used in the first step for the id token
+func GenerateMfaToken(keyId string, user models.User, clientId string, amr ...string) string {
+	var mfaTokenClaims jwt.Claims
+	authCtx := authorization_context.New()
+	now := time.Now().Round(time.Second)
+	validUntil := now.Add(time.Minute * time.Duration(authCtx.Options.MfaTokenDuration))
+
+	mfaTokenClaims.Subject = user.ID
+	mfaTokenClaims.Issuer = authCtx.Issuer
+	mfaTokenClaims.Issued = jwt.NewNumericTime(now)
+	id, idErr := cryptorand.GetRandomString(constants.ID_SIZE)
+	if idErr != nil {
+		return ""
+	}
+	mfaTokenClaims.Expires = jwt.NewNumericTime(validUntil)
+	mfaTokenClaims.ID = id
+
+	// Custom Claims
+	customClaims := make(map[string]interface{})
+	customClaims["scope"] = identity_constants.MfaScope
+	customClaims["uid"] = user.ID
+	if clientId != "" {
+		customClaims["client_id"] = clientId
+	}
+	if len(amr) > 0 {
+		customClaims["amr"] = amr
+	}
+	if authCtx.TenantId != "" {
+		customClaims["tid"] = authCtx.TenantId
+	}
+	mfaTokenClaims.KeyID = authCtx.Options.KeyId
+	mfaTokenClaims.Set = customClaims
+	mfaToken, err := signToken(keyId, mfaTokenClaims)
+	if err != nil {
+		logger.Error("There was an error signing the mfa token for user %v with key id %v", user.Username, keyId)
+		return ""
+	}
+
+	return mfaToken
+}
+
+// GenerateWebAuthnSessionToken signs the challenge of a webauthn ceremony so the server does
+// not need to keep it between the two requests, the scope defines the ceremony
+func GenerateWebAuthnSessionToken(keyId string, userId string, clientId string, challenge string, scope string) string {
+	var sessionClaims jwt.Claims
+	authCtx := authorization_context.New()
+	now := time.Now().Round(time.Second)
+	validUntil := now.Add(time.Minute * time.Duration(authCtx.Options.MfaTokenDuration))
+
+	sessionClaims.Subject = userId
+	sessionClaims.Issuer = authCtx.Issuer
+	sessionClaims.Issued = jwt.NewNumericTime(now)
+	id, idErr := cryptorand.GetRandomString(constants.ID_SIZE)
+	if idErr != nil {
+		return ""
+	}
+	sessionClaims.Expires = jwt.NewNumericTime(validUntil)
+	sessionClaims.ID = id
+
+	// Custom Claims
+	customClaims := make(map[string]interface{})
+	customClaims["scope"] = scope
+	customClaims["nonce"] = challenge
+	if clientId != "" {
+		customClaims["client_id"] = clientId
+	}
+	if authCtx.TenantId != "" {
+		customClaims["tid"] = authCtx.TenantId
+	}
+	sessionClaims.KeyID = authCtx.Options.KeyId
+	sessionClaims.Set = customClaims
+	sessionToken, err := signToken(keyId, sessionClaims)
+	if err != nil {
+		logger.Error("There was an error signing the webauthn session token with key id %v", keyId)
+		return ""
+	}
+
+	return sessionToken
+}
+
+// GenerateLoginLinkToken generates the token of a passwordless login link, it is single use
+// as only the token that was last issued to the user is accepted
+func GenerateLoginLinkToken(keyId string, user models.User, clientId string) string {
+	var loginLinkClaims jwt.Claims
+	authCtx := authorization_context.New()
+	now := time.Now().Round(time.Second)
+	validUntil := now.Add(time.Minute * time.Duration(authCtx.Options.LoginCodeDuration))
+
+	loginLinkClaims.Subject = user.ID
+	loginLinkClaims.Issuer = authCtx.Issuer
+	loginLinkClaims.Issued = jwt.NewNumericTime(now)
+	id, idErr := cryptorand.GetRandomString(constants.ID_SIZE)
+	if idErr != nil {
+		return ""
+	}
+	loginLinkClaims.Expires = jwt.NewNumericTime(validUntil)
+	loginLinkClaims.ID = id
+
+	// Custom Claims
+	customClaims := make(map[string]interface{})
+	customClaims["scope"] = identity_constants.LoginLinkScope
+	if clientId != "" {
+		customClaims["client_id"] = clientId
+	}
+	if authCtx.TenantId != "" {
+		customClaims["tid"] = authCtx.TenantId
+	}
+	loginLinkClaims.KeyID = authCtx.Options.KeyId
+	loginLinkClaims.Set = customClaims
+	loginLinkToken, err := signToken(keyId, loginLinkClaims)
+	if err != nil {
+		logger.Error("There was an error signing the login link token for user %v with key id %v", user.Username, keyId)
+		return ""
+	}
+
+	return loginLinkToken
+}
+
 func ValidateUserToken(token string, authorizationContext *authorization_context.AuthorizationContext) (*models.UserToken, error) {
 	if token == "" {
 		return nil, errors.New("token cannot be empty")
 	}
 
-	var tokenBytes []byte
-	var verifiedToken *jwt.Claims
-	tokenBytes = []byte(token)
+	var rawJsonToken []byte
+	var trustedIssuer *trusted_issuers.TrustedIssuer
 	var err error
-	var signKey *jwt_keyvault.JwtKeyVaultItem
-
-	rawToken, err := jwt.ParseWithoutCheck(tokenBytes)
-	if err != nil {
-		return nil, err
-	}
 
-	if authorizationContext.Options.KeyVaultEnabled {
-		// Verifying signature using the key that was sign with
-		signKey = authorizationContext.KeyVault.GetKey(rawToken.KeyID)
-		switch kt := signKey.PrivateKey.(type) {
-		case *ecdsa.PrivateKey:
-			key := kt.PublicKey
-			verifiedToken, err = jwt.ECDSACheck(tokenBytes, &key)
-			if err != nil {
-				return nil, err
-			}
-		case string:
-			verifiedToken, err = jwt.HMACCheck(tokenBytes, []byte(kt))
-			if err != nil {
-				return nil, err
-			}
-		case *rsa.PrivateKey:
-			key := kt.PublicKey
-			verifiedToken, err = jwt.RSACheck(tokenBytes, &key)
-			if err != nil {
-				return nil, err
-			}
-		}
-	} else {
-		if authorizationContext.Options.PublicKey == "" {
-			err = errors.New("public key not present for validation")
+	// Opaque tokens are only a reference to the claims kept in the token store
+	if IsOpaqueToken(token) {
+		rawJsonToken, err = resolveOpaqueToken(token, authorizationContext)
+		if err != nil {
 			return nil, err
 		}
-
-		var tokenHeader RawCertificateHeader
-		err = json.Unmarshal(rawToken.RawHeader, &tokenHeader)
+	} else {
+		var verifiedToken *jwt.Claims
+		verifiedToken, trustedIssuer, err = verifyUserToken([]byte(token), authorizationContext)
 		if err != nil {
 			return nil, err
 		}
-		switch tokenHeader.Algorithm {
-		case "HS256", "HS384", "HS512":
-			publicKey, err := base64.StdEncoding.DecodeString(authorizationContext.Options.PublicKey)
-			if err != nil {
-				return nil, err
-			}
-			verifiedToken, err = jwt.HMACCheck(tokenBytes, publicKey)
-			if err != nil {
-				return nil, err
-			}
-		case "ES256", "ES384", "ES512":
-			publicKey := encryption.ECDSAHelper{}.DecodePublicKeyFromPem(authorizationContext.Options.PublicKey)
-			if publicKey == nil {
-				return nil, errors.New("invalid public key")
-			}
-			verifiedToken, err = jwt.ECDSACheck(tokenBytes, publicKey)
-			if err != nil {
-				return nil, err
-			}
-		case "RS256", "RS384", "RS512":
-			publicKey := encryption.RSAHelper{}.DecodePublicKeyFromPem(authorizationContext.Options.PublicKey)
-			if publicKey == nil {
-				return nil, errors.New("invalid public key")
-			}
-			verifiedToken, err = jwt.RSACheck(tokenBytes, publicKey)
-			if err != nil {
-				return nil, err
-			}
-		}
-	}
-
-	if verifiedToken == nil {
-		err = errors.New("no public or private key found, exiting")
-		return nil, err
+		rawJsonToken, _ = verifiedToken.Raw.MarshalJSON()
 	}
 
 	// Transforming token into a user token
-	rawJsonToken, _ := verifiedToken.Raw.MarshalJSON()
 	var userToken models.UserToken
 	err = json.Unmarshal(rawJsonToken, &userToken)
 	if err != nil {
 		return nil, errors.New("token is not formated correctly")
 	}
 
-	// Validating the scope of the token
-	if !strings.EqualFold(authorizationContext.Scope, userToken.Scope) {
+	// Validating the scope of the token, client tokens carry the granted scopes after the context one
+	requiredScope := authorizationContext.Scope
+	if trustedIssuer != nil && trustedIssuer.Scope != "" {
+		requiredScope = trustedIssuer.Scope
+	}
+	if requiredScope == "" {
+		return &userToken, errors.New("no token scope is configured")
+	}
+	if !HasScope(userToken.Scope, requiredScope) {
 		return &userToken, errors.New("token scope is not valid")
 	}
 
@@ -372,8 +548,9 @@ func ValidateUserToken(token string, authorizationContext *authorization_context
 		return &userToken, errors.New("token is expired")
 	}
 
-	// If we require the Issuer to be validated we will be validating it
-	if authorizationContext.ValidationOptions.Issuer {
+	// If we require the Issuer to be validated we will be validating it, trusted issuers were
+	// already matched when verifying the token
+	if authorizationContext.ValidationOptions.Issuer && trustedIssuer == nil {
 		if !strings.EqualFold(userToken.Issuer, authorizationContext.Issuer) {
 			return &userToken, errors.New("token is not valid for subject " + userToken.DisplayName)
 		}
@@ -420,9 +597,121 @@ func ValidateUserToken(token string, authorizationContext *authorization_context
 		}
 	}
 
+	// Revoked tokens are still signed and not expired, only the revocation store knows about them
+	if trustedIssuer == nil && IsTokenRevoked(&userToken, authorizationContext) {
+		return &userToken, ErrTokenRevoked
+	}
+
 	return &userToken, nil
 }
 
+// verifyUserToken checks the signature of the jwt with the key it was signed with, the tokens of
+// the trusted issuers are checked with the keys of their discovery document
+func verifyUserToken(tokenBytes []byte, authorizationContext *authorization_context.AuthorizationContext) (*jwt.Claims, *trusted_issuers.TrustedIssuer, error) {
+	var verifiedToken *jwt.Claims
+	var signKey *jwt_keyvault.JwtKeyVaultItem
+	var trustedIssuer *trusted_issuers.TrustedIssuer
+
+	rawToken, err := jwt.ParseWithoutCheck(tokenBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Tokens of external issuers are verified with the keys published in their discovery document
+	if authorizationContext.TrustedIssuers.IsTrusted(rawToken.Issuer) {
+		verifiedToken, trustedIssuer, err = authorizationContext.TrustedIssuers.Verify(tokenBytes)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else if authorizationContext.Options.KeyVaultEnabled {
+		// Verifying signature using the key that was sign with
+		signKey = authorizationContext.KeyVault.GetVerificationKey(rawToken.KeyID)
+		if signKey == nil {
+			return nil, nil, errors.New("signing key " + rawToken.KeyID + " was not found")
+		}
+		switch kt := signKey.PrivateKey.(type) {
+		case *ecdsa.PrivateKey:
+			key := kt.PublicKey
+			verifiedToken, err = jwt.ECDSACheck(tokenBytes, &key)
+			if err != nil {
+				return nil, nil, err
+			}
+		case string:
+			verifiedToken, err = jwt.HMACCheck(tokenBytes, []byte(kt))
+			if err != nil {
+				return nil, nil, err
+			}
+		case *rsa.PrivateKey:
+			key := kt.PublicKey
+			verifiedToken, err = jwt.RSACheck(tokenBytes, &key)
+			if err != nil {
+				return nil, nil, err
+			}
+		case ed25519.PrivateKey:
+			verifiedToken, err = jwt.EdDSACheck(tokenBytes, kt.Public().(ed25519.PublicKey))
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	} else {
+		if authorizationContext.Options.PublicKey == "" {
+			err = errors.New("public key not present for validation")
+			return nil, nil, err
+		}
+
+		var tokenHeader RawCertificateHeader
+		err = json.Unmarshal(rawToken.RawHeader, &tokenHeader)
+		if err != nil {
+			return nil, nil, err
+		}
+		switch tokenHeader.Algorithm {
+		case "HS256", "HS384", "HS512":
+			publicKey, err := base64.StdEncoding.DecodeString(authorizationContext.Options.PublicKey)
+			if err != nil {
+				return nil, nil, err
+			}
+			verifiedToken, err = jwt.HMACCheck(tokenBytes, publicKey)
+			if err != nil {
+				return nil, nil, err
+			}
+		case "ES256", "ES384", "ES512":
+			publicKey := encryption.ECDSAHelper{}.DecodePublicKeyFromPem(authorizationContext.Options.PublicKey)
+			if publicKey == nil {
+				return nil, nil, errors.New("invalid public key")
+			}
+			verifiedToken, err = jwt.ECDSACheck(tokenBytes, publicKey)
+			if err != nil {
+				return nil, nil, err
+			}
+		case "RS256", "RS384", "RS512", "PS256", "PS384", "PS512":
+			publicKey := encryption.RSAHelper{}.DecodePublicKeyFromPem(authorizationContext.Options.PublicKey)
+			if publicKey == nil {
+				return nil, nil, errors.New("invalid public key")
+			}
+			verifiedToken, err = jwt.RSACheck(tokenBytes, publicKey)
+			if err != nil {
+				return nil, nil, err
+			}
+		case "EdDSA":
+			publicKey := decodeEd25519PublicKeyFromPem(authorizationContext.Options.PublicKey)
+			if publicKey == nil {
+				return nil, nil, errors.New("invalid public key")
+			}
+			verifiedToken, err = jwt.EdDSACheck(tokenBytes, publicKey)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	if verifiedToken == nil {
+		err = errors.New("no public or private key found, exiting")
+		return nil, nil, err
+	}
+
+	return verifiedToken, trustedIssuer, nil
+}
+
 func ValidateRefreshToken(token string, user string) (*models.UserToken, error) {
 	if token == "" {
 		return nil, errors.New("token cannot be empty")
@@ -440,7 +729,10 @@ func ValidateRefreshToken(token string, user string) (*models.UserToken, error)
 	}
 
 	// Verifying signature using the key that was sign with
-	signKey = authCtx.KeyVault.GetKey(rawToken.KeyID)
+	signKey = authCtx.KeyVault.GetVerificationKey(rawToken.KeyID)
+	if signKey == nil {
+		return nil, errors.New("signing key was not found")
+	}
 	switch kt := signKey.PrivateKey.(type) {
 	case *ecdsa.PrivateKey:
 		key := kt.PublicKey
@@ -459,6 +751,14 @@ func ValidateRefreshToken(token string, user string) (*models.UserToken, error)
 		if err != nil {
 			return nil, err
 		}
+	case ed25519.PrivateKey:
+		verifiedToken, err = jwt.EdDSACheck(tokenBytes, kt.Public().(ed25519.PublicKey))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if verifiedToken == nil {
+		return nil, errors.New("token signature could not be verified")
 	}
 
 	// Transforming token into a user token
@@ -521,7 +821,10 @@ func ValidateTokenByScope(token string, userId string, scope string) (*models.Us
 	}
 
 	// Verifying signature using the key that was sign with
-	signKey = authCtx.KeyVault.GetKey(rawToken.KeyID)
+	signKey = authCtx.KeyVault.GetVerificationKey(rawToken.KeyID)
+	if signKey == nil {
+		return nil, errors.New("signing key was not found")
+	}
 	switch kt := signKey.PrivateKey.(type) {
 	case *ecdsa.PrivateKey:
 		key := kt.PublicKey
@@ -540,6 +843,14 @@ func ValidateTokenByScope(token string, userId string, scope string) (*models.Us
 		if err != nil {
 			return nil, err
 		}
+	case ed25519.PrivateKey:
+		verifiedToken, err = jwt.EdDSACheck(tokenBytes, kt.Public().(ed25519.PublicKey))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if verifiedToken == nil {
+		return nil, errors.New("token signature could not be verified")
 	}
 
 	// Transforming token into a user token
@@ -585,7 +896,49 @@ func ValidateTokenByScope(token string, userId string, scope string) (*models.Us
 	return &userToken, nil
 }
 
+// getCertificateThumbprint returns the x5t of the key certificate, keys without a certificate
+// do not add the header
+func getCertificateThumbprint(signKey *jwt_keyvault.JwtKeyVaultItem) string {
+	if jsonWebKey := signKey.GetJsonWebKey(); jsonWebKey != nil {
+		return jsonWebKey.X5T
+	}
+
+	return ""
+}
+
+// getCertificateSha256Thumbprint returns the x5t#S256 of the key certificate, keys without a
+// certificate do not add the header
+func getCertificateSha256Thumbprint(signKey *jwt_keyvault.JwtKeyVaultItem) string {
+	if jsonWebKey := signKey.GetJsonWebKey(); jsonWebKey != nil {
+		return jsonWebKey.X5TS256
+	}
+
+	return ""
+}
+
+// decodeEd25519PublicKeyFromPem returns the Ed25519 public key of a PKIX pem block
+func decodeEd25519PublicKeyFromPem(pemEncoded string) ed25519.PublicKey {
+	block, _ := pem.Decode([]byte(pemEncoded))
+	if block == nil {
+		return nil
+	}
+
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil
+	}
+
+	ed25519Key, _ := publicKey.(ed25519.PublicKey)
+	return ed25519Key
+}
+
 func signToken(keyId string, claims jwt.Claims) (string, error) {
+	return signTokenWithType(keyId, claims, "")
+}
+
+// signTokenWithType signs the claims adding the media type of the token to the header, it is
+// needed by the tokens that must not be mistaken for access tokens
+func signTokenWithType(keyId string, claims jwt.Claims, tokenType string) (string, error) {
 	authCtx := authorization_context.New()
 	var rawToken []byte
 	var err error
@@ -607,8 +960,10 @@ func signToken(keyId string, claims jwt.Claims) (string, error) {
 	case *ecdsa.PrivateKey:
 		// Adding extra headers for some signing cases
 		extraHeaders, _ = json.Marshal(RawCertificateHeader{
-			KeyId: signKey.ID,
-			X5T:   signKey.Thumbprint,
+			Type:    tokenType,
+			KeyId:   signKey.ID,
+			X5T:     getCertificateThumbprint(signKey),
+			X5TS256: getCertificateSha256Thumbprint(signKey),
 		})
 		switch signKey.Size {
 		case encryption.Bit256:
@@ -621,6 +976,7 @@ func signToken(keyId string, claims jwt.Claims) (string, error) {
 	case string:
 		// Adding extra headers for some signing cases
 		extraHeaders, _ = json.Marshal(RawCertificateHeader{
+			Type:  tokenType,
 			KeyId: signKey.ID,
 		})
 		switch signKey.Size {
@@ -634,17 +990,22 @@ func signToken(keyId string, claims jwt.Claims) (string, error) {
 	case *rsa.PrivateKey:
 		// Adding extra headers for some signing cases
 		extraHeaders, _ = json.Marshal(RawCertificateHeader{
-			KeyId: signKey.ID,
-			X5T:   signKey.Thumbprint,
+			Type:    tokenType,
+			KeyId:   signKey.ID,
+			X5T:     getCertificateThumbprint(signKey),
+			X5TS256: getCertificateSha256Thumbprint(signKey),
 		})
-		switch signKey.Size {
-		case encryption.Bit256:
-			rawToken, err = claims.RSASign("RS256", kt, extraHeaders)
-		case encryption.Bit384:
-			rawToken, err = claims.RSASign("RS384", kt, extraHeaders)
-		case encryption.Bit512:
-			rawToken, err = claims.RSASign("RS512", kt, extraHeaders)
-		}
+		// the same rsa keys sign with PKCS#1 v1.5 or PSS depending on the key algorithm
+		rawToken, err = claims.RSASign(signKey.Algorithm(), kt, extraHeaders)
+	case ed25519.PrivateKey:
+		// Adding extra headers for some signing cases
+		extraHeaders, _ = json.Marshal(RawCertificateHeader{
+			Type:    tokenType,
+			KeyId:   signKey.ID,
+			X5T:     getCertificateThumbprint(signKey),
+			X5TS256: getCertificateSha256Thumbprint(signKey),
+		})
+		rawToken, err = claims.EdDSASign(kt, extraHeaders)
 	}
 
 	if err != nil {
@@ -0,0 +1,85 @@
+package jwt
+
+import (
+	"time"
+
+	cryptorand "github.com/cjlapao/common-go-cryptorand"
+	"github.com/cjlapao/common-go-identity/authorization_context"
+	identity_constants "github.com/cjlapao/common-go-identity/constants"
+	"github.com/cjlapao/common-go-identity/models"
+	"github.com/cjlapao/common-go/constants"
+	"github.com/pascaldekloe/jwt"
+)
+
+// GetUserInfoClaims returns the OpenID Connect claims of the user released by the scope, the
+// subject is always released, the profile, email and roles claims only with their scopes
+func GetUserInfoClaims(user models.User, scope string) map[string]interface{} {
+	claims := make(map[string]interface{})
+	claims["sub"] = user.ID
+
+	if HasScope(scope, identity_constants.ProfileScope) {
+		claims["name"] = user.DisplayName
+		claims["given_name"] = user.FirstName
+		claims["family_name"] = user.LastName
+		claims["preferred_username"] = user.Username
+	}
+
+	if HasScope(scope, identity_constants.EmailScope) {
+		claims["email"] = user.Email
+		claims["email_verified"] = user.EmailVerified
+	}
+
+	if HasScope(scope, identity_constants.RolesScope) {
+		roles := make([]string, 0)
+		for _, role := range user.Roles {
+			roles = append(roles, role.ID)
+		}
+		claims["roles"] = roles
+
+		userClaims := make([]string, 0)
+		for _, claim := range user.Claims {
+			userClaims = append(userClaims, claim.ID)
+		}
+		claims["claims"] = userClaims
+	}
+
+	return claims
+}
+
+// GenerateUserInfoToken signs the userinfo claims for the client that requested them as a
+// jwt, it is used when the client asks for an application/jwt response
+func GenerateUserInfoToken(keyId string, userInfo map[string]interface{}, clientId string) (string, error) {
+	var userInfoClaims jwt.Claims
+	authCtx := authorization_context.New()
+	now := time.Now().Round(time.Second)
+
+	userInfoClaims.Issuer = authCtx.Issuer
+	if clientId != "" {
+		userInfoClaims.Audiences = []string{clientId}
+	}
+	userInfoClaims.Issued = jwt.NewNumericTime(now)
+	id, idErr := cryptorand.GetRandomString(constants.ID_SIZE)
+	if idErr != nil {
+		return "", idErr
+	}
+	userInfoClaims.ID = id
+
+	customClaims := make(map[string]interface{})
+	for name, value := range userInfo {
+		customClaims[name] = value
+	}
+	if sub, ok := customClaims["sub"].(string); ok {
+		userInfoClaims.Subject = sub
+		delete(customClaims, "sub")
+	}
+
+	userInfoClaims.KeyID = authCtx.Options.KeyId
+	userInfoClaims.Set = customClaims
+	userInfoToken, err := signToken(keyId, userInfoClaims)
+	if err != nil {
+		logger.Error("There was an error signing the userinfo token for subject %v with key id %v", userInfoClaims.Subject, keyId)
+		return "", err
+	}
+
+	return userInfoToken, nil
+}
@@ -0,0 +1,245 @@
+package jwt_keyvault
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/pkcs12"
+)
+
+var (
+	ErrNoPrivateKey           = errors.New("no private key was found")
+	ErrUnsupportedKeyType     = errors.New("the private key type is not supported")
+	ErrCertificateKeyMismatch = errors.New("no certificate matches the private key")
+)
+
+// WithPemFile adds the private key of a pem file, the certificate chain is published with the
+// key if the file also contains it
+func (kv *JwtKeyVaultService) WithPemFile(id string, path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return kv.WithPem(id, content)
+}
+
+// WithCertificateFiles adds the private key and certificate chain kept in separate pem files
+func (kv *JwtKeyVaultService) WithCertificateFiles(id string, certificatePath string, keyPath string) error {
+	certificates, err := os.ReadFile(certificatePath)
+	if err != nil {
+		return err
+	}
+
+	privateKey, err := os.ReadFile(keyPath)
+	if err != nil {
+		return err
+	}
+
+	chain, err := parseCertificates(certificates)
+	if err != nil {
+		return err
+	}
+	if len(chain) == 0 {
+		return fmt.Errorf("no certificate was found in %v", certificatePath)
+	}
+
+	key, _, err := parsePem(privateKey)
+	if err != nil {
+		return err
+	}
+
+	return kv.WithCertificateChain(id, chain, key)
+}
+
+// WithPkcs12File adds the private key and certificate chain of a password protected PKCS#12
+// bundle
+func (kv *JwtKeyVaultService) WithPkcs12File(id string, path string, password string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return kv.WithPkcs12(id, content, password)
+}
+
+func (kv *JwtKeyVaultService) WithPkcs12(id string, data []byte, password string) error {
+	blocks, err := pkcs12.ToPEM(data, password)
+	if err != nil {
+		return err
+	}
+
+	content := make([]byte, 0)
+	for _, block := range blocks {
+		content = append(content, pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: block.Bytes})...)
+	}
+
+	return kv.WithPem(id, content)
+}
+
+// WithPem adds a PKCS#1, PKCS#8 or SEC1 private key, the certificates found with it are
+// published with the key
+func (kv *JwtKeyVaultService) WithPem(id string, data []byte) error {
+	privateKey, chain, err := parsePem(data)
+	if err != nil {
+		return err
+	}
+
+	if len(chain) > 0 {
+		return kv.WithCertificateChain(id, chain, privateKey)
+	}
+
+	return kv.WithPrivateKey(id, privateKey)
+}
+
+// WithPrivateKey adds a rsa, ecdsa or ed25519 private key, rsa keys sign with RS256 to RS512 by
+// their size unless SetKeyAlgorithm changes them to PSS
+func (kv *JwtKeyVaultService) WithPrivateKey(id string, privateKey interface{}) error {
+	if kv.GetKey(id) != nil {
+		return fmt.Errorf("key %v already exists in the key vault", id)
+	}
+
+	switch privateKey := privateKey.(type) {
+	case *rsa.PrivateKey:
+		kv.WithRsaKey(id, privateKey)
+	case *ecdsa.PrivateKey:
+		kv.WithEcdsaKey(id, privateKey)
+	case ed25519.PrivateKey:
+		kv.WithEd25519Key(id, privateKey)
+	default:
+		return ErrUnsupportedKeyType
+	}
+
+	return nil
+}
+
+// WithCertificateChain adds the private key of a certificate, the certificate of the key is moved
+// to the start of the chain and if the id is empty the certificate sha256 thumbprint is used
+func (kv *JwtKeyVaultService) WithCertificateChain(id string, chain []*x509.Certificate, privateKey interface{}) error {
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return ErrUnsupportedKeyType
+	}
+
+	publicKey, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok {
+		return ErrUnsupportedKeyType
+	}
+
+	orderedChain := make([]*x509.Certificate, 0)
+	for _, certificate := range chain {
+		if certificate != nil && len(orderedChain) == 0 && publicKey.Equal(certificate.PublicKey) {
+			orderedChain = append(orderedChain, certificate)
+		}
+	}
+	if len(orderedChain) == 0 {
+		return ErrCertificateKeyMismatch
+	}
+	for _, certificate := range chain {
+		if certificate != nil && certificate != orderedChain[0] {
+			orderedChain = append(orderedChain, certificate)
+		}
+	}
+
+	if id == "" {
+		thumbprint := sha256.Sum256(orderedChain[0].Raw)
+		id = base64.RawURLEncoding.EncodeToString(thumbprint[:])
+	}
+
+	if err := kv.WithPrivateKey(id, privateKey); err != nil {
+		return err
+	}
+
+	kv.mutex.Lock()
+	defer kv.mutex.Unlock()
+	if key := kv.getKey(id); key != nil {
+		key.Certificate = orderedChain[0]
+		key.CertificateChain = orderedChain
+		if jsonWebKey := key.GetJsonWebKey(); jsonWebKey != nil {
+			jsonWebKey.WithCertificateChain(orderedChain)
+		}
+	}
+
+	return nil
+}
+
+// parsePem returns the private key and the certificates of a pem encoded file, the key block
+// type is not trusted as some tools write PKCS#1 keys with the PKCS#8 type
+func parsePem(data []byte) (interface{}, []*x509.Certificate, error) {
+	var privateKey interface{}
+	chain := make([]*x509.Certificate, 0)
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		switch block.Type {
+		case "CERTIFICATE":
+			certificate, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, nil, err
+			}
+			chain = append(chain, certificate)
+		case "PRIVATE KEY", "RSA PRIVATE KEY", "EC PRIVATE KEY":
+			key, err := parsePrivateKey(block.Bytes)
+			if err != nil {
+				return nil, nil, err
+			}
+			privateKey = key
+		case "ENCRYPTED PRIVATE KEY":
+			return nil, nil, errors.New("encrypted pem keys are not supported, use a PKCS#12 bundle instead")
+		}
+	}
+
+	if privateKey == nil {
+		return nil, nil, ErrNoPrivateKey
+	}
+
+	return privateKey, chain, nil
+}
+
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	chain := make([]*x509.Certificate, 0)
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		if block.Type == "CERTIFICATE" {
+			certificate, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			chain = append(chain, certificate)
+		}
+	}
+
+	return chain, nil
+}
+
+func parsePrivateKey(der []byte) (interface{}, error) {
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
+
+	return nil, ErrUnsupportedKeyType
+}
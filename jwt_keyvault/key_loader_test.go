@@ -0,0 +1,145 @@
+package jwt_keyvault
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestCertificate returns a certificate for the key signed by the parent, or self signed
+func newTestCertificate(t *testing.T, name string, key crypto.Signer, parent *x509.Certificate, parentKey crypto.Signer) *x509.Certificate {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	raw, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certificate, err := x509.ParseCertificate(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return certificate
+}
+
+func writeTestPem(t *testing.T, blocks ...*pem.Block) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "key.pem")
+	content := make([]byte, 0)
+	for _, block := range blocks {
+		content = append(content, pem.EncodeToMemory(block)...)
+	}
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestWithPemFile(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecdsaKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	pkcs8Key, _ := x509.MarshalPKCS8PrivateKey(rsaKey)
+	sec1Key, _ := x509.MarshalECPrivateKey(ecdsaKey)
+
+	tests := map[string]*pem.Block{
+		"pkcs1": {Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)},
+		"pkcs8": {Type: "PRIVATE KEY", Bytes: pkcs8Key},
+		"sec1":  {Type: "EC PRIVATE KEY", Bytes: sec1Key},
+	}
+
+	vault := &JwtKeyVaultService{}
+	for id, block := range tests {
+		if err := vault.WithPemFile(id, writeTestPem(t, block)); err != nil {
+			t.Fatalf("expected the %v key to load, got %v", id, err)
+		}
+	}
+
+	if vault.GetKey("pkcs1").Algorithm() != "RS256" || vault.GetKey("pkcs8").Algorithm() != "RS256" {
+		t.Errorf("expected the rsa keys to sign with RS256")
+	}
+	if vault.GetKey("sec1").Algorithm() != "ES256" {
+		t.Errorf("expected the ecdsa key to sign with ES256")
+	}
+	if err := vault.WithPemFile("pkcs8", writeTestPem(t, tests["pkcs8"])); err == nil {
+		t.Errorf("expected an existing key not to be replaced")
+	}
+	if err := vault.WithPemFile("empty", writeTestPem(t)); err != ErrNoPrivateKey {
+		t.Errorf("expected no private key to be found, got %v", err)
+	}
+}
+
+func TestWithCertificateFiles(t *testing.T) {
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	signingKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ca := newTestCertificate(t, "ca", caKey, nil, nil)
+	certificate := newTestCertificate(t, "signing", signingKey, ca, caKey)
+	sec1Key, _ := x509.MarshalECPrivateKey(signingKey)
+
+	// the chain is published leaf first whatever the order of the file
+	certificatePath := writeTestPem(t, &pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}, &pem.Block{Type: "CERTIFICATE", Bytes: certificate.Raw})
+	keyPath := writeTestPem(t, &pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1Key})
+
+	vault := &JwtKeyVaultService{}
+	if err := vault.WithCertificateFiles("signing", certificatePath, keyPath); err != nil {
+		t.Fatal(err)
+	}
+
+	jsonWebKey := vault.GetKey("signing").GetJsonWebKey()
+	if len(jsonWebKey.X5C) != 2 || jsonWebKey.GetPublicKeyAt(0) == nil {
+		t.Fatalf("expected the certificate chain in the x5c, got %v", jsonWebKey.X5C)
+	}
+	if jsonWebKey.X5T == "" || jsonWebKey.X5TS256 == "" {
+		t.Errorf("expected the certificate thumbprints")
+	}
+
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err := vault.WithCertificateChain("other", []*x509.Certificate{certificate}, otherKey); err != ErrCertificateKeyMismatch {
+		t.Errorf("expected a certificate of another key to be rejected, got %v", err)
+	}
+
+	vault.WithCertificate(*certificate, signingKey)
+	if len(vault.Keys) != 2 || vault.Keys[1].ID != jsonWebKey.X5TS256 {
+		t.Errorf("expected the certificate thumbprint as the key id")
+	}
+}
+
+func TestWithPkcs12File(t *testing.T) {
+	vault := &JwtKeyVaultService{}
+	if err := vault.WithPkcs12File("bundle", filepath.Join("testdata", "signing.p12"), "wrong"); err == nil {
+		t.Errorf("expected the wrong password to fail")
+	}
+	if err := vault.WithPkcs12File("bundle", filepath.Join("testdata", "signing.p12"), "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	key := vault.GetKey("bundle")
+	if key.Certificate == nil || key.Certificate.Subject.CommonName != "Test Signing Key" {
+		t.Fatalf("expected the signing certificate, got %v", key.Certificate)
+	}
+	if len(key.CertificateChain) != 2 || len(key.GetJsonWebKey().X5C) != 2 {
+		t.Errorf("expected the ca in the certificate chain")
+	}
+}
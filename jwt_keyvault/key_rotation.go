@@ -0,0 +1,216 @@
+package jwt_keyvault
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cjlapao/common-go-identity/data_protection"
+	"github.com/cjlapao/common-go-identity/database/dto"
+	"github.com/cjlapao/common-go-identity/interfaces"
+	log "github.com/cjlapao/common-go-logger"
+)
+
+var logger = log.Get()
+
+// KeyRotationOptions controls the rotation of the signing keys, the retention needs to be longer
+// than the lifetime of the tokens so they can still be verified after their key is replaced
+type KeyRotationOptions struct {
+	Algorithm     string
+	Interval      time.Duration
+	PreAnnounce   time.Duration
+	Retention     time.Duration
+	CheckInterval time.Duration
+	EncryptionKey string
+}
+
+// KeyRotation generates the signing keys of the key vault on an interval, the keys are kept in
+// the key store so all the replicas sharing it sign with the same key
+type KeyRotation struct {
+	vault   *JwtKeyVaultService
+	store   interfaces.KeyStoreAdapter
+	options KeyRotationOptions
+	mutex   sync.Mutex
+	stop    chan struct{}
+}
+
+func NewKeyRotation(vault *JwtKeyVaultService, store interfaces.KeyStoreAdapter, options KeyRotationOptions) *KeyRotation {
+	if options.Algorithm == "" {
+		options.Algorithm = "RS256"
+	}
+	if options.Interval <= 0 {
+		options.Interval = time.Hour * 24 * 30
+	}
+	// a key needs to be published for a while before it signs, but not for its whole life
+	if options.PreAnnounce < 0 || options.PreAnnounce >= options.Interval {
+		options.PreAnnounce = options.Interval / 2
+	}
+	if options.CheckInterval <= 0 {
+		options.CheckInterval = time.Minute
+	}
+
+	return &KeyRotation{
+		vault:   vault,
+		store:   store,
+		options: options,
+	}
+}
+
+// Start rotates the keys straight away and then checks them on the check interval until the
+// rotation is stopped
+func (r *KeyRotation) Start() error {
+	if err := r.Rotate(); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.stop != nil {
+		return nil
+	}
+
+	r.stop = make(chan struct{})
+	go func(stop chan struct{}) {
+		ticker := time.NewTicker(r.options.CheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := r.Rotate(); err != nil {
+					logger.Exception(err, "There was an error rotating the signing keys")
+				}
+			case <-stop:
+				return
+			}
+		}
+	}(r.stop)
+
+	return nil
+}
+
+func (r *KeyRotation) Stop() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.stop != nil {
+		close(r.stop)
+		r.stop = nil
+	}
+}
+
+// Rotate generates the next key once the current one is due to be replaced, retires the keys
+// whose tokens have expired and loads the store state into the key vault
+func (r *KeyRotation) Rotate() error {
+	return r.rotateAt(time.Now())
+}
+
+func (r *KeyRotation) rotateAt(now time.Time) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	protector, err := data_protection.NewProtector(r.options.EncryptionKey)
+	if err != nil {
+		return err
+	}
+
+	keys := r.getActiveKeys()
+	var newest *dto.SigningKeyDTO
+	if len(keys) > 0 {
+		newest = &keys[len(keys)-1]
+	}
+
+	if newest == nil || !now.Before(newest.ActivatesAt.Add(r.options.Interval-r.options.PreAnnounce)) {
+		// the first key only signs straight away if there is nothing else to sign with, the
+		// replicas agree on the key id as it comes from when the key is due
+		activatesAt := now.Add(r.options.PreAnnounce)
+		dueAt := now.Truncate(time.Minute)
+		if newest == nil && r.vault.GetDefaultKey() == nil {
+			activatesAt = now
+		}
+		if newest != nil {
+			dueAt = newest.ActivatesAt.Add(r.options.Interval)
+			if dueAt.After(activatesAt) {
+				activatesAt = dueAt
+			}
+		}
+
+		key, err := r.generateKey(protector, dueAt, now, activatesAt)
+		if err != nil {
+			return err
+		}
+		if r.store.AddKey(*key) {
+			logger.Info("Generated the signing key %v, it will sign tokens from %v", key.ID, key.ActivatesAt.Format(time.RFC3339))
+		}
+
+		keys = r.getActiveKeys()
+	}
+
+	// a key is retired once the tokens it signed before its successor took over have expired
+	for i := 0; i < len(keys)-1; i++ {
+		if !now.Before(keys[i+1].ActivatesAt.Add(r.options.Retention)) {
+			if err := r.store.RetireKey(keys[i].ID); err != nil {
+				return err
+			}
+			logger.Info("Retired the signing key %v", keys[i].ID)
+		}
+	}
+
+	return r.vault.loadKeys(r.store, protector, now)
+}
+
+func (r *KeyRotation) getActiveKeys() []dto.SigningKeyDTO {
+	keys := make([]dto.SigningKeyDTO, 0)
+	for _, key := range r.store.GetKeys() {
+		if !key.Retired {
+			keys = append(keys, key)
+		}
+	}
+	sortKeys(keys)
+
+	return keys
+}
+
+func (r *KeyRotation) generateKey(protector *data_protection.Protector, dueAt time.Time, now time.Time, activatesAt time.Time) (*dto.SigningKeyDTO, error) {
+	var privateKey interface{}
+	var err error
+	switch r.options.Algorithm {
+	case "RS256", "PS256":
+		privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	case "RS384", "PS384":
+		privateKey, err = rsa.GenerateKey(rand.Reader, 3072)
+	case "RS512", "PS512":
+		privateKey, err = rsa.GenerateKey(rand.Reader, 4096)
+	case "ES256":
+		privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "ES384":
+		privateKey, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case "ES512":
+		privateKey, err = ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	case "EdDSA":
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("key rotation does not support the %v algorithm", r.options.Algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	encodedKey, err := protectPrivateKey(protector, privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.SigningKeyDTO{
+		ID:          dueAt.UTC().Format("20060102150405"),
+		Algorithm:   r.options.Algorithm,
+		PrivateKey:  encodedKey,
+		CreatedAt:   now,
+		ActivatesAt: activatesAt,
+		ExpiresAt:   activatesAt.Add(r.options.Interval),
+	}, nil
+}
@@ -0,0 +1,98 @@
+package jwt_keyvault
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cjlapao/common-go-identity/database/memory"
+)
+
+func isPublished(vault *JwtKeyVaultService, id string) bool {
+	for _, key := range vault.GetVerificationKeys() {
+		if key.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestKeyRotation(t *testing.T) {
+	day := time.Hour * 24
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := memory.NewMemoryKeyStoreAdapter()
+	options := KeyRotationOptions{
+		Algorithm:     "ES256",
+		Interval:      day * 10,
+		PreAnnounce:   day,
+		Retention:     day * 2,
+		EncryptionKey: "test",
+	}
+
+	vault := &JwtKeyVaultService{}
+	rotation := NewKeyRotation(vault, store, options)
+	if err := rotation.rotateAt(start); err != nil {
+		t.Fatal(err)
+	}
+
+	first := vault.GetDefaultKey()
+	if first == nil || first.Algorithm() != "ES256" {
+		t.Fatalf("expected the first key to sign straight away, got %v", first)
+	}
+
+	if err := rotation.rotateAt(start.Add(day * 8)); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.GetKeys()) != 1 {
+		t.Fatalf("expected no new key before the pre-announce period, got %v", len(store.GetKeys()))
+	}
+
+	// the next key is published before it signs
+	if err := rotation.rotateAt(start.Add(day * 9)); err != nil {
+		t.Fatal(err)
+	}
+	keys := store.GetKeys()
+	if len(keys) != 2 {
+		t.Fatalf("expected the next key to be generated, got %v keys", len(keys))
+	}
+	second := keys[1]
+	if !second.ActivatesAt.Equal(start.Add(day * 10)) {
+		t.Errorf("expected the next key to activate after the interval, got %v", second.ActivatesAt)
+	}
+	if !isPublished(vault, second.ID) || vault.GetDefaultKey().ID != first.ID {
+		t.Fatalf("expected the next key to be published but not signing")
+	}
+
+	// a second replica sharing the store agrees on the keys
+	replica := &JwtKeyVaultService{}
+	if err := NewKeyRotation(replica, store, options).rotateAt(start.Add(day * 9)); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.GetKeys()) != 2 || replica.GetDefaultKey().ID != first.ID || !isPublished(replica, second.ID) {
+		t.Fatalf("expected the replica to load the same keys")
+	}
+
+	if err := rotation.rotateAt(start.Add(day * 10)); err != nil {
+		t.Fatal(err)
+	}
+	if vault.GetDefaultKey().ID != second.ID {
+		t.Fatalf("expected the next key to sign once active, got %v", vault.GetDefaultKey().ID)
+	}
+	if vault.GetVerificationKey(first.ID) == nil {
+		t.Fatalf("expected the previous key to still verify its tokens")
+	}
+
+	// the previous key is retired once its tokens have expired
+	if err := rotation.rotateAt(start.Add(day * 12)); err != nil {
+		t.Fatal(err)
+	}
+	if vault.GetVerificationKey(first.ID) != nil || isPublished(vault, first.ID) {
+		t.Fatalf("expected the previous key to be retired")
+	}
+	if err := NewKeyRotation(replica, store, options).rotateAt(start.Add(day * 12)); err != nil {
+		t.Fatal(err)
+	}
+	if replica.GetVerificationKey(first.ID) != nil || replica.GetDefaultKey().ID != second.ID {
+		t.Fatalf("expected the replica to retire the previous key")
+	}
+}
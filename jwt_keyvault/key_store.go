@@ -0,0 +1,161 @@
+package jwt_keyvault
+
+import (
+	"crypto/x509"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cjlapao/common-go-identity/data_protection"
+	"github.com/cjlapao/common-go-identity/database/dto"
+	"github.com/cjlapao/common-go-identity/interfaces"
+	"github.com/cjlapao/common-go/security/encryption"
+)
+
+// LoadKeys adds the keys kept in the store to the key vault so every process shares the same
+// keys, the newest key that is already active becomes the default key
+func (kv *JwtKeyVaultService) LoadKeys(store interfaces.KeyStoreAdapter, encryptionKey string) error {
+	protector, err := data_protection.NewProtector(encryptionKey)
+	if err != nil {
+		return err
+	}
+
+	return kv.loadKeys(store, protector, time.Now())
+}
+
+// SaveKey keeps a key of the key vault in the store, the private key is encrypted with the
+// encryption key before it leaves the process
+func (kv *JwtKeyVaultService) SaveKey(store interfaces.KeyStoreAdapter, encryptionKey string, id string) error {
+	protector, err := data_protection.NewProtector(encryptionKey)
+	if err != nil {
+		return err
+	}
+
+	key := kv.GetKey(id)
+	if key == nil {
+		return fmt.Errorf("signing key %v was not found", id)
+	}
+
+	privateKey, err := protectPrivateKey(protector, key.PrivateKey)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	storedKey := dto.SigningKeyDTO{
+		ID:          key.ID,
+		Algorithm:   key.Algorithm(),
+		PrivateKey:  privateKey,
+		CreatedAt:   key.CreatedAt,
+		ActivatesAt: key.ActivatesAt,
+		ExpiresAt:   key.ExpiresAt,
+		Retired:     key.Retired,
+	}
+	if storedKey.CreatedAt.IsZero() {
+		storedKey.CreatedAt = now
+	}
+	if storedKey.ActivatesAt.IsZero() {
+		storedKey.ActivatesAt = now
+	}
+
+	if !store.AddKey(storedKey) {
+		return fmt.Errorf("signing key %v already exists in the store", id)
+	}
+
+	return nil
+}
+
+func (kv *JwtKeyVaultService) loadKeys(store interfaces.KeyStoreAdapter, protector *data_protection.Protector, now time.Time) error {
+	keys := store.GetKeys()
+	sortKeys(keys)
+
+	var defaultKey *dto.SigningKeyDTO
+	for i, key := range keys {
+		if key.Retired {
+			continue
+		}
+		if kv.GetKey(key.ID) == nil {
+			if err := kv.addStoredKey(protector, key); err != nil {
+				return err
+			}
+		}
+		if !now.Before(key.ActivatesAt) {
+			defaultKey = &keys[i]
+		}
+	}
+
+	if defaultKey != nil {
+		if current := kv.GetDefaultKey(); current == nil || current.ID != defaultKey.ID {
+			kv.SetDefaultKey(defaultKey.ID)
+		}
+	}
+
+	for _, key := range keys {
+		if key.Retired && kv.GetVerificationKey(key.ID) != nil {
+			kv.RetireKey(key.ID)
+		}
+	}
+
+	return nil
+}
+
+func (kv *JwtKeyVaultService) addStoredKey(protector *data_protection.Protector, key dto.SigningKeyDTO) error {
+	decryptedKey, err := protector.Unprotect(key.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("signing key %v cannot be decrypted, %w", key.ID, err)
+	}
+
+	if strings.HasPrefix(key.Algorithm, "HS") {
+		var size encryption.EncryptionKeySize
+		kv.WithHmacKey(key.ID, string(decryptedKey), size.FromString(strings.TrimPrefix(key.Algorithm, "HS")))
+	} else {
+		privateKey, err := x509.ParsePKCS8PrivateKey(decryptedKey)
+		if err != nil {
+			return err
+		}
+
+		if err := kv.WithPrivateKey(key.ID, privateKey); err != nil {
+			return fmt.Errorf("signing key %v cannot be added, %w", key.ID, err)
+		}
+		if strings.HasPrefix(key.Algorithm, "PS") {
+			if err := kv.SetKeyAlgorithm(key.ID, key.Algorithm); err != nil {
+				return err
+			}
+		}
+	}
+
+	kv.setKeyDates(key.ID, key.CreatedAt, key.ActivatesAt, key.ExpiresAt)
+	return nil
+}
+
+func (kv *JwtKeyVaultService) setKeyDates(id string, createdAt time.Time, activatesAt time.Time, expiresAt time.Time) {
+	kv.mutex.Lock()
+	defer kv.mutex.Unlock()
+
+	if key := kv.getKey(id); key != nil {
+		key.CreatedAt = createdAt
+		key.ActivatesAt = activatesAt
+		key.ExpiresAt = expiresAt
+	}
+}
+
+// protectPrivateKey encrypts the PKCS#8 encoding of the asymmetric keys and the raw hmac secrets
+func protectPrivateKey(protector *data_protection.Protector, privateKey interface{}) (string, error) {
+	if secret, ok := privateKey.(string); ok {
+		return protector.ProtectString(secret)
+	}
+
+	encodedKey, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	return protector.Protect(encodedKey)
+}
+
+func sortKeys(keys []dto.SigningKeyDTO) {
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].ActivatesAt.Before(keys[j].ActivatesAt)
+	})
+}
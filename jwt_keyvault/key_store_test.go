@@ -0,0 +1,95 @@
+package jwt_keyvault
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cjlapao/common-go-identity/database/file"
+	"github.com/cjlapao/common-go/security/encryption"
+)
+
+func TestKeyStore(t *testing.T) {
+	store := file.NewFileKeyStoreAdapter(filepath.Join(t.TempDir(), "keys.json"))
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vault := &JwtKeyVaultService{}
+	vault.WithHmacKey("hmac", "secret", encryption.Bit256)
+	vault.WithEcdsaKey("ecdsa", privateKey)
+	for _, id := range []string{"hmac", "ecdsa"} {
+		if err := vault.SaveKey(store, "master", id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := vault.SaveKey(store, "master", "hmac"); err == nil {
+		t.Errorf("expected an existing key not to be replaced")
+	}
+
+	storedKey := store.GetKey("hmac")
+	if storedKey == nil || storedKey.Algorithm != "HS256" || strings.Contains(storedKey.PrivateKey, "secret") {
+		t.Fatalf("expected the hmac key to be stored encrypted, got %v", storedKey)
+	}
+
+	if err := (&JwtKeyVaultService{}).LoadKeys(store, "wrong"); err == nil {
+		t.Errorf("expected the keys not to load with another encryption key")
+	}
+
+	loaded := &JwtKeyVaultService{}
+	if err := loaded.LoadKeys(store, "master"); err != nil {
+		t.Fatal(err)
+	}
+	if key := loaded.GetKey("hmac"); key == nil || key.PrivateKey != "secret" {
+		t.Errorf("expected the hmac key to be loaded, got %v", key)
+	}
+	key := loaded.GetKey("ecdsa")
+	if key == nil || !key.PrivateKey.(*ecdsa.PrivateKey).Equal(privateKey) {
+		t.Fatalf("expected the ecdsa key to be loaded")
+	}
+
+	if err := store.RetireKey("hmac"); err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.LoadKeys(store, "master"); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.GetVerificationKey("hmac") != nil || loaded.GetDefaultKey().ID != "ecdsa" {
+		t.Errorf("expected the retired key to stop verifying")
+	}
+}
+
+func TestKeyStoreAlgorithms(t *testing.T) {
+	store := file.NewFileKeyStoreAdapter(filepath.Join(t.TempDir(), "keys.json"))
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	_, ed25519Key, _ := ed25519.GenerateKey(rand.Reader)
+
+	vault := &JwtKeyVaultService{}
+	vault.WithRsaPssKey("pss", rsaKey, encryption.Bit384)
+	vault.WithEd25519Key("eddsa", ed25519Key)
+	for _, id := range []string{"pss", "eddsa"} {
+		if err := vault.SaveKey(store, "master", id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	loaded := &JwtKeyVaultService{}
+	if err := loaded.LoadKeys(store, "master"); err != nil {
+		t.Fatal(err)
+	}
+	if key := loaded.GetKey("pss"); key == nil || key.Algorithm() != "PS384" || key.GetJsonWebKey().Algorithm != "PS384" {
+		t.Errorf("expected the PSS key to keep its algorithm, got %v", key)
+	}
+	if key := loaded.GetKey("eddsa"); key == nil || key.Algorithm() != "EdDSA" || key.GetJsonWebKey().KeyType != "OKP" {
+		t.Errorf("expected the Ed25519 key, got %v", key)
+	}
+	if err := loaded.SetKeyAlgorithm("eddsa", "PS256"); err == nil {
+		t.Errorf("expected only rsa keys to change algorithm")
+	}
+}
@@ -2,11 +2,14 @@ package jwt_keyvault
 
 import (
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/base64"
 	"fmt"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/cjlapao/common-go-identity/jwk"
 	"github.com/cjlapao/common-go/security/encryption"
@@ -18,16 +21,22 @@ type JwtKeyVaultItem struct {
 	Size              encryption.EncryptionKeySize
 	Thumbprint        string
 	Certificate       *x509.Certificate
+	CertificateChain  []*x509.Certificate
 	EncodedPrivateKey string
 	PrivateKey        interface{}
 	EncodedPublicKey  string
 	PublicKey         interface{}
 	IsDefault         bool
+	Retired           bool
+	CreatedAt         time.Time
+	ActivatesAt       time.Time
+	ExpiresAt         time.Time
 	JWK               *jwk.JsonWebKeys
 }
 
 type JwtKeyVaultService struct {
-	Keys []*JwtKeyVaultItem
+	Keys  []*JwtKeyVaultItem
+	mutex sync.RWMutex
 }
 
 var globalKeyVault *JwtKeyVaultService
@@ -50,7 +59,13 @@ func Get() *JwtKeyVaultService {
 	return NewKeyVault()
 }
 
+// WithCertificate adds the private key of a certificate, the key id is the certificate sha256
+// thumbprint and the certificate is published with the key in the jwks
 func (kv *JwtKeyVaultService) WithCertificate(certificate x509.Certificate, privateKey interface{}) *JwtKeyVaultService {
+	if err := kv.WithCertificateChain("", []*x509.Certificate{&certificate}, privateKey); err != nil {
+		logger.Exception(err, "There was an error adding the certificate %v", certificate.Subject.String())
+	}
+
 	return kv
 }
 
@@ -60,14 +75,27 @@ func (kv *JwtKeyVaultService) WithBase64RsaKey(id string, privateKey string) *Jw
 }
 
 func (kv *JwtKeyVaultService) WithRsaKey(id string, privateKey *rsa.PrivateKey) *JwtKeyVaultService {
+	return kv.withRsaKey(id, privateKey, fmt.Sprintf("RS%v", privateKey.Size()))
+}
+
+// WithRsaPssKey adds a rsa key that signs with RSASSA-PSS, the size is the one of the hash as
+// in PS256, PS384 and PS512
+func (kv *JwtKeyVaultService) WithRsaPssKey(id string, privateKey *rsa.PrivateKey, size encryption.EncryptionKeySize) *JwtKeyVaultService {
+	return kv.withRsaKey(id, privateKey, "PS"+size.String())
+}
+
+func (kv *JwtKeyVaultService) withRsaKey(id string, privateKey *rsa.PrivateKey, algorithm string) *JwtKeyVaultService {
+	kv.mutex.Lock()
+	defer kv.mutex.Unlock()
+
 	if !kv.keyExists(id) {
 		key := JwtKeyVaultItem{
 			ID:         id,
 			PrivateKey: privateKey,
 			PublicKey:  privateKey.PublicKey,
 		}
-		key.Type = key.Type.FromString(fmt.Sprintf("RS%v", privateKey.Size()))
-		key.Size = key.Size.FromString(fmt.Sprintf("%v", privateKey.Size()))
+		key.Type = getKeyType(algorithm)
+		key.Size = key.Size.FromString(algorithm[2:])
 		key.Thumbprint = encryption.GetBase64KeyFingerprint(privateKey.PublicKey)
 
 		x509PrivateEncodedBlock := x509.MarshalPKCS1PrivateKey(privateKey)
@@ -77,6 +105,7 @@ func (kv *JwtKeyVaultService) WithRsaKey(id string, privateKey *rsa.PrivateKey)
 
 		key.JWK = jwk.New()
 		key.JWK.Add(id, privateKey)
+		key.JWK.Keys[0].Algorithm = algorithm
 		key.Thumbprint = key.JWK.Keys[0].Thumbprint
 
 		if len(kv.Keys) == 0 {
@@ -94,6 +123,9 @@ func (kv *JwtKeyVaultService) WithBase64EcdsaKey(id string, privateKey string) *
 }
 
 func (kv *JwtKeyVaultService) WithEcdsaKey(id string, privateKey *ecdsa.PrivateKey) *JwtKeyVaultService {
+	kv.mutex.Lock()
+	defer kv.mutex.Unlock()
+
 	if !kv.keyExists(id) {
 		key := JwtKeyVaultItem{
 			ID:         id,
@@ -101,12 +133,17 @@ func (kv *JwtKeyVaultService) WithEcdsaKey(id string, privateKey *ecdsa.PrivateK
 			PublicKey:  privateKey.PublicKey,
 		}
 
-		key.Type = key.Type.FromString("ES" + fmt.Sprintf("%v", privateKey.Params().BitSize))
-		key.Size = key.Size.FromString(fmt.Sprintf("%v", privateKey.Params().BitSize))
+		// P-521 keys sign with ES512
+		bitSize := privateKey.Params().BitSize
+		if bitSize == 521 {
+			bitSize = 512
+		}
+		key.Type = key.Type.FromString("ES" + fmt.Sprintf("%v", bitSize))
+		key.Size = key.Size.FromString(fmt.Sprintf("%v", bitSize))
 		key.Thumbprint = encryption.GetBase64KeyFingerprint(privateKey.PublicKey)
 
 		x509PrivateEncodedBlock, _ := x509.MarshalECPrivateKey(privateKey)
-		genericPublicKey, _ := x509.MarshalPKIXPublicKey(privateKey.PublicKey)
+		genericPublicKey, _ := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
 		key.EncodedPrivateKey = base64.StdEncoding.EncodeToString(x509PrivateEncodedBlock)
 		key.EncodedPublicKey = base64.StdEncoding.EncodeToString(genericPublicKey)
 
@@ -123,12 +160,48 @@ func (kv *JwtKeyVaultService) WithEcdsaKey(id string, privateKey *ecdsa.PrivateK
 	return kv
 }
 
+// WithEd25519Key adds an Ed25519 key that signs with EdDSA, the tokens hashes use sha512 as the
+// algorithm does
+func (kv *JwtKeyVaultService) WithEd25519Key(id string, privateKey ed25519.PrivateKey) *JwtKeyVaultService {
+	kv.mutex.Lock()
+	defer kv.mutex.Unlock()
+
+	if !kv.keyExists(id) {
+		publicKey := privateKey.Public().(ed25519.PublicKey)
+		key := JwtKeyVaultItem{
+			ID:         id,
+			PrivateKey: privateKey,
+			PublicKey:  publicKey,
+		}
+		key.Size = encryption.Bit512
+
+		x509PrivateEncodedBlock, _ := x509.MarshalPKCS8PrivateKey(privateKey)
+		genericPublicKey, _ := x509.MarshalPKIXPublicKey(publicKey)
+		key.EncodedPrivateKey = base64.StdEncoding.EncodeToString(x509PrivateEncodedBlock)
+		key.EncodedPublicKey = base64.StdEncoding.EncodeToString(genericPublicKey)
+
+		key.JWK = jwk.New()
+		key.JWK.Add(id, privateKey)
+		key.Thumbprint = key.JWK.Keys[0].Thumbprint
+
+		if len(kv.Keys) == 0 {
+			key.IsDefault = true
+		}
+
+		kv.Keys = append(kv.Keys, &key)
+	}
+	return kv
+}
+
 func (kv *JwtKeyVaultService) WithBase64HmacKey(id string, privateKey string, size encryption.EncryptionKeySize) *JwtKeyVaultService {
 	private, _ := base64.StdEncoding.DecodeString(privateKey)
 	return kv.WithHmacKey(id, string(private), size)
 }
 
 func (kv *JwtKeyVaultService) WithHmacKey(id string, privateKey string, size encryption.EncryptionKeySize) *JwtKeyVaultService {
+	kv.mutex.Lock()
+	defer kv.mutex.Unlock()
+
 	if !kv.keyExists(id) {
 		key := JwtKeyVaultItem{
 			ID:         id,
@@ -150,6 +223,9 @@ func (kv *JwtKeyVaultService) WithHmacKey(id string, privateKey string, size enc
 }
 
 func (kv *JwtKeyVaultService) SetDefaultKey(id string) {
+	kv.mutex.Lock()
+	defer kv.mutex.Unlock()
+
 	if kv.keyExists(id) {
 		// Removing all defaults from other keys
 		for _, key := range kv.Keys {
@@ -165,7 +241,164 @@ func (kv *JwtKeyVaultService) SetDefaultKey(id string) {
 	}
 }
 
+// SetKeyAlgorithm changes the algorithm a rsa key signs with, rsa keys can sign with both the
+// PKCS#1 v1.5 (RS256 to RS512) and the PSS (PS256 to PS512) algorithms
+func (kv *JwtKeyVaultService) SetKeyAlgorithm(id string, algorithm string) error {
+	kv.mutex.Lock()
+	defer kv.mutex.Unlock()
+
+	key := kv.getKey(id)
+	if key == nil {
+		return fmt.Errorf("key %v was not found", id)
+	}
+	if _, ok := key.PrivateKey.(*rsa.PrivateKey); !ok {
+		return fmt.Errorf("key %v is not a rsa key", id)
+	}
+
+	switch algorithm {
+	case "RS256", "RS384", "RS512", "PS256", "PS384", "PS512":
+	default:
+		return fmt.Errorf("algorithm %v is not supported for rsa keys", algorithm)
+	}
+
+	key.Type = getKeyType(algorithm)
+	key.Size = key.Size.FromString(algorithm[2:])
+	if jsonWebKey := key.GetJsonWebKey(); jsonWebKey != nil {
+		jsonWebKey.Algorithm = algorithm
+	}
+
+	return nil
+}
+
+// RetireKey stops publishing the key in the jwks so the tokens it signed are no longer valid,
+// the default key cannot be retired
+func (kv *JwtKeyVaultService) RetireKey(id string) bool {
+	kv.mutex.Lock()
+	defer kv.mutex.Unlock()
+
+	key := kv.getKey(id)
+	if key == nil || key.IsDefault {
+		return false
+	}
+
+	key.Retired = true
+	return true
+}
+
+// GetVerificationKeys returns the keys that are not retired and can be published in the jwks
+func (kv *JwtKeyVaultService) GetVerificationKeys() []*JwtKeyVaultItem {
+	kv.mutex.RLock()
+	defer kv.mutex.RUnlock()
+
+	keys := make([]*JwtKeyVaultItem, 0)
+	for _, key := range kv.Keys {
+		if !key.Retired && key.GetJsonWebKey() != nil {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
 func (kv *JwtKeyVaultService) GetDefaultKey() *JwtKeyVaultItem {
+	kv.mutex.RLock()
+	defer kv.mutex.RUnlock()
+
+	return kv.getDefaultKey()
+}
+
+func (kv *JwtKeyVaultService) GetKey(id string) *JwtKeyVaultItem {
+	kv.mutex.RLock()
+	defer kv.mutex.RUnlock()
+
+	return kv.getKey(id)
+}
+
+// GetVerificationKey returns the key a token was signed with, retired keys are not returned so
+// the tokens they signed are rejected
+func (kv *JwtKeyVaultService) GetVerificationKey(id string) *JwtKeyVaultItem {
+	kv.mutex.RLock()
+	defer kv.mutex.RUnlock()
+
+	key := kv.getKey(id)
+	if key == nil || key.Retired {
+		return nil
+	}
+
+	return key
+}
+
+// SigningAlgorithms returns the jws algorithms of the keys that are not retired, the default key
+// algorithm first
+func (kv *JwtKeyVaultService) SigningAlgorithms() []string {
+	kv.mutex.RLock()
+	defer kv.mutex.RUnlock()
+
+	algorithms := make([]string, 0)
+	if defaultKey := kv.getDefaultKey(); defaultKey != nil {
+		algorithms = append(algorithms, defaultKey.Algorithm())
+	}
+
+	for _, key := range kv.Keys {
+		if key.Retired {
+			continue
+		}
+		algorithm := key.Algorithm()
+		exists := false
+		for _, value := range algorithms {
+			if value == algorithm {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			algorithms = append(algorithms, algorithm)
+		}
+	}
+
+	return algorithms
+}
+
+// GetJsonWebKey returns the public json web key of the rsa and ecdsa keys, hmac keys are secret
+// and have none
+func (item *JwtKeyVaultItem) GetJsonWebKey() *jwk.JsonWebKey {
+	if item.JWK == nil || len(item.JWK.Keys) == 0 {
+		return nil
+	}
+
+	return item.JWK.Keys[0]
+}
+
+// Algorithm returns the jws algorithm the key signs the tokens with
+func (item *JwtKeyVaultItem) Algorithm() string {
+	prefix := "HS"
+	switch item.PrivateKey.(type) {
+	case *ecdsa.PrivateKey:
+		prefix = "ES"
+	case *rsa.PrivateKey:
+		prefix = "RS"
+		if item.Type == encryption.PS256 || item.Type == encryption.PS384 || item.Type == encryption.PS512 {
+			prefix = "PS"
+		}
+	case ed25519.PrivateKey:
+		return "EdDSA"
+	}
+
+	return prefix + item.Size.String()
+}
+
+// getKeyType returns the encryption key of an algorithm, the common-go lookup has PS256 keyed
+// as P256 so it is mapped here
+func getKeyType(algorithm string) encryption.EncryptionKey {
+	if algorithm == "PS256" {
+		return encryption.PS256
+	}
+
+	var keyType encryption.EncryptionKey
+	return keyType.FromString(algorithm)
+}
+
+func (kv *JwtKeyVaultService) getDefaultKey() *JwtKeyVaultItem {
 	for _, key := range kv.Keys {
 		if key.IsDefault {
 			return key
@@ -175,7 +408,7 @@ func (kv *JwtKeyVaultService) GetDefaultKey() *JwtKeyVaultItem {
 	return nil
 }
 
-func (kv *JwtKeyVaultService) GetKey(id string) *JwtKeyVaultItem {
+func (kv *JwtKeyVaultService) getKey(id string) *JwtKeyVaultItem {
 	for _, key := range kv.Keys {
 		if strings.EqualFold(key.ID, id) {
 			return key
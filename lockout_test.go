@@ -0,0 +1,99 @@
+package identity
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/cjlapao/common-go-identity/authorization_context"
+	"github.com/cjlapao/common-go-identity/models"
+	"github.com/cjlapao/common-go-identity/password_hasher"
+)
+
+// the lockout tests use a dedicated user so locking it does not affect the other tests
+const (
+	testLockoutUserId   = "lockout-test-user"
+	testLockoutUsername = testLockoutUserId + "@localhost.com"
+	testLockoutPassword = "l0ck0ut-p@ssw0rd"
+)
+
+func TestUserLockout(t *testing.T) {
+	addTestUser(t, testLockoutUserId, testLockoutPassword)
+	authCtx := authorization_context.GetBaseContext()
+	attemptsToBlock := authCtx.ValidationOptions.AttemptsToBlock
+	if attemptsToBlock <= 0 {
+		t.Fatalf("expected the lockout to be enabled by default")
+	}
+
+	// a successful login resets the failed attempts counter
+	for i := 0; i < attemptsToBlock-1; i++ {
+		passwordLogin(t, testLockoutUsername, "wrong-password", nil)
+	}
+	if status, body := passwordLogin(t, testLockoutUsername, testLockoutPassword, nil); status != http.StatusOK {
+		t.Fatalf("expected status %v, got %v %v", http.StatusOK, status, body)
+	}
+
+	for i := 0; i < attemptsToBlock; i++ {
+		if status, _ := passwordLogin(t, testLockoutUsername, "wrong-password", nil); status == http.StatusOK {
+			t.Fatalf("expected the login with a wrong password to fail")
+		}
+	}
+
+	status, body := passwordLogin(t, testLockoutUsername, testLockoutPassword, nil)
+	if status == http.StatusOK || body["error"] != models.OAuthUserBlocked.String() {
+		t.Fatalf("expected the locked user to be rejected, got %v %v", status, body)
+	}
+
+	// once the lock expires the user can log in again
+	expired := "2000-01-01T00:00:00Z"
+	authCtx.UserDatabaseAdapter.UpdateUserInvalidAttempts(testLockoutUserId, 0, expired)
+	if status, body := passwordLogin(t, testLockoutUsername, testLockoutPassword, nil); status != http.StatusOK {
+		t.Fatalf("expected the user to be unlocked, got %v %v", status, body)
+	}
+}
+
+func TestUserLockoutParallelAttempts(t *testing.T) {
+	addTestUser(t, testLockoutUserId, testLockoutPassword)
+	server := getTestServer(t)
+	authCtx := authorization_context.GetBaseContext()
+	authCtx.UserDatabaseAdapter.UpdateUserInvalidAttempts(testLockoutUserId, 0, "")
+
+	// verifying an argon2id hash is slow enough for the guesses to overlap
+	hash, err := password_hasher.Hash(testLockoutPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authCtx.UserDatabaseAdapter.UpdateUserPassword(testLockoutUserId, hash)
+
+	// concurrent guesses are all counted so they cannot get around the lockout
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < authCtx.ValidationOptions.AttemptsToBlock; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			response, err := newTestClient().PostForm(server.URL+"/auth/token", url.Values{
+				"grant_type": {"password"},
+				"client_id":  {"spa"},
+				"username":   {testLockoutUsername},
+				"password":   {"wrong-password"},
+			})
+			if err != nil {
+				t.Errorf("token request failed, %v", err)
+				return
+			}
+			response.Body.Close()
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	status, body := passwordLogin(t, testLockoutUsername, testLockoutPassword, nil)
+	if status == http.StatusOK || body["error"] != models.OAuthUserBlocked.String() {
+		t.Fatalf("expected the parallel attempts to lock the user, got %v %v", status, body)
+	}
+
+	authCtx.UserDatabaseAdapter.UpdateUserInvalidAttempts(testLockoutUserId, 0, "")
+}
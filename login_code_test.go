@@ -0,0 +1,204 @@
+package identity
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/cjlapao/common-go-identity/authorization_context"
+	"github.com/cjlapao/common-go-identity/database/dto"
+	"github.com/cjlapao/common-go-identity/models"
+)
+
+const (
+	testLoginCodeUserId   = "login-code-test-user"
+	testLoginCodeUsername = testLoginCodeUserId + "@localhost.com"
+	testLoginLinkUserId   = "login-link-test-user"
+	testLoginLinkUsername = testLoginLinkUserId + "@localhost.com"
+	testCodeGuessUserId   = "login-code-guess-user"
+	testCodeGuessUsername = testCodeGuessUserId + "@localhost.com"
+	testCodeStateUserId   = "login-code-state-user"
+	testCodeStateUsername = testCodeStateUserId + "@localhost.com"
+)
+
+// requestLoginCode asks for a login code and returns the one delivered to the notification callback
+func requestLoginCode(t *testing.T, username string) string {
+	t.Helper()
+	var code string
+	authorization_context.GetBaseContext().NotificationCallback = func(notification models.OAuthNotification) error {
+		if notification.Type == models.LoginCodeRequest && notification.Success() {
+			code = notification.Data.(models.OAuthLoginCodeNotification).LoginCode
+		}
+		return nil
+	}
+	defer func() {
+		authorization_context.GetBaseContext().NotificationCallback = nil
+	}()
+
+	request := models.OAuthLoginCodeRequest{Username: username, ClientID: "spa"}
+	if status := sendJsonRequest(t, http.MethodPost, "login/code", "", request, nil); status != http.StatusAccepted {
+		t.Fatalf("expected status %v, got %v", http.StatusAccepted, status)
+	}
+
+	return code
+}
+
+func loginWithCode(t *testing.T, username string, code string) (int, map[string]interface{}) {
+	t.Helper()
+	values := url.Values{
+		"grant_type": {"login_code"},
+		"client_id":  {"spa"},
+		"login_code": {code},
+	}
+	if username != "" {
+		values.Set("username", username)
+	}
+
+	return requestToken(t, values)
+}
+
+func TestLoginCode(t *testing.T) {
+	addTestUser(t, testLoginCodeUserId, testMfaPassword)
+
+	if code := requestLoginCode(t, "unknown@localhost.com"); code != "" {
+		t.Fatalf("expected no code for an unknown user, got %v", code)
+	}
+
+	code := requestLoginCode(t, testLoginCodeUsername)
+	if len(code) != 6 {
+		t.Fatalf("expected a six digit code, got %v", code)
+	}
+
+	wrongCode := "000000"
+	if code == wrongCode {
+		wrongCode = "111111"
+	}
+	if status, body := loginWithCode(t, testLoginCodeUsername, wrongCode); status != http.StatusBadRequest || body["error"] != "invalid_grant" {
+		t.Fatalf("expected the wrong code to be rejected, got %v %v", status, body)
+	}
+	status, body := requestToken(t, url.Values{
+		"grant_type": {"login_code"},
+		"client_id":  {"unregistered-app"},
+		"username":   {testLoginCodeUsername},
+		"login_code": {code},
+	})
+	if status != http.StatusUnauthorized || body["error"] != "invalid_client" {
+		t.Fatalf("expected an unregistered client to be rejected, got %v %v", status, body)
+	}
+
+	status, body = loginWithCode(t, testLoginCodeUsername, code)
+	if status != http.StatusOK || body["access_token"] == nil || body["refresh_token"] == nil {
+		t.Fatalf("expected the code to login, got %v %v", status, body)
+	}
+
+	if status, _ := loginWithCode(t, testLoginCodeUsername, code); status != http.StatusBadRequest {
+		t.Fatalf("expected the code to be single use, got %v", status)
+	}
+
+	// the code is discarded once it runs out of attempts
+	code = requestLoginCode(t, testLoginCodeUsername)
+	wrongCode = "000000"
+	if code == wrongCode {
+		wrongCode = "111111"
+	}
+	for i := 0; i < authorization_context.GetBaseContext().Options.LoginCodeMaxAttempts; i++ {
+		loginWithCode(t, testLoginCodeUsername, wrongCode)
+	}
+	if status, _ := loginWithCode(t, testLoginCodeUsername, code); status != http.StatusBadRequest {
+		t.Fatalf("expected the code to be discarded after the attempts, got %v", status)
+	}
+}
+
+func TestLoginLink(t *testing.T) {
+	addTestUser(t, testLoginLinkUserId, testMfaPassword)
+	options := authorization_context.GetBaseContext().Options
+	processor := options.EmailVerificationProcessor
+	interval := options.LoginCodeRequestInterval
+	options.EmailVerificationProcessor = "jwt"
+	options.LoginCodeRequestInterval = 0
+	defer func() {
+		options.EmailVerificationProcessor = processor
+		options.LoginCodeRequestInterval = interval
+	}()
+
+	token := requestLoginCode(t, testLoginLinkUsername)
+	if token == "" {
+		t.Fatal("expected a login link token")
+	}
+
+	// an older link stops working once a new one is requested
+	newToken := requestLoginCode(t, testLoginLinkUsername)
+	if status, _ := loginWithCode(t, "", token); status != http.StatusBadRequest {
+		t.Fatalf("expected the replaced link to be rejected, got %v", status)
+	}
+
+	status, body := loginWithCode(t, "", newToken)
+	if status != http.StatusOK || body["access_token"] == nil {
+		t.Fatalf("expected the link to login, got %v %v", status, body)
+	}
+
+	if status, _ := loginWithCode(t, "", newToken); status != http.StatusBadRequest {
+		t.Fatalf("expected the link to be single use, got %v", status)
+	}
+}
+
+func TestLoginCodeGuessing(t *testing.T) {
+	addTestUser(t, testCodeGuessUserId, testMfaPassword)
+	options := authorization_context.GetBaseContext().Options
+	interval := options.LoginCodeRequestInterval
+	defer func() {
+		options.LoginCodeRequestInterval = interval
+	}()
+
+	// a pending code cannot be replaced before the interval passes
+	code := requestLoginCode(t, testCodeGuessUsername)
+	if code == "" {
+		t.Fatal("expected a login code")
+	}
+	if newCode := requestLoginCode(t, testCodeGuessUsername); newCode != "" {
+		t.Fatalf("expected the code request to be rate limited, got %v", newCode)
+	}
+
+	// the attempts are kept for the user so a new code does not give more guesses
+	options.LoginCodeRequestInterval = 0
+	wrongCode := "000000"
+	if code == wrongCode {
+		wrongCode = "111111"
+	}
+	for i := 0; i < options.LoginCodeMaxAttempts-1; i++ {
+		loginWithCode(t, testCodeGuessUsername, wrongCode)
+	}
+	code = requestLoginCode(t, testCodeGuessUsername)
+	if code == wrongCode {
+		wrongCode = "222222"
+	}
+	loginWithCode(t, testCodeGuessUsername, wrongCode)
+	if status, _ := loginWithCode(t, testCodeGuessUsername, code); status != http.StatusBadRequest {
+		t.Fatalf("expected the new code to have no attempts left, got %v", status)
+	}
+
+	// guessing codes never locks the user out of the other logins
+	if status, body := passwordLogin(t, testCodeGuessUsername, testMfaPassword, nil); status != http.StatusOK {
+		t.Fatalf("expected the password login to succeed, got %v %v", status, body)
+	}
+}
+
+func TestLoginCodeUserState(t *testing.T) {
+	addTestUser(t, testCodeStateUserId, testMfaPassword)
+	authCtx := authorization_context.GetBaseContext()
+
+	// the code proves who the user is but the user still needs to be allowed to login
+	authCtx.ValidationOptions.VerifiedEmail = true
+	updateTestUser(t, testCodeStateUserId, func(user *dto.UserDTO) { user.EmailVerified = false })
+	status, body := loginWithCode(t, testCodeStateUsername, requestLoginCode(t, testCodeStateUsername))
+	authCtx.ValidationOptions.VerifiedEmail = false
+	if status != http.StatusBadRequest || body["error"] != models.OAuthEmailNotVerified.String() {
+		t.Fatalf("expected an unverified email to be rejected, got %v %v", status, body)
+	}
+
+	code := requestLoginCode(t, testCodeStateUsername)
+	updateTestUser(t, testCodeStateUserId, func(user *dto.UserDTO) { user.Blocked = true })
+	if status, body := loginWithCode(t, testCodeStateUsername, code); status != http.StatusBadRequest || body["error"] != models.OAuthUserBlocked.String() {
+		t.Fatalf("expected a blocked user to be rejected, got %v %v", status, body)
+	}
+}
@@ -8,7 +8,10 @@ import (
 	"github.com/cjlapao/common-go-identity/controllers"
 	"github.com/cjlapao/common-go-identity/database/memory"
 	"github.com/cjlapao/common-go-identity/interfaces"
+	"github.com/cjlapao/common-go-identity/jwt_keyvault"
 	"github.com/cjlapao/common-go-identity/middleware"
+	"github.com/cjlapao/common-go-identity/password_hasher"
+	"github.com/cjlapao/common-go-identity/trusted_issuers"
 	restapi "github.com/cjlapao/common-go-restapi"
 	restapi_controller "github.com/cjlapao/common-go-restapi/controllers"
 	"github.com/cjlapao/common-go/helper/http_helper"
@@ -19,9 +22,6 @@ import (
 // var httpListener *restapi.HttpListener
 
 //TODO: Create API_KEY authorization
-//TODO: Get jwt public key using openid configuration
-//TODO: Cache the openid configuration for tokens based in the subject
-//TODO: repurpose the JWT Keyvault as a generic key vault to keep secrets
 //TODO: Make all errors variables for reusability purpose
 //TODO: Move all log.error to log.exception for a cleaner implementation
 
@@ -51,17 +51,147 @@ func WithInMemoryApiKeyAuthentication(l *restapi.HttpListener) *restapi.HttpList
 	return l
 }
 
+func WithAuthorizationCodeContext(l *restapi.HttpListener, context interfaces.AuthorizationCodeContextAdapter) *restapi.HttpListener {
+	authCtx := authorization_context.GetBaseContext()
+	if authCtx != nil {
+		authorization_context.SetAuthorizationCodeContext(context)
+	} else {
+		l.Logger.Error("No authorization context found, ignoring authorization code context")
+	}
+	return l
+}
+
+func WithClientContext(l *restapi.HttpListener, context interfaces.ClientContextAdapter) *restapi.HttpListener {
+	authCtx := authorization_context.GetBaseContext()
+	if authCtx != nil {
+		authorization_context.SetClientContext(context)
+	} else {
+		l.Logger.Error("No authorization context found, ignoring client context")
+	}
+	return l
+}
+
+func WithRefreshTokenContext(l *restapi.HttpListener, context interfaces.RefreshTokenContextAdapter) *restapi.HttpListener {
+	authCtx := authorization_context.GetBaseContext()
+	if authCtx != nil {
+		authorization_context.SetRefreshTokenContext(context)
+	} else {
+		l.Logger.Error("No authorization context found, ignoring refresh token context")
+	}
+	return l
+}
+
+func WithRevocationContext(l *restapi.HttpListener, context interfaces.RevocationContextAdapter) *restapi.HttpListener {
+	authCtx := authorization_context.GetBaseContext()
+	if authCtx != nil {
+		authorization_context.SetRevocationContext(context)
+	} else {
+		l.Logger.Error("No authorization context found, ignoring revocation context")
+	}
+	return l
+}
+
+func WithOpaqueTokenContext(l *restapi.HttpListener, context interfaces.OpaqueTokenContextAdapter) *restapi.HttpListener {
+	authCtx := authorization_context.GetBaseContext()
+	if authCtx != nil {
+		authorization_context.SetOpaqueTokenContext(context)
+	} else {
+		l.Logger.Error("No authorization context found, ignoring opaque token context")
+	}
+	return l
+}
+
+// WithKeyStore loads the signing keys kept in the store into the key vault, the keys are
+// encrypted with the configured encryption key
+func WithKeyStore(l *restapi.HttpListener, store interfaces.KeyStoreAdapter) *restapi.HttpListener {
+	authCtx := authorization_context.GetBaseContext()
+	if authCtx != nil {
+		if err := authCtx.KeyVault.LoadKeys(store, authCtx.Options.EncryptionKey); err != nil {
+			l.Logger.Exception(err, "There was an error loading the signing keys")
+		}
+	} else {
+		l.Logger.Error("No authorization context found, ignoring key store")
+	}
+	return l
+}
+
+// WithKeyRotation generates the signing keys on the configured interval, the keys are kept in
+// the store so all the replicas using it sign with the same key
+func WithKeyRotation(l *restapi.HttpListener, store interfaces.KeyStoreAdapter) *restapi.HttpListener {
+	authCtx := authorization_context.GetBaseContext()
+	if authCtx != nil {
+		rotation := jwt_keyvault.NewKeyRotation(authCtx.KeyVault, store, authCtx.GetKeyRotationOptions())
+		if err := rotation.Start(); err != nil {
+			l.Logger.Exception(err, "There was an error starting the key rotation")
+		}
+	} else {
+		l.Logger.Error("No authorization context found, ignoring key rotation")
+	}
+	return l
+}
+
+// WithTrustedIssuer validates the tokens of an external issuer with the keys published in its
+// OpenID Connect discovery document
+func WithTrustedIssuer(l *restapi.HttpListener, issuer trusted_issuers.TrustedIssuer) *restapi.HttpListener {
+	authCtx := authorization_context.GetBaseContext()
+	if authCtx != nil {
+		authCtx.WithTrustedIssuer(issuer)
+	} else {
+		l.Logger.Error("No authorization context found, ignoring trusted issuer")
+	}
+	return l
+}
+
+// WithTokenExchangePolicy allows a client to exchange the tokens of the users for tokens of the
+// audiences of the policy with the token exchange grant
+func WithTokenExchangePolicy(l *restapi.HttpListener, policy authorization_context.TokenExchangePolicy) *restapi.HttpListener {
+	authCtx := authorization_context.GetBaseContext()
+	if authCtx != nil {
+		authCtx.WithTokenExchangePolicy(policy)
+	} else {
+		l.Logger.Error("No authorization context found, ignoring token exchange policy")
+	}
+	return l
+}
+
+// WithPasswordHasher changes the hasher used for new passwords, existing hashes
+// are upgraded to it the next time each user logs in
+func WithPasswordHasher(l *restapi.HttpListener, hasher password_hasher.PasswordHasher) *restapi.HttpListener {
+	password_hasher.SetDefault(hasher)
+	return l
+}
+
 func WithAuthentication(l *restapi.HttpListener, context interfaces.UserContextAdapter) *restapi.HttpListener {
 	// httpListener = l
 	authCtx := authorization_context.GetBaseContext()
 	if authCtx != nil {
 		defaultAuthControllers := controllers.NewAuthorizationControllers(context)
+		defaultAuthControllers.Router = l.Router
+		if authCtx.AuthorizationCodeDatabaseAdapter == nil {
+			authorization_context.SetAuthorizationCodeContext(memory.NewMemoryAuthorizationCodeAdapter())
+		}
+		if authCtx.ClientDatabaseAdapter == nil {
+			authorization_context.SetClientContext(memory.NewMemoryClientAdapter())
+		}
+		if authCtx.RefreshTokenDatabaseAdapter == nil {
+			authorization_context.SetRefreshTokenContext(memory.NewMemoryRefreshTokenAdapter())
+		}
+		if authCtx.RevocationDatabaseAdapter == nil {
+			authorization_context.SetRevocationContext(memory.NewMemoryRevocationAdapter())
+		}
+		if authCtx.OpaqueTokenDatabaseAdapter == nil {
+			authorization_context.SetOpaqueTokenContext(memory.NewMemoryOpaqueTokenAdapter())
+		}
 
 		l.AddController(defaultAuthControllers.OtpForEmailValidation(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "otp"), "GET")
 
 		l.AddController(defaultAuthControllers.Token(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "token"), "POST")
 		l.AddController(defaultAuthControllers.Token(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "{tenantId}", "token"), "POST")
 
+		// Authorization Code
+		l.AddController(defaultAuthControllers.Authorize(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "authorize"), "GET", "POST")
+		l.AddController(defaultAuthControllers.Authorize(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "{tenantId}", "authorize"), "GET", "POST")
+
 		// Password Recovery
 		l.AddController(defaultAuthControllers.RecoverPasswordRequest(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "users", "{userID}", "password", "recover", "request"), "POST")
 		l.AddController(defaultAuthControllers.RecoverPasswordRequest(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "{tenantId}", "users", "{userID}", "password", "recover", "request"), "POST")
@@ -78,6 +208,32 @@ func WithAuthentication(l *restapi.HttpListener, context interfaces.UserContextA
 		AddAuthorizedController(l, defaultAuthControllers.ChangePassword(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "users", "{userID}", "password", "change"), "POST")
 		AddAuthorizedController(l, defaultAuthControllers.ChangePassword(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "{tenantId}", "users", "{userID}", "password", "change"), "POST")
 
+		// Multi-factor Authentication
+		AddAuthorizedController(l, defaultAuthControllers.EnrollTotp(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "mfa", "totp"), "POST")
+		AddAuthorizedController(l, defaultAuthControllers.EnrollTotp(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "{tenantId}", "mfa", "totp"), "POST")
+		AddAuthorizedController(l, defaultAuthControllers.ConfirmTotp(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "mfa", "totp", "confirm"), "POST")
+		AddAuthorizedController(l, defaultAuthControllers.ConfirmTotp(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "{tenantId}", "mfa", "totp", "confirm"), "POST")
+		AddAuthorizedController(l, defaultAuthControllers.DisableTotp(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "mfa", "totp"), "DELETE")
+		AddAuthorizedController(l, defaultAuthControllers.DisableTotp(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "{tenantId}", "mfa", "totp"), "DELETE")
+		AddAuthorizedController(l, defaultAuthControllers.GetRecoveryCodes(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "mfa", "recovery-codes"), "GET")
+		AddAuthorizedController(l, defaultAuthControllers.GetRecoveryCodes(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "{tenantId}", "mfa", "recovery-codes"), "GET")
+		AddAuthorizedController(l, defaultAuthControllers.RegenerateRecoveryCodes(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "mfa", "recovery-codes"), "POST")
+		AddAuthorizedController(l, defaultAuthControllers.RegenerateRecoveryCodes(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "{tenantId}", "mfa", "recovery-codes"), "POST")
+
+		// WebAuthn
+		AddAuthorizedController(l, defaultAuthControllers.BeginWebAuthnRegistration(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "webauthn", "register", "begin"), "POST")
+		AddAuthorizedController(l, defaultAuthControllers.BeginWebAuthnRegistration(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "{tenantId}", "webauthn", "register", "begin"), "POST")
+		AddAuthorizedController(l, defaultAuthControllers.FinishWebAuthnRegistration(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "webauthn", "register", "finish"), "POST")
+		AddAuthorizedController(l, defaultAuthControllers.FinishWebAuthnRegistration(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "{tenantId}", "webauthn", "register", "finish"), "POST")
+		AddAuthorizedController(l, defaultAuthControllers.GetWebAuthnCredentials(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "webauthn", "credentials"), "GET")
+		AddAuthorizedController(l, defaultAuthControllers.GetWebAuthnCredentials(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "{tenantId}", "webauthn", "credentials"), "GET")
+		AddAuthorizedController(l, defaultAuthControllers.RemoveWebAuthnCredential(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "webauthn", "credentials", "{credentialId}"), "DELETE")
+		AddAuthorizedController(l, defaultAuthControllers.RemoveWebAuthnCredential(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "{tenantId}", "webauthn", "credentials", "{credentialId}"), "DELETE")
+		l.AddController(defaultAuthControllers.BeginWebAuthnLogin(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "webauthn", "login", "begin"), "POST")
+		l.AddController(defaultAuthControllers.BeginWebAuthnLogin(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "{tenantId}", "webauthn", "login", "begin"), "POST")
+		l.AddController(defaultAuthControllers.RequestLoginCode(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "login", "code"), "POST")
+		l.AddController(defaultAuthControllers.RequestLoginCode(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "{tenantId}", "login", "code"), "POST")
+
 		// Email Verification
 		l.AddController(defaultAuthControllers.EmailVerificationRequest(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "users", "{userID}", "email", "request"), "POST")
 		l.AddController(defaultAuthControllers.EmailVerificationRequest(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "users", "email", "request"), "POST")
@@ -88,8 +244,12 @@ func WithAuthentication(l *restapi.HttpListener, context interfaces.UserContextA
 		l.AddController(defaultAuthControllers.VerifyEmail(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "{tenantId}", "users", "{userID}", "email", "verify"), "POST")
 		l.AddController(defaultAuthControllers.VerifyEmail(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "{tenantId}", "users", "email", "verify"), "POST")
 
-		l.AddController(defaultAuthControllers.Introspection(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "token", "introspect"), "POST")
-		l.AddController(defaultAuthControllers.Introspection(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "{tenantId}", "token", "introspect"), "POST")
+		// OpenID Connect UserInfo
+		AddAuthorizedController(l, defaultAuthControllers.UserInfo(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "userinfo"), "GET", "POST")
+		AddAuthorizedController(l, defaultAuthControllers.UserInfo(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "{tenantId}", "userinfo"), "GET", "POST")
+
+		addApiKeyAuthenticatedController(l, defaultAuthControllers.Introspection(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "token", "introspect"), "POST")
+		addApiKeyAuthenticatedController(l, defaultAuthControllers.Introspection(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "{tenantId}", "token", "introspect"), "POST")
 		if l.Options.PublicRegistration {
 			l.AddController(defaultAuthControllers.Register(true), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "register"), "POST")
 			l.AddController(defaultAuthControllers.Register(true), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "{tenantId}", "register"), "POST")
@@ -98,11 +258,34 @@ func WithAuthentication(l *restapi.HttpListener, context interfaces.UserContextA
 			AddAuthorizedControllerWithRoles(l, defaultAuthControllers.Register(false), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "{tenantId}", "register"), []string{"_su,_admin"}, "POST")
 		}
 
-		AddAuthorizedControllerWithRoles(l, defaultAuthControllers.Revoke(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "revoke"), []string{"_su,_admin"}, "POST")
-		AddAuthorizedControllerWithRoles(l, defaultAuthControllers.Revoke(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "{tenantId}", "revoke"), []string{"_su,_admin"}, "POST")
+		// Client Registration
+		registrationAdapters := []restapi_controller.Adapter{middleware.InitialAccessTokenAuthorizationMiddlewareAdapter()}
+		addAuthorizedControllerWithAdapters(l, defaultAuthControllers.RegisterClient(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "register", "clients"), []string{"_su,_admin"}, []string{}, registrationAdapters, "POST")
+		addAuthorizedControllerWithAdapters(l, defaultAuthControllers.RegisterClient(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "{tenantId}", "register", "clients"), []string{"_su,_admin"}, []string{}, registrationAdapters, "POST")
+		managementAdapters := []restapi_controller.Adapter{middleware.RegistrationAccessTokenAuthorizationMiddlewareAdapter()}
+		addAuthorizedControllerWithAdapters(l, defaultAuthControllers.GetRegisteredClient(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "register", "clients", "{clientId}"), []string{"_su,_admin"}, []string{}, managementAdapters, "GET")
+		addAuthorizedControllerWithAdapters(l, defaultAuthControllers.GetRegisteredClient(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "{tenantId}", "register", "clients", "{clientId}"), []string{"_su,_admin"}, []string{}, managementAdapters, "GET")
+		addAuthorizedControllerWithAdapters(l, defaultAuthControllers.UpdateRegisteredClient(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "register", "clients", "{clientId}"), []string{"_su,_admin"}, []string{}, managementAdapters, "PUT")
+		addAuthorizedControllerWithAdapters(l, defaultAuthControllers.UpdateRegisteredClient(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "{tenantId}", "register", "clients", "{clientId}"), []string{"_su,_admin"}, []string{}, managementAdapters, "PUT")
+		addAuthorizedControllerWithAdapters(l, defaultAuthControllers.RemoveRegisteredClient(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "register", "clients", "{clientId}"), []string{"_su,_admin"}, []string{}, managementAdapters, "DELETE")
+		addAuthorizedControllerWithAdapters(l, defaultAuthControllers.RemoveRegisteredClient(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "{tenantId}", "register", "clients", "{clientId}"), []string{"_su,_admin"}, []string{}, managementAdapters, "DELETE")
+
+		// Token Revocation
+		l.AddController(defaultAuthControllers.Revoke(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "revoke"), "POST")
+		l.AddController(defaultAuthControllers.Revoke(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "{tenantId}", "revoke"), "POST")
+
+		// User Administration
+		AddAuthorizedControllerWithRoles(l, defaultAuthControllers.RemoveUser(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "users", "{userID}"), []string{"_su,_admin"}, "DELETE")
+		AddAuthorizedControllerWithRoles(l, defaultAuthControllers.RemoveUser(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "{tenantId}", "users", "{userID}"), []string{"_su,_admin"}, "DELETE")
+		AddAuthorizedControllerWithRoles(l, defaultAuthControllers.RevokeUserSessions(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "users", "{userID}", "sessions"), []string{"_su,_admin"}, "DELETE")
+		AddAuthorizedControllerWithRoles(l, defaultAuthControllers.RevokeUserSessions(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "{tenantId}", "users", "{userID}", "sessions"), []string{"_su,_admin"}, "DELETE")
 
 		l.AddController(defaultAuthControllers.Configuration(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, ".well-known", "openid-configuration"), "GET")
 		l.AddController(defaultAuthControllers.Configuration(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "{tenantId}", ".well-known", "openid-configuration"), "GET")
+		l.AddController(defaultAuthControllers.AuthorizationServerMetadata(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, ".well-known", "oauth-authorization-server"), "GET")
+		l.AddController(defaultAuthControllers.AuthorizationServerMetadata(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "{tenantId}", ".well-known", "oauth-authorization-server"), "GET")
+		l.AddController(defaultAuthControllers.AuthorizationServerMetadata(), http_helper.JoinUrl(".well-known", "oauth-authorization-server", authCtx.Options.ControllerPrefix), "GET")
+		l.AddController(defaultAuthControllers.AuthorizationServerMetadata(), http_helper.JoinUrl(".well-known", "oauth-authorization-server", authCtx.Options.ControllerPrefix, "{tenantId}"), "GET")
 		l.AddController(defaultAuthControllers.Jwks(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, ".well-known", "openid-configuration", "jwks"), "GET")
 		l.AddController(defaultAuthControllers.Jwks(), http_helper.JoinUrl(authCtx.Options.ControllerPrefix, "{tenantId}", ".well-known", "openid-configuration", "jwks"), "GET")
 
@@ -151,6 +334,13 @@ func AddAuthorizedControllerWithClaims(l *restapi.HttpListener, c restapi_contro
 }
 
 func AddAuthorizedControllerWithRolesAndClaims(l *restapi.HttpListener, c restapi_controller.Controller, path string, roles []string, claims []string, methods ...string) {
+	addAuthorizedControllerWithAdapters(l, c, path, roles, claims, []restapi_controller.Adapter{}, methods...)
+}
+
+// addAuthorizedControllerWithAdapters adds a controller protected by the token and api key
+// authorization layers, the extra adapters run after them and can authorize the request
+// using other credentials
+func addAuthorizedControllerWithAdapters(l *restapi.HttpListener, c restapi_controller.Controller, path string, roles []string, claims []string, extraAdapters []restapi_controller.Adapter, methods ...string) {
 	l.Controllers = append(l.Controllers, c)
 	var subRouter *mux.Router
 	if len(methods) > 0 {
@@ -166,6 +356,7 @@ func AddAuthorizedControllerWithRolesAndClaims(l *restapi.HttpListener, c restap
 	if authCtx != nil && authCtx.ApiKeyManager != nil && authCtx.ApiKeyManager.IsEnabled() {
 		adapters = append(adapters, middleware.ApiKeyAuthorizationMiddlewareAdapter(roles, claims))
 	}
+	adapters = append(adapters, extraAdapters...)
 	adapters = append(adapters, middleware.EndAuthorizationMiddlewareAdapter())
 
 	if l.Options.ApiPrefix != "" {
@@ -177,3 +368,31 @@ func AddAuthorizedControllerWithRolesAndClaims(l *restapi.HttpListener, c restap
 			http.HandlerFunc(c),
 			adapters...).ServeHTTP)
 }
+
+// addApiKeyAuthenticatedController adds a controller that can be called with an api key, the
+// requests without one are not rejected as the controller authenticates them itself
+func addApiKeyAuthenticatedController(l *restapi.HttpListener, c restapi_controller.Controller, path string, methods ...string) {
+	l.Controllers = append(l.Controllers, c)
+	var subRouter *mux.Router
+	if len(methods) > 0 {
+		subRouter = l.Router.Methods(methods...).Subrouter()
+	} else {
+		subRouter = l.Router.Methods("GET").Subrouter()
+	}
+	adapters := make([]restapi_controller.Adapter, 0)
+	adapters = append(adapters, l.DefaultAdapters...)
+	adapters = append(adapters, middleware.AddAuthorizationContextMiddlewareAdapter())
+	authCtx := authorization_context.GetBaseContext()
+	if authCtx != nil && authCtx.ApiKeyManager != nil && authCtx.ApiKeyManager.IsEnabled() {
+		adapters = append(adapters, middleware.ApiKeyAuthorizationMiddlewareAdapter([]string{}, []string{}))
+	}
+
+	if l.Options.ApiPrefix != "" {
+		path = http_helper.JoinUrl(l.Options.ApiPrefix, path)
+	}
+
+	subRouter.HandleFunc(path,
+		restapi_controller.Adapt(
+			http.HandlerFunc(c),
+			adapters...).ServeHTTP)
+}
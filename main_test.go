@@ -0,0 +1,133 @@
+package identity
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/cjlapao/common-go-identity/authorization_context"
+	"github.com/cjlapao/common-go-identity/database/dto"
+	"github.com/cjlapao/common-go-identity/mappers"
+	"github.com/cjlapao/common-go-identity/models"
+	restapi "github.com/cjlapao/common-go-restapi"
+	"github.com/cjlapao/common-go/security"
+	"github.com/cjlapao/common-go/security/encryption"
+)
+
+const (
+	testRedirectUri   = "https://app.example.com/callback"
+	testLoginUrl      = "https://login.example.com/login"
+	testAdminUsername = "admin@localhost.com"
+	testAdminPassword = "p@ssw0rd"
+
+	testRegularUserPassword = "r3gul@r-p@ssw0rd"
+
+	testInitialAccessToken = "initial-access-token-used-only-by-the-tests"
+)
+
+var (
+	testServerOnce sync.Once
+	testServer     *httptest.Server
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if testServer != nil {
+		testServer.Close()
+	}
+
+	os.Exit(code)
+}
+
+// getTestServer starts the identity routes with the default in memory adapters, the
+// listener and the authorization context are singletons so this is only done once
+func getTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	testServerOnce.Do(func() {
+		authCtx := authorization_context.WithDefaultAuthorization()
+		authCtx.KeyVault.WithHmacKey("test", "a-very-secret-key-used-only-by-the-tests", encryption.Bit256)
+		authCtx.Options.KeyVaultEnabled = true
+		authCtx.Options.AllowedRedirectUris = []string{testRedirectUri}
+		authCtx.Options.LoginUrl = testLoginUrl
+		authCtx.Options.InitialAccessToken = testInitialAccessToken
+		authCtx.Options.SelfRegistrationGrantTypes = []string{"authorization_code", "client_credentials"}
+		authCtx.Options.SelfRegistrationScopes = []string{"orders.read", "orders.write"}
+
+		listener := restapi.GetHttpListener()
+		WithDefaultAuthentication(listener)
+
+		// the public clients the users of the tests log in with
+		for _, clientId := range []string{"spa", "another-app"} {
+			client := models.NewClient()
+			client.ID = clientId
+			client.TokenEndpointAuthMethod = models.NoneAuthMethod
+			client.GrantTypes = []string{models.OAuthAuthorizationCodeGrant.String()}
+			authCtx.ClientDatabaseAdapter.UpsertClient(mappers.ToClientDTO(*client))
+		}
+		testServer = httptest.NewServer(listener.Router)
+	})
+
+	return testServer
+}
+
+// addTestUser adds a verified user with the id@localhost.com username so the tests that
+// change a user do not affect the admin user the other tests log in with
+func addTestUser(t *testing.T, id string, password string, roles ...models.UserRole) {
+	t.Helper()
+	getTestServer(t)
+	err := authorization_context.GetBaseContext().UserDatabaseAdapter.UpsertUser(dto.UserDTO{
+		ID:            id,
+		Email:         id + "@localhost.com",
+		EmailVerified: true,
+		Username:      id + "@localhost.com",
+		Password:      security.SHA256Encode(password),
+		Roles:         mappers.ToUserRolesDTO(roles),
+	})
+	if err != nil {
+		t.Fatalf("failed to add the test user %v, %v", id, err)
+	}
+}
+
+// updateTestUser changes the stored test user, the grants read the user from the store
+func updateTestUser(t *testing.T, id string, update func(user *dto.UserDTO)) {
+	t.Helper()
+	adapter := authorization_context.GetBaseContext().UserDatabaseAdapter
+	user := adapter.GetUserById(id)
+	if user == nil {
+		t.Fatalf("test user %v was not found", id)
+	}
+	update(user)
+	if err := adapter.UpsertUser(*user); err != nil {
+		t.Fatalf("failed to update the test user %v, %v", id, err)
+	}
+}
+
+// passwordLogin requests a token with the password grant for the spa client, the extra
+// values are added to the form
+func passwordLogin(t *testing.T, username string, password string, extra url.Values) (int, map[string]interface{}) {
+	t.Helper()
+	form := url.Values{
+		"grant_type": {"password"},
+		"client_id":  {"spa"},
+		"username":   {username},
+		"password":   {password},
+	}
+	for key, values := range extra {
+		form[key] = values
+	}
+
+	return requestToken(t, form)
+}
+
+// newTestClient returns a client that does not follow redirects so the
+// authorization responses can be inspected
+func newTestClient() *http.Client {
+	return &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
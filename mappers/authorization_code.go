@@ -0,0 +1,42 @@
+package mappers
+
+import (
+	"strings"
+
+	"github.com/cjlapao/common-go-identity/database/dto"
+	"github.com/cjlapao/common-go-identity/models"
+)
+
+func ToAuthorizationCode(code dto.AuthorizationCodeDTO) models.AuthorizationCode {
+	return models.AuthorizationCode{
+		ID:                  code.ID,
+		ClientID:            code.ClientID,
+		UserID:              code.UserID,
+		TenantID:            code.TenantID,
+		RedirectUri:         code.RedirectUri,
+		Scope:               code.Scope,
+		Nonce:               code.Nonce,
+		CodeChallenge:       code.CodeChallenge,
+		CodeChallengeMethod: code.CodeChallengeMethod,
+		AuthTime:            code.AuthTime,
+		Amr:                 strings.Fields(code.Amr),
+		ExpiresAt:           code.ExpiresAt,
+	}
+}
+
+func ToAuthorizationCodeDTO(code models.AuthorizationCode) dto.AuthorizationCodeDTO {
+	return dto.AuthorizationCodeDTO{
+		ID:                  code.ID,
+		ClientID:            code.ClientID,
+		UserID:              code.UserID,
+		TenantID:            code.TenantID,
+		RedirectUri:         code.RedirectUri,
+		Scope:               code.Scope,
+		Nonce:               code.Nonce,
+		CodeChallenge:       code.CodeChallenge,
+		CodeChallengeMethod: code.CodeChallengeMethod,
+		AuthTime:            code.AuthTime,
+		Amr:                 strings.Join(code.Amr, " "),
+		ExpiresAt:           code.ExpiresAt,
+	}
+}
@@ -0,0 +1,46 @@
+package mappers
+
+import (
+	"github.com/cjlapao/common-go-identity/database/dto"
+	"github.com/cjlapao/common-go-identity/models"
+)
+
+func ToClient(client dto.ClientDTO) models.Client {
+	return models.Client{
+		ID:                      client.ID,
+		Name:                    client.Name,
+		Secret:                  client.Secret,
+		TenantID:                client.TenantID,
+		RedirectUris:            copyStrings(client.RedirectUris),
+		GrantTypes:              copyStrings(client.GrantTypes),
+		Scopes:                  copyStrings(client.Scopes),
+		TokenEndpointAuthMethod: client.TokenEndpointAuthMethod,
+		AccessTokenFormat:       client.AccessTokenFormat,
+		Blocked:                 client.Blocked,
+		RegistrationAccessToken: client.RegistrationAccessToken,
+		IssuedAt:                client.IssuedAt,
+	}
+}
+
+func ToClientDTO(client models.Client) dto.ClientDTO {
+	return dto.ClientDTO{
+		ID:                      client.ID,
+		Name:                    client.Name,
+		Secret:                  client.Secret,
+		TenantID:                client.TenantID,
+		RedirectUris:            copyStrings(client.RedirectUris),
+		GrantTypes:              copyStrings(client.GrantTypes),
+		Scopes:                  copyStrings(client.Scopes),
+		TokenEndpointAuthMethod: client.TokenEndpointAuthMethod,
+		AccessTokenFormat:       client.AccessTokenFormat,
+		Blocked:                 client.Blocked,
+		RegistrationAccessToken: client.RegistrationAccessToken,
+		IssuedAt:                client.IssuedAt,
+	}
+}
+
+func copyStrings(values []string) []string {
+	result := make([]string, 0)
+	result = append(result, values...)
+	return result
+}
@@ -78,6 +78,7 @@ func ToUser(user dto.UserDTO) models.User {
 	decodedRefreshToken := ""
 	decodedRecoveryToken := ""
 	decodedEmailVerifyToken := ""
+	blockedUntil := ""
 	var err error
 
 	if user.RefreshToken != nil {
@@ -101,6 +102,10 @@ func ToUser(user dto.UserDTO) models.User {
 		}
 	}
 
+	if user.BlockedUntil != nil {
+		blockedUntil = *user.BlockedUntil
+	}
+
 	return models.User{
 		ID:               user.ID,
 		Email:            user.Email,
@@ -115,7 +120,7 @@ func ToUser(user dto.UserDTO) models.User {
 		EmailVerifyToken: decodedEmailVerifyToken,
 		InvalidAttempts:  user.InvalidAttempts,
 		Blocked:          user.Blocked,
-		BlockedUntil:     *user.BlockedUntil,
+		BlockedUntil:     blockedUntil,
 		Roles:            ToUserRoles(user.Roles),
 		Claims:           ToUserClaims(user.Claims),
 	}
@@ -0,0 +1,36 @@
+package mappers
+
+import (
+	"github.com/cjlapao/common-go-identity/database/dto"
+	"github.com/cjlapao/common-go-identity/models"
+)
+
+func ToRefreshTokenRecord(token dto.RefreshTokenDTO) models.RefreshTokenRecord {
+	return models.RefreshTokenRecord{
+		ID:        token.ID,
+		FamilyID:  token.FamilyID,
+		UserID:    token.UserID,
+		ClientID:  token.ClientID,
+		TenantID:  token.TenantID,
+		Scope:     token.Scope,
+		Rotated:   token.Rotated,
+		Revoked:   token.Revoked,
+		IssuedAt:  token.IssuedAt,
+		ExpiresAt: token.ExpiresAt,
+	}
+}
+
+func ToRefreshTokenDTO(token models.RefreshTokenRecord) dto.RefreshTokenDTO {
+	return dto.RefreshTokenDTO{
+		ID:        token.ID,
+		FamilyID:  token.FamilyID,
+		UserID:    token.UserID,
+		ClientID:  token.ClientID,
+		TenantID:  token.TenantID,
+		Scope:     token.Scope,
+		Rotated:   token.Rotated,
+		Revoked:   token.Revoked,
+		IssuedAt:  token.IssuedAt,
+		ExpiresAt: token.ExpiresAt,
+	}
+}
@@ -0,0 +1,26 @@
+package mappers
+
+import (
+	"github.com/cjlapao/common-go-identity/database/dto"
+	"github.com/cjlapao/common-go-identity/models"
+)
+
+func ToUserTotp(totp dto.UserTotpDTO) models.UserTotp {
+	return models.UserTotp{
+		UserID:       totp.UserID,
+		Secret:       totp.Secret,
+		Enabled:      totp.Enabled,
+		LastUsedStep: totp.LastUsedStep,
+		CreatedAt:    totp.CreatedAt,
+	}
+}
+
+func ToUserTotpDTO(totp models.UserTotp) dto.UserTotpDTO {
+	return dto.UserTotpDTO{
+		UserID:       totp.UserID,
+		Secret:       totp.Secret,
+		Enabled:      totp.Enabled,
+		LastUsedStep: totp.LastUsedStep,
+		CreatedAt:    totp.CreatedAt,
+	}
+}
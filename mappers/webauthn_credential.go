@@ -0,0 +1,34 @@
+package mappers
+
+import (
+	"github.com/cjlapao/common-go-identity/database/dto"
+	"github.com/cjlapao/common-go-identity/models"
+)
+
+func ToWebAuthnCredential(credential dto.WebAuthnCredentialDTO) models.WebAuthnCredential {
+	return models.WebAuthnCredential{
+		ID:              credential.ID,
+		UserID:          credential.UserID,
+		Name:            credential.Name,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		AAGUID:          credential.AAGUID,
+		SignCount:       credential.SignCount,
+		CreatedAt:       credential.CreatedAt,
+		LastUsedAt:      credential.LastUsedAt,
+	}
+}
+
+func ToWebAuthnCredentialDTO(credential models.WebAuthnCredential) dto.WebAuthnCredentialDTO {
+	return dto.WebAuthnCredentialDTO{
+		ID:              credential.ID,
+		UserID:          credential.UserID,
+		Name:            credential.Name,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		AAGUID:          credential.AAGUID,
+		SignCount:       credential.SignCount,
+		CreatedAt:       credential.CreatedAt,
+		LastUsedAt:      credential.LastUsedAt,
+	}
+}
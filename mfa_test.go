@@ -0,0 +1,222 @@
+package identity
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cjlapao/common-go-identity-otp/totp"
+	"github.com/cjlapao/common-go-identity/authorization_context"
+	"github.com/cjlapao/common-go-identity/models"
+)
+
+const (
+	testMfaUserId           = "mfa-test-user"
+	testMfaUsername         = testMfaUserId + "@localhost.com"
+	testRecoveryUserId      = "recovery-test-user"
+	testRecoveryUsername    = testRecoveryUserId + "@localhost.com"
+	testMfaPassword         = "mf@-p@ssw0rd"
+	testRecoveryCodesLength = 10
+)
+
+func sendMfaRequest(t *testing.T, method string, path string, accessToken string, code string) (int, map[string]interface{}) {
+	t.Helper()
+	server := getTestServer(t)
+	body, _ := json.Marshal(models.OAuthTotpCodeRequest{Code: code})
+	request, _ := http.NewRequest(method, server.URL+"/auth/"+path, strings.NewReader(string(body)))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Authorization", "Bearer "+accessToken)
+	response, err := newTestClient().Do(request)
+	if err != nil {
+		t.Fatalf("mfa request failed, %v", err)
+	}
+	defer response.Body.Close()
+
+	var responseBody map[string]interface{}
+	json.NewDecoder(response.Body).Decode(&responseBody)
+	return response.StatusCode, responseBody
+}
+
+// totpCodeAt generates the code of the step before, at or after now
+func totpCodeAt(t *testing.T, secret string, now time.Time, step int) string {
+	t.Helper()
+	code, err := totp.GenerateCode(secret, now.Add(time.Duration(step)*30*time.Second), totp.NewDefaultTotpOptions())
+	if err != nil {
+		t.Fatalf("failed to generate the totp code, %v", err)
+	}
+
+	return code
+}
+
+func TestTotpEnrollmentAndLogin(t *testing.T) {
+	addTestUser(t, testMfaUserId, testMfaPassword)
+
+	status, body := passwordLogin(t, testMfaUsername, testMfaPassword, nil)
+	if status != http.StatusOK {
+		t.Fatalf("expected status %v, got %v %v", http.StatusOK, status, body)
+	}
+	accessToken := body["access_token"].(string)
+
+	status, body = sendMfaRequest(t, http.MethodPost, "mfa/totp", accessToken, "")
+	if status != http.StatusOK {
+		t.Fatalf("expected status %v, got %v %v", http.StatusOK, status, body)
+	}
+	secret, _ := body["secret"].(string)
+	if secret == "" || !strings.HasPrefix(body["otpauth_uri"].(string), "otpauth://totp/") || !strings.HasPrefix(body["qr_code"].(string), "data:image/png;base64,") {
+		t.Fatalf("expected the secret, otpauth uri and QR code, got %v", body)
+	}
+
+	stored := authorization_context.GetBaseContext().UserDatabaseAdapter.GetUserTotp(testMfaUserId)
+	if stored == nil || stored.Enabled || strings.Contains(stored.Secret, secret) {
+		t.Fatalf("expected a pending enrollment with an encrypted secret")
+	}
+
+	// avoid crossing a time step while the codes are used
+	if time.Now().Unix()%30 > 25 {
+		time.Sleep(6 * time.Second)
+	}
+	now := time.Now()
+
+	if status, body = sendMfaRequest(t, http.MethodPost, "mfa/totp/confirm", accessToken, "000000"); status != http.StatusBadRequest {
+		t.Fatalf("expected a wrong code to be rejected, got %v %v", status, body)
+	}
+	status, body = sendMfaRequest(t, http.MethodPost, "mfa/totp/confirm", accessToken, totpCodeAt(t, secret, now, -1))
+	if status != http.StatusOK {
+		t.Fatalf("expected status %v, got %v %v", http.StatusOK, status, body)
+	}
+	if codes, _ := body["recovery_codes"].([]interface{}); len(codes) != testRecoveryCodesLength {
+		t.Fatalf("expected the confirmation to return the recovery codes, got %v", body)
+	}
+
+	status, body = passwordLogin(t, testMfaUsername, testMfaPassword, nil)
+	if status != http.StatusForbidden || body["error"] != models.OAuthMfaRequired.String() {
+		t.Fatalf("expected the login to require mfa, got %v %v", status, body)
+	}
+	mfaToken, _ := body["mfa_token"].(string)
+	if mfaToken == "" || body["access_token"] != nil {
+		t.Fatalf("expected only an mfa token, got %v", body)
+	}
+
+	code := totpCodeAt(t, secret, now, 0)
+	status, body = requestToken(t, url.Values{
+		"grant_type": {"mfa_otp"},
+		"client_id":  {"another-app"},
+		"mfa_token":  {mfaToken},
+		"otp":        {code},
+	})
+	if status != http.StatusBadRequest || body["error"] != models.OAuthInvalidGrant.String() {
+		t.Fatalf("expected the mfa token to be bound to the client, got %v %v", status, body)
+	}
+	status, body = requestToken(t, url.Values{
+		"grant_type": {"mfa_otp"},
+		"client_id":  {"unregistered-app"},
+		"mfa_token":  {mfaToken},
+		"otp":        {code},
+	})
+	if status != http.StatusUnauthorized || body["error"] != models.OAuthInvalidClientError.String() {
+		t.Fatalf("expected an unregistered client to be rejected, got %v %v", status, body)
+	}
+
+	mfaLogin := url.Values{
+		"grant_type": {"mfa_otp"},
+		"client_id":  {"spa"},
+		"mfa_token":  {mfaToken},
+		"otp":        {code},
+	}
+	status, body = requestToken(t, mfaLogin)
+	if status != http.StatusOK || body["access_token"] == nil {
+		t.Fatalf("expected status %v, got %v %v", http.StatusOK, status, body)
+	}
+	accessToken = body["access_token"].(string)
+
+	// a code can only be used once
+	if status, body = requestToken(t, mfaLogin); status != http.StatusBadRequest {
+		t.Fatalf("expected a replayed code to be rejected, got %v %v", status, body)
+	}
+
+	if status, body = sendMfaRequest(t, http.MethodDelete, "mfa/totp", accessToken, totpCodeAt(t, secret, now, 1)); status != http.StatusNoContent {
+		t.Fatalf("expected status %v, got %v %v", http.StatusNoContent, status, body)
+	}
+	if status, body = passwordLogin(t, testMfaUsername, testMfaPassword, nil); status != http.StatusOK {
+		t.Fatalf("expected the login to succeed without mfa, got %v %v", status, body)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	addTestUser(t, testRecoveryUserId, testMfaPassword)
+	_, body := passwordLogin(t, testRecoveryUsername, testMfaPassword, nil)
+	accessToken := body["access_token"].(string)
+
+	_, body = sendMfaRequest(t, http.MethodPost, "mfa/totp", accessToken, "")
+	secret := body["secret"].(string)
+	_, body = sendMfaRequest(t, http.MethodPost, "mfa/totp/confirm", accessToken, totpCodeAt(t, secret, time.Now(), 0))
+	codes, _ := body["recovery_codes"].([]interface{})
+	if len(codes) != testRecoveryCodesLength {
+		t.Fatalf("expected %v recovery codes, got %v", testRecoveryCodesLength, body)
+	}
+
+	// codes are only stored as hashes
+	for _, code := range codes {
+		if authorization_context.GetBaseContext().UserDatabaseAdapter.UseUserRecoveryCode(testRecoveryUserId, code.(string)) {
+			t.Fatalf("expected the recovery code not to be stored in plain text")
+		}
+	}
+
+	var notifications []models.OAuthNotificationType
+	authorization_context.GetBaseContext().NotificationCallback = func(notification models.OAuthNotification) error {
+		notifications = append(notifications, notification.Type)
+		return nil
+	}
+	defer func() {
+		authorization_context.GetBaseContext().NotificationCallback = nil
+	}()
+
+	_, body = passwordLogin(t, testRecoveryUsername, testMfaPassword, nil)
+	mfaLogin := url.Values{
+		"grant_type": {"mfa_otp"},
+		"client_id":  {"spa"},
+		"mfa_token":  {body["mfa_token"].(string)},
+		"otp":        {strings.ToUpper(codes[0].(string))},
+	}
+	status, body := requestToken(t, mfaLogin)
+	if status != http.StatusOK || body["access_token"] == nil {
+		t.Fatalf("expected the recovery code to complete the login, got %v %v", status, body)
+	}
+	accessToken = body["access_token"].(string)
+
+	if status, body = requestToken(t, mfaLogin); status != http.StatusBadRequest {
+		t.Fatalf("expected a used recovery code to be rejected, got %v %v", status, body)
+	}
+
+	status, body = sendMfaRequest(t, http.MethodGet, "mfa/recovery-codes", accessToken, "")
+	if status != http.StatusOK || body["remaining"] != float64(testRecoveryCodesLength-1) {
+		t.Fatalf("expected %v remaining codes, got %v %v", testRecoveryCodesLength-1, status, body)
+	}
+
+	if status, body = sendMfaRequest(t, http.MethodPost, "mfa/recovery-codes", accessToken, codes[0].(string)); status != http.StatusBadRequest {
+		t.Fatalf("expected the regeneration to require a valid code, got %v %v", status, body)
+	}
+	status, body = sendMfaRequest(t, http.MethodPost, "mfa/recovery-codes", accessToken, codes[1].(string))
+	newCodes, _ := body["recovery_codes"].([]interface{})
+	if status != http.StatusOK || len(newCodes) != testRecoveryCodesLength {
+		t.Fatalf("expected new recovery codes, got %v %v", status, body)
+	}
+
+	if status, body = sendMfaRequest(t, http.MethodDelete, "mfa/totp", accessToken, codes[2].(string)); status != http.StatusBadRequest {
+		t.Fatalf("expected the old recovery codes to be replaced, got %v %v", status, body)
+	}
+
+	expected := []models.OAuthNotificationType{models.MfaRecoveryCodeUsed, models.MfaRecoveryCodesGenerated}
+	for _, notificationType := range expected {
+		found := false
+		for _, notification := range notifications {
+			found = found || notification == notificationType
+		}
+		if !found {
+			t.Errorf("expected a %v notification, got %v", notificationType, notifications)
+		}
+	}
+}
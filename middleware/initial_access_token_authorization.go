@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/cjlapao/common-go-identity/authorization_context"
+	"github.com/cjlapao/common-go-identity/constants"
+	"github.com/cjlapao/common-go-restapi/controllers"
+	"github.com/cjlapao/common-go/helper/http_helper"
+)
+
+// InitialAccessTokenAuthorizationMiddlewareAdapter authorizes a client registration request
+// that carries the configured initial access token as a bearer token, this allows tenants
+// to register their own clients without an admin user
+func InitialAccessTokenAuthorizationMiddlewareAdapter() controllers.Adapter {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var authorizationContext *authorization_context.AuthorizationContext
+			authCtxFromRequest := r.Context().Value(constants.AUTHORIZATION_CONTEXT_KEY)
+			if authCtxFromRequest != nil {
+				authorizationContext = authCtxFromRequest.(*authorization_context.AuthorizationContext)
+			} else {
+				authorizationContext = authorization_context.New()
+			}
+
+			// If the authorization context is already authorized we will skip this middleware
+			if authorizationContext.IsAuthorized {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			initialAccessToken := authorizationContext.Options.InitialAccessToken
+			token, valid := http_helper.GetAuthorizationToken(r.Header)
+			if initialAccessToken == "" || !valid {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if subtle.ConstantTimeCompare([]byte(token), []byte(initialAccessToken)) != 1 {
+				logger.Error("%sThe initial access token is not valid", logger.GetRequestPrefix(r, false))
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			authorizationContext.IsAuthorized = true
+			authorizationContext.AuthorizationError = nil
+			authorizationContext.AuthorizedBy = "InitialAccessTokenAuthorization"
+			ctx := context.WithValue(r.Context(), constants.AUTHORIZATION_CONTEXT_KEY, authorizationContext)
+			logger.Info("%sInitial Access Token Authorization layer finished", logger.GetRequestPrefix(r, false))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
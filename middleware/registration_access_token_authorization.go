@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/cjlapao/common-go-identity/authorization_context"
+	"github.com/cjlapao/common-go-identity/constants"
+	"github.com/cjlapao/common-go-restapi/controllers"
+	"github.com/cjlapao/common-go/helper/http_helper"
+	"github.com/cjlapao/common-go/security"
+	"github.com/gorilla/mux"
+)
+
+// RegistrationAccessTokenAuthorizationMiddlewareAdapter authorizes the management of a registered
+// client using the registration access token that was issued when the client was registered
+func RegistrationAccessTokenAuthorizationMiddlewareAdapter() controllers.Adapter {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var authorizationContext *authorization_context.AuthorizationContext
+			authCtxFromRequest := r.Context().Value(constants.AUTHORIZATION_CONTEXT_KEY)
+			if authCtxFromRequest != nil {
+				authorizationContext = authCtxFromRequest.(*authorization_context.AuthorizationContext)
+			} else {
+				authorizationContext = authorization_context.New()
+			}
+
+			// If the authorization context is already authorized we will skip this middleware
+			if authorizationContext.IsAuthorized {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			vars := mux.Vars(r)
+			clientId := vars["clientId"]
+			token, valid := http_helper.GetAuthorizationToken(r.Header)
+			if clientId == "" || !valid || authorizationContext.ClientDatabaseAdapter == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			client := authorizationContext.ClientDatabaseAdapter.GetClientById(clientId)
+			if client == nil || client.RegistrationAccessToken == "" {
+				logger.Error("%sClient %v was not found or cannot be managed", logger.GetRequestPrefix(r, false), clientId)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			hashedToken := security.SHA256Encode(token)
+			if subtle.ConstantTimeCompare([]byte(hashedToken), []byte(client.RegistrationAccessToken)) != 1 {
+				logger.Error("%sThe registration access token is not valid for client %v", logger.GetRequestPrefix(r, false), clientId)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			authorizationContext.IsAuthorized = true
+			authorizationContext.AuthorizationError = nil
+			authorizationContext.AuthorizedBy = "RegistrationAccessTokenAuthorization"
+			ctx := context.WithValue(r.Context(), constants.AUTHORIZATION_CONTEXT_KEY, authorizationContext)
+			logger.Info("%sRegistration Access Token Authorization layer finished", logger.GetRequestPrefix(r, false))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -84,10 +84,15 @@ func TokenAuthorizationMiddlewareAdapter(roles []string, claims []string) contro
 			// Validating userToken against the keys
 			if authorized {
 				var validateUserTokenError error
-				if authorizationContext.Options.KeyVaultEnabled {
+				// opaque tokens are resolved in the token store so they do not need any key
+				if jwt.IsOpaqueToken(jwt_token) && authorizationContext.OpaqueTokenDatabaseAdapter != nil {
+					userToken, validateUserTokenError = jwt.ValidateUserToken(jwt_token, authorizationContext)
+				} else if authorizationContext.Options.KeyVaultEnabled {
 					userToken, validateUserTokenError = jwt.ValidateUserToken(jwt_token, authorizationContext)
 				} else if authorizationContext.Options.PublicKey != "" {
 					userToken, validateUserTokenError = jwt.ValidateUserToken(jwt_token, authorizationContext)
+				} else if authorizationContext.TrustedIssuers.HasIssuers() {
+					userToken, validateUserTokenError = jwt.ValidateUserToken(jwt_token, authorizationContext)
 				} else {
 					validateUserTokenError = errors.New("no public or private key found to validate token")
 				}
@@ -118,9 +123,20 @@ func TokenAuthorizationMiddlewareAdapter(roles []string, claims []string) contro
 				}
 			}
 
-			// To gain speed we will only get the db user if there is any role or claim to validate
-			// otherwise we don't need anything else to validate it
-			if (len(roles) > 0 || len(claims) > 0) && !isSuperUser {
+			// The subject of an external issuer is not one of our users even if it matches the
+			// email of one, so its roles only come from its own token and it has no claims
+			if authorized && (len(roles) > 0 || len(claims) > 0) && !isSuperUser && authorizationContext.TrustedIssuers.IsTrusted(userToken.Issuer) {
+				if err = validateUserRoles(userToken.Roles, roles); err == nil {
+					err = validateUserClaims(make([]string, 0), claims)
+				}
+				if err != nil {
+					authorized = false
+					validateError = errors.New("bearer token of issuer " + userToken.Issuer + " does not contain one or more roles or claims required by the context, " + err.Error())
+					logger.Error("%sError validating token, %v", logger.GetRequestPrefix(r, false), validateError.Error())
+				}
+			} else if (len(roles) > 0 || len(claims) > 0) && !isSuperUser {
+				// To gain speed we will only get the db user if there is any role or claim to validate
+				// otherwise we don't need anything else to validate it
 				// Getting the user from the database to validate roles and claims
 				if authorized {
 					dbUser = usrManager.GetUserByEmail(userToken.User)
@@ -156,7 +172,7 @@ func TokenAuthorizationMiddlewareAdapter(roles []string, claims []string) contro
 				}
 			}
 
-			if authorized && userToken != nil && userToken.ID != "" {
+			if authorized && userToken != nil {
 				oldOptions := authorizationContext.Options
 				oldBaseUrl := authorizationContext.BaseUrl
 
@@ -167,6 +183,8 @@ func TokenAuthorizationMiddlewareAdapter(roles []string, claims []string) contro
 				user.Email = userToken.User
 				user.Audiences = userToken.Audiences
 				user.Issuer = userToken.Issuer
+				user.Scope = userToken.Scope
+				user.ClientID = userToken.ClientID
 				user.ValidatedClaims = claims
 				user.Roles = userToken.Roles
 
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"time"
+)
+
+// AuthorizationCode entity, the Code is only populated when it is issued as
+// the store only keeps its hash
+type AuthorizationCode struct {
+	ID                  string    `json:"id" bson:"_id"`
+	Code                string    `json:"-" bson:"-"`
+	ClientID            string    `json:"clientId" bson:"clientId"`
+	UserID              string    `json:"userId" bson:"userId"`
+	TenantID            string    `json:"tenantId" bson:"tenantId"`
+	RedirectUri         string    `json:"redirectUri" bson:"redirectUri"`
+	Scope               string    `json:"scope" bson:"scope"`
+	Nonce               string    `json:"nonce" bson:"nonce"`
+	CodeChallenge       string    `json:"codeChallenge" bson:"codeChallenge"`
+	CodeChallengeMethod string    `json:"codeChallengeMethod" bson:"codeChallengeMethod"`
+	AuthTime            time.Time `json:"authTime" bson:"authTime"`
+	Amr                 []string  `json:"amr" bson:"amr"`
+	ExpiresAt           time.Time `json:"expiresAt" bson:"expiresAt"`
+}
+
+func (c AuthorizationCode) IsExpired() bool {
+	return c.ExpiresAt.Before(time.Now())
+}
+
+// VerifyCodeChallenge checks the PKCE code verifier against the challenge
+// that was sent in the authorize request
+func (c AuthorizationCode) VerifyCodeChallenge(verifier string) bool {
+	if c.CodeChallenge == "" || verifier == "" {
+		return false
+	}
+
+	switch c.CodeChallengeMethod {
+	case "S256":
+		hash := sha256.Sum256([]byte(verifier))
+		return base64.RawURLEncoding.EncodeToString(hash[:]) == strings.TrimRight(c.CodeChallenge, "=")
+	default:
+		return false
+	}
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	ClientSecretBasicAuthMethod = "client_secret_basic"
+	ClientSecretPostAuthMethod  = "client_secret_post"
+	NoneAuthMethod              = "none"
+)
+
+// The access tokens are signed jwt tokens unless the client or its tenant use opaque tokens,
+// these are only a reference to the claims kept by the identity server
+const (
+	JwtAccessTokenFormat    = "jwt"
+	OpaqueAccessTokenFormat = "opaque"
+)
+
+// Client entity, represents an OAuth client application, the secret is
+// kept hashed and is only returned when it is generated
+type Client struct {
+	ID                      string    `json:"client_id" bson:"_id"`
+	Name                    string    `json:"client_name" bson:"name"`
+	Secret                  string    `json:"-" bson:"secret"`
+	TenantID                string    `json:"tenant_id,omitempty" bson:"tenantId"`
+	RedirectUris            []string  `json:"redirect_uris" bson:"redirectUris"`
+	GrantTypes              []string  `json:"grant_types" bson:"grantTypes"`
+	Scopes                  []string  `json:"scopes" bson:"scopes"`
+	TokenEndpointAuthMethod string    `json:"token_endpoint_auth_method" bson:"tokenEndpointAuthMethod"`
+	AccessTokenFormat       string    `json:"access_token_format,omitempty" bson:"accessTokenFormat"`
+	Blocked                 bool      `json:"blocked" bson:"blocked"`
+	RegistrationAccessToken string    `json:"-" bson:"registrationAccessToken"`
+	IssuedAt                time.Time `json:"client_id_issued_at" bson:"issuedAt"`
+}
+
+func NewClient() *Client {
+	client := Client{
+		RedirectUris:            make([]string, 0),
+		GrantTypes:              make([]string, 0),
+		Scopes:                  make([]string, 0),
+		TokenEndpointAuthMethod: ClientSecretBasicAuthMethod,
+	}
+
+	return &client
+}
+
+// IsConfidential returns true if the client needs to authenticate with a secret
+func (c Client) IsConfidential() bool {
+	return c.TokenEndpointAuthMethod != NoneAuthMethod
+}
+
+func (c Client) AllowsGrantType(grantType string) bool {
+	for _, allowedGrantType := range c.GrantTypes {
+		if strings.EqualFold(allowedGrantType, grantType) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c Client) AllowsScope(scope string) bool {
+	for _, allowedScope := range c.Scopes {
+		if allowedScope == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c Client) HasRedirectUri(redirectUri string) bool {
+	for _, allowedRedirectUri := range c.RedirectUris {
+		if allowedRedirectUri == redirectUri {
+			return true
+		}
+	}
+
+	return false
+}
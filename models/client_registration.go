@@ -0,0 +1,32 @@
+package models
+
+// OAuthClientRegistrationRequest entity, client metadata as defined in RFC 7591
+type OAuthClientRegistrationRequest struct {
+	ClientID                string   `json:"client_id,omitempty"`
+	ClientSecret            string   `json:"client_secret,omitempty"`
+	ClientName              string   `json:"client_name,omitempty"`
+	RedirectUris            []string `json:"redirect_uris,omitempty"`
+	GrantTypes              []string `json:"grant_types,omitempty"`
+	ResponseTypes           []string `json:"response_types,omitempty"`
+	Scope                   string   `json:"scope,omitempty"`
+	TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method,omitempty"`
+	AccessTokenFormat       string   `json:"access_token_format,omitempty"`
+}
+
+// OAuthClientRegistrationResponse entity, client information response as defined in RFC 7591
+// the client secret and registration access token are only returned when they are generated
+type OAuthClientRegistrationResponse struct {
+	ClientID                string   `json:"client_id"`
+	ClientSecret            string   `json:"client_secret,omitempty"`
+	ClientIdIssuedAt        int64    `json:"client_id_issued_at"`
+	ClientSecretExpiresAt   int64    `json:"client_secret_expires_at"`
+	RegistrationAccessToken string   `json:"registration_access_token,omitempty"`
+	RegistrationClientUri   string   `json:"registration_client_uri,omitempty"`
+	ClientName              string   `json:"client_name,omitempty"`
+	RedirectUris            []string `json:"redirect_uris"`
+	GrantTypes              []string `json:"grant_types"`
+	ResponseTypes           []string `json:"response_types"`
+	Scope                   string   `json:"scope,omitempty"`
+	TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method"`
+	AccessTokenFormat       string   `json:"access_token_format,omitempty"`
+}
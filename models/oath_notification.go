@@ -3,6 +3,7 @@ package models
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/cjlapao/common-go/helper/http_helper"
@@ -22,6 +23,21 @@ const (
 	TokenRequest
 	TokenRevoked
 	ConfigurationRequest
+	AuthorizationRequest
+	ClientRegistrationRequest
+	ClientManagementRequest
+	UserLockedOut
+	MfaEnrollment
+	MfaRemoval
+	MfaRecoveryCodesGenerated
+	MfaRecoveryCodeUsed
+	WebAuthnRegistration
+	WebAuthnRemoval
+	LoginCodeRequest
+	UserRemoved
+	UserSessionsRevoked
+	TokenIntrospection
+	TokenExchanged
 )
 
 func (OAuthNotificationType OAuthNotificationType) String() string {
@@ -43,6 +59,21 @@ var toOAuthNotificationTypeString = map[OAuthNotificationType]string{
 	TokenRequest:               "TokenRequest",
 	TokenRevoked:               "TokenRevoked",
 	ConfigurationRequest:       "ConfigurationRequest",
+	AuthorizationRequest:       "AuthorizationRequest",
+	ClientRegistrationRequest:  "ClientRegistrationRequest",
+	ClientManagementRequest:    "ClientManagementRequest",
+	UserLockedOut:              "UserLockedOut",
+	MfaEnrollment:              "MfaEnrollment",
+	MfaRemoval:                 "MfaRemoval",
+	MfaRecoveryCodesGenerated:  "MfaRecoveryCodesGenerated",
+	MfaRecoveryCodeUsed:        "MfaRecoveryCodeUsed",
+	WebAuthnRegistration:       "WebAuthnRegistration",
+	WebAuthnRemoval:            "WebAuthnRemoval",
+	LoginCodeRequest:           "LoginCodeRequest",
+	UserRemoved:                "UserRemoved",
+	UserSessionsRevoked:        "UserSessionsRevoked",
+	TokenIntrospection:         "TokenIntrospection",
+	TokenExchanged:             "TokenExchanged",
 }
 
 var toOAuthNotificationTypeID = map[string]OAuthNotificationType{
@@ -56,6 +87,21 @@ var toOAuthNotificationTypeID = map[string]OAuthNotificationType{
 	"TokenRequest":               TokenRequest,
 	"TokenRevoked":               TokenRevoked,
 	"ConfigurationRequest":       ConfigurationRequest,
+	"AuthorizationRequest":       AuthorizationRequest,
+	"ClientRegistrationRequest":  ClientRegistrationRequest,
+	"ClientManagementRequest":    ClientManagementRequest,
+	"UserLockedOut":              UserLockedOut,
+	"MfaEnrollment":              MfaEnrollment,
+	"MfaRemoval":                 MfaRemoval,
+	"MfaRecoveryCodesGenerated":  MfaRecoveryCodesGenerated,
+	"MfaRecoveryCodeUsed":        MfaRecoveryCodeUsed,
+	"WebAuthnRegistration":       WebAuthnRegistration,
+	"WebAuthnRemoval":            WebAuthnRemoval,
+	"LoginCodeRequest":           LoginCodeRequest,
+	"UserRemoved":                UserRemoved,
+	"UserSessionsRevoked":        UserSessionsRevoked,
+	"TokenIntrospection":         TokenIntrospection,
+	"TokenExchanged":             TokenExchanged,
 }
 
 func (OAuthNotificationType OAuthNotificationType) MarshalJSON() ([]byte, error) {
@@ -88,5 +134,10 @@ func (n OAuthNotification) Success() bool {
 }
 
 func (n OAuthNotification) GetBody(dest interface{}) error {
+	// notifications raised outside of a request do not have a body
+	if n.Request == nil {
+		return errors.New("notification has no request")
+	}
+
 	return http_helper.MapRequestBody(n.Request, dest)
 }
@@ -6,7 +6,6 @@ import (
 	"fmt"
 
 	log "github.com/cjlapao/common-go-logger"
-	"github.com/cjlapao/common-go/security/encryption"
 )
 
 // OAuthGrantType Enum
@@ -14,6 +13,13 @@ type OAuthGrantType int64
 
 const (
 	OAuthPasswordGrant OAuthGrantType = iota
+	OAuthAuthorizationCodeGrant
+	OAuthClientCredentialsGrant
+	OAuthRefreshTokenGrant
+	OAuthMfaOtpGrant
+	OAuthWebAuthnGrant
+	OAuthLoginCodeGrant
+	OAuthTokenExchangeGrant
 )
 
 func (oauthGrantType OAuthGrantType) String() string {
@@ -25,11 +31,25 @@ func (oauthGrantType OAuthGrantType) FromString(keyType string) OAuthGrantType {
 }
 
 var toOAuthGrantTypeString = map[OAuthGrantType]string{
-	OAuthPasswordGrant: "password",
+	OAuthPasswordGrant:          "password",
+	OAuthAuthorizationCodeGrant: "authorization_code",
+	OAuthClientCredentialsGrant: "client_credentials",
+	OAuthRefreshTokenGrant:      "refresh_token",
+	OAuthMfaOtpGrant:            "mfa_otp",
+	OAuthWebAuthnGrant:          "webauthn",
+	OAuthLoginCodeGrant:         "login_code",
+	OAuthTokenExchangeGrant:     "urn:ietf:params:oauth:grant-type:token-exchange",
 }
 
 var toOAuthGrantTypeID = map[string]OAuthGrantType{
-	"password": OAuthPasswordGrant,
+	"password":           OAuthPasswordGrant,
+	"authorization_code": OAuthAuthorizationCodeGrant,
+	"client_credentials": OAuthClientCredentialsGrant,
+	"refresh_token":      OAuthRefreshTokenGrant,
+	"mfa_otp":            OAuthMfaOtpGrant,
+	"webauthn":           OAuthWebAuthnGrant,
+	"login_code":         OAuthLoginCodeGrant,
+	"urn:ietf:params:oauth:grant-type:token-exchange": OAuthTokenExchangeGrant,
 }
 
 func (oauthGrantType OAuthGrantType) MarshalJSON() ([]byte, error) {
@@ -66,6 +86,15 @@ const (
 	OAuthUserValidation
 	OAuthEmailNotVerified
 	OAuthUserBlocked
+	OAuthUnsupportedResponseType
+	OAuthAccessDenied
+	OAuthLoginRequired
+	OAuthInvalidRedirectUri
+	OAuthInvalidClientMetadata
+	OAuthMfaRequired
+	OAuthInvalidMfaCode
+	OAuthInsufficientScope
+	OAuthInvalidTarget
 	UnknownError
 )
 
@@ -78,35 +107,53 @@ func (oAuthErrorType OAuthErrorType) FromString(keyType string) OAuthErrorType {
 }
 
 var toOAuthErrorTypeString = map[OAuthErrorType]string{
-	OAuthInvalidRequestError:  "invalid_request",
-	OAuthInvalidClientError:   "invalid_client",
-	OAuthInvalidGrant:         "invalid_grant",
-	OAuthInvalidScope:         "invalid_scope",
-	OAuthUnauthorizedClient:   "unauthorized_client",
-	OAuthUnsupportedGrantType: "unsupported_grant_type",
-	OAuthPasswordMismatch:     "password_mismatch",
-	OAuthPasswordValidation:   "password_validation",
-	OAuthUserValidation:       "user_validation",
-	OAuthUserExists:           "user_exists",
-	OAuthEmailNotVerified:     "email_not_verified",
-	OAuthUserBlocked:          "user_blocked",
-	UnknownError:              "unknown_error",
+	OAuthInvalidRequestError:     "invalid_request",
+	OAuthInvalidClientError:      "invalid_client",
+	OAuthInvalidGrant:            "invalid_grant",
+	OAuthInvalidScope:            "invalid_scope",
+	OAuthUnauthorizedClient:      "unauthorized_client",
+	OAuthUnsupportedGrantType:    "unsupported_grant_type",
+	OAuthPasswordMismatch:        "password_mismatch",
+	OAuthPasswordValidation:      "password_validation",
+	OAuthUserValidation:          "user_validation",
+	OAuthUserExists:              "user_exists",
+	OAuthEmailNotVerified:        "email_not_verified",
+	OAuthUserBlocked:             "user_blocked",
+	OAuthUnsupportedResponseType: "unsupported_response_type",
+	OAuthAccessDenied:            "access_denied",
+	OAuthLoginRequired:           "login_required",
+	OAuthInvalidRedirectUri:      "invalid_redirect_uri",
+	OAuthInvalidClientMetadata:   "invalid_client_metadata",
+	OAuthMfaRequired:             "mfa_required",
+	OAuthInvalidMfaCode:          "invalid_mfa_code",
+	OAuthInsufficientScope:       "insufficient_scope",
+	OAuthInvalidTarget:           "invalid_target",
+	UnknownError:                 "unknown_error",
 }
 
 var toOAuthErrorTypeID = map[string]OAuthErrorType{
-	"invalid_request":        OAuthInvalidRequestError,
-	"invalid_client":         OAuthInvalidClientError,
-	"invalid_grant":          OAuthInvalidGrant,
-	"invalid_scope":          OAuthInvalidScope,
-	"unauthorized_client":    OAuthUnauthorizedClient,
-	"unsupported_grant_type": OAuthUnsupportedGrantType,
-	"password_mismatch":      OAuthPasswordMismatch,
-	"password_validation":    OAuthPasswordValidation,
-	"user_validation":        OAuthUserValidation,
-	"user_exists":            OAuthUserExists,
-	"email_not_verified":     OAuthEmailNotVerified,
-	"user_blocked":           OAuthUserBlocked,
-	"unknown_error":          UnknownError,
+	"invalid_request":           OAuthInvalidRequestError,
+	"invalid_client":            OAuthInvalidClientError,
+	"invalid_grant":             OAuthInvalidGrant,
+	"invalid_scope":             OAuthInvalidScope,
+	"unauthorized_client":       OAuthUnauthorizedClient,
+	"unsupported_grant_type":    OAuthUnsupportedGrantType,
+	"password_mismatch":         OAuthPasswordMismatch,
+	"password_validation":       OAuthPasswordValidation,
+	"user_validation":           OAuthUserValidation,
+	"user_exists":               OAuthUserExists,
+	"email_not_verified":        OAuthEmailNotVerified,
+	"user_blocked":              OAuthUserBlocked,
+	"unsupported_response_type": OAuthUnsupportedResponseType,
+	"access_denied":             OAuthAccessDenied,
+	"login_required":            OAuthLoginRequired,
+	"invalid_redirect_uri":      OAuthInvalidRedirectUri,
+	"invalid_client_metadata":   OAuthInvalidClientMetadata,
+	"mfa_required":              OAuthMfaRequired,
+	"invalid_mfa_code":          OAuthInvalidMfaCode,
+	"insufficient_scope":        OAuthInsufficientScope,
+	"invalid_target":            OAuthInvalidTarget,
+	"unknown_error":             UnknownError,
 }
 
 func (oAuthErrorType OAuthErrorType) MarshalJSON() ([]byte, error) {
@@ -137,6 +184,27 @@ type OAuthLoginRequest struct {
 	ProviderID   string `json:"providerId,omitempty"`
 	ClientID     string `json:"client_id,omitempty"`
 	ClientSecret string `json:"client_secret,omitempty"`
+	Code         string `json:"code,omitempty"`
+	RedirectUri  string `json:"redirect_uri,omitempty"`
+	CodeVerifier string `json:"code_verifier,omitempty"`
+	Nonce        string `json:"nonce,omitempty"`
+	MfaToken     string `json:"mfa_token,omitempty"`
+	Otp          string `json:"otp,omitempty"`
+	// WebAuthnSession is the session of the login options, WebAuthnResponse is the json
+	// serialized assertion returned by the browser
+	WebAuthnSession  string `json:"webauthn_session,omitempty"`
+	WebAuthnResponse string `json:"webauthn_response,omitempty"`
+	// LoginCode is the passwordless code or the token of the login link sent to the user
+	LoginCode string `json:"login_code,omitempty"`
+	// SubjectToken and ActorToken are the tokens exchanged with the token exchange grant, the
+	// audience and resource select the service the issued token is for
+	SubjectToken       string `json:"subject_token,omitempty"`
+	SubjectTokenType   string `json:"subject_token_type,omitempty"`
+	ActorToken         string `json:"actor_token,omitempty"`
+	ActorTokenType     string `json:"actor_token_type,omitempty"`
+	RequestedTokenType string `json:"requested_token_type,omitempty"`
+	Audience           string `json:"audience,omitempty"`
+	Resource           string `json:"resource,omitempty"`
 }
 
 // OAuthLoginRequest Entity
@@ -146,18 +214,61 @@ type OAuthLoginResponse struct {
 	ExpiresIn    string `json:"expires_in"`
 	RefreshToken string `json:"refresh_token"`
 	Scope        string `json:"scope"`
+	IdToken      string `json:"id_token,omitempty"`
+	// IssuedTokenType is only returned by the token exchange grant
+	IssuedTokenType string `json:"issued_token_type,omitempty"`
 }
 
-// OAuthIntrospectResponse entity
+// OAuthAuthorizeRequest entity
+type OAuthAuthorizeRequest struct {
+	ResponseType        string `json:"response_type"`
+	ClientID            string `json:"client_id"`
+	RedirectUri         string `json:"redirect_uri"`
+	Scope               string `json:"scope,omitempty"`
+	State               string `json:"state,omitempty"`
+	Nonce               string `json:"nonce,omitempty"`
+	CodeChallenge       string `json:"code_challenge,omitempty"`
+	CodeChallengeMethod string `json:"code_challenge_method,omitempty"`
+	Username            string `json:"username,omitempty"`
+}
+
+// Token type hints of the revocation and introspection requests
+const (
+	AccessTokenTypeHint  = "access_token"
+	RefreshTokenTypeHint = "refresh_token"
+)
+
+// Token types of the token exchange grant as defined in RFC 8693
+const (
+	AccessTokenTypeUrn = "urn:ietf:params:oauth:token-type:access_token"
+	JwtTokenTypeUrn    = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+// OAuthIntrospectRequest is the RFC 7662 introspection request, the hint is only used to look for
+// the token type first
+type OAuthIntrospectRequest struct {
+	Token         string `json:"token"`
+	TokenTypeHint string `json:"token_type_hint"`
+	ClientID      string `json:"client_id"`
+	ClientSecret  string `json:"client_secret"`
+}
+
+// OAuthIntrospectResponse is the RFC 7662 introspection response, inactive tokens only return
+// the active flag
 type OAuthIntrospectResponse struct {
-	ID        string `json:"jti,omitempty"`
-	Active    bool   `json:"active"`
-	TokenType string `json:"token_type,omitempty"`
-	Subject   string `json:"sub,omitempty"`
-	ClientId  string `json:"client_id,omitempty"`
-	ExpiresAt string `json:"exp,omitempty"`
-	IssuedAt  string `json:"iat,omitempty"`
-	Issuer    string `json:"iss,omitempty"`
+	Active    bool        `json:"active"`
+	Scope     string      `json:"scope,omitempty"`
+	ClientId  string      `json:"client_id,omitempty"`
+	Username  string      `json:"username,omitempty"`
+	TokenType string      `json:"token_type,omitempty"`
+	ExpiresAt int64       `json:"exp,omitempty"`
+	IssuedAt  int64       `json:"iat,omitempty"`
+	NotBefore int64       `json:"nbf,omitempty"`
+	Subject   string      `json:"sub,omitempty"`
+	Audiences []string    `json:"aud,omitempty"`
+	Issuer    string      `json:"iss,omitempty"`
+	ID        string      `json:"jti,omitempty"`
+	Actor     *TokenActor `json:"act,omitempty"`
 }
 
 // OAuthErrorResponse entity
@@ -165,6 +276,7 @@ type OAuthErrorResponse struct {
 	Error            OAuthErrorType `json:"error"`
 	ErrorDescription string         `json:"error_description,omitempty"`
 	ErrorUri         string         `json:"error_uri,omitempty"`
+	MfaToken         string         `json:"mfa_token,omitempty"`
 }
 
 func NewOAuthErrorResponse(err OAuthErrorType, description string) OAuthErrorResponse {
@@ -188,30 +300,36 @@ func (err OAuthErrorResponse) Log(extraLogs ...string) {
 	}
 }
 
+// OAuthConfigurationResponse is the discovery document of the authorization server, the OpenID
+// Connect provider metadata and the RFC 8414 authorization server metadata share it
 type OAuthConfigurationResponse struct {
 	Issuer                             string   `json:"issuer"`
-	JwksURI                            string   `json:"jwks_uri"`
-	AuthorizationEndpoint              string   `json:"authorization_endpoint"`
-	TokenEndpoint                      string   `json:"token_endpoint"`
-	UserinfoEndpoint                   string   `json:"userinfo_endpoint"`
-	EndSessionEndpoint                 string   `json:"end_session_endpoint"`
-	CheckSessionIframe                 string   `json:"check_session_iframe"`
-	RevocationEndpoint                 string   `json:"revocation_endpoint"`
-	IntrospectionEndpoint              string   `json:"introspection_endpoint"`
-	DeviceAuthorizationEndpoint        string   `json:"device_authorization_endpoint"`
+	JwksURI                            string   `json:"jwks_uri,omitempty"`
+	AuthorizationEndpoint              string   `json:"authorization_endpoint,omitempty"`
+	TokenEndpoint                      string   `json:"token_endpoint,omitempty"`
+	UserinfoEndpoint                   string   `json:"userinfo_endpoint,omitempty"`
+	RegistrationEndpoint               string   `json:"registration_endpoint,omitempty"`
+	EndSessionEndpoint                 string   `json:"end_session_endpoint,omitempty"`
+	CheckSessionIframe                 string   `json:"check_session_iframe,omitempty"`
+	RevocationEndpoint                 string   `json:"revocation_endpoint,omitempty"`
+	IntrospectionEndpoint              string   `json:"introspection_endpoint,omitempty"`
+	IntrospectionEndpointAuthMethods   []string `json:"introspection_endpoint_auth_methods_supported,omitempty"`
+	IntrospectionSigningAlgValues      []string `json:"introspection_signing_alg_values_supported,omitempty"`
+	DeviceAuthorizationEndpoint        string   `json:"device_authorization_endpoint,omitempty"`
 	FrontchannelLogoutSupported        bool     `json:"frontchannel_logout_supported"`
 	FrontchannelLogoutSessionSupported bool     `json:"frontchannel_logout_session_supported"`
 	BackchannelLogoutSupported         bool     `json:"backchannel_logout_supported"`
 	BackchannelLogoutSessionSupported  bool     `json:"backchannel_logout_session_supported"`
-	ScopesSupported                    []string `json:"scopes_supported"`
-	ClaimsSupported                    []string `json:"claims_supported"`
-	GrantTypesSupported                []string `json:"grant_types_supported"`
+	ScopesSupported                    []string `json:"scopes_supported,omitempty"`
+	ClaimsSupported                    []string `json:"claims_supported,omitempty"`
+	GrantTypesSupported                []string `json:"grant_types_supported,omitempty"`
 	ResponseTypesSupported             []string `json:"response_types_supported"`
-	ResponseModesSupported             []string `json:"response_modes_supported"`
-	TokenEndpointAuthMethodsSupported  []string `json:"token_endpoint_auth_methods_supported"`
-	SubjectTypesSupported              []string `json:"subject_types_supported"`
-	IDTokenSigningAlgValuesSupported   []string `json:"id_token_signing_alg_values_supported"`
-	CodeChallengeMethodsSupported      []string `json:"code_challenge_methods_supported"`
+	ResponseModesSupported             []string `json:"response_modes_supported,omitempty"`
+	TokenEndpointAuthMethodsSupported  []string `json:"token_endpoint_auth_methods_supported,omitempty"`
+	SubjectTypesSupported              []string `json:"subject_types_supported,omitempty"`
+	IDTokenSigningAlgValuesSupported   []string `json:"id_token_signing_alg_values_supported,omitempty"`
+	UserinfoSigningAlgValuesSupported  []string `json:"userinfo_signing_alg_values_supported,omitempty"`
+	CodeChallengeMethodsSupported      []string `json:"code_challenge_methods_supported,omitempty"`
 	RequestParameterSupported          bool     `json:"request_parameter_supported"`
 }
 
@@ -220,17 +338,18 @@ type OAuthJwksResponse struct {
 }
 
 type OAuthJwksKey struct {
-	ID              string                       `json:"kid"`
-	Algorithm       encryption.EncryptionKey     `json:"alg"`
-	AlgorithmFamily encryption.EncryptionKeyType `json:"kty"`
-	Use             string                       `json:"use"`
-	X5C             []string                     `json:"x5c"`
-	Exponent        string                       `json:"e,omitempty"`
-	Modulus         string                       `json:"n,omitempty"`
-	Curve           string                       `json:"curve,omitempty"`
-	X               string                       `json:"x,omitempty"`
-	Y               string                       `json:"y,omitempty"`
-	Thumbprint      string                       `json:"x5t"`
+	ID        string   `json:"kid"`
+	KeyType   string   `json:"kty"`
+	Algorithm string   `json:"alg"`
+	Use       string   `json:"use"`
+	X5C       []string `json:"x5c,omitempty"`
+	X5T       string   `json:"x5t,omitempty"`
+	X5TS256   string   `json:"x5t#S256,omitempty"`
+	Exponent  string   `json:"e,omitempty"`
+	Modulus   string   `json:"n,omitempty"`
+	Curve     string   `json:"crv,omitempty"`
+	X         string   `json:"x,omitempty"`
+	Y         string   `json:"y,omitempty"`
 }
 
 type OAuthRegisterRequest struct {
@@ -243,9 +362,13 @@ type OAuthRegisterRequest struct {
 	Claims    []string `json:"claims"`
 }
 
+// OAuthRevokeRequest is the RFC 7009 revocation request, the hint is only used to look for the
+// token type first
 type OAuthRevokeRequest struct {
-	ClientID  string `json:"client_id"`
-	GrantType string `json:"grant_type"`
+	Token         string `json:"token"`
+	TokenTypeHint string `json:"token_type_hint"`
+	ClientID      string `json:"client_id"`
+	ClientSecret  string `json:"client_secret"`
 }
 
 type OAuthRecoverPasswordRequest struct {
@@ -277,6 +400,33 @@ type OAuthVerifyEmailRequest struct {
 	Email string `json:"email"`
 }
 
+// OAuthLoginCodeRequest entity, used to request a passwordless login code or link
+type OAuthLoginCodeRequest struct {
+	Username string `json:"username"`
+	ClientID string `json:"client_id,omitempty"`
+}
+
+// OAuthLoginCodeNotification entity, sent to the notification callback so the code or
+// link can be delivered to the user
+type OAuthLoginCodeNotification struct {
+	UserID      string `json:"userId"`
+	Email       string `json:"email"`
+	DisplayName string `json:"displayName,omitempty"`
+	FirstName   string `json:"firstName,omitempty"`
+	LastName    string `json:"lastName,omitempty"`
+	ClientID    string `json:"clientId,omitempty"`
+	LoginCode   string `json:"loginCode"`
+	ExpiresIn   int    `json:"expiresIn"`
+}
+
+// OAuthTokenExchangeNotification entity, sent to the notification callback so the tokens issued
+// for the users by other clients and actors can be audited
+type OAuthTokenExchangeNotification struct {
+	ClientID string      `json:"clientId"`
+	Subject  string      `json:"subject"`
+	Actor    *TokenActor `json:"actor,omitempty"`
+}
+
 type OAuthVerifyEmailResponse struct {
 	Email        string `json:"email"`
 	AccessToken  string `json:"access_token"`
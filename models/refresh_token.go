@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// RefreshTokenRecord entity, represents an issued refresh token, the token itself is never
+// stored only its hash, all the tokens rotated from the same login share the family id
+type RefreshTokenRecord struct {
+	ID        string    `json:"id" bson:"_id"`
+	FamilyID  string    `json:"familyId" bson:"familyId"`
+	UserID    string    `json:"userId" bson:"userId"`
+	ClientID  string    `json:"clientId,omitempty" bson:"clientId"`
+	TenantID  string    `json:"tenantId,omitempty" bson:"tenantId"`
+	Scope     string    `json:"scope,omitempty" bson:"scope"`
+	Rotated   bool      `json:"rotated" bson:"rotated"`
+	Revoked   bool      `json:"revoked" bson:"revoked"`
+	IssuedAt  time.Time `json:"issuedAt" bson:"issuedAt"`
+	ExpiresAt time.Time `json:"expiresAt" bson:"expiresAt"`
+}
+
+func (r RefreshTokenRecord) IsExpired() bool {
+	return r.ExpiresAt.Before(time.Now())
+}
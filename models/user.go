@@ -1,9 +1,11 @@
 package models
 
 import (
+	"time"
+
 	cryptorand "github.com/cjlapao/common-go-cryptorand"
+	"github.com/cjlapao/common-go-identity/password_hasher"
 	"github.com/cjlapao/common-go/constants"
-	"github.com/cjlapao/common-go/security"
 	"github.com/cjlapao/common-go/validators"
 )
 
@@ -51,14 +53,56 @@ func (u User) GetHashedPassword() string {
 	return ""
 }
 
+// HashPassword hashes the password with the default password hasher, it returns
+// an empty string if the password is empty or could not be hashed
 func (u User) HashPassword(password string) string {
 	if password != "" {
-		return security.SHA256Encode(password)
+		hashedPassword, err := password_hasher.Hash(password)
+		if err != nil {
+			return ""
+		}
+
+		return hashedPassword
 	}
 
 	return ""
 }
 
+// VerifyPassword checks the password against the stored hash in any of the
+// supported formats
+func (u User) VerifyPassword(password string) bool {
+	if password == "" || u.Password == "" {
+		return false
+	}
+
+	valid, err := password_hasher.Verify(password, u.Password)
+	if err != nil {
+		return false
+	}
+
+	return valid
+}
+
+// NeedsPasswordRehash returns true if the stored hash was not created by the
+// default password hasher with its current parameters
+func (u User) NeedsPasswordRehash() bool {
+	return u.Password != "" && password_hasher.NeedsRehash(u.Password)
+}
+
+// IsLockedOut returns true if the user was temporarily blocked after too many failed logins
+func (u User) IsLockedOut() bool {
+	if u.BlockedUntil == "" {
+		return false
+	}
+
+	blockedUntil, err := time.Parse(time.RFC3339, u.BlockedUntil)
+	if err != nil {
+		return false
+	}
+
+	return blockedUntil.After(time.Now())
+}
+
 func (u User) IsValid() bool {
 	if u.ID == "" {
 		return false
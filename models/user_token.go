@@ -2,31 +2,43 @@ package models
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 )
 
 type UserToken struct {
-	ID            string    `json:"jti,omitempty"`
-	Scope         string    `json:"scope,omitempty"`
-	User          string    `json:"sub,omitempty"`
-	Issuer        string    `json:"iss,omitempty"`
-	FirstName     string    `json:"given_name,omitempty"`
-	LastName      string    `json:"family_name,omitempty"`
-	UserID        string    `json:"uid,omitempty"`
-	TenantId      string    `json:"tid,omitempty"`
-	DisplayName   string    `json:"name,omitempty"`
-	Email         string    `json:"email,omitempty"`
-	EmailVerified bool      `json:"email_verified,omitempty"`
-	Nonce         string    `json:"nonce,omitempty"`
-	NotBefore     time.Time `json:"nbf,omitempty"`
-	ExpiresAt     time.Time `json:"exp,omitempty"`
-	IssuedAt      time.Time `json:"iat,omitempty"`
-	Audiences     []string  `json:"aud,omitempty"`
-	Roles         []string  `json:"roles,omitempty"`
-	UsedKeyID     string    `json:"-"`
-	RefreshToken  string    `json:"-"`
-	Token         string    `json:"-"`
+	ID            string      `json:"jti,omitempty"`
+	Scope         string      `json:"scope,omitempty"`
+	User          string      `json:"sub,omitempty"`
+	Issuer        string      `json:"iss,omitempty"`
+	FirstName     string      `json:"given_name,omitempty"`
+	LastName      string      `json:"family_name,omitempty"`
+	UserID        string      `json:"uid,omitempty"`
+	TenantId      string      `json:"tid,omitempty"`
+	ClientID      string      `json:"client_id,omitempty"`
+	DisplayName   string      `json:"name,omitempty"`
+	Email         string      `json:"email,omitempty"`
+	EmailVerified bool        `json:"email_verified,omitempty"`
+	Nonce         string      `json:"nonce,omitempty"`
+	NotBefore     time.Time   `json:"nbf,omitempty"`
+	ExpiresAt     time.Time   `json:"exp,omitempty"`
+	IssuedAt      time.Time   `json:"iat,omitempty"`
+	Audiences     []string    `json:"aud,omitempty"`
+	Roles         []string    `json:"roles,omitempty"`
+	Amr           []string    `json:"amr,omitempty"`
+	Actor         *TokenActor `json:"act,omitempty"`
+	UsedKeyID     string      `json:"-"`
+	RefreshToken  string      `json:"-"`
+	Token         string      `json:"-"`
+}
+
+// TokenActor is the act claim of the tokens issued by the token exchange grant, it is the party
+// acting for the subject and the nested actor is the one that acted before it
+type TokenActor struct {
+	Subject  string      `json:"sub"`
+	ClientID string      `json:"client_id,omitempty"`
+	Actor    *TokenActor `json:"act,omitempty"`
 }
 
 func (userToken *UserToken) UnmarshalJSON(b []byte) error {
@@ -35,48 +47,85 @@ func (userToken *UserToken) UnmarshalJSON(b []byte) error {
 	if err != nil {
 		return err
 	}
-	m := f.(map[string]interface{})
+	// tokens of external issuers can use other types for some claims so they are ignored
+	// instead of failing the whole token
+	m, ok := f.(map[string]interface{})
+	if !ok {
+		return errors.New("token claims are not a json object")
+	}
 	for k, v := range m {
 		switch k {
 		case "jti":
-			userToken.ID = v.(string)
+			userToken.ID, _ = v.(string)
 		case "scope":
-			userToken.Scope = v.(string)
+			userToken.Scope, _ = v.(string)
 		case "sub":
-			userToken.User = v.(string)
+			userToken.User, _ = v.(string)
 		case "iss":
-			userToken.Issuer = v.(string)
+			userToken.Issuer, _ = v.(string)
 		case "given_name":
-			userToken.FirstName = v.(string)
+			userToken.FirstName, _ = v.(string)
 		case "family_name":
-			userToken.LastName = v.(string)
+			userToken.LastName, _ = v.(string)
 		case "email":
-			userToken.Email = v.(string)
+			userToken.Email, _ = v.(string)
 		case "email_verified":
-			userToken.EmailVerified = v.(bool)
+			userToken.EmailVerified, _ = v.(bool)
 		case "uid":
-			userToken.UserID = v.(string)
+			userToken.UserID, _ = v.(string)
 		case "tid":
-			userToken.TenantId = v.(string)
+			userToken.TenantId, _ = v.(string)
+		case "client_id":
+			userToken.ClientID, _ = v.(string)
 		case "name":
-			userToken.DisplayName = v.(string)
+			userToken.DisplayName, _ = v.(string)
 		case "nonce":
-			userToken.Nonce = v.(string)
+			userToken.Nonce, _ = v.(string)
 		case "nbf":
-			userToken.NotBefore = time.Unix(int64(v.(float64)), 0)
+			if value, ok := v.(float64); ok {
+				userToken.NotBefore = time.Unix(int64(value), 0)
+			}
 		case "exp":
-			userToken.ExpiresAt = time.Unix(int64(v.(float64)), 0)
+			if value, ok := v.(float64); ok {
+				userToken.ExpiresAt = time.Unix(int64(value), 0)
+			}
 		case "iat":
-			userToken.IssuedAt = time.Unix(int64(v.(float64)), 0)
+			if value, ok := v.(float64); ok {
+				userToken.IssuedAt = time.Unix(int64(value), 0)
+			}
 		case "aud":
-			audienceValues := v.([]interface{})
-			for _, v := range audienceValues {
-				userToken.Audiences = append(userToken.Audiences, v.(string))
+			// a single audience is serialized as a string
+			switch audienceValues := v.(type) {
+			case string:
+				userToken.Audiences = append(userToken.Audiences, audienceValues)
+			case []interface{}:
+				for _, v := range audienceValues {
+					if audience, ok := v.(string); ok {
+						userToken.Audiences = append(userToken.Audiences, audience)
+					}
+				}
 			}
 		case "roles":
-			rolesValues := v.([]interface{})
+			rolesValues, _ := v.([]interface{})
 			for _, v := range rolesValues {
-				userToken.Roles = append(userToken.Audiences, v.(string))
+				if value, ok := v.(string); ok {
+					userToken.Roles = append(userToken.Roles, value)
+				}
+			}
+		case "act":
+			// the actor is an object so it is decoded on its own
+			if actorJson, err := json.Marshal(v); err == nil {
+				var actor TokenActor
+				if json.Unmarshal(actorJson, &actor) == nil && actor.Subject != "" {
+					userToken.Actor = &actor
+				}
+			}
+		case "amr":
+			amrValues, _ := v.([]interface{})
+			for _, v := range amrValues {
+				if value, ok := v.(string); ok {
+					userToken.Amr = append(userToken.Amr, value)
+				}
 			}
 		}
 	}
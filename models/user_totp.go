@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// UserTotp entity, the time based one time password enrollment of a user, the secret is
+// kept encrypted and the enrollment is only enabled after the user confirms a first code
+type UserTotp struct {
+	UserID       string    `json:"userId" bson:"_id"`
+	Secret       string    `json:"-" bson:"secret"`
+	Enabled      bool      `json:"enabled" bson:"enabled"`
+	LastUsedStep int64     `json:"-" bson:"lastUsedStep"`
+	CreatedAt    time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+// OAuthTotpEnrollmentResponse entity, returned when a user starts a totp enrollment
+type OAuthTotpEnrollmentResponse struct {
+	Secret     string `json:"secret"`
+	OtpAuthUri string `json:"otpauth_uri"`
+	QrCode     string `json:"qr_code"`
+}
+
+// OAuthTotpCodeRequest entity, used to confirm and disable a totp enrollment
+type OAuthTotpCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// OAuthRecoveryCodesResponse entity, the codes are only returned when they are generated
+type OAuthRecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+	Remaining     int      `json:"remaining"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/cjlapao/common-go-identity/webauthn"
+)
+
+// WebAuthnCredential entity, a passkey or security key registered by a user, the id and the
+// public key are base64url encoded
+type WebAuthnCredential struct {
+	ID              string    `json:"id" bson:"_id"`
+	UserID          string    `json:"-" bson:"userId"`
+	Name            string    `json:"name" bson:"name"`
+	PublicKey       string    `json:"-" bson:"publicKey"`
+	AttestationType string    `json:"attestationType" bson:"attestationType"`
+	AAGUID          string    `json:"aaguid" bson:"aaguid"`
+	SignCount       int64     `json:"-" bson:"signCount"`
+	CreatedAt       time.Time `json:"createdAt" bson:"createdAt"`
+	LastUsedAt      time.Time `json:"lastUsedAt" bson:"lastUsedAt"`
+}
+
+// OAuthWebAuthnBeginResponse entity, the options for the browser webauthn api and the session
+// that needs to be sent back to finish the ceremony
+type OAuthWebAuthnBeginResponse struct {
+	Session   string      `json:"session"`
+	PublicKey interface{} `json:"publicKey"`
+}
+
+// OAuthWebAuthnRegistrationRequest entity, used to finish the registration of a credential
+type OAuthWebAuthnRegistrationRequest struct {
+	Session    string                        `json:"session"`
+	Name       string                        `json:"name,omitempty"`
+	Credential webauthn.RegistrationResponse `json:"credential"`
+}
+
+// OAuthWebAuthnLoginRequest entity, used to start a passwordless login, without a username
+// the authenticator can offer any of its discoverable credentials
+type OAuthWebAuthnLoginRequest struct {
+	Username string `json:"username,omitempty"`
+	ClientID string `json:"client_id,omitempty"`
+}
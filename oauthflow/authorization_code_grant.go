@@ -0,0 +1,234 @@
+package oauthflow
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	cryptorand "github.com/cjlapao/common-go-cryptorand"
+	"github.com/cjlapao/common-go-identity/authorization_context"
+	"github.com/cjlapao/common-go-identity/mappers"
+	"github.com/cjlapao/common-go-identity/models"
+	"github.com/cjlapao/common-go-identity/user_manager"
+	"github.com/cjlapao/common-go/constants"
+	"github.com/cjlapao/common-go/security"
+)
+
+var codeChallengeRegex = regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`)
+
+type AuthorizationCodeGrantFlow struct {
+	AuthenticationMethod string
+}
+
+// ValidateAuthorizeRequest validates the parameters of an authorize request, the returned
+// flag indicates if the redirect uri is trusted and the error can be sent back to it
+func (flow AuthorizationCodeGrantFlow) ValidateAuthorizeRequest(request *models.OAuthAuthorizeRequest) (bool, *models.OAuthErrorResponse) {
+	var errorResponse models.OAuthErrorResponse
+	authCtx := authorization_context.Clone()
+
+	if request.ClientID == "" {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidRequestError, "client_id is required")
+		logger.Error(errorResponse.ErrorDescription)
+		return false, &errorResponse
+	}
+
+	client := getRegisteredClient(authCtx, request.ClientID)
+	if client == nil {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidRequestError, fmt.Sprintf("Client %v was not found", request.ClientID))
+		logger.Error(errorResponse.ErrorDescription)
+		return false, &errorResponse
+	}
+
+	if client.Blocked || !client.AllowsGrantType(models.OAuthAuthorizationCodeGrant.String()) {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthUnauthorizedClient, fmt.Sprintf("Client %v is not allowed to use the authorization code grant", request.ClientID))
+		logger.Error(errorResponse.ErrorDescription)
+		return false, &errorResponse
+	}
+
+	if !flow.IsRedirectUriAllowed(authCtx, client, request.RedirectUri) {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidRequestError, fmt.Sprintf("redirect_uri %v is not allowed", request.RedirectUri))
+		logger.Error(errorResponse.ErrorDescription)
+		return false, &errorResponse
+	}
+
+	if request.ResponseType != "code" {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthUnsupportedResponseType, fmt.Sprintf("response_type %v is not supported", request.ResponseType))
+		logger.Error(errorResponse.ErrorDescription)
+		return true, &errorResponse
+	}
+
+	if request.CodeChallenge == "" {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidRequestError, "code_challenge is required")
+		logger.Error(errorResponse.ErrorDescription)
+		return true, &errorResponse
+	}
+
+	// the plain method sends the verifier itself so only S256 is accepted
+	if request.CodeChallengeMethod == "" {
+		request.CodeChallengeMethod = "S256"
+	}
+
+	if request.CodeChallengeMethod != "S256" {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidRequestError, fmt.Sprintf("code_challenge_method %v is not supported", request.CodeChallengeMethod))
+		logger.Error(errorResponse.ErrorDescription)
+		return true, &errorResponse
+	}
+
+	if !codeChallengeRegex.MatchString(request.CodeChallenge) {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidRequestError, "code_challenge is not valid")
+		logger.Error(errorResponse.ErrorDescription)
+		return true, &errorResponse
+	}
+
+	return true, nil
+}
+
+// IsRedirectUriAllowed checks if the redirect uri is an absolute uri without fragment that
+// exactly matches one of the registered client redirect uris, clients that were not registered
+// with redirect uris use the globally allowed redirect uris
+func (flow AuthorizationCodeGrantFlow) IsRedirectUriAllowed(authCtx *authorization_context.AuthorizationContext, client *models.Client, redirectUri string) bool {
+	if redirectUri == "" {
+		return false
+	}
+
+	parsedUri, err := url.Parse(redirectUri)
+	if err != nil || !parsedUri.IsAbs() || parsedUri.Fragment != "" {
+		return false
+	}
+
+	if client != nil && len(client.RedirectUris) > 0 {
+		return client.HasRedirectUri(redirectUri)
+	}
+
+	for _, allowedUri := range authCtx.Options.AllowedRedirectUris {
+		if allowedUri == redirectUri {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Authorize issues a new single use authorization code for an authenticated user, the amr are the
+// methods the user authenticated with if they are known
+func (flow AuthorizationCodeGrantFlow) Authorize(request *models.OAuthAuthorizeRequest, user *models.User, tenantId string, amr ...string) (*models.AuthorizationCode, *models.OAuthErrorResponse) {
+	var errorResponse models.OAuthErrorResponse
+	authCtx := authorization_context.Clone()
+
+	if authCtx.AuthorizationCodeDatabaseAdapter == nil {
+		errorResponse = models.NewOAuthErrorResponse(models.UnknownError, "No authorization code context was found")
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	code, err := cryptorand.GetRandomString(constants.ID_SIZE)
+	if err != nil {
+		errorResponse = models.NewOAuthErrorResponse(models.UnknownError, fmt.Sprintf("There was an error generating the authorization code, %v", err.Error()))
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	now := time.Now()
+	authorizationCode := models.AuthorizationCode{
+		ID:                  security.SHA256Encode(code),
+		Code:                code,
+		ClientID:            request.ClientID,
+		UserID:              user.ID,
+		TenantID:            tenantId,
+		RedirectUri:         request.RedirectUri,
+		Scope:               request.Scope,
+		Nonce:               request.Nonce,
+		CodeChallenge:       request.CodeChallenge,
+		CodeChallengeMethod: request.CodeChallengeMethod,
+		AuthTime:            now,
+		Amr:                 amr,
+		ExpiresAt:           now.Add(time.Second * time.Duration(authCtx.Options.AuthorizationCodeDuration)),
+	}
+
+	if err := authCtx.AuthorizationCodeDatabaseAdapter.UpsertAuthorizationCode(mappers.ToAuthorizationCodeDTO(authorizationCode)); err != nil {
+		errorResponse = models.NewOAuthErrorResponse(models.UnknownError, fmt.Sprintf("There was an error storing the authorization code, %v", err.Error()))
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	logger.Success("Authorization code for user %v and client %v was generated successfully", user.Username, request.ClientID)
+	return &authorizationCode, nil
+}
+
+// Authenticate exchanges an authorization code and its PKCE verifier for the user tokens
+func (flow AuthorizationCodeGrantFlow) Authenticate(request *models.OAuthLoginRequest) (*models.OAuthLoginResponse, *models.OAuthErrorResponse) {
+	var errorResponse models.OAuthErrorResponse
+	authCtx := authorization_context.Clone()
+
+	if authCtx.AuthorizationCodeDatabaseAdapter == nil {
+		errorResponse = models.NewOAuthErrorResponse(models.UnknownError, "No authorization code context was found")
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	// registered clients need to authenticate before using the code
+	if _, clientErrorResponse := AuthenticateRegisteredClient(request.ClientID, request.ClientSecret, flow.AuthenticationMethod); clientErrorResponse != nil {
+		return nil, clientErrorResponse
+	}
+
+	if request.Code == "" {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidRequestError, "code is required")
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	id := security.SHA256Encode(request.Code)
+	dtoCode := authCtx.AuthorizationCodeDatabaseAdapter.GetAuthorizationCode(id)
+	// Removing the code straight away, only the caller that removes it can use it
+	if dtoCode == nil || !authCtx.AuthorizationCodeDatabaseAdapter.RemoveAuthorizationCode(id) {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidGrant, "Authorization code is invalid or was already used")
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	authorizationCode := mappers.ToAuthorizationCode(*dtoCode)
+	if authorizationCode.IsExpired() {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidGrant, "Authorization code is expired")
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	if !strings.EqualFold(authorizationCode.ClientID, request.ClientID) {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidGrant, fmt.Sprintf("Authorization code was not issued to client %v", request.ClientID))
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	if authorizationCode.RedirectUri != request.RedirectUri {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidGrant, "redirect_uri does not match the authorization request")
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	if !authorizationCode.VerifyCodeChallenge(request.CodeVerifier) {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidGrant, "code_verifier is not valid")
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	user := user_manager.Get().GetUserById(authorizationCode.UserID)
+	if user == nil || user.ID == "" {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidGrant, fmt.Sprintf("User %v was not found", authorizationCode.UserID))
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	if errorResponse := validateUserCanLogin(authCtx, user); errorResponse != nil {
+		return nil, errorResponse
+	}
+
+	return generateLoginResponse(authCtx, user, authorizationCode.ClientID, loginDetails{
+		Scope:    authorizationCode.Scope,
+		Nonce:    authorizationCode.Nonce,
+		Code:     request.Code,
+		AuthTime: authorizationCode.AuthTime,
+		Amr:      authorizationCode.Amr,
+	})
+}
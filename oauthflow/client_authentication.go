@@ -0,0 +1,94 @@
+package oauthflow
+
+import (
+	"crypto/subtle"
+	"fmt"
+
+	"github.com/cjlapao/common-go-identity/authorization_context"
+	"github.com/cjlapao/common-go-identity/mappers"
+	"github.com/cjlapao/common-go-identity/models"
+	"github.com/cjlapao/common-go/security"
+)
+
+// AuthenticateClient validates the client credentials using the authentication method the client
+// was registered with, public clients are only identified by their client id
+func AuthenticateClient(clientId string, clientSecret string, authenticationMethod string) (*models.Client, *models.OAuthErrorResponse) {
+	var errorResponse models.OAuthErrorResponse
+	authCtx := authorization_context.Clone()
+
+	if clientId == "" {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidClientError, "Client id is required")
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	if authCtx.ClientDatabaseAdapter == nil {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidClientError, "No client store was configured")
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	dbClient := authCtx.ClientDatabaseAdapter.GetClientById(clientId)
+	if dbClient == nil {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidClientError, fmt.Sprintf("Client %v was not found", clientId))
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	client := mappers.ToClient(*dbClient)
+	if client.Blocked {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidClientError, fmt.Sprintf("Client %v is blocked", clientId))
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	if !client.IsConfidential() {
+		if clientSecret != "" || authenticationMethod != models.NoneAuthMethod {
+			errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidClientError, fmt.Sprintf("Client %v is a public client and cannot use a secret", clientId))
+			logger.Error(errorResponse.ErrorDescription)
+			return nil, &errorResponse
+		}
+
+		return &client, nil
+	}
+
+	if authenticationMethod != client.TokenEndpointAuthMethod {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidClientError, fmt.Sprintf("Client %v is not allowed to authenticate with %v", clientId, authenticationMethod))
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	hashedSecret := security.SHA256Encode(clientSecret)
+	if clientSecret == "" || subtle.ConstantTimeCompare([]byte(hashedSecret), []byte(client.Secret)) != 1 {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidClientError, fmt.Sprintf("Invalid secret for client %v", clientId))
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	return &client, nil
+}
+
+// AuthenticateRegisteredClient authenticates the client of a user grant, requests without any
+// client credentials are not bound to a client and any other client needs to be registered
+func AuthenticateRegisteredClient(clientId string, clientSecret string, authenticationMethod string) (*models.Client, *models.OAuthErrorResponse) {
+	if clientId == "" && clientSecret == "" {
+		return nil, nil
+	}
+
+	return AuthenticateClient(clientId, clientSecret, authenticationMethod)
+}
+
+// getRegisteredClient returns the client from the client store or nil if it was not registered
+func getRegisteredClient(authCtx *authorization_context.AuthorizationContext, clientId string) *models.Client {
+	if clientId == "" || authCtx.ClientDatabaseAdapter == nil {
+		return nil
+	}
+
+	dbClient := authCtx.ClientDatabaseAdapter.GetClientById(clientId)
+	if dbClient == nil {
+		return nil
+	}
+
+	client := mappers.ToClient(*dbClient)
+	return &client
+}
@@ -0,0 +1,75 @@
+package oauthflow
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cjlapao/common-go-identity/authorization_context"
+	"github.com/cjlapao/common-go-identity/jwt"
+	"github.com/cjlapao/common-go-identity/models"
+)
+
+type ClientCredentialsGrantFlow struct {
+	AuthenticationMethod string
+}
+
+func (clientCredentialsGrantFlow ClientCredentialsGrantFlow) Authenticate(request *models.OAuthLoginRequest) (*models.OAuthLoginResponse, *models.OAuthErrorResponse) {
+	var errorResponse models.OAuthErrorResponse
+	authCtx := authorization_context.Clone()
+
+	client, clientErrorResponse := AuthenticateClient(request.ClientID, request.ClientSecret, clientCredentialsGrantFlow.AuthenticationMethod)
+	if clientErrorResponse != nil {
+		return nil, clientErrorResponse
+	}
+
+	if !client.IsConfidential() {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthUnauthorizedClient, fmt.Sprintf("Client %v is a public client", client.ID))
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	if !client.AllowsGrantType(models.OAuthClientCredentialsGrant.String()) {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthUnauthorizedClient, fmt.Sprintf("Client %v is not allowed to use the client credentials grant", client.ID))
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	// when no scope is requested the client gets all the scopes it was registered with
+	scopes := strings.Fields(request.Scope)
+	if len(scopes) == 0 {
+		scopes = client.Scopes
+	}
+
+	for _, scope := range scopes {
+		if !client.AllowsScope(scope) {
+			errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidScope, fmt.Sprintf("Scope %v is not allowed for client %v", scope, client.ID))
+			logger.Error(errorResponse.ErrorDescription)
+			return nil, &errorResponse
+		}
+	}
+
+	token, err := jwt.GenerateClientToken("", *client, scopes, authCtx.Audiences...)
+	if err != nil {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidClientError, fmt.Sprintf("There was an error generating the client token, %v", err.Error()))
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	if err := formatAccessToken(authCtx, token, client); err != nil {
+		errorResponse = models.NewOAuthErrorResponse(models.UnknownError, fmt.Sprintf("There was an error issuing the opaque access token, %v", err.Error()))
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	expiresIn := authCtx.Options.TokenDuration * 60
+	response := models.OAuthLoginResponse{
+		AccessToken: token.Token,
+		ExpiresIn:   fmt.Sprintf("%v", expiresIn),
+		TokenType:   "Bearer",
+		Scope:       strings.Join(scopes, " "),
+	}
+
+	logger.Success("Token for client %v was generated successfully", client.ID)
+
+	return &response, nil
+}
@@ -0,0 +1,105 @@
+package oauthflow
+
+import (
+	"github.com/cjlapao/common-go-identity/authorization_context"
+	"github.com/cjlapao/common-go-identity/constants"
+	"github.com/cjlapao/common-go-identity/jwt"
+	"github.com/cjlapao/common-go-identity/mappers"
+	"github.com/cjlapao/common-go-identity/models"
+	"github.com/cjlapao/common-go-identity/user_manager"
+	"github.com/cjlapao/common-go/security"
+)
+
+// IntrospectToken returns the RFC 7662 state of an access or a refresh token, the token type hint
+// only decides which type is looked for first. Revoked, expired and unknown tokens are inactive
+func IntrospectToken(authCtx *authorization_context.AuthorizationContext, token string, tokenTypeHint string) models.OAuthIntrospectResponse {
+	lookups := []func(*authorization_context.AuthorizationContext, string) *models.OAuthIntrospectResponse{
+		introspectAccessToken,
+		introspectRefreshToken,
+	}
+	if tokenTypeHint == models.RefreshTokenTypeHint {
+		lookups[0], lookups[1] = lookups[1], lookups[0]
+	}
+
+	for _, lookup := range lookups {
+		if response := lookup(authCtx, token); response != nil {
+			return *response
+		}
+	}
+
+	return models.OAuthIntrospectResponse{Active: false}
+}
+
+func introspectAccessToken(authCtx *authorization_context.AuthorizationContext, token string) *models.OAuthIntrospectResponse {
+	userToken, err := jwt.ValidateUserToken(token, authCtx)
+	if err != nil {
+		return nil
+	}
+
+	// refresh tokens are signed by the same keys but they are not access tokens
+	if jwt.HasScope(userToken.Scope, constants.RefreshTokenScope) {
+		return nil
+	}
+
+	// the tokens of external issuers were not issued by us and their subject is not one of our users
+	if authCtx.TrustedIssuers.IsTrusted(userToken.Issuer) {
+		return nil
+	}
+
+	response := models.OAuthIntrospectResponse{
+		Active:    true,
+		Scope:     userToken.Scope,
+		ClientId:  userToken.ClientID,
+		Username:  userToken.User,
+		TokenType: "Bearer",
+		ExpiresAt: userToken.ExpiresAt.Unix(),
+		Subject:   userToken.User,
+		Audiences: userToken.Audiences,
+		Issuer:    userToken.Issuer,
+		ID:        userToken.ID,
+		Actor:     userToken.Actor,
+	}
+	if !userToken.IssuedAt.IsZero() {
+		response.IssuedAt = userToken.IssuedAt.Unix()
+	}
+	if !userToken.NotBefore.IsZero() {
+		response.NotBefore = userToken.NotBefore.Unix()
+	}
+	if user := user_manager.Get().GetUserById(userToken.UserID); user != nil {
+		response.Username = user.Username
+	}
+
+	return &response
+}
+
+func introspectRefreshToken(authCtx *authorization_context.AuthorizationContext, token string) *models.OAuthIntrospectResponse {
+	if authCtx.RefreshTokenDatabaseAdapter == nil {
+		return nil
+	}
+
+	dtoRecord := authCtx.RefreshTokenDatabaseAdapter.GetRefreshToken(security.SHA256Encode(token))
+	if dtoRecord == nil {
+		return nil
+	}
+
+	// a used refresh token is as good as revoked, using it again revokes its family
+	record := mappers.ToRefreshTokenRecord(*dtoRecord)
+	if record.Rotated || record.Revoked || record.IsExpired() {
+		return &models.OAuthIntrospectResponse{Active: false}
+	}
+
+	response := models.OAuthIntrospectResponse{
+		Active:    true,
+		Scope:     record.Scope,
+		ClientId:  record.ClientID,
+		ExpiresAt: record.ExpiresAt.Unix(),
+		IssuedAt:  record.IssuedAt.Unix(),
+		Issuer:    authCtx.Issuer,
+	}
+	if user := user_manager.Get().GetUserById(record.UserID); user != nil {
+		response.Subject = user.Email
+		response.Username = user.Username
+	}
+
+	return &response
+}
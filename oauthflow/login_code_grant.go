@@ -0,0 +1,72 @@
+package oauthflow
+
+import (
+	"fmt"
+
+	"github.com/cjlapao/common-go-identity/authorization_context"
+	identity_constants "github.com/cjlapao/common-go-identity/constants"
+	"github.com/cjlapao/common-go-identity/jwt"
+	"github.com/cjlapao/common-go-identity/models"
+	"github.com/cjlapao/common-go-identity/user_manager"
+)
+
+// LoginCodeGrantFlow signs a user in with the passwordless code or link that was sent to the
+// user email, wrong codes also count as failed logins so the user gets locked out
+type LoginCodeGrantFlow struct{}
+
+func (loginCodeGrantFlow LoginCodeGrantFlow) Authenticate(request *models.OAuthLoginRequest) (*models.OAuthLoginResponse, *models.OAuthErrorResponse) {
+	var errorResponse models.OAuthErrorResponse
+	authCtx := authorization_context.Clone()
+	usrManager := user_manager.Get()
+
+	if request.LoginCode == "" {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidRequestError, "The login_code parameter is required")
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	// login links carry the user so the username is only required for the codes
+	var user *models.User
+	if request.Username != "" {
+		user = usrManager.GetUserByUsername(request.Username)
+	} else if userId := jwt.GetTokenClaim(request.LoginCode, "sub"); userId != "" {
+		user = usrManager.GetUserById(userId)
+	}
+
+	if user == nil || user.ID == "" {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidGrant, fmt.Sprintf("User %v was not found", request.Username))
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	if user.IsLockedOut() {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthUserBlocked, fmt.Sprintf("User %v is locked until %v", user.Username, user.BlockedUntil))
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	if err := usrManager.ValidateLoginCode(user.ID, request.LoginCode, request.ClientID); err != nil {
+		err.Log()
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidGrant, fmt.Sprintf("Invalid login code for user %v", user.Username))
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	// A successful login clears any previous failed attempts
+	if user.InvalidAttempts > 0 || user.BlockedUntil != "" {
+		if err := usrManager.UpdateUserInvalidAttempts(user.ID, 0, ""); err != nil {
+			logger.Exception(err, "There was an error resetting the invalid attempts for user %v", user.Username)
+		}
+	}
+
+	if errorResponse := validateUserCanLogin(authCtx, user); errorResponse != nil {
+		return nil, errorResponse
+	}
+
+	// The code replaces the password only, users with a second factor still need it
+	if errorResponse := RequireMfa(user, request.ClientID, identity_constants.AmrOneTimeCode); errorResponse != nil {
+		return nil, errorResponse
+	}
+
+	return generateLoginResponse(authCtx, user, request.ClientID, newLoginDetails(request, identity_constants.AmrOneTimeCode))
+}
@@ -0,0 +1,136 @@
+package oauthflow
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cjlapao/common-go-identity/authorization_context"
+	identity_constants "github.com/cjlapao/common-go-identity/constants"
+	"github.com/cjlapao/common-go-identity/jwt"
+	"github.com/cjlapao/common-go-identity/models"
+)
+
+// loginDetails describes how the user authenticated, it is used to build the id token
+type loginDetails struct {
+	Scope    string
+	Nonce    string
+	Code     string
+	AuthTime time.Time
+	Amr      []string
+}
+
+// newLoginDetails returns the details of a login that was just completed in the token request
+func newLoginDetails(request *models.OAuthLoginRequest, amr ...string) loginDetails {
+	return loginDetails{
+		Scope:    request.Scope,
+		Nonce:    request.Nonce,
+		AuthTime: time.Now(),
+		Amr:      amr,
+	}
+}
+
+// withMfa adds the second factor to the methods used in the first step of a login
+func withMfa(amr []string) []string {
+	result := append([]string{}, amr...)
+	if len(result) == 0 {
+		result = append(result, identity_constants.AmrPassword)
+	}
+	if !slices.Contains(result, identity_constants.AmrOneTimeCode) {
+		result = append(result, identity_constants.AmrOneTimeCode)
+	}
+
+	return append(result, identity_constants.AmrMultiFactor)
+}
+
+// userInfoScopes returns the requested scopes that select the user claims, they are granted in
+// the access token so the userinfo endpoint releases the same claims as the id token
+func userInfoScopes(scope string) []string {
+	scopes := make([]string, 0)
+	for _, requested := range strings.Fields(scope) {
+		for _, userInfoScope := range identity_constants.UserInfoScopes {
+			if strings.EqualFold(requested, userInfoScope) && !slices.Contains(scopes, userInfoScope) {
+				scopes = append(scopes, userInfoScope)
+			}
+		}
+	}
+
+	return scopes
+}
+
+// formatAccessToken replaces the jwt access token with an opaque handle when the client or its
+// tenant use opaque access tokens, the jwt is never sent to the client in that case
+func formatAccessToken(authCtx *authorization_context.AuthorizationContext, token *models.UserToken, client *models.Client) error {
+	if authCtx.GetAccessTokenFormat(client) != models.OpaqueAccessTokenFormat {
+		return nil
+	}
+
+	return jwt.ToOpaqueToken(token, authCtx)
+}
+
+// generateLoginResponse issues the access and refresh tokens for an already authenticated user,
+// the id token is added when the openid scope was requested
+func generateLoginResponse(authCtx *authorization_context.AuthorizationContext, user *models.User, clientId string, details loginDetails) (*models.OAuthLoginResponse, *models.OAuthErrorResponse) {
+	var errorResponse models.OAuthErrorResponse
+
+	scopes := userInfoScopes(details.Scope)
+	token, err := jwt.GenerateUserTokenWithOptions("", *user, jwt.UserTokenOptions{
+		ClientID: clientId,
+		Scopes:   scopes,
+	}, authCtx.Audiences...)
+	if err != nil {
+		errorResponse = models.OAuthErrorResponse{
+			Error:            models.OAuthInvalidClientError,
+			ErrorDescription: fmt.Sprintf("There was an error validating user token, %v", err.Error()),
+		}
+		return nil, &errorResponse
+	}
+
+	if err := formatAccessToken(authCtx, token, getRegisteredClient(authCtx, clientId)); err != nil {
+		errorResponse = models.NewOAuthErrorResponse(models.UnknownError, fmt.Sprintf("There was an error issuing the opaque access token, %v", err.Error()))
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	// Every login starts a new refresh token family so each session can be rotated on its own
+	if err := RegisterRefreshToken(token.RefreshToken, *user, clientId, strings.Join(scopes, " ")); err != nil {
+		errorResponse = models.OAuthErrorResponse{
+			Error:            models.OAuthInvalidClientError,
+			ErrorDescription: fmt.Sprintf("There was an error storing the user refresh token, %v", err.Error()),
+		}
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	expiresIn := authCtx.Options.TokenDuration * 60
+	response := models.OAuthLoginResponse{
+		AccessToken:  token.Token,
+		RefreshToken: token.RefreshToken,
+		ExpiresIn:    fmt.Sprintf("%v", expiresIn),
+		TokenType:    "Bearer",
+		Scope:        token.Scope,
+	}
+
+	if clientId != "" && jwt.HasScope(details.Scope, identity_constants.OpenIdScope) {
+		idToken, err := jwt.GenerateIdToken("", *user, jwt.IdTokenOptions{
+			ClientID:    clientId,
+			Scope:       details.Scope,
+			Nonce:       details.Nonce,
+			AuthTime:    details.AuthTime,
+			Amr:         details.Amr,
+			AccessToken: token.Token,
+			Code:        details.Code,
+		})
+		if err != nil {
+			errorResponse = models.NewOAuthErrorResponse(models.UnknownError, fmt.Sprintf("There was an error generating the id token, %v", err.Error()))
+			logger.Error(errorResponse.ErrorDescription)
+			return nil, &errorResponse
+		}
+		response.IdToken = idToken
+	}
+
+	logger.Success("Token for user %v was generated successfully", user.Username)
+
+	return &response, nil
+}
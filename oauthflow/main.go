@@ -1,5 +1,27 @@
 package oauthflow
 
-import log "github.com/cjlapao/common-go-logger"
+import (
+	"github.com/cjlapao/common-go-identity/authorization_context"
+	"github.com/cjlapao/common-go-identity/models"
+	log "github.com/cjlapao/common-go-logger"
+)
 
 var logger = log.Get()
+
+// notify sends a notification raised by a flow, flows run outside of the
+// controllers so the notification does not carry the request
+func notify(authCtx *authorization_context.AuthorizationContext, notificationType models.OAuthNotificationType, data interface{}, err *models.OAuthErrorResponse) {
+	if authCtx.NotificationCallback == nil {
+		return
+	}
+
+	notification := models.OAuthNotification{
+		Type:  notificationType,
+		Data:  data,
+		Error: err,
+	}
+
+	if callbackErr := authCtx.NotificationCallback(notification); callbackErr != nil {
+		logger.Exception(callbackErr, "There was an error executing the %v notification callback", notificationType.String())
+	}
+}
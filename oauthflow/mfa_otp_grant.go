@@ -0,0 +1,129 @@
+package oauthflow
+
+import (
+	"fmt"
+
+	"github.com/cjlapao/common-go-identity/authorization_context"
+	identity_constants "github.com/cjlapao/common-go-identity/constants"
+	"github.com/cjlapao/common-go-identity/jwt"
+	"github.com/cjlapao/common-go-identity/models"
+	"github.com/cjlapao/common-go-identity/user_manager"
+)
+
+// MfaOtpGrantFlow completes a login that was interrupted with mfa_required, the mfa token
+// proves the password step and the one time password is the second factor
+type MfaOtpGrantFlow struct{}
+
+func (mfaOtpGrantFlow MfaOtpGrantFlow) Authenticate(request *models.OAuthLoginRequest) (*models.OAuthLoginResponse, *models.OAuthErrorResponse) {
+	var errorResponse models.OAuthErrorResponse
+	authCtx := authorization_context.Clone()
+	usrManager := user_manager.Get()
+
+	if request.MfaToken == "" || request.Otp == "" {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidRequestError, "The mfa_token and otp parameters are required")
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	userId := jwt.GetTokenClaim(request.MfaToken, "sub")
+	mfaToken, err := jwt.ValidateTokenByScope(request.MfaToken, userId, identity_constants.MfaScope)
+	if err != nil {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidGrant, fmt.Sprintf("The mfa token is not valid, %v", err.Error()))
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	// The second step must come from the same client that started the login
+	if mfaToken.ClientID != request.ClientID {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidGrant, "The mfa token was issued to another client")
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	user := usrManager.GetUserById(userId)
+	if user == nil || user.ID == "" {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidGrant, fmt.Sprintf("User %v was not found", userId))
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	if errorResponse := validateUserCanLogin(authCtx, user); errorResponse != nil {
+		return nil, errorResponse
+	}
+
+	// Wrong codes count as failed logins so the second factor cannot be brute forced
+	usedRecoveryCode, mfaErr := usrManager.ValidateMfaCode(user.ID, request.Otp)
+	if mfaErr != nil {
+		mfaErr.Log()
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidGrant, fmt.Sprintf("Invalid one time password for user %v", user.Username))
+		logger.Error(errorResponse.ErrorDescription)
+		PasswordGrantFlow{}.registerFailedAttempt(authCtx, user)
+		return nil, &errorResponse
+	}
+
+	if usedRecoveryCode {
+		notifyRecoveryCodeUsed(authCtx, user)
+	}
+
+	return generateLoginResponse(authCtx, user, request.ClientID, newLoginDetails(request, withMfa(mfaToken.Amr)...))
+}
+
+// RequireMfa returns the mfa_required error with a new mfa token if the user has a second
+// factor enabled, it returns nil if the user can login with the first factor only, the amr are
+// the methods used in the first factor
+func RequireMfa(user *models.User, clientId string, amr ...string) *models.OAuthErrorResponse {
+	authCtx := authorization_context.Clone()
+	if !user_manager.Get().IsTotpEnabled(user.ID) {
+		return nil
+	}
+
+	mfaToken := jwt.GenerateMfaToken(authCtx.Options.KeyId, *user, clientId, amr...)
+	if mfaToken == "" {
+		errorResponse := models.NewOAuthErrorResponse(models.UnknownError, fmt.Sprintf("There was an error generating the mfa token for user %v", user.Username))
+		logger.Error(errorResponse.ErrorDescription)
+		return &errorResponse
+	}
+
+	errorResponse := models.NewOAuthErrorResponse(models.OAuthMfaRequired, "Multi-factor authentication is required")
+	errorResponse.MfaToken = mfaToken
+	logger.Info("User %v needs to complete the multi-factor authentication", user.Username)
+	return &errorResponse
+}
+
+// ValidateMfa checks the one time password or recovery code of a user that has a second factor enabled when
+// the login happens in a single request, like the authorize endpoint
+func ValidateMfa(user *models.User, otp string) *models.OAuthErrorResponse {
+	usrManager := user_manager.Get()
+	if !usrManager.IsTotpEnabled(user.ID) {
+		return nil
+	}
+
+	if otp == "" {
+		errorResponse := models.NewOAuthErrorResponse(models.OAuthMfaRequired, "Multi-factor authentication is required")
+		return &errorResponse
+	}
+
+	authCtx := authorization_context.Clone()
+	usedRecoveryCode, err := usrManager.ValidateMfaCode(user.ID, otp)
+	if err != nil {
+		err.Log()
+		errorResponse := models.NewOAuthErrorResponse(models.OAuthInvalidMfaCode, fmt.Sprintf("Invalid one time password for user %v", user.Username))
+		logger.Error(errorResponse.ErrorDescription)
+		PasswordGrantFlow{}.registerFailedAttempt(authCtx, user)
+		return &errorResponse
+	}
+
+	if usedRecoveryCode {
+		notifyRecoveryCodeUsed(authCtx, user)
+	}
+
+	return nil
+}
+
+// notifyRecoveryCodeUsed raises the notification with the remaining codes so the user can be
+// warned when they are running out
+func notifyRecoveryCodeUsed(authCtx *authorization_context.AuthorizationContext, user *models.User) {
+	remaining := user_manager.Get().CountRecoveryCodes(user.ID)
+	logger.Info("User %v logged in with a recovery code, %v codes remaining", user.Username, remaining)
+	notify(authCtx, models.MfaRecoveryCodeUsed, models.OAuthRecoveryCodesResponse{Remaining: remaining}, nil)
+}
@@ -6,7 +6,9 @@ import (
 	"time"
 
 	"github.com/cjlapao/common-go-identity/authorization_context"
+	identity_constants "github.com/cjlapao/common-go-identity/constants"
 	"github.com/cjlapao/common-go-identity/jwt"
+	"github.com/cjlapao/common-go-identity/mappers"
 	"github.com/cjlapao/common-go-identity/models"
 	"github.com/cjlapao/common-go-identity/user_manager"
 	"github.com/cjlapao/common-go/security"
@@ -15,21 +17,37 @@ import (
 type PasswordGrantFlow struct{}
 
 func (passwordGrantFlow PasswordGrantFlow) Authenticate(request *models.OAuthLoginRequest) (*models.OAuthLoginResponse, *models.OAuthErrorResponse) {
+	authCtx := authorization_context.Clone()
+	user, errorResponse := passwordGrantFlow.ValidateCredentials(request.Username, request.Password)
+	if errorResponse != nil {
+		return nil, errorResponse
+	}
+
+	// Users with a second factor get an mfa token to exchange with the mfa_otp grant
+	if errorResponse := RequireMfa(user, request.ClientID, identity_constants.AmrPassword); errorResponse != nil {
+		return nil, errorResponse
+	}
+
+	return generateLoginResponse(authCtx, user, request.ClientID, newLoginDetails(request, identity_constants.AmrPassword))
+}
+
+// ValidateCredentials checks the username and password of a user and if the user is allowed to login
+func (passwordGrantFlow PasswordGrantFlow) ValidateCredentials(username string, password string) (*models.User, *models.OAuthErrorResponse) {
 	var errorResponse models.OAuthErrorResponse
 	authCtx := authorization_context.Clone()
 	usrManager := user_manager.Get()
-	user := usrManager.GetUserByUsername(request.Username)
+	user := usrManager.GetUserByUsername(username)
 
 	if user == nil || user.ID == "" {
 		if user == nil {
 			errorResponse = models.OAuthErrorResponse{
 				Error:            models.OAuthInvalidClientError,
-				ErrorDescription: fmt.Sprintf("User %v was not found", request.Username),
+				ErrorDescription: fmt.Sprintf("User %v was not found", username),
 			}
 		} else if user.Email == "" {
 			errorResponse = models.OAuthErrorResponse{
 				Error:            models.OAuthInvalidClientError,
-				ErrorDescription: fmt.Sprintf("User %v was not found", request.Username),
+				ErrorDescription: fmt.Sprintf("User %v was not found", username),
 			}
 		} else {
 			errorResponse = models.OAuthErrorResponse{
@@ -41,84 +59,112 @@ func (passwordGrantFlow PasswordGrantFlow) Authenticate(request *models.OAuthLog
 		return nil, &errorResponse
 	}
 
-	password := security.SHA256Encode(request.Password)
-
-	if password != user.Password {
+	// Locked out users are rejected before checking the password so the lock cannot be probed
+	if user.IsLockedOut() {
 		errorResponse = models.OAuthErrorResponse{
-			Error:            models.OAuthInvalidClientError,
-			ErrorDescription: fmt.Sprintf("Invalid password for user %v", request.Username),
+			Error:            models.OAuthUserBlocked,
+			ErrorDescription: fmt.Sprintf("User %v is locked until %v", username, user.BlockedUntil),
 		}
 		logger.Error(errorResponse.ErrorDescription)
 		return nil, &errorResponse
 	}
 
-	if authCtx.ValidationOptions.VerifiedEmail && !user.EmailVerified {
+	if !user.VerifyPassword(password) {
 		errorResponse = models.OAuthErrorResponse{
-			Error:            models.OAuthEmailNotVerified,
-			ErrorDescription: fmt.Sprintf("User %v email not verified", request.Username),
+			Error:            models.OAuthInvalidClientError,
+			ErrorDescription: fmt.Sprintf("Invalid password for user %v", username),
 		}
 		logger.Error(errorResponse.ErrorDescription)
+		passwordGrantFlow.registerFailedAttempt(authCtx, user)
 		return nil, &errorResponse
+	}
 
+	// Hashes from older algorithms or parameters are upgraded now that we know the password
+	if user.NeedsPasswordRehash() {
+		if err := usrManager.RehashPassword(user, password); err != nil {
+			err.Log()
+		}
 	}
 
-	if user.Blocked {
-		errorResponse = models.OAuthErrorResponse{
-			Error:            models.OAuthUserBlocked,
-			ErrorDescription: fmt.Sprintf("User %v is blocked", request.Username),
+	// A successful login clears any previous failed attempts
+	if user.InvalidAttempts > 0 || user.BlockedUntil != "" {
+		if err := usrManager.UpdateUserInvalidAttempts(user.ID, 0, ""); err != nil {
+			logger.Exception(err, "There was an error resetting the invalid attempts for user %v", username)
 		}
+	}
+
+	if errorResponse := validateUserCanLogin(authCtx, user); errorResponse != nil {
+		return nil, errorResponse
+	}
+
+	return user, nil
+}
+
+// validateUserCanLogin checks the state of a user that already proved who it is, every grant
+// rejects the users that are blocked, locked out or did not verify their email
+func validateUserCanLogin(authCtx *authorization_context.AuthorizationContext, user *models.User) *models.OAuthErrorResponse {
+	var errorResponse models.OAuthErrorResponse
+	if authCtx.ValidationOptions.VerifiedEmail && !user.EmailVerified {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthEmailNotVerified, fmt.Sprintf("User %v email not verified", user.Username))
 		logger.Error(errorResponse.ErrorDescription)
-		return nil, &errorResponse
+		return &errorResponse
 	}
 
-	token, err := jwt.GenerateDefaultUserToken(*user)
-	if err != nil {
-		errorResponse = models.OAuthErrorResponse{
-			Error:            models.OAuthInvalidClientError,
-			ErrorDescription: fmt.Sprintf("There was an error validating user token, %v", err.Error()),
-		}
-		return nil, &errorResponse
+	if user.Blocked {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthUserBlocked, fmt.Sprintf("User %v is blocked", user.Username))
+		logger.Error(errorResponse.ErrorDescription)
+		return &errorResponse
 	}
 
-	encodedToken, err := security.EncodeString(token.RefreshToken)
-	if err != nil {
-		errorResponse = models.OAuthErrorResponse{
-			Error:            models.OAuthInvalidClientError,
-			ErrorDescription: fmt.Sprintf("There was an error encoding user token, %v", err.Error()),
-		}
-		return nil, &errorResponse
+	if user.IsLockedOut() {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthUserBlocked, fmt.Sprintf("User %v is locked until %v", user.Username, user.BlockedUntil))
+		logger.Error(errorResponse.ErrorDescription)
+		return &errorResponse
 	}
 
-	authCtx.UserDatabaseAdapter.UpdateUserRefreshToken(user.ID, encodedToken)
+	return nil
+}
 
-	expiresIn := authCtx.Options.TokenDuration * 60
-	response := models.OAuthLoginResponse{
-		AccessToken:  token.Token,
-		RefreshToken: token.RefreshToken,
-		ExpiresIn:    fmt.Sprintf("%v", expiresIn),
-		TokenType:    "Bearer",
-		Scope:        authCtx.Scope,
+// registerFailedAttempt counts a failed login and locks the user for the block duration
+// once it reaches the attempts to block, the counter starts again after the lock
+func (passwordGrantFlow PasswordGrantFlow) registerFailedAttempt(authCtx *authorization_context.AuthorizationContext, user *models.User) {
+	usrManager := user_manager.Get()
+	// the counter is incremented by the store so concurrent attempts cannot overwrite each other
+	attempts, err := usrManager.IncrementUserInvalidAttempts(user.ID)
+	if err != nil {
+		logger.Exception(err, "There was an error updating the invalid attempts for user %v", user.Username)
+		return
 	}
 
-	logger.Success("Token for user %v was generated successfully", user.Username)
+	attemptsToBlock := authCtx.ValidationOptions.AttemptsToBlock
+	if attemptsToBlock <= 0 || attempts < attemptsToBlock {
+		return
+	}
 
-	return &response, nil
+	blockedUntil := time.Now().Add(time.Minute * time.Duration(authCtx.ValidationOptions.BlockDuration)).Format(time.RFC3339)
+	if err := usrManager.UpdateUserInvalidAttempts(user.ID, 0, blockedUntil); err != nil {
+		logger.Exception(err, "There was an error locking user %v", user.Username)
+		return
+	}
+
+	errorResponse := models.NewOAuthErrorResponse(models.OAuthUserBlocked, fmt.Sprintf("User %v was locked until %v after %v failed attempts", user.Username, blockedUntil, attemptsToBlock))
+	logger.Error(errorResponse.ErrorDescription)
+	notify(authCtx, models.UserLockedOut, user.ID, &errorResponse)
 }
 
+// RefreshToken exchanges a refresh token for new tokens, refresh tokens are single use and are
+// rotated on every call, replaying an already rotated token revokes its whole family
 func (passwordGrantFlow PasswordGrantFlow) RefreshToken(request *models.OAuthLoginRequest) (*models.OAuthLoginResponse, *models.OAuthErrorResponse) {
 	var errorResponse models.OAuthErrorResponse
 	authCtx := authorization_context.New()
 
+	if authCtx.RefreshTokenDatabaseAdapter == nil {
+		errorResponse = models.NewOAuthErrorResponse(models.UnknownError, "No refresh token context was found")
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
 	userEmail := jwt.GetTokenClaim(request.RefreshToken, "sub")
-	// encodedToken, err := security.EncodeString(request.RefreshToken)
-	// if err != nil {
-	// 	errorResponse = models.OAuthErrorResponse{
-	// 		Error:            models.OAuthInvalidRequestError,
-	// 		ErrorDescription: fmt.Sprintf("Unable to decode the token for user %s", userEmail),
-	// 	}
-	// 	logger.Error(errorResponse.ErrorDescription)
-	// 	return nil, &errorResponse
-	// }
 	usrManager := user_manager.Get()
 	user := usrManager.GetUserByEmail(userEmail)
 
@@ -147,47 +193,83 @@ func (passwordGrantFlow PasswordGrantFlow) RefreshToken(request *models.OAuthLog
 		return nil, &errorResponse
 	}
 
-	userRefreshToken := user.RefreshToken
-	if !strings.EqualFold(request.RefreshToken, userRefreshToken) {
+	if _, err := jwt.ValidateRefreshToken(request.RefreshToken, user.Email); err != nil {
 		errorResponse = models.OAuthErrorResponse{
-			Error:            models.OAuthInvalidClientError,
-			ErrorDescription: "Refresh token is invalid",
+			Error:            models.OAuthInvalidGrant,
+			ErrorDescription: fmt.Sprintf("There was an error validating user token, %v", err.Error()),
 		}
 		logger.Error(errorResponse.ErrorDescription)
 		return nil, &errorResponse
 	}
 
-	token, err := jwt.ValidateRefreshToken(request.RefreshToken, user.Email)
+	if errorResponse := validateUserCanLogin(authCtx, user); errorResponse != nil {
+		return nil, errorResponse
+	}
+
+	tokenId := security.SHA256Encode(request.RefreshToken)
+	dtoRecord := authCtx.RefreshTokenDatabaseAdapter.GetRefreshToken(tokenId)
+	if dtoRecord == nil {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidGrant, "Refresh token is invalid")
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	record := mappers.ToRefreshTokenRecord(*dtoRecord)
+	if record.Revoked || record.IsExpired() || record.UserID != user.ID {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidGrant, "Refresh token is invalid")
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	if record.ClientID != "" && !strings.EqualFold(record.ClientID, request.ClientID) {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidGrant, fmt.Sprintf("Refresh token was not issued to client %v", request.ClientID))
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	// Only one caller can rotate a token, anyone else is replaying it
+	if !authCtx.RefreshTokenDatabaseAdapter.RotateRefreshToken(tokenId) {
+		authCtx.RefreshTokenDatabaseAdapter.RevokeRefreshTokenFamily(record.FamilyID)
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidGrant, "Refresh token was already used, the session was revoked")
+		logger.Error("Refresh token reuse detected for user %v, revoking token family %v", user.Username, record.FamilyID)
+		return nil, &errorResponse
+	}
+
+	// The refreshed tokens keep the client and the user info scopes granted at login
+	newToken, err := jwt.GenerateUserTokenWithOptions("", *user, jwt.UserTokenOptions{
+		ClientID: record.ClientID,
+		Scopes:   strings.Fields(record.Scope),
+	}, authCtx.Audiences...)
 	if err != nil {
 		errorResponse = models.OAuthErrorResponse{
 			Error:            models.OAuthInvalidClientError,
-			ErrorDescription: fmt.Sprintf("There was an error validating user token, %v", err.Error()),
+			ErrorDescription: fmt.Sprintf("There was an error generating the new user token, %v", err.Error()),
 		}
 		return nil, &errorResponse
 	}
 
-	newToken, err := jwt.GenerateDefaultUserToken(*user)
-	if err != nil {
+	if err := formatAccessToken(authCtx, newToken, getRegisteredClient(authCtx, record.ClientID)); err != nil {
+		errorResponse = models.NewOAuthErrorResponse(models.UnknownError, fmt.Sprintf("There was an error issuing the opaque access token, %v", err.Error()))
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	if err := storeRefreshToken(authCtx, newToken.RefreshToken, *user, record.ClientID, record.Scope, record.FamilyID); err != nil {
 		errorResponse = models.OAuthErrorResponse{
 			Error:            models.OAuthInvalidClientError,
-			ErrorDescription: fmt.Sprintf("There was an error generating the new user token, %v", err.Error()),
+			ErrorDescription: fmt.Sprintf("There was an error storing the user refresh token, %v", err.Error()),
 		}
+		logger.Error(errorResponse.ErrorDescription)
 		return nil, &errorResponse
 	}
 
 	expiresIn := authCtx.Options.TokenDuration * 60
 	response := models.OAuthLoginResponse{
 		AccessToken:  newToken.Token,
-		RefreshToken: request.RefreshToken,
+		RefreshToken: newToken.RefreshToken,
 		ExpiresIn:    fmt.Sprintf("%v", expiresIn),
 		TokenType:    "Bearer",
-		Scope:        authCtx.Scope,
-	}
-
-	todayPlus30 := time.Now().Add((time.Hour * 24) * 30)
-	if token.ExpiresAt.Before(todayPlus30) {
-		response.RefreshToken = newToken.RefreshToken
-		authCtx.UserDatabaseAdapter.UpdateUserRefreshToken(user.ID, newToken.RefreshToken)
+		Scope:        newToken.Scope,
 	}
 
 	logger.Success("Token for user %v was generated successfully", user.Username)
@@ -0,0 +1,46 @@
+package oauthflow
+
+import (
+	"errors"
+	"time"
+
+	cryptorand "github.com/cjlapao/common-go-cryptorand"
+	"github.com/cjlapao/common-go-identity/authorization_context"
+	"github.com/cjlapao/common-go-identity/jwt"
+	"github.com/cjlapao/common-go-identity/mappers"
+	"github.com/cjlapao/common-go-identity/models"
+	"github.com/cjlapao/common-go/constants"
+	"github.com/cjlapao/common-go/security"
+)
+
+// RegisterRefreshToken stores a refresh token issued at login as the start of a new token family,
+// the scope is the user info scope granted at login so the refreshed tokens keep it
+func RegisterRefreshToken(refreshToken string, user models.User, clientId string, scope string) error {
+	authCtx := authorization_context.Clone()
+	familyId, err := cryptorand.GetRandomString(constants.ID_SIZE)
+	if err != nil {
+		return err
+	}
+
+	return storeRefreshToken(authCtx, refreshToken, user, clientId, scope, security.SHA256Encode(familyId))
+}
+
+// storeRefreshToken keeps the hash of the refresh token so it can be rotated and revoked
+func storeRefreshToken(authCtx *authorization_context.AuthorizationContext, refreshToken string, user models.User, clientId string, scope string, familyId string) error {
+	if authCtx.RefreshTokenDatabaseAdapter == nil {
+		return errors.New("no refresh token context was found")
+	}
+
+	record := models.RefreshTokenRecord{
+		ID:        security.SHA256Encode(refreshToken),
+		FamilyID:  familyId,
+		UserID:    user.ID,
+		ClientID:  clientId,
+		TenantID:  authCtx.TenantId,
+		Scope:     scope,
+		IssuedAt:  time.Now(),
+		ExpiresAt: jwt.GetTokenExpiry(refreshToken),
+	}
+
+	return authCtx.RefreshTokenDatabaseAdapter.UpsertRefreshToken(mappers.ToRefreshTokenDTO(record))
+}
@@ -0,0 +1,105 @@
+package oauthflow
+
+import (
+	"strings"
+	"time"
+
+	"github.com/cjlapao/common-go-identity/authorization_context"
+	"github.com/cjlapao/common-go-identity/database/dto"
+	"github.com/cjlapao/common-go-identity/jwt"
+	"github.com/cjlapao/common-go/security"
+)
+
+// RevokeToken revokes a refresh token with its whole family or denies an access token until it
+// expires, tokens issued to another client are left alone. The token type hint is not needed as
+// refresh tokens are found by their hash before trying the token as an access token
+func RevokeToken(authCtx *authorization_context.AuthorizationContext, token string, clientId string) bool {
+	if revokeRefreshToken(authCtx, token, clientId) {
+		return true
+	}
+
+	return revokeAccessToken(authCtx, token, clientId)
+}
+
+func revokeRefreshToken(authCtx *authorization_context.AuthorizationContext, token string, clientId string) bool {
+	if authCtx.RefreshTokenDatabaseAdapter == nil {
+		return false
+	}
+
+	record := authCtx.RefreshTokenDatabaseAdapter.GetRefreshToken(security.SHA256Encode(token))
+	if record == nil {
+		return false
+	}
+
+	if record.ClientID != "" && !strings.EqualFold(record.ClientID, clientId) {
+		logger.Error("Client %v cannot revoke a refresh token issued to client %v", clientId, record.ClientID)
+		return false
+	}
+
+	if err := authCtx.RefreshTokenDatabaseAdapter.RevokeRefreshTokenFamily(record.FamilyID); err != nil {
+		logger.Exception(err, "There was an error revoking the refresh token family %v", record.FamilyID)
+		return false
+	}
+
+	logger.Info("Refresh token family %v was revoked by client %v", record.FamilyID, clientId)
+	return true
+}
+
+func revokeAccessToken(authCtx *authorization_context.AuthorizationContext, token string, clientId string) bool {
+	if authCtx.RevocationDatabaseAdapter == nil {
+		return false
+	}
+
+	// tokens that are no longer valid do not need to be revoked
+	userToken, err := jwt.ValidateUserToken(token, authCtx)
+	if err != nil || userToken.ID == "" {
+		return false
+	}
+
+	if userToken.ClientID != "" && !strings.EqualFold(userToken.ClientID, clientId) {
+		logger.Error("Client %v cannot revoke an access token issued to client %v", clientId, userToken.ClientID)
+		return false
+	}
+
+	if err := authCtx.RevocationDatabaseAdapter.RevokeToken(userToken.ID, userToken.ExpiresAt); err != nil {
+		logger.Exception(err, "There was an error revoking the access token %v", userToken.ID)
+		return false
+	}
+	jwt.CacheTokenRevocation(userToken.ID, userToken.ExpiresAt)
+
+	// the claims of an opaque token are not needed anymore once it is denied
+	if jwt.IsOpaqueToken(token) {
+		if err := jwt.RemoveOpaqueToken(token, authCtx); err != nil {
+			logger.Exception(err, "There was an error removing the opaque access token %v", userToken.ID)
+		}
+	}
+
+	logger.Info("Access token %v was revoked by client %v", userToken.ID, clientId)
+	return true
+}
+
+// RevokeUserTokens revokes every access token issued to the user until now, including the ones
+// issued in the same second, the revocation is kept until the last of them expires
+func RevokeUserTokens(authCtx *authorization_context.AuthorizationContext, userId string) error {
+	if authCtx.RevocationDatabaseAdapter == nil {
+		return nil
+	}
+
+	// truncated like the issue time of the access tokens, rounding up would also revoke the tokens
+	// issued in the next second, access tokens expire two minutes after their duration to allow
+	// for clock skew
+	now := time.Now().Truncate(time.Second)
+	revocation := dto.UserTokenRevocationDTO{
+		UserID:        userId,
+		RevokedBefore: now,
+		ExpiresAt:     now.Add(time.Minute * time.Duration(authCtx.Options.TokenDuration+2)),
+	}
+
+	if err := authCtx.RevocationDatabaseAdapter.RevokeUserTokens(revocation); err != nil {
+		return err
+	}
+	jwt.CacheUserTokenRevocation(revocation)
+
+	logger.Info("Access tokens of user %v issued before %v were revoked", userId, now)
+	return nil
+}
@@ -0,0 +1,247 @@
+package oauthflow
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cjlapao/common-go-identity/authorization_context"
+	identity_constants "github.com/cjlapao/common-go-identity/constants"
+	"github.com/cjlapao/common-go-identity/jwt"
+	"github.com/cjlapao/common-go-identity/models"
+	"github.com/cjlapao/common-go-identity/user_manager"
+)
+
+// TokenExchangeGrantFlow exchanges the token of a user for a token of another audience as defined
+// in RFC 8693. With an actor token the issued token records the actor in the act claim, without
+// it the client acts for the user on its own only if its policy allows it
+type TokenExchangeGrantFlow struct {
+	AuthenticationMethod string
+}
+
+func (tokenExchangeGrantFlow TokenExchangeGrantFlow) Authenticate(request *models.OAuthLoginRequest) (*models.OAuthLoginResponse, *models.OAuthErrorResponse) {
+	var errorResponse models.OAuthErrorResponse
+	authCtx := authorization_context.Clone()
+
+	client, clientErrorResponse := AuthenticateClient(request.ClientID, request.ClientSecret, tokenExchangeGrantFlow.AuthenticationMethod)
+	if clientErrorResponse != nil {
+		return nil, clientErrorResponse
+	}
+
+	if !client.IsConfidential() {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthUnauthorizedClient, fmt.Sprintf("Client %v is a public client", client.ID))
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	policy := authCtx.GetTokenExchangePolicy(client.ID)
+	if !client.AllowsGrantType(models.OAuthTokenExchangeGrant.String()) || policy == nil {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthUnauthorizedClient, fmt.Sprintf("Client %v is not allowed to use the token exchange grant", client.ID))
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	if request.SubjectToken == "" || !isExchangeTokenType(request.SubjectTokenType) {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidRequestError, "A subject_token of a supported subject_token_type is required")
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	if request.RequestedTokenType != "" && request.RequestedTokenType != models.AccessTokenTypeUrn {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidRequestError, fmt.Sprintf("requested_token_type %v is not supported", request.RequestedTokenType))
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	subjectToken, err := validateExchangeToken(authCtx, request.SubjectToken)
+	if err != nil {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidGrant, fmt.Sprintf("Subject token is not valid, %v", err.Error()))
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	user := user_manager.Get().GetUserById(subjectToken.UserID)
+	if user == nil || user.ID == "" {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidGrant, "Subject token does not belong to a user")
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	actor, actorErrorResponse := exchangeActor(authCtx, *policy, request, subjectToken)
+	if actorErrorResponse != nil {
+		return nil, actorErrorResponse
+	}
+
+	audiences, audienceErrorResponse := exchangeAudiences(authCtx, *policy, request)
+	if audienceErrorResponse != nil {
+		return nil, audienceErrorResponse
+	}
+
+	scopes, scopeErrorResponse := exchangeScopes(authCtx, *policy, request, subjectToken)
+	if scopeErrorResponse != nil {
+		return nil, scopeErrorResponse
+	}
+
+	token, err := jwt.GenerateUserTokenWithOptions("", *user, jwt.UserTokenOptions{
+		ClientID:  client.ID,
+		Scopes:    scopes,
+		Actor:     actor,
+		ExpiresAt: subjectToken.ExpiresAt,
+	}, audiences...)
+	if err != nil {
+		errorResponse = models.NewOAuthErrorResponse(models.UnknownError, fmt.Sprintf("There was an error generating the exchanged token, %v", err.Error()))
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	if err := formatAccessToken(authCtx, token, client); err != nil {
+		errorResponse = models.NewOAuthErrorResponse(models.UnknownError, fmt.Sprintf("There was an error issuing the opaque access token, %v", err.Error()))
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	response := models.OAuthLoginResponse{
+		AccessToken:     token.Token,
+		IssuedTokenType: models.AccessTokenTypeUrn,
+		ExpiresIn:       fmt.Sprintf("%v", int(time.Until(token.ExpiresAt).Seconds())),
+		TokenType:       "Bearer",
+		Scope:           strings.Join(scopes, " "),
+	}
+
+	notify(authCtx, models.TokenExchanged, models.OAuthTokenExchangeNotification{
+		ClientID: client.ID,
+		Subject:  user.ID,
+		Actor:    actor,
+	}, nil)
+
+	if actor != nil {
+		logger.Success("Token of user %v was exchanged by client %v for actor %v", user.Username, client.ID, actor.Subject)
+	} else {
+		logger.Success("Token of user %v was exchanged by client %v", user.Username, client.ID)
+	}
+
+	return &response, nil
+}
+
+// isExchangeTokenType returns true for the token types that are validated as access tokens
+func isExchangeTokenType(tokenType string) bool {
+	return tokenType == models.AccessTokenTypeUrn || tokenType == models.JwtTokenTypeUrn
+}
+
+// validateExchangeToken validates a subject or actor token, only the access tokens issued by this
+// identity server can be exchanged
+func validateExchangeToken(authCtx *authorization_context.AuthorizationContext, token string) (*models.UserToken, error) {
+	userToken, err := jwt.ValidateUserToken(token, authCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	if jwt.HasScope(userToken.Scope, identity_constants.RefreshTokenScope) {
+		return nil, errors.New("refresh tokens cannot be exchanged")
+	}
+	if authCtx.TrustedIssuers.IsTrusted(userToken.Issuer) {
+		return nil, errors.New("tokens of external issuers cannot be exchanged")
+	}
+
+	return userToken, nil
+}
+
+// exchangeActor returns the act claim of the exchanged token, the actors of the subject token are
+// kept so the whole delegation chain can be audited
+func exchangeActor(authCtx *authorization_context.AuthorizationContext, policy authorization_context.TokenExchangePolicy, request *models.OAuthLoginRequest, subjectToken *models.UserToken) (*models.TokenActor, *models.OAuthErrorResponse) {
+	var errorResponse models.OAuthErrorResponse
+
+	if request.ActorToken == "" {
+		if request.ActorTokenType != "" || !policy.AllowWithoutActor {
+			errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidRequestError, "An actor_token is required")
+			logger.Error(errorResponse.ErrorDescription)
+			return nil, &errorResponse
+		}
+
+		return subjectToken.Actor, nil
+	}
+
+	if !isExchangeTokenType(request.ActorTokenType) {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidRequestError, "An actor_token_type of a supported type is required")
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	actorToken, err := validateExchangeToken(authCtx, request.ActorToken)
+	if err != nil {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidGrant, fmt.Sprintf("Actor token is not valid, %v", err.Error()))
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	if !policy.AllowsActor(actorToken.Roles) {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidGrant, fmt.Sprintf("Actor %v is not allowed to act for the subject", actorToken.User))
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	return &models.TokenActor{
+		Subject:  actorToken.User,
+		ClientID: actorToken.ClientID,
+		Actor:    subjectToken.Actor,
+	}, nil
+}
+
+// exchangeAudiences returns the audiences of the exchanged token, the requested audience and
+// resource need to be allowed by the policy of the client
+func exchangeAudiences(authCtx *authorization_context.AuthorizationContext, policy authorization_context.TokenExchangePolicy, request *models.OAuthLoginRequest) ([]string, *models.OAuthErrorResponse) {
+	audiences := make([]string, 0)
+	for _, audience := range []string{request.Audience, request.Resource} {
+		if audience == "" {
+			continue
+		}
+
+		if !policy.AllowsAudience(audience) {
+			errorResponse := models.NewOAuthErrorResponse(models.OAuthInvalidTarget, fmt.Sprintf("Client %v cannot request tokens for %v", policy.ClientID, audience))
+			logger.Error(errorResponse.ErrorDescription)
+			return nil, &errorResponse
+		}
+		audiences = append(audiences, audience)
+	}
+
+	if len(audiences) == 0 {
+		return authCtx.Audiences, nil
+	}
+
+	return audiences, nil
+}
+
+// exchangeScopes returns the scopes of the exchanged token, the requested scopes can only narrow
+// the scopes granted to the subject token and the ones allowed by the policy
+func exchangeScopes(authCtx *authorization_context.AuthorizationContext, policy authorization_context.TokenExchangePolicy, request *models.OAuthLoginRequest, subjectToken *models.UserToken) ([]string, *models.OAuthErrorResponse) {
+	// the context scope is added to every token so it is not a granted scope
+	granted := make([]string, 0)
+	for _, scope := range strings.Fields(subjectToken.Scope) {
+		if !jwt.HasScope(authCtx.Scope, scope) {
+			granted = append(granted, scope)
+		}
+	}
+
+	requested := strings.Fields(request.Scope)
+	if len(requested) == 0 {
+		scopes := make([]string, 0)
+		for _, scope := range granted {
+			if policy.AllowsScope(scope) {
+				scopes = append(scopes, scope)
+			}
+		}
+
+		return scopes, nil
+	}
+
+	for _, scope := range requested {
+		if !jwt.HasScope(strings.Join(granted, " "), scope) || !policy.AllowsScope(scope) {
+			errorResponse := models.NewOAuthErrorResponse(models.OAuthInvalidScope, fmt.Sprintf("Scope %v cannot be granted to the exchanged token", scope))
+			logger.Error(errorResponse.ErrorDescription)
+			return nil, &errorResponse
+		}
+	}
+
+	return requested, nil
+}
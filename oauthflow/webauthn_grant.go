@@ -0,0 +1,66 @@
+package oauthflow
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cjlapao/common-go-identity/authorization_context"
+	identity_constants "github.com/cjlapao/common-go-identity/constants"
+	"github.com/cjlapao/common-go-identity/jwt"
+	"github.com/cjlapao/common-go-identity/models"
+	"github.com/cjlapao/common-go-identity/user_manager"
+	"github.com/cjlapao/common-go-identity/webauthn"
+)
+
+// WebAuthnGrantFlow signs a user in with a passkey, the session comes from the login options
+// and the response is the assertion the authenticator signed with its challenge
+type WebAuthnGrantFlow struct{}
+
+func (webAuthnGrantFlow WebAuthnGrantFlow) Authenticate(request *models.OAuthLoginRequest) (*models.OAuthLoginResponse, *models.OAuthErrorResponse) {
+	var errorResponse models.OAuthErrorResponse
+	authCtx := authorization_context.Clone()
+	usrManager := user_manager.Get()
+
+	if request.WebAuthnSession == "" || request.WebAuthnResponse == "" {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidRequestError, "The webauthn_session and webauthn_response parameters are required")
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	var assertion webauthn.AssertionResponse
+	if err := json.Unmarshal([]byte(request.WebAuthnResponse), &assertion); err != nil {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidRequestError, "The webauthn_response is not a valid assertion")
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	// the session has no subject when the login starts without a username
+	userId := jwt.GetTokenClaim(request.WebAuthnSession, "sub")
+	session, err := jwt.ValidateTokenByScope(request.WebAuthnSession, userId, identity_constants.WebAuthnLoginScope)
+	if err != nil {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidGrant, fmt.Sprintf("The webauthn session is not valid, %v", err.Error()))
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	if session.ClientID != request.ClientID {
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidGrant, "The webauthn session was issued to another client")
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	user, usrErr := usrManager.ValidateWebAuthnAssertion(session.Nonce, userId, assertion)
+	if usrErr != nil {
+		usrErr.Log()
+		errorResponse = models.NewOAuthErrorResponse(models.OAuthInvalidGrant, "The webauthn assertion is not valid")
+		logger.Error(errorResponse.ErrorDescription)
+		return nil, &errorResponse
+	}
+
+	if errorResponse := validateUserCanLogin(authCtx, user); errorResponse != nil {
+		return nil, errorResponse
+	}
+
+	// passkeys require user verification so they are a second factor on their own
+	return generateLoginResponse(authCtx, user, request.ClientID, newLoginDetails(request, identity_constants.AmrHardwareKey, identity_constants.AmrMultiFactor))
+}
@@ -0,0 +1,109 @@
+package identity
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/cjlapao/common-go-identity/authorization_context"
+	"github.com/cjlapao/common-go-identity/models"
+	"github.com/cjlapao/common-go/security"
+)
+
+func TestOpaqueAccessToken(t *testing.T) {
+	client := models.NewClient()
+	client.ID = "opaque-spa"
+	client.TokenEndpointAuthMethod = models.NoneAuthMethod
+	client.GrantTypes = []string{"password", "refresh_token"}
+	client.AccessTokenFormat = models.OpaqueAccessTokenFormat
+	addTestClient(t, *client)
+
+	status, body := requestToken(t, url.Values{
+		"grant_type": {"password"},
+		"client_id":  {"opaque-spa"},
+		"username":   {testAdminUsername},
+		"password":   {testAdminPassword},
+		"scope":      {"openid"},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("expected a login, got %v %v", status, body)
+	}
+	accessToken := body["access_token"].(string)
+	if strings.Contains(accessToken, ".") {
+		t.Fatalf("expected an opaque access token, got %v", accessToken)
+	}
+	if claims := decodeTestToken(t, body["id_token"].(string)); claims["at_hash"] != testTokenHash(accessToken) {
+		t.Errorf("expected the id token to be bound to the opaque token, got %v", claims["at_hash"])
+	}
+
+	// only the hash of the handle is stored
+	tokens := authorization_context.GetBaseContext().OpaqueTokenDatabaseAdapter
+	if tokens.GetOpaqueToken(accessToken) != nil || tokens.GetOpaqueToken(security.SHA256Encode(accessToken)) == nil {
+		t.Errorf("expected the opaque token to be stored by its hash")
+	}
+
+	if status := sendJsonRequest(t, http.MethodGet, "userinfo", accessToken, nil, nil); status != http.StatusOK {
+		t.Fatalf("expected the opaque token to be valid, got %v", status)
+	}
+	state := introspectTokenState(t, url.Values{"token": {accessToken}})
+	if state["active"] != true || state["client_id"] != "opaque-spa" || state["username"] != testAdminUsername {
+		t.Fatalf("expected an active opaque token, got %v", state)
+	}
+
+	status, body = requestToken(t, url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {"opaque-spa"},
+		"refresh_token": {body["refresh_token"].(string)},
+	})
+	if status != http.StatusOK || strings.Contains(body["access_token"].(string), ".") {
+		t.Fatalf("expected the refreshed access token to be opaque, got %v %v", status, body)
+	}
+
+	revokeToken(t, url.Values{"token": {accessToken}, "client_id": {"opaque-spa"}})
+	if status := sendJsonRequest(t, http.MethodGet, "userinfo", accessToken, nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("expected the revoked opaque token to be rejected, got %v", status)
+	}
+	if state := introspectTokenState(t, url.Values{"token": {accessToken}}); state["active"] != false {
+		t.Errorf("expected the revoked opaque token to be inactive, got %v", state)
+	}
+	if status := sendJsonRequest(t, http.MethodGet, "userinfo", "not-an-opaque-token", nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("expected an unknown opaque token to be rejected, got %v", status)
+	}
+}
+
+func TestOpaqueAccessTokenTenant(t *testing.T) {
+	getTestServer(t)
+	authCtx := authorization_context.GetBaseContext()
+	tenants := authCtx.Options.OpaqueTokenTenants
+	defer func() {
+		authCtx.Options.OpaqueTokenTenants = tenants
+	}()
+	authCtx.WithOpaqueAccessTokens("opaque-tenant")
+
+	client := models.NewClient()
+	client.ID = "opaque-tenant-service"
+	client.TenantID = "opaque-tenant"
+	client.Secret = security.SHA256Encode(testClientSecret)
+	client.GrantTypes = []string{"client_credentials"}
+	client.Scopes = []string{"orders.read"}
+	addTestClient(t, *client)
+
+	status, body := requestTokenWithBasicAuth(t, url.Values{"grant_type": {"client_credentials"}}, client.ID, testClientSecret)
+	if status != http.StatusOK || strings.Contains(body["access_token"].(string), ".") {
+		t.Fatalf("expected an opaque client token, got %v %v", status, body)
+	}
+	state := introspectTokenState(t, url.Values{"token": {body["access_token"].(string)}})
+	if state["active"] != true || state["client_id"] != client.ID || state["sub"] != client.ID {
+		t.Errorf("expected an active opaque client token, got %v", state)
+	}
+
+	// the format chosen by the client is used before the one of its tenant
+	client.AccessTokenFormat = models.JwtAccessTokenFormat
+	if format := authCtx.GetAccessTokenFormat(client); format != models.JwtAccessTokenFormat {
+		t.Errorf("expected the client format, got %v", format)
+	}
+	if format := authCtx.GetAccessTokenFormat(&models.Client{TenantID: "other-tenant"}); format != models.JwtAccessTokenFormat {
+		t.Errorf("expected other tenants to keep jwt tokens, got %v", format)
+	}
+}
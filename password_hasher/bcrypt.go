@@ -0,0 +1,70 @@
+package password_hasher
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const BcryptAlgorithm = "2a"
+
+// BcryptHasher hashes passwords with bcrypt, the modular crypt format it produces
+// ($2a$<cost>$<salt+hash>) is already a PHC compatible string
+type BcryptHasher struct {
+	Cost int
+}
+
+func NewBcryptHasher() BcryptHasher {
+	return BcryptHasher{
+		Cost: 12,
+	}
+}
+
+func (h BcryptHasher) Name() string {
+	return BcryptAlgorithm
+}
+
+// Hash returns the bcrypt hash of the password, bcrypt only uses the first 72
+// bytes so longer passwords are rejected instead of silently truncated
+func (h BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+func (h BcryptHasher) Verify(password string, hash string) (bool, error) {
+	if !isBcryptHash(hash) {
+		return false, ErrInvalidHash
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+
+	return false, err
+}
+
+func (h BcryptHasher) NeedsRehash(hash string) bool {
+	if !isBcryptHash(hash) {
+		return true
+	}
+
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return true
+	}
+
+	return cost != h.Cost
+}
+
+func isBcryptHash(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
@@ -0,0 +1,95 @@
+package password_hasher
+
+import (
+	"crypto/subtle"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/cjlapao/common-go/security"
+)
+
+var (
+	ErrInvalidHash         = errors.New("the password hash is not in a supported format")
+	ErrIncompatibleVersion = errors.New("the password hash was created with an incompatible version")
+)
+
+// PasswordHasher hashes passwords into a self describing PHC string and verifies
+// passwords against the hashes it produced
+type PasswordHasher interface {
+	// Name returns the algorithm identifier used in the PHC string
+	Name() string
+	// Hash returns the PHC formatted hash of the password using a random salt
+	Hash(password string) (string, error)
+	// Verify returns true if the password matches the hash
+	Verify(password string, hash string) (bool, error)
+	// NeedsRehash returns true if the hash was not produced by this hasher with its
+	// current parameters and should be replaced
+	NeedsRehash(hash string) bool
+}
+
+var (
+	defaultHasherLock sync.RWMutex
+	defaultHasher     PasswordHasher = NewArgon2idHasher()
+)
+
+// Get returns the hasher used to hash new passwords, argon2id unless changed with SetDefault
+func Get() PasswordHasher {
+	defaultHasherLock.RLock()
+	defer defaultHasherLock.RUnlock()
+	return defaultHasher
+}
+
+// SetDefault changes the hasher used to hash new passwords, existing hashes keep
+// verifying with their own algorithm and are upgraded on the next login
+func SetDefault(hasher PasswordHasher) {
+	if hasher == nil {
+		return
+	}
+
+	defaultHasherLock.Lock()
+	defer defaultHasherLock.Unlock()
+	defaultHasher = hasher
+}
+
+// Hash hashes the password with the default hasher
+func Hash(password string) (string, error) {
+	return Get().Hash(password)
+}
+
+// Verify checks the password against a hash created by any of the supported
+// algorithms, including the legacy unsalted SHA-256 hex digests
+func Verify(password string, hash string) (bool, error) {
+	switch {
+	case strings.HasPrefix(hash, "$"+Argon2idAlgorithm+"$"):
+		return NewArgon2idHasher().Verify(password, hash)
+	case isBcryptHash(hash):
+		return NewBcryptHasher().Verify(password, hash)
+	case IsLegacyHash(hash):
+		hashedPassword := security.SHA256Encode(password)
+		return subtle.ConstantTimeCompare([]byte(strings.ToLower(hash)), []byte(hashedPassword)) == 1, nil
+	default:
+		return false, ErrInvalidHash
+	}
+}
+
+// NeedsRehash returns true if the hash should be replaced by one from the default hasher
+func NeedsRehash(hash string) bool {
+	return Get().NeedsRehash(hash)
+}
+
+// IsLegacyHash returns true for the unsalted SHA-256 hex digests used before the
+// PHC formatted hashes
+func IsLegacyHash(hash string) bool {
+	if len(hash) != 64 {
+		return false
+	}
+
+	for _, c := range strings.ToLower(hash) {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+
+	return true
+}
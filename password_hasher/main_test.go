@@ -0,0 +1,85 @@
+package password_hasher
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cjlapao/common-go/security"
+)
+
+func TestHashersRoundTrip(t *testing.T) {
+	hashers := []PasswordHasher{
+		NewArgon2idHasher(),
+		BcryptHasher{Cost: 4},
+	}
+
+	for _, hasher := range hashers {
+		t.Run(hasher.Name(), func(t *testing.T) {
+			hash, err := hasher.Hash("p@ssw0rd")
+			if err != nil {
+				t.Fatalf("failed to hash the password, %v", err)
+			}
+			if !strings.HasPrefix(hash, "$"+hasher.Name()+"$") {
+				t.Errorf("expected a PHC string for %v, got %v", hasher.Name(), hash)
+			}
+
+			otherHash, _ := hasher.Hash("p@ssw0rd")
+			if hash == otherHash {
+				t.Errorf("expected the hashes to use a random salt")
+			}
+
+			if valid, err := Verify("p@ssw0rd", hash); err != nil || !valid {
+				t.Errorf("expected the password to verify, got %v %v", valid, err)
+			}
+			if valid, _ := Verify("wrong-password", hash); valid {
+				t.Errorf("expected a wrong password not to verify")
+			}
+			if hasher.NeedsRehash(hash) {
+				t.Errorf("expected a fresh hash not to need a rehash")
+			}
+		})
+	}
+}
+
+func TestLegacyHash(t *testing.T) {
+	legacyHash := security.SHA256Encode("p@ssw0rd")
+	if !IsLegacyHash(legacyHash) {
+		t.Fatalf("expected %v to be a legacy hash", legacyHash)
+	}
+
+	if valid, err := Verify("p@ssw0rd", legacyHash); err != nil || !valid {
+		t.Errorf("expected the legacy hash to verify, got %v %v", valid, err)
+	}
+	if valid, _ := Verify("wrong-password", legacyHash); valid {
+		t.Errorf("expected a wrong password not to verify")
+	}
+	if !NewArgon2idHasher().NeedsRehash(legacyHash) || !NewBcryptHasher().NeedsRehash(legacyHash) {
+		t.Errorf("expected the legacy hash to need a rehash")
+	}
+}
+
+func TestNeedsRehashOnParameterChange(t *testing.T) {
+	weaker := NewArgon2idHasher()
+	weaker.Iterations = 1
+	hash, _ := weaker.Hash("p@ssw0rd")
+
+	if !NewArgon2idHasher().NeedsRehash(hash) {
+		t.Errorf("expected a hash with older parameters to need a rehash")
+	}
+	if valid, _ := NewArgon2idHasher().Verify("p@ssw0rd", hash); !valid {
+		t.Errorf("expected a hash with older parameters to still verify")
+	}
+
+	bcryptHash, _ := BcryptHasher{Cost: 4}.Hash("p@ssw0rd")
+	if !NewArgon2idHasher().NeedsRehash(bcryptHash) {
+		t.Errorf("expected a bcrypt hash to need a rehash when argon2id is the default")
+	}
+}
+
+func TestVerifyInvalidHash(t *testing.T) {
+	for _, hash := range []string{"", "plain-text", "$argon2id$v=19$m=1$salt$key", "$argon2id$v=16$m=19456,t=2,p=1$c2FsdA$a2V5"} {
+		if valid, err := Verify("p@ssw0rd", hash); valid || err == nil {
+			t.Errorf("expected %q to be rejected, got %v %v", hash, valid, err)
+		}
+	}
+}
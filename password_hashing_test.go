@@ -0,0 +1,41 @@
+package identity
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cjlapao/common-go-identity/authorization_context"
+	"github.com/cjlapao/common-go-identity/password_hasher"
+	"github.com/cjlapao/common-go/security"
+)
+
+func TestDefaultUsersUseArgon2id(t *testing.T) {
+	getTestServer(t)
+	user := authorization_context.GetBaseContext().UserDatabaseAdapter.GetUserByUsername(testAdminUsername)
+	if user == nil || !strings.HasPrefix(user.Password, "$"+password_hasher.Argon2idAlgorithm+"$") {
+		t.Fatalf("expected the default admin password to be an argon2id hash")
+	}
+}
+
+func TestLegacyPasswordIsRehashedOnLogin(t *testing.T) {
+	addTestUser(t, testLockoutUserId, testLockoutPassword)
+	authCtx := authorization_context.GetBaseContext()
+	if err := authCtx.UserDatabaseAdapter.UpdateUserPassword(testLockoutUserId, security.SHA256Encode(testLockoutPassword)); err != nil {
+		t.Fatalf("failed to set the legacy password hash, %v", err)
+	}
+
+	if status, body := passwordLogin(t, testLockoutUsername, testLockoutPassword, nil); status != http.StatusOK {
+		t.Fatalf("expected status %v, got %v %v", http.StatusOK, status, body)
+	}
+
+	user := authCtx.UserDatabaseAdapter.GetUserById(testLockoutUserId)
+	if password_hasher.IsLegacyHash(user.Password) || password_hasher.NeedsRehash(user.Password) {
+		t.Fatalf("expected the legacy hash to be upgraded after login, got %v", user.Password)
+	}
+
+	// the upgraded hash keeps working
+	if status, body := passwordLogin(t, testLockoutUsername, testLockoutPassword, nil); status != http.StatusOK {
+		t.Fatalf("expected status %v, got %v %v", http.StatusOK, status, body)
+	}
+}
@@ -0,0 +1,105 @@
+package identity
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/cjlapao/common-go-identity/models"
+)
+
+func loginWithPassword(t *testing.T) string {
+	t.Helper()
+	status, body := requestToken(t, url.Values{
+		"grant_type": {"password"},
+		"client_id":  {"spa"},
+		"username":   {testAdminUsername},
+		"password":   {testAdminPassword},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("expected status %v, got %v %v", http.StatusOK, status, body)
+	}
+
+	refreshToken, _ := body["refresh_token"].(string)
+	if refreshToken == "" {
+		t.Fatalf("expected a refresh token, got %v", body)
+	}
+
+	return refreshToken
+}
+
+func refreshToken(t *testing.T, token string) (int, map[string]interface{}) {
+	t.Helper()
+	return requestToken(t, url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {"spa"},
+		"refresh_token": {token},
+	})
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	firstDevice := loginWithPassword(t)
+	secondDevice := loginWithPassword(t)
+
+	status, body := refreshToken(t, firstDevice)
+	if status != http.StatusOK {
+		t.Fatalf("expected status %v, got %v %v", http.StatusOK, status, body)
+	}
+	rotated := body["refresh_token"].(string)
+	if rotated == "" || rotated == firstDevice {
+		t.Fatalf("expected a new refresh token after rotation")
+	}
+
+	// logging in on another device does not invalidate the first session
+	status, body = refreshToken(t, secondDevice)
+	if status != http.StatusOK {
+		t.Fatalf("expected the second session to refresh, got %v %v", status, body)
+	}
+
+	status, body = refreshToken(t, rotated)
+	if status != http.StatusOK {
+		t.Fatalf("expected the rotated token to refresh, got %v %v", status, body)
+	}
+}
+
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	original := loginWithPassword(t)
+	otherSession := loginWithPassword(t)
+
+	status, body := refreshToken(t, original)
+	if status != http.StatusOK {
+		t.Fatalf("expected status %v, got %v %v", http.StatusOK, status, body)
+	}
+	rotated := body["refresh_token"].(string)
+
+	// replaying the original token is detected
+	status, body = refreshToken(t, original)
+	if status != http.StatusBadRequest || body["error"] != models.OAuthInvalidGrant.String() {
+		t.Fatalf("expected invalid_grant when replaying a token, got %v %v", status, body)
+	}
+
+	// and the whole family is revoked, including the legitimately rotated token
+	status, body = refreshToken(t, rotated)
+	if status != http.StatusBadRequest || body["error"] != models.OAuthInvalidGrant.String() {
+		t.Fatalf("expected the rotated token to be revoked, got %v %v", status, body)
+	}
+
+	// other sessions are not affected
+	status, body = refreshToken(t, otherSession)
+	if status != http.StatusOK {
+		t.Fatalf("expected other sessions to keep working, got %v %v", status, body)
+	}
+}
+
+func TestRefreshTokenBoundToClient(t *testing.T) {
+	token := loginWithPassword(t)
+
+	status, body := requestToken(t, url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {"another-app"},
+		"refresh_token": {token},
+	})
+	if status != http.StatusBadRequest || body["error"] != models.OAuthInvalidGrant.String() {
+		t.Fatalf("expected invalid_grant for another client, got %v %v", status, body)
+	}
+}
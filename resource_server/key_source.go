@@ -0,0 +1,140 @@
+package resource_server
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+
+	"github.com/cjlapao/common-go-identity/trusted_issuers"
+)
+
+var (
+	ErrKeyNotFound         = errors.New("the signing key was not found")
+	ErrUnsupportedKeyType  = errors.New("the key type is not supported")
+	ErrInvalidPemPublicKey = errors.New("no public key or certificate was found in the pem")
+)
+
+// KeySource returns the key that verifies the tokens signed with the key id, the keys are rsa,
+// ecdsa or ed25519 public keys or the []byte secret of a hmac key
+type KeySource interface {
+	GetKey(keyId string) (interface{}, error)
+}
+
+// StaticKeySource verifies the tokens with a fixed set of keys, a single key is also used for the
+// tokens without a key id
+type StaticKeySource struct {
+	keys map[string]interface{}
+}
+
+func NewStaticKeySource() *StaticKeySource {
+	return &StaticKeySource{
+		keys: make(map[string]interface{}),
+	}
+}
+
+// NewPublicKeySource verifies the tokens with a single public key whatever their key id is
+func NewPublicKeySource(key interface{}) (*StaticKeySource, error) {
+	source := NewStaticKeySource()
+	if err := source.Add("", key); err != nil {
+		return nil, err
+	}
+
+	return source, nil
+}
+
+// NewPemKeySource verifies the tokens with the public key of a PKIX public key or a certificate
+func NewPemKeySource(data []byte) (*StaticKeySource, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrInvalidPemPublicKey
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var certificate *x509.Certificate
+		certificate, err = x509.ParseCertificate(block.Bytes)
+		if err == nil {
+			key = certificate.PublicKey
+		}
+	default:
+		return nil, ErrInvalidPemPublicKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return NewPublicKeySource(key)
+}
+
+// NewHmacKeySource verifies the tokens with a shared hmac secret
+func NewHmacKeySource(secret []byte) *StaticKeySource {
+	source := NewStaticKeySource()
+	source.keys[""] = secret
+	return source
+}
+
+// Add adds a key for the key id, an empty id is used for any key id
+func (s *StaticKeySource) Add(keyId string, key interface{}) error {
+	switch key := key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey, []byte:
+		s.keys[keyId] = key
+	case *rsa.PrivateKey:
+		s.keys[keyId] = &key.PublicKey
+	case *ecdsa.PrivateKey:
+		s.keys[keyId] = &key.PublicKey
+	case ed25519.PrivateKey:
+		s.keys[keyId] = key.Public()
+	default:
+		return ErrUnsupportedKeyType
+	}
+
+	return nil
+}
+
+func (s *StaticKeySource) GetKey(keyId string) (interface{}, error) {
+	if key, ok := s.keys[keyId]; ok {
+		return key, nil
+	}
+	if key, ok := s.keys[""]; ok {
+		return key, nil
+	}
+
+	return nil, ErrKeyNotFound
+}
+
+// DiscoveryKeySource verifies the tokens with the keys published in the OpenID Connect discovery
+// document of the issuer, the keys are cached and fetched again for unknown key ids
+type DiscoveryKeySource struct {
+	issuer   string
+	registry *trusted_issuers.TrustedIssuerRegistry
+}
+
+func NewDiscoveryKeySource(issuer trusted_issuers.TrustedIssuer, options trusted_issuers.TrustedIssuerRegistryOptions) *DiscoveryKeySource {
+	return &DiscoveryKeySource{
+		issuer:   issuer.Issuer,
+		registry: trusted_issuers.NewTrustedIssuerRegistry(options).Add(issuer),
+	}
+}
+
+func (s *DiscoveryKeySource) GetKey(keyId string) (interface{}, error) {
+	key, err := s.registry.GetKey(s.issuer, keyId)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey := key.GetKey()
+	if publicKey == nil {
+		return nil, ErrUnsupportedKeyType
+	}
+
+	return publicKey, nil
+}
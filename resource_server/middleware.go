@@ -0,0 +1,120 @@
+package resource_server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/cjlapao/common-go-identity/constants"
+	"github.com/cjlapao/common-go-identity/models"
+	"github.com/cjlapao/common-go/helper/http_helper"
+)
+
+type contextKey struct{}
+
+// TokenFromContext returns the validated token of the request, it is nil if the request did not
+// go through the validator middleware
+func TokenFromContext(ctx context.Context) *models.UserToken {
+	token, _ := ctx.Value(contextKey{}).(*models.UserToken)
+	return token
+}
+
+// Middleware authorizes the requests with a valid bearer token and keeps the token claims in the
+// request context, the other requests are answered with the RFC 6750 errors
+func (v *Validator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, found := http_helper.GetAuthorizationToken(r.Header)
+		if !found || token == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, models.OAuthUnauthorizedClient, "bearer token not found in request")
+			return
+		}
+
+		userToken, err := v.Validate(token)
+		if errors.Is(err, ErrInsufficientScope) {
+			scope := strings.Join(v.options.RequiredScopes, " ")
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope="%v"`, scope))
+			writeError(w, http.StatusForbidden, models.OAuthInsufficientScope, "bearer token does not contain the required scopes "+scope)
+			return
+		}
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			writeError(w, http.StatusUnauthorized, models.OAuthUnauthorizedClient, "bearer token is not valid, "+err.Error())
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, userToken)))
+	})
+}
+
+// RequireScopes only lets through the requests whose token contains all of the scopes, it needs
+// to run after the validator middleware
+func RequireScopes(scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userToken := TokenFromContext(r.Context())
+			if userToken == nil {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeError(w, http.StatusUnauthorized, models.OAuthUnauthorizedClient, "request was not authorized")
+				return
+			}
+
+			if !hasScopes(userToken.Scope, scopes) {
+				scope := strings.Join(scopes, " ")
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope="%v"`, scope))
+				writeError(w, http.StatusForbidden, models.OAuthInsufficientScope, "bearer token does not contain the required scopes "+scope)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireRoles only lets through the requests whose token contains all of the roles, a role
+// like "_read,_write" is valid if the token has any of them and super users have every role.
+// It needs to run after the validator middleware
+func RequireRoles(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userToken := TokenFromContext(r.Context())
+			if userToken == nil {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeError(w, http.StatusUnauthorized, models.OAuthUnauthorizedClient, "request was not authorized")
+				return
+			}
+
+			if !hasRole(userToken.Roles, constants.SuperUser) {
+				for _, role := range roles {
+					if !hasRole(userToken.Roles, strings.Split(role, ",")...) {
+						writeError(w, http.StatusForbidden, models.OAuthAccessDenied, "bearer token user does not contain the required role "+role)
+						return
+					}
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func hasRole(tokenRoles []string, roles ...string) bool {
+	for _, role := range roles {
+		for _, tokenRole := range tokenRoles {
+			if strings.EqualFold(strings.TrimSpace(role), tokenRole) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func writeError(w http.ResponseWriter, status int, errorType models.OAuthErrorType, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(models.NewOAuthErrorResponse(errorType, description))
+}
@@ -0,0 +1,193 @@
+// Package resource_server validates the access tokens of an issuer in the services that only
+// need to authorize requests, it does not use the identity server context, key vault or users
+package resource_server
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/cjlapao/common-go-identity/constants"
+	"github.com/cjlapao/common-go-identity/models"
+	"github.com/pascaldekloe/jwt"
+)
+
+var (
+	ErrEmptyToken        = errors.New("token cannot be empty")
+	ErrNoIssuer          = errors.New("the validator needs an issuer")
+	ErrNoKeySource       = errors.New("the validator needs a key source")
+	ErrInvalidIssuer     = errors.New("token issuer is not valid")
+	ErrInvalidAudience   = errors.New("token is not valid for any of the audiences")
+	ErrTokenExpired      = errors.New("token is expired")
+	ErrTokenNotValidYet  = errors.New("token is not yet valid")
+	ErrInsufficientScope = errors.New("token does not contain the required scopes")
+	ErrNotAccessToken    = errors.New("token is not an access token")
+)
+
+// defaultAccessTokenScope is the scope the identity server adds to every access token
+const defaultAccessTokenScope = "authorization"
+
+// nonAccessTokenScopes are the scopes of the other tokens the issuer signs with the same keys,
+// they only prove a step of a flow and are never access tokens
+var nonAccessTokenScopes = []string{
+	constants.RefreshTokenScope,
+	constants.MfaScope,
+	constants.LoginLinkScope,
+	constants.WebAuthnRegistrationScope,
+	constants.WebAuthnLoginScope,
+	constants.EmailVerificationScope,
+	constants.PasswordRecoveryScope,
+}
+
+// ValidatorOptions configures the tokens accepted by the validator, the token needs to be valid
+// for at least one of the audiences and contain all of the required scopes. The access token
+// scope is always required, it defaults to the scope of the identity server access tokens
+type ValidatorOptions struct {
+	Issuer           string
+	Audiences        []string
+	Keys             KeySource
+	ClockSkew        time.Duration
+	AccessTokenScope string
+	RequiredScopes   []string
+}
+
+// Validator checks the signature and the claims of the access tokens of an issuer
+type Validator struct {
+	options ValidatorOptions
+	now     func() time.Time
+}
+
+func NewValidator(options ValidatorOptions) (*Validator, error) {
+	if options.Issuer == "" {
+		return nil, ErrNoIssuer
+	}
+	if options.Keys == nil {
+		return nil, ErrNoKeySource
+	}
+	if options.ClockSkew < 0 {
+		options.ClockSkew = 0
+	}
+	if options.AccessTokenScope == "" {
+		options.AccessTokenScope = defaultAccessTokenScope
+	}
+
+	return &Validator{
+		options: options,
+		now:     time.Now,
+	}, nil
+}
+
+// Validate returns the claims of the token if it was signed by the issuer and is valid for the
+// validator options
+func (v *Validator) Validate(token string) (*models.UserToken, error) {
+	if token == "" {
+		return nil, ErrEmptyToken
+	}
+
+	tokenBytes := []byte(token)
+	rawToken, err := jwt.ParseWithoutCheck(tokenBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := v.options.Keys.GetKey(rawToken.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	// the check functions only accept the algorithms of their key type
+	var verifiedToken *jwt.Claims
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		verifiedToken, err = jwt.RSACheck(tokenBytes, key)
+	case *ecdsa.PublicKey:
+		verifiedToken, err = jwt.ECDSACheck(tokenBytes, key)
+	case ed25519.PublicKey:
+		verifiedToken, err = jwt.EdDSACheck(tokenBytes, key)
+	case []byte:
+		verifiedToken, err = jwt.HMACCheck(tokenBytes, key)
+	default:
+		err = ErrUnsupportedKeyType
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if verifiedToken.Issuer != v.options.Issuer {
+		return nil, ErrInvalidIssuer
+	}
+
+	now := v.now()
+	if verifiedToken.Expires == nil || now.After(verifiedToken.Expires.Time().Add(v.options.ClockSkew)) {
+		return nil, ErrTokenExpired
+	}
+	if verifiedToken.NotBefore != nil && now.Add(v.options.ClockSkew).Before(verifiedToken.NotBefore.Time()) {
+		return nil, ErrTokenNotValidYet
+	}
+
+	if len(v.options.Audiences) > 0 {
+		isValid := false
+		for _, audience := range v.options.Audiences {
+			if verifiedToken.AcceptAudience(audience) {
+				isValid = true
+				break
+			}
+		}
+		if !isValid {
+			return nil, ErrInvalidAudience
+		}
+	}
+
+	rawJsonToken, _ := verifiedToken.Raw.MarshalJSON()
+	var userToken models.UserToken
+	if err := json.Unmarshal(rawJsonToken, &userToken); err != nil {
+		return nil, errors.New("token is not formated correctly")
+	}
+	userToken.Token = token
+	userToken.UsedKeyID = rawToken.KeyID
+
+	// the issuer signs the tokens of the login steps with the same keys
+	if !hasScopes(userToken.Scope, []string{v.options.AccessTokenScope}) || hasAnyScope(userToken.Scope, nonAccessTokenScopes) {
+		return &userToken, ErrNotAccessToken
+	}
+
+	if !hasScopes(userToken.Scope, v.options.RequiredScopes) {
+		return &userToken, ErrInsufficientScope
+	}
+
+	return &userToken, nil
+}
+
+// hasScopes returns true if the space delimited token scope contains all of the scopes
+func hasScopes(tokenScope string, scopes []string) bool {
+	tokenScopes := strings.Fields(tokenScope)
+	for _, scope := range scopes {
+		found := false
+		for _, value := range tokenScopes {
+			if strings.EqualFold(value, scope) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// hasAnyScope returns true if the space delimited token scope contains any of the scopes
+func hasAnyScope(tokenScope string, scopes []string) bool {
+	for _, scope := range scopes {
+		if hasScopes(tokenScope, []string{scope}) {
+			return true
+		}
+	}
+
+	return false
+}
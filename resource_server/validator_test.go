@@ -0,0 +1,261 @@
+package resource_server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cjlapao/common-go-identity/jwk"
+	"github.com/cjlapao/common-go-identity/trusted_issuers"
+	"github.com/pascaldekloe/jwt"
+)
+
+const testIssuer = "https://identity.example.com/auth/global"
+
+func newTestKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+func signTestToken(t *testing.T, key *ecdsa.PrivateKey, update func(claims *jwt.Claims)) string {
+	var claims jwt.Claims
+	claims.Issuer = testIssuer
+	claims.Subject = "user@example.com"
+	claims.Audiences = []string{"orders"}
+	claims.Expires = jwt.NewNumericTime(time.Now().Add(time.Hour))
+	claims.KeyID = "signing"
+	claims.Set = map[string]interface{}{
+		"scope": "authorization orders.read",
+		"roles": []string{"_user"},
+	}
+	if update != nil {
+		update(&claims)
+	}
+
+	token, err := claims.ECDSASign(jwt.ES256, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(token)
+}
+
+func newTestValidator(t *testing.T, key *ecdsa.PrivateKey, options ValidatorOptions) *Validator {
+	keys := NewStaticKeySource()
+	if err := keys.Add("signing", key); err != nil {
+		t.Fatal(err)
+	}
+
+	options.Issuer = testIssuer
+	options.Keys = keys
+	validator, err := NewValidator(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return validator
+}
+
+func TestValidate(t *testing.T) {
+	key := newTestKey(t)
+	validator := newTestValidator(t, key, ValidatorOptions{
+		Audiences:      []string{"billing", "orders"},
+		ClockSkew:      time.Minute,
+		RequiredScopes: []string{"orders.read"},
+	})
+
+	userToken, err := validator.Validate(signTestToken(t, key, nil))
+	if err != nil {
+		t.Fatalf("expected the token to be valid, %v", err)
+	}
+	if userToken.User != "user@example.com" || userToken.Issuer != testIssuer || len(userToken.Roles) != 1 {
+		t.Errorf("expected the token claims, got %v", userToken)
+	}
+
+	// the expiry is checked with the clock skew
+	expired := signTestToken(t, key, func(claims *jwt.Claims) {
+		claims.Expires = jwt.NewNumericTime(time.Now().Add(time.Second * -30))
+	})
+	if _, err := validator.Validate(expired); err != nil {
+		t.Errorf("expected a token expired inside the clock skew to be valid, %v", err)
+	}
+	expired = signTestToken(t, key, func(claims *jwt.Claims) {
+		claims.Expires = jwt.NewNumericTime(time.Now().Add(time.Minute * -2))
+	})
+	if _, err := validator.Validate(expired); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("expected an expired token to be rejected, got %v", err)
+	}
+	notYetValid := signTestToken(t, key, func(claims *jwt.Claims) {
+		claims.NotBefore = jwt.NewNumericTime(time.Now().Add(time.Minute * 5))
+	})
+	if _, err := validator.Validate(notYetValid); !errors.Is(err, ErrTokenNotValidYet) {
+		t.Errorf("expected a token that is not valid yet to be rejected, got %v", err)
+	}
+
+	otherIssuer := signTestToken(t, key, func(claims *jwt.Claims) {
+		claims.Issuer = "https://other.example.com"
+	})
+	if _, err := validator.Validate(otherIssuer); !errors.Is(err, ErrInvalidIssuer) {
+		t.Errorf("expected a token of another issuer to be rejected, got %v", err)
+	}
+
+	otherAudience := signTestToken(t, key, func(claims *jwt.Claims) {
+		claims.Audiences = []string{"inventory"}
+	})
+	if _, err := validator.Validate(otherAudience); !errors.Is(err, ErrInvalidAudience) {
+		t.Errorf("expected a token of another audience to be rejected, got %v", err)
+	}
+
+	missingScope := signTestToken(t, key, func(claims *jwt.Claims) {
+		claims.Set["scope"] = "authorization"
+	})
+	if _, err := validator.Validate(missingScope); !errors.Is(err, ErrInsufficientScope) {
+		t.Errorf("expected a token without the required scope to be rejected, got %v", err)
+	}
+
+	if _, err := validator.Validate(signTestToken(t, newTestKey(t), nil)); err == nil {
+		t.Errorf("expected a token signed with another key to be rejected")
+	}
+}
+
+func TestValidateAccessTokenScope(t *testing.T) {
+	key := newTestKey(t)
+	validator := newTestValidator(t, key, ValidatorOptions{})
+
+	// the tokens of the login steps are signed with the same keys and have no audience
+	for _, scope := range []string{"", "mfa", "refresh_token", "authorization refresh_token", "login_link", "webauthn_login", "verify_email", "password_recovery"} {
+		token := signTestToken(t, key, func(claims *jwt.Claims) {
+			claims.Audiences = nil
+			claims.Set["scope"] = scope
+		})
+		if _, err := validator.Validate(token); !errors.Is(err, ErrNotAccessToken) {
+			t.Errorf("expected a token with scope %q to be rejected, got %v", scope, err)
+		}
+	}
+
+	validator = newTestValidator(t, key, ValidatorOptions{AccessTokenScope: "api"})
+	if _, err := validator.Validate(signTestToken(t, key, nil)); !errors.Is(err, ErrNotAccessToken) {
+		t.Errorf("expected a token without the configured access token scope to be rejected, got %v", err)
+	}
+	token := signTestToken(t, key, func(claims *jwt.Claims) {
+		claims.Set["scope"] = "api orders.read"
+	})
+	if _, err := validator.Validate(token); err != nil {
+		t.Errorf("expected a token with the configured access token scope to be valid, %v", err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	key := newTestKey(t)
+	validator := newTestValidator(t, key, ValidatorOptions{})
+	handler := validator.Middleware(RequireScopes("orders.read")(RequireRoles("_admin,_user")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"sub": TokenFromContext(r.Context()).User})
+	}))))
+
+	send := func(token string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		if token != "" {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	response := send(signTestToken(t, key, nil))
+	var body map[string]string
+	json.NewDecoder(response.Body).Decode(&body)
+	if response.Code != http.StatusOK || body["sub"] != "user@example.com" {
+		t.Fatalf("expected the request to be authorized, got %v %v", response.Code, body)
+	}
+
+	response = send("")
+	if response.Code != http.StatusUnauthorized || response.Header().Get("WWW-Authenticate") != "Bearer" {
+		t.Errorf("expected a request without a token to be challenged, got %v %v", response.Code, response.Header())
+	}
+
+	response = send(signTestToken(t, newTestKey(t), nil))
+	if response.Code != http.StatusUnauthorized || response.Header().Get("WWW-Authenticate") != `Bearer error="invalid_token"` {
+		t.Errorf("expected an invalid token to be rejected, got %v %v", response.Code, response.Header())
+	}
+
+	response = send(signTestToken(t, key, func(claims *jwt.Claims) {
+		claims.Set["scope"] = "authorization"
+	}))
+	if response.Code != http.StatusForbidden || response.Header().Get("WWW-Authenticate") != `Bearer error="insufficient_scope", scope="orders.read"` {
+		t.Errorf("expected a token without the scope to be forbidden, got %v %v", response.Code, response.Header())
+	}
+
+	response = send(signTestToken(t, key, func(claims *jwt.Claims) {
+		claims.Set["roles"] = []string{"_guest"}
+	}))
+	if response.Code != http.StatusForbidden {
+		t.Errorf("expected a token without the role to be forbidden, got %v", response.Code)
+	}
+
+	response = send(signTestToken(t, key, func(claims *jwt.Claims) {
+		claims.Set["roles"] = []string{"_su"}
+	}))
+	if response.Code != http.StatusOK {
+		t.Errorf("expected a super user to have every role, got %v", response.Code)
+	}
+}
+
+func TestKeySources(t *testing.T) {
+	key := newTestKey(t)
+
+	// the keys published by the issuer discovery document
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{"issuer": testIssuer, "jwks_uri": server.URL + "/keys"})
+		case "/keys":
+			keys := jwk.New()
+			keys.Add("signing", key)
+			json.NewEncoder(w).Encode(keys)
+		}
+	}))
+	defer server.Close()
+
+	discoveryKeys := NewDiscoveryKeySource(trusted_issuers.TrustedIssuer{
+		Issuer:       testIssuer,
+		DiscoveryUrl: server.URL + "/.well-known/openid-configuration",
+	}, trusted_issuers.TrustedIssuerRegistryOptions{})
+	validator, err := NewValidator(ValidatorOptions{Issuer: testIssuer, Keys: discoveryKeys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := validator.Validate(signTestToken(t, key, nil)); err != nil {
+		t.Errorf("expected the token to be valid with the discovery keys, %v", err)
+	}
+
+	// a shared hmac secret only verifies hmac tokens
+	secret := []byte("a-very-secret-key-used-only-by-the-tests")
+	validator, _ = NewValidator(ValidatorOptions{Issuer: testIssuer, Keys: NewHmacKeySource(secret)})
+	var claims jwt.Claims
+	claims.Issuer = testIssuer
+	claims.Expires = jwt.NewNumericTime(time.Now().Add(time.Hour))
+	claims.Set = map[string]interface{}{"scope": "authorization"}
+	token, _ := claims.HMACSign(jwt.HS256, secret)
+	if _, err := validator.Validate(string(token)); err != nil {
+		t.Errorf("expected the token to be valid with the hmac secret, %v", err)
+	}
+	if _, err := validator.Validate(signTestToken(t, key, nil)); err == nil {
+		t.Errorf("expected an ecdsa token to be rejected by the hmac secret")
+	}
+
+	if _, err := NewValidator(ValidatorOptions{Keys: NewHmacKeySource(secret)}); !errors.Is(err, ErrNoIssuer) {
+		t.Errorf("expected a validator without an issuer to fail, got %v", err)
+	}
+}
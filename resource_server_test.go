@@ -0,0 +1,57 @@
+package identity
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/cjlapao/common-go-identity/authorization_context"
+	"github.com/cjlapao/common-go-identity/jwt"
+	"github.com/cjlapao/common-go-identity/models"
+	"github.com/cjlapao/common-go-identity/resource_server"
+)
+
+func TestResourceServerValidator(t *testing.T) {
+	status, body := requestToken(t, url.Values{
+		"grant_type": {"password"},
+		"client_id":  {"spa"},
+		"username":   {testAdminUsername},
+		"password":   {testAdminPassword},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("expected a login, got %v %v", status, body)
+	}
+	accessToken := body["access_token"].(string)
+	claims := decodeTestToken(t, accessToken)
+
+	// a service sharing the signing secret validates the tokens without the identity server
+	validator, err := resource_server.NewValidator(resource_server.ValidatorOptions{
+		Issuer: claims["iss"].(string),
+		Keys:   resource_server.NewHmacKeySource([]byte("a-very-secret-key-used-only-by-the-tests")),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userToken, err := validator.Validate(accessToken)
+	if err != nil {
+		t.Fatalf("expected the identity service token to be valid, %v", err)
+	}
+	if userToken.User != testAdminUsername || userToken.UserID != testAdminUserId {
+		t.Errorf("expected the token user, got %v %v", userToken.User, userToken.UserID)
+	}
+
+	// the other tokens signed by the identity service are not access tokens
+	if _, err := validator.Validate(body["refresh_token"].(string)); !errors.Is(err, resource_server.ErrNotAccessToken) {
+		t.Errorf("expected the refresh token to be rejected, got %v", err)
+	}
+	mfaToken := jwt.GenerateMfaToken(authorization_context.GetBaseContext().Options.KeyId, models.User{
+		ID:       testAdminUserId,
+		Email:    testAdminUsername,
+		Username: testAdminUsername,
+	}, "spa")
+	if _, err := validator.Validate(mfaToken); !errors.Is(err, resource_server.ErrNotAccessToken) {
+		t.Errorf("expected the mfa token to be rejected, got %v", err)
+	}
+}
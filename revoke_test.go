@@ -0,0 +1,182 @@
+package identity
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/cjlapao/common-go-identity/authorization_context"
+	"github.com/cjlapao/common-go-identity/constants"
+	"github.com/cjlapao/common-go-identity/database/dto"
+	"github.com/cjlapao/common-go-identity/models"
+	"github.com/cjlapao/common-go-identity/oauthflow"
+)
+
+func revokeToken(t *testing.T, form url.Values) (int, map[string]interface{}) {
+	t.Helper()
+	server := getTestServer(t)
+	response, err := newTestClient().PostForm(server.URL+"/auth/revoke", form)
+	if err != nil {
+		t.Fatalf("revoke request failed, %v", err)
+	}
+	defer response.Body.Close()
+
+	var body map[string]interface{}
+	json.NewDecoder(response.Body).Decode(&body)
+	return response.StatusCode, body
+}
+
+func TestRevokeRefreshToken(t *testing.T) {
+	token := loginWithPassword(t)
+	otherSession := loginWithPassword(t)
+
+	// a client cannot revoke the tokens of another client but it is not told about it
+	if status, _ := revokeToken(t, url.Values{"token": {token}, "client_id": {"another-app"}}); status != http.StatusOK {
+		t.Fatalf("expected status %v, got %v", http.StatusOK, status)
+	}
+	status, body := refreshToken(t, token)
+	if status != http.StatusOK {
+		t.Fatalf("expected the token of another client to still work, got %v %v", status, body)
+	}
+	rotated := body["refresh_token"].(string)
+
+	// revoking the rotated token revokes the whole family
+	if status, _ := revokeToken(t, url.Values{"token": {rotated}, "token_type_hint": {"refresh_token"}, "client_id": {"spa"}}); status != http.StatusOK {
+		t.Fatalf("expected status %v, got %v", http.StatusOK, status)
+	}
+	if status, body := refreshToken(t, rotated); status != http.StatusBadRequest || body["error"] != models.OAuthInvalidGrant.String() {
+		t.Fatalf("expected the revoked token to be rejected, got %v %v", status, body)
+	}
+
+	if status, body := refreshToken(t, otherSession); status != http.StatusOK {
+		t.Fatalf("expected other sessions to keep working, got %v %v", status, body)
+	}
+}
+
+func TestRevokeAccessToken(t *testing.T) {
+	_, accessTokenLogin := passwordLogin(t, testAdminUsername, testAdminPassword, url.Values{"scope": {"openid"}})
+	accessToken := accessTokenLogin["access_token"].(string)
+	jti := decodeTestToken(t, accessToken)["jti"].(string)
+	revocations := authorization_context.GetBaseContext().RevocationDatabaseAdapter
+	if status := sendJsonRequest(t, http.MethodGet, "userinfo", accessToken, nil, nil); status != http.StatusOK {
+		t.Fatalf("expected the token to be valid, got %v", status)
+	}
+
+	if status, _ := revokeToken(t, url.Values{"token": {accessToken}, "token_type_hint": {"access_token"}, "client_id": {"another-app"}}); status != http.StatusOK || revocations.IsTokenRevoked(jti) {
+		t.Fatalf("expected the token of another client not to be revoked, got %v", status)
+	}
+
+	if status, _ := revokeToken(t, url.Values{"token": {accessToken}, "token_type_hint": {"access_token"}, "client_id": {"spa"}}); status != http.StatusOK {
+		t.Fatalf("expected status %v, got %v", http.StatusOK, status)
+	}
+	if !revocations.IsTokenRevoked(jti) {
+		t.Errorf("expected the access token id to be denied")
+	}
+	if status := sendJsonRequest(t, http.MethodGet, "userinfo", accessToken, nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("expected the revoked access token to be rejected, got %v", status)
+	}
+}
+
+func TestRevokeRequest(t *testing.T) {
+	// unknown tokens are not an error
+	if status, _ := revokeToken(t, url.Values{"token": {"not-a-token"}, "client_id": {"spa"}}); status != http.StatusOK {
+		t.Errorf("expected status %v for an unknown token, got %v", http.StatusOK, status)
+	}
+
+	if status, body := revokeToken(t, url.Values{"client_id": {"spa"}}); status != http.StatusBadRequest || body["error"] != models.OAuthInvalidRequestError.String() {
+		t.Errorf("expected invalid_request without a token, got %v %v", status, body)
+	}
+
+	if status, body := revokeToken(t, url.Values{"token": {"not-a-token"}}); status != http.StatusUnauthorized || body["error"] != models.OAuthInvalidClientError.String() {
+		t.Errorf("expected invalid_client without a client, got %v %v", status, body)
+	}
+}
+
+func TestRevokeUserTokens(t *testing.T) {
+	addTestUser(t, "revoked-tokens-test-user", testRegularUserPassword, constants.RegularUserRole)
+	_, accessTokenLogin := passwordLogin(t, "revoked-tokens-test-user@localhost.com", testRegularUserPassword, url.Values{"scope": {"openid"}})
+	accessToken := accessTokenLogin["access_token"].(string)
+	if status := sendJsonRequest(t, http.MethodGet, "userinfo", accessToken, nil, nil); status != http.StatusOK {
+		t.Fatalf("expected the token to be valid, got %v", status)
+	}
+
+	// a revocation done by another replica is only in the store
+	now := time.Now().Truncate(time.Second)
+	err := authorization_context.GetBaseContext().RevocationDatabaseAdapter.RevokeUserTokens(dto.UserTokenRevocationDTO{
+		UserID:        "revoked-tokens-test-user",
+		RevokedBefore: now,
+		ExpiresAt:     now.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status := sendJsonRequest(t, http.MethodGet, "userinfo", accessToken, nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("expected the tokens of the user to be revoked, got %v", status)
+	}
+}
+
+func TestRevokeUserTokensSecondBoundary(t *testing.T) {
+	authCtx := authorization_context.GetBaseContext()
+	addTestUser(t, "revoked-boundary-test-user", testRegularUserPassword, constants.RegularUserRole)
+	_, accessTokenLogin := passwordLogin(t, "revoked-boundary-test-user@localhost.com", testRegularUserPassword, url.Values{"scope": {"openid"}})
+	accessToken := accessTokenLogin["access_token"].(string)
+
+	// revoking in the second half of a second used to round the revocation into the next one
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(600 * time.Millisecond)))
+	if err := oauthflow.RevokeUserTokens(authCtx, "revoked-boundary-test-user"); err != nil {
+		t.Fatal(err)
+	}
+	if status := sendJsonRequest(t, http.MethodGet, "userinfo", accessToken, nil, nil); status != http.StatusUnauthorized {
+		t.Fatalf("expected the token issued before the revocation to be revoked, got %v", status)
+	}
+
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+	_, accessTokenLogin = passwordLogin(t, "revoked-boundary-test-user@localhost.com", testRegularUserPassword, url.Values{"scope": {"openid"}})
+	accessToken = accessTokenLogin["access_token"].(string)
+	if status := sendJsonRequest(t, http.MethodGet, "userinfo", accessToken, nil, nil); status != http.StatusOK {
+		t.Errorf("expected the token issued after the revocation to be valid, got %v", status)
+	}
+}
+
+func TestRemoveUser(t *testing.T) {
+	authCtx := authorization_context.GetBaseContext()
+	addTestUser(t, "removed-test-user", testRegularUserPassword, constants.RegularUserRole)
+	_, body := passwordLogin(t, "removed-test-user@localhost.com", testRegularUserPassword, url.Values{"scope": {"openid"}})
+	userToken := body["access_token"].(string)
+	userRefreshToken := body["refresh_token"].(string)
+	_, adminTokenLogin := passwordLogin(t, testAdminUsername, testAdminPassword, url.Values{"scope": {"openid"}})
+	adminToken := adminTokenLogin["access_token"].(string)
+
+	// only administrators can manage the users
+	if status := sendJsonRequest(t, http.MethodDelete, "users/removed-test-user", userToken, nil, nil); status != http.StatusUnauthorized {
+		t.Fatalf("expected the user not to be able to remove itself, got %v", status)
+	}
+
+	if status := sendJsonRequest(t, http.MethodDelete, "users/removed-test-user/sessions", adminToken, nil, nil); status != http.StatusNoContent {
+		t.Fatalf("expected the sessions to be revoked, got %v", status)
+	}
+	if status := sendJsonRequest(t, http.MethodGet, "userinfo", userToken, nil, nil); status != http.StatusUnauthorized {
+		t.Fatalf("expected the access token to be revoked, got %v", status)
+	}
+	status, body := requestToken(t, url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {"spa"},
+		"refresh_token": {userRefreshToken},
+	})
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected the refresh token to be revoked, got %v %v", status, body)
+	}
+
+	if status := sendJsonRequest(t, http.MethodDelete, "users/removed-test-user", adminToken, nil, nil); status != http.StatusNoContent {
+		t.Fatalf("expected the user to be removed, got %v", status)
+	}
+	if authCtx.UserDatabaseAdapter.GetUserById("removed-test-user") != nil {
+		t.Errorf("expected the user to be removed from the store")
+	}
+	if status := sendJsonRequest(t, http.MethodDelete, "users/removed-test-user", adminToken, nil, nil); status != http.StatusNotFound {
+		t.Errorf("expected status %v for a removed user, got %v", http.StatusNotFound, status)
+	}
+}
@@ -0,0 +1,94 @@
+package identity
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/cjlapao/common-go-identity/authorization_context"
+	"github.com/cjlapao/common-go/security/encryption"
+)
+
+// decodeTestTokenHeader returns the header of a jwt without checking its signature
+func decodeTestTokenHeader(t *testing.T, token string) map[string]interface{} {
+	t.Helper()
+	header, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[0])
+	if err != nil {
+		t.Fatalf("invalid jwt header, %v", err)
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(header, &result); err != nil {
+		t.Fatalf("invalid jwt header, %v", err)
+	}
+
+	return result
+}
+
+func TestSigningAlgorithms(t *testing.T) {
+	getTestServer(t)
+	keyVault := authorization_context.GetBaseContext().KeyVault
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	_, ed25519Key, _ := ed25519.GenerateKey(rand.Reader)
+	keyVault.WithRsaPssKey("signing-ps256", rsaKey, encryption.Bit256)
+	keyVault.WithEd25519Key("signing-eddsa", ed25519Key)
+	t.Cleanup(func() {
+		keyVault.SetDefaultKey("test")
+		keyVault.RetireKey("signing-ps256")
+		keyVault.RetireKey("signing-eddsa")
+	})
+
+	for kid, algorithm := range map[string]string{"signing-ps256": "PS256", "signing-eddsa": "EdDSA"} {
+		keyVault.SetDefaultKey(kid)
+
+		status, body := requestToken(t, url.Values{
+			"grant_type": {"password"},
+			"client_id":  {"spa"},
+			"username":   {testAdminUsername},
+			"password":   {testAdminPassword},
+			"scope":      {"openid"},
+		})
+		if status != http.StatusOK {
+			t.Fatalf("expected a login with %v, got %v %v", algorithm, status, body)
+		}
+		for _, name := range []string{"access_token", "id_token"} {
+			if header := decodeTestTokenHeader(t, body[name].(string)); header["alg"] != algorithm || header["kid"] != kid {
+				t.Errorf("expected the %v signed with %v, got %v", name, algorithm, header)
+			}
+		}
+
+		// the tokens are verified by the middleware and the refresh grant
+		if status := sendJsonRequest(t, http.MethodGet, "userinfo", body["access_token"].(string), nil, nil); status != http.StatusOK {
+			t.Errorf("expected the %v access token to be accepted, got %v", algorithm, status)
+		}
+		status, body = requestToken(t, url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {"spa"},
+			"refresh_token": {body["refresh_token"].(string)},
+		})
+		if status != http.StatusOK {
+			t.Errorf("expected the %v refresh token to be accepted, got %v %v", algorithm, status, body)
+		}
+
+		var configuration map[string]interface{}
+		sendJsonRequest(t, http.MethodGet, ".well-known/openid-configuration", "", nil, &configuration)
+		if algorithms := toStrings(configuration["id_token_signing_alg_values_supported"]); len(algorithms) == 0 || algorithms[0] != algorithm || !slices.Contains(algorithms, "HS256") {
+			t.Errorf("expected %v to be advertised first, got %v", algorithm, algorithms)
+		}
+	}
+
+	_, jwks := getTestJwks(t, "")
+	if key := findTestJwk(jwks, "signing-eddsa"); key == nil || key["kty"] != "OKP" || key["crv"] != "Ed25519" || key["alg"] != "EdDSA" {
+		t.Errorf("expected the Ed25519 key as an octet key pair, got %v", key)
+	}
+	if key := findTestJwk(jwks, "signing-ps256"); key == nil || key["kty"] != "RSA" || key["alg"] != "PS256" {
+		t.Errorf("expected the PSS key, got %v", key)
+	}
+}
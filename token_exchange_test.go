@@ -0,0 +1,206 @@
+package identity
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/cjlapao/common-go-identity/authorization_context"
+	"github.com/cjlapao/common-go-identity/constants"
+	"github.com/cjlapao/common-go-identity/models"
+	"github.com/cjlapao/common-go/security"
+)
+
+func addTokenExchangeTestClient(t *testing.T, id string, policy *authorization_context.TokenExchangePolicy) {
+	t.Helper()
+	client := models.NewClient()
+	client.ID = id
+	client.Secret = security.SHA256Encode(testClientSecret)
+	client.GrantTypes = []string{models.OAuthTokenExchangeGrant.String()}
+	addTestClient(t, *client)
+
+	if policy != nil {
+		policy.ClientID = id
+		authorization_context.GetBaseContext().WithTokenExchangePolicy(*policy)
+	}
+}
+
+func exchangeToken(t *testing.T, clientId string, form url.Values) (int, map[string]interface{}) {
+	t.Helper()
+	form.Set("grant_type", models.OAuthTokenExchangeGrant.String())
+	return requestTokenWithBasicAuth(t, form, clientId, testClientSecret)
+}
+
+func TestTokenExchangeDelegation(t *testing.T) {
+	addTokenExchangeTestClient(t, "api-gateway", &authorization_context.TokenExchangePolicy{
+		Audiences:         []string{"orders-service"},
+		AllowWithoutActor: true,
+	})
+	_, subjectLogin := passwordLogin(t, testAdminUsername, testAdminPassword, url.Values{"scope": {"openid"}})
+	subject := subjectLogin["access_token"].(string)
+
+	status, body := exchangeToken(t, "api-gateway", url.Values{
+		"subject_token":      {subject},
+		"subject_token_type": {models.AccessTokenTypeUrn},
+		"audience":           {"orders-service"},
+		"scope":              {"openid"},
+	})
+	if status != http.StatusOK || body["issued_token_type"] != models.AccessTokenTypeUrn || body["refresh_token"] != "" {
+		t.Fatalf("expected an exchanged access token, got %v %v", status, body)
+	}
+
+	claims := decodeTestToken(t, body["access_token"].(string))
+	subjectClaims := decodeTestToken(t, subject)
+	if claims["sub"] != subjectClaims["sub"] || claims["client_id"] != "api-gateway" || claims["act"] != nil {
+		t.Errorf("expected a token of the subject for the gateway, got %v", claims)
+	}
+	if audiences, ok := claims["aud"].([]interface{}); !ok || len(audiences) != 1 || audiences[0] != "orders-service" {
+		t.Errorf("expected the orders-service audience, got %v", claims["aud"])
+	}
+	if claims["exp"].(float64) > subjectClaims["exp"].(float64) {
+		t.Errorf("expected the exchanged token not to outlive the subject token, got %v", claims["exp"])
+	}
+
+	// the issued token can only narrow the subject token
+	if status, body := exchangeToken(t, "api-gateway", url.Values{
+		"subject_token":      {subject},
+		"subject_token_type": {models.AccessTokenTypeUrn},
+		"scope":              {"orders.write"},
+	}); status != http.StatusBadRequest || body["error"] != models.OAuthInvalidScope.String() {
+		t.Errorf("expected invalid_scope for a scope of another token, got %v %v", status, body)
+	}
+	if status, body := exchangeToken(t, "api-gateway", url.Values{
+		"subject_token":      {subject},
+		"subject_token_type": {models.AccessTokenTypeUrn},
+		"resource":           {"billing-service"},
+	}); status != http.StatusBadRequest || body["error"] != models.OAuthInvalidTarget.String() {
+		t.Errorf("expected invalid_target for an audience outside the policy, got %v %v", status, body)
+	}
+}
+
+func TestTokenExchangeImpersonation(t *testing.T) {
+	addTokenExchangeTestClient(t, "support-console", &authorization_context.TokenExchangePolicy{
+		ActorRoles: []string{constants.RegularUserRole.ID},
+	})
+	_, subjectLogin := passwordLogin(t, testAdminUsername, testAdminPassword, url.Values{"scope": {"openid"}})
+	subject := subjectLogin["access_token"].(string)
+	addTestUser(t, "support-agent", testRegularUserPassword, constants.RegularUserRole)
+	_, actorLogin := passwordLogin(t, "support-agent@localhost.com", testRegularUserPassword, url.Values{"scope": {"openid"}})
+	actor := actorLogin["access_token"].(string)
+
+	var notifications []models.OAuthNotification
+	authorization_context.GetBaseContext().NotificationCallback = func(notification models.OAuthNotification) error {
+		if notification.Type == models.TokenExchanged {
+			notifications = append(notifications, notification)
+		}
+		return nil
+	}
+	t.Cleanup(func() {
+		authorization_context.GetBaseContext().NotificationCallback = nil
+	})
+
+	status, body := exchangeToken(t, "support-console", url.Values{
+		"subject_token":      {subject},
+		"subject_token_type": {models.AccessTokenTypeUrn},
+		"actor_token":        {actor},
+		"actor_token_type":   {models.AccessTokenTypeUrn},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("expected an exchanged access token, got %v %v", status, body)
+	}
+	accessToken := body["access_token"].(string)
+	act, ok := decodeTestToken(t, accessToken)["act"].(map[string]interface{})
+	if !ok || act["sub"] != decodeTestToken(t, actor)["sub"] || act["client_id"] != "spa" {
+		t.Fatalf("expected the act claim to record the actor, got %v", act)
+	}
+
+	if len(notifications) != 1 {
+		t.Fatalf("expected a token exchange notification, got %v", notifications)
+	}
+	exchange, ok := notifications[0].Data.(models.OAuthTokenExchangeNotification)
+	if !ok || exchange.ClientID != "support-console" || exchange.Subject != decodeTestToken(t, subject)["uid"] || exchange.Actor == nil || exchange.Actor.Subject != act["sub"] {
+		t.Errorf("expected the notification to carry the client, subject and actor, got %v", notifications[0].Data)
+	}
+
+	if status := sendJsonRequest(t, http.MethodGet, "userinfo", accessToken, nil, nil); status != http.StatusOK {
+		t.Errorf("expected the exchanged token to be valid, got %v", status)
+	}
+	state := introspectTokenState(t, url.Values{"token": {accessToken}})
+	if stateAct, ok := state["act"].(map[string]interface{}); state["active"] != true || !ok || stateAct["sub"] != act["sub"] {
+		t.Errorf("expected the introspection to return the actor, got %v", state)
+	}
+
+	if status, body := exchangeToken(t, "support-console", url.Values{
+		"subject_token":      {subject},
+		"subject_token_type": {models.AccessTokenTypeUrn},
+	}); status != http.StatusBadRequest || body["error"] != models.OAuthInvalidRequestError.String() {
+		t.Errorf("expected invalid_request without an actor, got %v %v", status, body)
+	}
+	if len(notifications) != 1 {
+		t.Errorf("expected only the issued tokens to be notified, got %v", notifications)
+	}
+
+	// the administrator has no roles so it cannot act for other users
+	if status, body := exchangeToken(t, "support-console", url.Values{
+		"subject_token":      {actor},
+		"subject_token_type": {models.AccessTokenTypeUrn},
+		"actor_token":        {subject},
+		"actor_token_type":   {models.AccessTokenTypeUrn},
+	}); status != http.StatusBadRequest || body["error"] != models.OAuthInvalidGrant.String() {
+		t.Errorf("expected invalid_grant for an actor without the role, got %v %v", status, body)
+	}
+}
+
+func TestTokenExchangeDefaultPolicy(t *testing.T) {
+	addTokenExchangeTestClient(t, "default-policy-service", &authorization_context.TokenExchangePolicy{})
+	_, subjectLogin := passwordLogin(t, testAdminUsername, testAdminPassword, url.Values{"scope": {"openid"}})
+	subject := subjectLogin["access_token"].(string)
+	addTestUser(t, "default-policy-agent", testRegularUserPassword, constants.RegularUserRole)
+	_, actorLogin := passwordLogin(t, "default-policy-agent@localhost.com", testRegularUserPassword, url.Values{"scope": {"openid"}})
+
+	// the client cannot act for the subject on its own unless the policy allows it
+	if status, body := exchangeToken(t, "default-policy-service", url.Values{
+		"subject_token":      {subject},
+		"subject_token_type": {models.AccessTokenTypeUrn},
+	}); status != http.StatusBadRequest || body["error"] != models.OAuthInvalidRequestError.String() {
+		t.Errorf("expected invalid_request without an actor, got %v %v", status, body)
+	}
+
+	// without actor roles no user can act for the subject
+	if status, body := exchangeToken(t, "default-policy-service", url.Values{
+		"subject_token":      {subject},
+		"subject_token_type": {models.AccessTokenTypeUrn},
+		"actor_token":        {actorLogin["access_token"].(string)},
+		"actor_token_type":   {models.AccessTokenTypeUrn},
+	}); status != http.StatusBadRequest || body["error"] != models.OAuthInvalidGrant.String() {
+		t.Errorf("expected invalid_grant for an actor without actor roles in the policy, got %v %v", status, body)
+	}
+}
+
+func TestTokenExchangeRequest(t *testing.T) {
+	addTokenExchangeTestClient(t, "exchange-service", &authorization_context.TokenExchangePolicy{})
+	addTokenExchangeTestClient(t, "no-policy-service", nil)
+	_, login := passwordLogin(t, testAdminUsername, testAdminPassword, url.Values{"scope": {"openid"}})
+
+	if status, body := exchangeToken(t, "no-policy-service", url.Values{
+		"subject_token":      {login["access_token"].(string)},
+		"subject_token_type": {models.AccessTokenTypeUrn},
+	}); status != http.StatusBadRequest || body["error"] != models.OAuthUnauthorizedClient.String() {
+		t.Errorf("expected unauthorized_client without a policy, got %v %v", status, body)
+	}
+
+	if status, body := exchangeToken(t, "exchange-service", url.Values{
+		"subject_token": {login["access_token"].(string)},
+	}); status != http.StatusBadRequest || body["error"] != models.OAuthInvalidRequestError.String() {
+		t.Errorf("expected invalid_request without a subject_token_type, got %v %v", status, body)
+	}
+
+	for _, subject := range []string{"not-a-token", login["refresh_token"].(string)} {
+		if status, body := exchangeToken(t, "exchange-service", url.Values{
+			"subject_token":      {subject},
+			"subject_token_type": {models.AccessTokenTypeUrn},
+		}); status != http.StatusBadRequest || body["error"] != models.OAuthInvalidGrant.String() {
+			t.Errorf("expected invalid_grant for an invalid subject token, got %v %v", status, body)
+		}
+	}
+}
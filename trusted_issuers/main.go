@@ -0,0 +1,328 @@
+package trusted_issuers
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cjlapao/common-go-identity/jwk"
+	"github.com/cjlapao/common-go-identity/models"
+	log "github.com/cjlapao/common-go-logger"
+	"github.com/pascaldekloe/jwt"
+)
+
+// documents bigger than this are not a discovery document or a key set
+const maxDocumentSize = 1 << 20
+
+var logger = log.Get()
+
+var (
+	ErrIssuerNotTrusted    = errors.New("the token issuer is not trusted")
+	ErrSigningKeyNotFound  = errors.New("the signing key was not found in the issuer keys")
+	ErrAlgorithmNotAllowed = errors.New("the token algorithm does not match the issuer key")
+	ErrInvalidAudience     = errors.New("the token is not valid for any of the issuer audiences")
+)
+
+// TrustedIssuer is an issuer whose tokens are accepted, the keys are found with its OpenID
+// Connect discovery document which by default is the well known configuration of the issuer.
+// When audiences are set the tokens need to be valid for at least one of them and the scope
+// replaces the context scope for the tokens of this issuer, without it the tokens need the
+// context scope like the ones of the identity service
+type TrustedIssuer struct {
+	Issuer       string
+	DiscoveryUrl string
+	Audiences    []string
+	Scope        string
+}
+
+type TrustedIssuerRegistryOptions struct {
+	// CacheDuration is how long the discovery document and the keys are used before fetching them again
+	CacheDuration time.Duration
+	// RefreshInterval is the minimum time between fetching the keys for tokens signed with an unknown key
+	RefreshInterval time.Duration
+	HttpClient      *http.Client
+}
+
+// TrustedIssuerRegistry validates the tokens of external issuers with the keys published in their
+// discovery document, the keys are cached and fetched again when a token is signed with a key
+// that is not known yet so the issuer can rotate them
+type TrustedIssuerRegistry struct {
+	options TrustedIssuerRegistryOptions
+	mutex   sync.RWMutex
+	issuers map[string]*issuerKeys
+	now     func() time.Time
+}
+
+type issuerKeys struct {
+	issuer    TrustedIssuer
+	mutex     sync.Mutex
+	jwksUri   string
+	keys      []*jwk.JsonWebKey
+	fetchedAt time.Time
+	checkedAt time.Time
+}
+
+func NewTrustedIssuerRegistry(options TrustedIssuerRegistryOptions) *TrustedIssuerRegistry {
+	if options.CacheDuration <= 0 {
+		options.CacheDuration = time.Hour
+	}
+	if options.RefreshInterval <= 0 {
+		options.RefreshInterval = time.Minute
+	}
+	if options.HttpClient == nil {
+		options.HttpClient = &http.Client{Timeout: time.Second * 10}
+	}
+
+	return &TrustedIssuerRegistry{
+		options: options,
+		issuers: make(map[string]*issuerKeys),
+		now:     time.Now,
+	}
+}
+
+// Add trusts the tokens of the issuer, adding an issuer again replaces its options and clears its
+// cached keys
+func (r *TrustedIssuerRegistry) Add(issuer TrustedIssuer) *TrustedIssuerRegistry {
+	issuer.Issuer = strings.TrimSpace(issuer.Issuer)
+	if issuer.Issuer == "" {
+		return r
+	}
+	if issuer.DiscoveryUrl == "" {
+		issuer.DiscoveryUrl = strings.TrimSuffix(issuer.Issuer, "/") + "/.well-known/openid-configuration"
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.issuers[issuer.Issuer] = &issuerKeys{
+		issuer: issuer,
+	}
+
+	return r
+}
+
+func (r *TrustedIssuerRegistry) Remove(issuer string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.issuers, issuer)
+}
+
+// IsTrusted returns true if the issuer was added, issuers are compared exactly as required by
+// the OpenID Connect specification
+func (r *TrustedIssuerRegistry) IsTrusted(issuer string) bool {
+	return r.getIssuer(issuer) != nil
+}
+
+func (r *TrustedIssuerRegistry) HasIssuers() bool {
+	if r == nil {
+		return false
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return len(r.issuers) > 0
+}
+
+func (r *TrustedIssuerRegistry) Get(issuer string) *TrustedIssuer {
+	if issuerKeys := r.getIssuer(issuer); issuerKeys != nil {
+		result := issuerKeys.issuer
+		return &result
+	}
+
+	return nil
+}
+
+// GetKey returns the published key of the issuer, the keys are fetched again if the key is not
+// known and they were not fetched in the refresh interval
+func (r *TrustedIssuerRegistry) GetKey(issuer string, keyId string) (*jwk.JsonWebKey, error) {
+	issuerKeys := r.getIssuer(issuer)
+	if issuerKeys == nil {
+		return nil, ErrIssuerNotTrusted
+	}
+
+	issuerKeys.mutex.Lock()
+	defer issuerKeys.mutex.Unlock()
+
+	now := r.now()
+	if issuerKeys.keys == nil || !now.Before(issuerKeys.fetchedAt.Add(r.options.CacheDuration)) {
+		if err := r.refresh(issuerKeys, true, now); err != nil {
+			// keys we already have are better than no keys while the issuer is not reachable
+			if issuerKeys.keys == nil {
+				return nil, err
+			}
+			logger.Warn("Could not refresh the keys of the issuer %v, using the cached keys, %v", issuer, err.Error())
+		}
+	}
+
+	if key := issuerKeys.findKey(keyId); key != nil {
+		return key, nil
+	}
+
+	if !now.Before(issuerKeys.checkedAt.Add(r.options.RefreshInterval)) {
+		if err := r.refresh(issuerKeys, false, now); err != nil {
+			return nil, err
+		}
+		if key := issuerKeys.findKey(keyId); key != nil {
+			return key, nil
+		}
+	}
+
+	return nil, ErrSigningKeyNotFound
+}
+
+// Verify checks the token signature with the keys of its issuer and that it is valid for the
+// issuer audiences, the expiry and the other claims are left to the caller
+func (r *TrustedIssuerRegistry) Verify(token []byte) (*jwt.Claims, *TrustedIssuer, error) {
+	rawToken, err := jwt.ParseWithoutCheck(token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	issuer := r.Get(rawToken.Issuer)
+	if issuer == nil {
+		return nil, nil, ErrIssuerNotTrusted
+	}
+
+	var header struct {
+		Algorithm string `json:"alg"`
+	}
+	if err := json.Unmarshal(rawToken.RawHeader, &header); err != nil {
+		return nil, nil, err
+	}
+
+	key, err := r.GetKey(issuer.Issuer, rawToken.KeyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if key.Algorithm != "" && key.Algorithm != header.Algorithm {
+		return nil, nil, ErrAlgorithmNotAllowed
+	}
+
+	// the check functions only accept the algorithms of their key type so a public key can never
+	// be used as a hmac secret
+	var verifiedToken *jwt.Claims
+	switch publicKey := key.GetKey().(type) {
+	case *rsa.PublicKey:
+		verifiedToken, err = jwt.RSACheck(token, publicKey)
+	case *ecdsa.PublicKey:
+		verifiedToken, err = jwt.ECDSACheck(token, publicKey)
+	case ed25519.PublicKey:
+		verifiedToken, err = jwt.EdDSACheck(token, publicKey)
+	default:
+		err = ErrAlgorithmNotAllowed
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if verifiedToken.Issuer != issuer.Issuer {
+		return nil, nil, ErrIssuerNotTrusted
+	}
+
+	if len(issuer.Audiences) > 0 {
+		isValid := false
+		for _, audience := range issuer.Audiences {
+			if verifiedToken.AcceptAudience(audience) {
+				isValid = true
+				break
+			}
+		}
+		if !isValid {
+			return nil, nil, ErrInvalidAudience
+		}
+	}
+
+	return verifiedToken, issuer, nil
+}
+
+func (r *TrustedIssuerRegistry) getIssuer(issuer string) *issuerKeys {
+	if r == nil || issuer == "" {
+		return nil
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.issuers[issuer]
+}
+
+// refresh fetches the keys of the issuer, the discovery document is only fetched again once
+// it is no longer cached as a new key does not change where the keys are published
+func (r *TrustedIssuerRegistry) refresh(issuerKeys *issuerKeys, withDiscovery bool, now time.Time) error {
+	issuerKeys.checkedAt = now
+	if withDiscovery || issuerKeys.jwksUri == "" {
+		var configuration models.OAuthConfigurationResponse
+		if err := r.getDocument(issuerKeys.issuer.DiscoveryUrl, &configuration); err != nil {
+			return err
+		}
+		if configuration.Issuer != issuerKeys.issuer.Issuer {
+			return fmt.Errorf("the discovery document issuer %v does not match the trusted issuer %v", configuration.Issuer, issuerKeys.issuer.Issuer)
+		}
+		if configuration.JwksURI == "" {
+			return fmt.Errorf("the discovery document of the issuer %v does not publish any keys", issuerKeys.issuer.Issuer)
+		}
+		issuerKeys.jwksUri = configuration.JwksURI
+	}
+
+	var keySet jwk.JsonWebKeys
+	if err := r.getDocument(issuerKeys.jwksUri, &keySet); err != nil {
+		return err
+	}
+
+	keys := make([]*jwk.JsonWebKey, 0)
+	for _, key := range keySet.Keys {
+		if key != nil && (key.Use == "" || key.Use == "sig") && key.GetKey() != nil {
+			keys = append(keys, key)
+		}
+	}
+
+	issuerKeys.keys = keys
+	issuerKeys.fetchedAt = now
+	logger.Info("Loaded %v keys of the trusted issuer %v", len(keys), issuerKeys.issuer.Issuer)
+	return nil
+}
+
+func (r *TrustedIssuerRegistry) getDocument(url string, document interface{}) error {
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/json")
+
+	response, err := r.options.HttpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%v returned the status code %v", url, response.StatusCode)
+	}
+
+	return json.NewDecoder(io.LimitReader(response.Body, maxDocumentSize)).Decode(document)
+}
+
+// findKey returns the key with the id, tokens without a key id can only use the key of an issuer
+// that publishes a single one
+func (i *issuerKeys) findKey(keyId string) *jwk.JsonWebKey {
+	if keyId == "" {
+		if len(i.keys) == 1 {
+			return i.keys[0]
+		}
+		return nil
+	}
+
+	for _, key := range i.keys {
+		if key.ID == keyId {
+			return key
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,218 @@
+package trusted_issuers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cjlapao/common-go-identity/jwk"
+	"github.com/pascaldekloe/jwt"
+)
+
+// testIssuer is a stand-in OpenID Connect provider that publishes the keys it is given
+type testIssuer struct {
+	server        *httptest.Server
+	mutex         sync.Mutex
+	issuer        string
+	keys          map[string]*ecdsa.PrivateKey
+	discoveryHits int
+	jwksHits      int
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	issuer := &testIssuer{
+		keys: make(map[string]*ecdsa.PrivateKey),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		issuer.mutex.Lock()
+		defer issuer.mutex.Unlock()
+		issuer.discoveryHits++
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   issuer.issuer,
+			"jwks_uri": issuer.server.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		issuer.mutex.Lock()
+		defer issuer.mutex.Unlock()
+		issuer.jwksHits++
+		keys := jwk.New()
+		for id, key := range issuer.keys {
+			keys.Add(id, key)
+		}
+		json.NewEncoder(w).Encode(keys)
+	})
+
+	issuer.server = httptest.NewServer(mux)
+	issuer.issuer = issuer.server.URL
+	t.Cleanup(issuer.server.Close)
+
+	return issuer
+}
+
+func (i *testIssuer) addKey(t *testing.T, id string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	i.keys[id] = key
+}
+
+func (i *testIssuer) sign(t *testing.T, id string, audience string) []byte {
+	i.mutex.Lock()
+	key := i.keys[id]
+	issuer := i.issuer
+	i.mutex.Unlock()
+
+	var claims jwt.Claims
+	claims.Issuer = issuer
+	claims.Subject = "external@example.com"
+	claims.Audiences = []string{audience}
+	claims.Expires = jwt.NewNumericTime(time.Now().Add(time.Hour))
+	claims.KeyID = id
+	token, err := claims.ECDSASign(jwt.ES256, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+func (i *testIssuer) hits() (int, int) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	return i.discoveryHits, i.jwksHits
+}
+
+func TestVerify(t *testing.T) {
+	issuer := newTestIssuer(t)
+	issuer.addKey(t, "first")
+
+	registry := NewTrustedIssuerRegistry(TrustedIssuerRegistryOptions{})
+	registry.Add(TrustedIssuer{Issuer: issuer.issuer, Audiences: []string{"api"}})
+
+	claims, trustedIssuer, err := registry.Verify(issuer.sign(t, "first", "api"))
+	if err != nil {
+		t.Fatalf("expected the token to be valid, %v", err)
+	}
+	if claims.Subject != "external@example.com" || trustedIssuer.Issuer != issuer.issuer {
+		t.Errorf("expected the token claims and issuer, got %v %v", claims.Subject, trustedIssuer.Issuer)
+	}
+
+	if _, _, err := registry.Verify(issuer.sign(t, "first", "other-api")); !errors.Is(err, ErrInvalidAudience) {
+		t.Errorf("expected a token for another audience to be rejected, got %v", err)
+	}
+
+	registry.Remove(issuer.issuer)
+	if _, _, err := registry.Verify(issuer.sign(t, "first", "api")); !errors.Is(err, ErrIssuerNotTrusted) {
+		t.Errorf("expected a token of an issuer that is not trusted to be rejected, got %v", err)
+	}
+
+	// the discovery document needs to be of the issuer it was fetched for
+	registry.Add(TrustedIssuer{Issuer: issuer.issuer})
+	issuer.mutex.Lock()
+	issuer.issuer = "https://other.example.com"
+	issuer.mutex.Unlock()
+	if _, err := registry.GetKey(issuer.server.URL, "first"); err == nil {
+		t.Errorf("expected a discovery document of another issuer to be rejected")
+	}
+}
+
+func TestVerifyRejectsHmacTokens(t *testing.T) {
+	issuer := newTestIssuer(t)
+	issuer.addKey(t, "first")
+
+	registry := NewTrustedIssuerRegistry(TrustedIssuerRegistryOptions{})
+	registry.Add(TrustedIssuer{Issuer: issuer.issuer})
+	key, err := registry.GetKey(issuer.issuer, "first")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a published key cannot be used as a hmac secret
+	publicKey, _ := json.Marshal(key)
+	var claims jwt.Claims
+	claims.Issuer = issuer.issuer
+	claims.Subject = "external@example.com"
+	claims.KeyID = "first"
+	token, err := claims.HMACSign(jwt.HS256, publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := registry.Verify(token); err == nil {
+		t.Errorf("expected a hmac token to be rejected")
+	}
+}
+
+func TestKeyCache(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	issuer := newTestIssuer(t)
+	issuer.addKey(t, "first")
+
+	registry := NewTrustedIssuerRegistry(TrustedIssuerRegistryOptions{
+		CacheDuration:   time.Hour,
+		RefreshInterval: time.Minute,
+	})
+	registry.now = func() time.Time { return now }
+	registry.Add(TrustedIssuer{Issuer: issuer.issuer})
+
+	for i := 0; i < 3; i++ {
+		if _, _, err := registry.Verify(issuer.sign(t, "first", "api")); err != nil {
+			t.Fatalf("expected the token to be valid, %v", err)
+		}
+	}
+	if discoveryHits, jwksHits := issuer.hits(); discoveryHits != 1 || jwksHits != 1 {
+		t.Fatalf("expected the keys to be cached, got %v discovery and %v jwks requests", discoveryHits, jwksHits)
+	}
+
+	// a token signed with a new key fetches the keys again
+	now = now.Add(time.Minute * 2)
+	issuer.addKey(t, "second")
+	if _, _, err := registry.Verify(issuer.sign(t, "second", "api")); err != nil {
+		t.Fatalf("expected the keys to be fetched for a new key, %v", err)
+	}
+	if discoveryHits, jwksHits := issuer.hits(); discoveryHits != 1 || jwksHits != 2 {
+		t.Fatalf("expected only the keys to be fetched again, got %v discovery and %v jwks requests", discoveryHits, jwksHits)
+	}
+
+	// unknown keys do not fetch the keys again until the refresh interval has passed
+	if _, err := registry.GetKey(issuer.issuer, "unknown"); !errors.Is(err, ErrSigningKeyNotFound) {
+		t.Fatalf("expected the key not to be found, got %v", err)
+	}
+	if _, err := registry.GetKey(issuer.issuer, "unknown"); !errors.Is(err, ErrSigningKeyNotFound) {
+		t.Fatalf("expected the key not to be found, got %v", err)
+	}
+	if _, jwksHits := issuer.hits(); jwksHits != 2 {
+		t.Fatalf("expected unknown keys to be rate limited, got %v jwks requests", jwksHits)
+	}
+
+	// everything is fetched again once the cache expires and the cached keys are kept if the
+	// issuer cannot be reached
+	now = now.Add(time.Hour)
+	if _, _, err := registry.Verify(issuer.sign(t, "first", "api")); err != nil {
+		t.Fatalf("expected the token to be valid, %v", err)
+	}
+	if discoveryHits, jwksHits := issuer.hits(); discoveryHits != 2 || jwksHits != 3 {
+		t.Fatalf("expected the keys to be fetched after the cache expired, got %v discovery and %v jwks requests", discoveryHits, jwksHits)
+	}
+
+	token := issuer.sign(t, "first", "api")
+	issuer.server.Close()
+	now = now.Add(time.Hour)
+	if _, _, err := registry.Verify(token); err != nil {
+		t.Fatalf("expected the cached keys to be used while the issuer is down, %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+package identity
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cjlapao/common-go-identity/authorization_context"
+	"github.com/cjlapao/common-go-identity/constants"
+	"github.com/cjlapao/common-go-identity/jwk"
+	"github.com/cjlapao/common-go-identity/trusted_issuers"
+	restapi "github.com/cjlapao/common-go-restapi"
+	"github.com/pascaldekloe/jwt"
+)
+
+// addTestTrustedIssuer starts a stand-in for a third party identity provider and trusts it with the
+// scope, the returned function signs tokens with its key for any issuer with the extra claims
+func addTestTrustedIssuer(t *testing.T, scope string) (string, func(iss string, subject string, scope string, extraClaims map[string]interface{}) string) {
+	t.Helper()
+	getTestServer(t)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var issuer *httptest.Server
+	issuer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{"issuer": issuer.URL, "jwks_uri": issuer.URL + "/keys"})
+		case "/keys":
+			keys := jwk.New()
+			keys.Add("external", key)
+			json.NewEncoder(w).Encode(keys)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(issuer.Close)
+
+	authCtx := authorization_context.GetBaseContext()
+	WithTrustedIssuer(restapi.GetHttpListener(), trusted_issuers.TrustedIssuer{Issuer: issuer.URL, Scope: scope})
+	t.Cleanup(func() { authCtx.TrustedIssuers.Remove(issuer.URL) })
+
+	signTokenFor := func(iss string, subject string, scope string, extraClaims map[string]interface{}) string {
+		var claims jwt.Claims
+		claims.Issuer = iss
+		claims.Subject = subject
+		claims.Expires = jwt.NewNumericTime(time.Now().Add(time.Hour))
+		claims.KeyID = "external"
+		claims.Set = map[string]interface{}{"scope": scope}
+		for name, value := range extraClaims {
+			claims.Set[name] = value
+		}
+		token, err := claims.RSASign(jwt.RS256, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(token)
+	}
+
+	return issuer.URL, signTokenFor
+}
+
+func TestTrustedIssuer(t *testing.T) {
+	issuerUrl, signTokenFor := addTestTrustedIssuer(t, "api.read")
+	authCtx := authorization_context.GetBaseContext()
+
+	AddAuthorizedController(restapi.GetHttpListener(), func(w http.ResponseWriter, r *http.Request) {
+		authorizedCtx := r.Context().Value(constants.AUTHORIZATION_CONTEXT_KEY).(*authorization_context.AuthorizationContext)
+		json.NewEncoder(w).Encode(map[string]string{"sub": authorizedCtx.User.Email, "iss": authorizedCtx.User.Issuer})
+	}, "/auth/trusted-issuer-test", "GET")
+
+	signToken := func(iss string, scope string) string {
+		return signTokenFor(iss, "external@example.com", scope, nil)
+	}
+
+	// tokens of the trusted issuer need its scope and not the one of the identity service
+	var result map[string]string
+	if status := sendJsonRequest(t, http.MethodGet, "trusted-issuer-test", signToken(issuerUrl, "openid api.read"), nil, &result); status != http.StatusOK {
+		t.Fatalf("expected the external token to be authorized, got %v", status)
+	}
+	if result["sub"] != "external@example.com" || result["iss"] != issuerUrl {
+		t.Errorf("expected the external user in the context, got %v", result)
+	}
+
+	if status := sendJsonRequest(t, http.MethodGet, "trusted-issuer-test", signToken(issuerUrl, "openid"), nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("expected a token without the issuer scope to be rejected, got %v", status)
+	}
+
+	if status := sendJsonRequest(t, http.MethodGet, "trusted-issuer-test", signToken("https://untrusted.example.com", "openid api.read"), nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("expected a token of an issuer that is not trusted to be rejected, got %v", status)
+	}
+
+	// the tokens of the identity service are still validated with the key vault
+	status, body := requestToken(t, url.Values{
+		"grant_type": {"password"},
+		"client_id":  {"spa"},
+		"username":   {testAdminUsername},
+		"password":   {testAdminPassword},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("expected a login, got %v %v", status, body)
+	}
+	if status := sendJsonRequest(t, http.MethodGet, "trusted-issuer-test", body["access_token"].(string), nil, nil); status != http.StatusOK {
+		t.Errorf("expected the identity service token to be authorized, got %v", status)
+	}
+
+	// the roles of an external subject come from its token and never from a local user with
+	// the same email
+	addTestUser(t, "trusted-issuer-local-user", testRegularUserPassword, constants.RegularUserRole)
+	AddAuthorizedControllerWithRoles(restapi.GetHttpListener(), func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}, "/auth/trusted-issuer-roles-test", []string{constants.RegularUserRole.ID}, "GET")
+	localEmail := "trusted-issuer-local-user@localhost.com"
+	if status := sendJsonRequest(t, http.MethodGet, "trusted-issuer-roles-test", signTokenFor(issuerUrl, localEmail, "api.read", nil), nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("expected the external token not to get the roles of the local user, got %v", status)
+	}
+	if status := sendJsonRequest(t, http.MethodGet, "trusted-issuer-roles-test", signTokenFor(issuerUrl, localEmail, "api.read", map[string]interface{}{"roles": []string{constants.RegularUserRole.ID}}), nil, nil); status != http.StatusOK {
+		t.Errorf("expected the roles of the external token to be used, got %v", status)
+	}
+
+	// without a scope the issuer tokens need the context scope so other token types are rejected
+	WithTrustedIssuer(restapi.GetHttpListener(), trusted_issuers.TrustedIssuer{Issuer: issuerUrl})
+	if status := sendJsonRequest(t, http.MethodGet, "trusted-issuer-test", signToken(issuerUrl, constants.MfaScope), nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("expected an mfa token of the issuer to be rejected, got %v", status)
+	}
+	if status := sendJsonRequest(t, http.MethodGet, "trusted-issuer-test", signToken(issuerUrl, authCtx.Scope), nil, nil); status != http.StatusOK {
+		t.Errorf("expected a token with the context scope to be authorized, got %v", status)
+	}
+}
+
+func TestTrustedIssuerCannotActAsLocalUser(t *testing.T) {
+	server := getTestServer(t)
+	issuerUrl, signTokenFor := addTestTrustedIssuer(t, "api.read")
+
+	// the external token names a local user in both its subject and its uid claim
+	addTestUser(t, "trusted-issuer-impersonated-user", testRegularUserPassword)
+	token := signTokenFor(issuerUrl, "trusted-issuer-impersonated-user@localhost.com", "openid api.read", map[string]interface{}{"uid": "trusted-issuer-impersonated-user"})
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{"userinfo", http.MethodGet, "userinfo"},
+		{"totp enrollment", http.MethodPost, "mfa/totp"},
+		{"totp disable", http.MethodDelete, "mfa/totp"},
+		{"passkey registration", http.MethodPost, "webauthn/register/begin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := sendJsonRequest(t, tt.method, tt.path, token, map[string]string{}, nil); status != http.StatusUnauthorized {
+				t.Errorf("expected the external token not to act as the local user, got %v", status)
+			}
+		})
+	}
+
+	t.Run("authorization code", func(t *testing.T) {
+		form := authorizeForm(map[string]string{"username": "", "password": ""})
+		request, _ := http.NewRequest(http.MethodGet, server.URL+"/auth/authorize?"+form.Encode(), nil)
+		request.Header.Set("Authorization", "Bearer "+token)
+		response, err := newTestClient().Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+
+		location, _ := url.Parse(response.Header.Get("Location"))
+		if location.Query().Get("code") != "" || !strings.HasPrefix(location.String(), testLoginUrl) {
+			t.Errorf("expected the external user to be sent to the login page, got %v %v", response.StatusCode, location)
+		}
+	})
+}
@@ -17,6 +17,14 @@ const (
 	InvalidKeyError
 	PasswordValidationError
 	EmailValidationError
+	PasswordHashingError
+	MfaNotEnrolledError
+	MfaAlreadyEnabledError
+	InvalidMfaCodeError
+	InvalidWebAuthnResponseError
+	WebAuthnCredentialNotFoundError
+	InvalidLoginCodeError
+	LoginCodeRateLimitedError
 	UnknownError
 )
 
@@ -29,24 +37,40 @@ func (UserManagerErrorType UserManagerErrorType) FromString(keyType string) User
 }
 
 var toUserManagerErrorTypeString = map[UserManagerErrorType]string{
-	DatabaseError:           "database_error",
-	InvalidModelError:       "invalid_model_error",
-	InvalidTokenError:       "invalid_token_error",
-	InvalidKeyError:         "invalid_key_error",
-	PasswordValidationError: "password_validation_error",
-	EmailValidationError:    "EmailValidationError",
-	UserAlreadyExistsError:  "user_already_exists_error",
-	UnknownError:            "unknown_error",
+	DatabaseError:                   "database_error",
+	InvalidModelError:               "invalid_model_error",
+	InvalidTokenError:               "invalid_token_error",
+	InvalidKeyError:                 "invalid_key_error",
+	PasswordValidationError:         "password_validation_error",
+	EmailValidationError:            "EmailValidationError",
+	UserAlreadyExistsError:          "user_already_exists_error",
+	PasswordHashingError:            "password_hashing_error",
+	MfaNotEnrolledError:             "mfa_not_enrolled_error",
+	MfaAlreadyEnabledError:          "mfa_already_enabled_error",
+	InvalidMfaCodeError:             "invalid_mfa_code_error",
+	InvalidWebAuthnResponseError:    "invalid_webauthn_response_error",
+	WebAuthnCredentialNotFoundError: "webauthn_credential_not_found_error",
+	InvalidLoginCodeError:           "invalid_login_code_error",
+	LoginCodeRateLimitedError:       "login_code_rate_limited_error",
+	UnknownError:                    "unknown_error",
 }
 
 var toUserManagerErrorTypeID = map[string]UserManagerErrorType{
-	"database_error":            DatabaseError,
-	"invalid_model_error":       InvalidModelError,
-	"invalid_token_error":       InvalidTokenError,
-	"invalid_key_error":         InvalidKeyError,
-	"EmailValidationError":      EmailValidationError,
-	"user_already_exists_error": UserAlreadyExistsError,
-	"unknown_error":             UnknownError,
+	"database_error":                      DatabaseError,
+	"invalid_model_error":                 InvalidModelError,
+	"invalid_token_error":                 InvalidTokenError,
+	"invalid_key_error":                   InvalidKeyError,
+	"EmailValidationError":                EmailValidationError,
+	"user_already_exists_error":           UserAlreadyExistsError,
+	"password_hashing_error":              PasswordHashingError,
+	"mfa_not_enrolled_error":              MfaNotEnrolledError,
+	"mfa_already_enabled_error":           MfaAlreadyEnabledError,
+	"invalid_mfa_code_error":              InvalidMfaCodeError,
+	"invalid_webauthn_response_error":     InvalidWebAuthnResponseError,
+	"webauthn_credential_not_found_error": WebAuthnCredentialNotFoundError,
+	"invalid_login_code_error":            InvalidLoginCodeError,
+	"login_code_rate_limited_error":       LoginCodeRateLimitedError,
+	"unknown_error":                       UnknownError,
 }
 
 type UserManagerError struct {
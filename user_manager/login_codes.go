@@ -0,0 +1,127 @@
+package user_manager
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/cjlapao/common-go-identity/constants"
+	"github.com/cjlapao/common-go-identity/database/dto"
+	"github.com/cjlapao/common-go-identity/jwt"
+	"github.com/cjlapao/common-go-identity/models"
+)
+
+const loginCodeMax = 1000000
+
+// GenerateLoginCode issues a passwordless login code for the user replacing any pending one,
+// with the otp email verification processor it is a six digit code and with jwt it is the
+// token of a login link, the code is only returned here as only its hash is stored
+func (um *UserManager) GenerateLoginCode(user models.User, clientId string) (string, *UserManagerError) {
+	now := time.Now().UTC()
+	attempts := 0
+
+	// a new code keeps the attempts of the pending one so requesting codes does not give more
+	// guesses, and it can only be requested once the interval since the pending one passed
+	if pendingCode := um.UserContext.GetUserLoginCode(user.ID); pendingCode != nil && now.Before(pendingCode.ExpiresAt) {
+		interval := time.Second * time.Duration(um.AuthorizationContext.Options.LoginCodeRequestInterval)
+		if now.Before(pendingCode.CreatedAt.Add(interval)) {
+			err := NewUserManagerError(LoginCodeRateLimitedError, fmt.Errorf("user %v requested a login code before the interval passed", user.ID))
+			return "", &err
+		}
+		attempts = pendingCode.Attempts
+	}
+
+	var code string
+	if um.AuthorizationContext.Options.EmailVerificationProcessor == "otp" {
+		n, err := rand.Int(rand.Reader, big.NewInt(loginCodeMax))
+		if err != nil {
+			resultErr := NewUserManagerError(UnknownError, err)
+			return "", &resultErr
+		}
+		code = fmt.Sprintf("%06d", n.Int64())
+	} else {
+		code = jwt.GenerateLoginLinkToken(um.AuthorizationContext.Options.KeyId, user, clientId)
+	}
+
+	if code == "" {
+		err := NewUserManagerError(InvalidTokenError, fmt.Errorf("generated login code is empty for user %v", user.ID))
+		err.Log()
+		return "", &err
+	}
+
+	loginCode := dto.UserLoginCodeDTO{
+		UserID:    user.ID,
+		Code:      hashLoginCode(user.ID, code),
+		ClientID:  clientId,
+		Attempts:  attempts,
+		ExpiresAt: now.Add(time.Minute * time.Duration(um.AuthorizationContext.Options.LoginCodeDuration)),
+		CreatedAt: now,
+	}
+
+	if err := um.UserContext.UpsertUserLoginCode(loginCode); err != nil {
+		resultErr := NewUserManagerError(DatabaseError, fmt.Errorf("error persisting login code for user %v", user.ID), err)
+		resultErr.Log()
+		return "", &resultErr
+	}
+
+	return code, nil
+}
+
+// ValidateLoginCode checks the pending login code of the user for the client it was issued to,
+// the code is discarded once it is used or expires, a code that ran out of attempts is kept until
+// it expires so its attempts still count for the next code
+func (um *UserManager) ValidateLoginCode(userId string, code string, clientId string) *UserManagerError {
+	loginCode := um.UserContext.GetUserLoginCode(userId)
+	if loginCode == nil || code == "" {
+		err := NewUserManagerError(InvalidLoginCodeError, fmt.Errorf("user %v has no pending login code", userId))
+		return &err
+	}
+
+	if time.Now().UTC().After(loginCode.ExpiresAt) {
+		um.UserContext.RemoveUserLoginCode(userId, loginCode.Code)
+		err := NewUserManagerError(InvalidLoginCodeError, fmt.Errorf("login code for user %v is expired", userId))
+		return &err
+	}
+
+	if loginCode.ClientID != clientId {
+		err := NewUserManagerError(InvalidLoginCodeError, fmt.Errorf("login code for user %v was issued to another client", userId))
+		return &err
+	}
+
+	// the attempt is counted before comparing so concurrent guesses cannot go over the limit
+	attempts := loginCode.Attempts + 1
+	maxAttempts := um.AuthorizationContext.Options.LoginCodeMaxAttempts
+	if attempts > maxAttempts || !um.UserContext.UpdateUserLoginCodeAttempts(userId, attempts) {
+		err := NewUserManagerError(InvalidLoginCodeError, fmt.Errorf("login code for user %v has no attempts left", userId))
+		return &err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashLoginCode(userId, code)), []byte(loginCode.Code)) != 1 {
+		err := NewUserManagerError(InvalidLoginCodeError, fmt.Errorf("login code for user %v did not match", userId))
+		return &err
+	}
+
+	if um.AuthorizationContext.Options.EmailVerificationProcessor != "otp" {
+		if _, err := jwt.ValidateTokenByScope(code, userId, constants.LoginLinkScope); err != nil {
+			resultErr := NewUserManagerError(InvalidLoginCodeError, fmt.Errorf("login link for user %v is not valid", userId), err)
+			return &resultErr
+		}
+	}
+
+	if !um.UserContext.RemoveUserLoginCode(userId, loginCode.Code) {
+		err := NewUserManagerError(InvalidLoginCodeError, fmt.Errorf("login code for user %v was already used", userId))
+		return &err
+	}
+
+	return nil
+}
+
+func hashLoginCode(userId string, code string) string {
+	hash := sha256.Sum256([]byte(strings.ToLower(userId) + ":" + strings.TrimSpace(code)))
+	return hex.EncodeToString(hash[:])
+}
@@ -121,6 +121,15 @@ func (um *UserManager) RemoveUser(id string) bool {
 	return um.UserContext.RemoveUser(id)
 }
 
+func (um *UserManager) UpdateUserInvalidAttempts(id string, attempts int, blockedUntil string) error {
+	return um.UserContext.UpdateUserInvalidAttempts(id, attempts, blockedUntil)
+}
+
+// IncrementUserInvalidAttempts atomically counts a failed login and returns the new count
+func (um *UserManager) IncrementUserInvalidAttempts(id string) (int, error) {
+	return um.UserContext.IncrementUserInvalidAttempts(id)
+}
+
 func (um *UserManager) GetUserRefreshToken(id string) *string {
 	return um.UserContext.GetUserRefreshToken(id)
 }
@@ -222,6 +231,12 @@ func (um *UserManager) AddUser(user models.User) *UserManagerError {
 	}
 
 	user.Password = user.GetHashedPassword()
+	if user.Password == "" {
+		err := NewUserManagerError(PasswordHashingError, fmt.Errorf("there was an error hashing the password for user %v", user.ID))
+		err.Log()
+		return &err
+	}
+
 	if err := um.UpsertUser(user); err != nil {
 		err := NewUserManagerError(DatabaseError, fmt.Errorf("there was an error persisting user %v into database", user.ID), err)
 		err.Log()
@@ -378,7 +393,13 @@ func (um *UserManager) UpdatePassword(userId string, password string) *UserManag
 		Password: password,
 	}
 
-	err := um.UserContext.UpdateUserPassword(userId, user.GetHashedPassword())
+	hashedPassword := user.GetHashedPassword()
+	if hashedPassword == "" {
+		returnErr := NewUserManagerError(PasswordHashingError, fmt.Errorf("there was an error hashing the password for user %v", userId))
+		return &returnErr
+	}
+
+	err := um.UserContext.UpdateUserPassword(userId, hashedPassword)
 	if err != nil {
 		returnErr := NewUserManagerError(DatabaseError, err)
 		return &returnErr
@@ -387,6 +408,25 @@ func (um *UserManager) UpdatePassword(userId string, password string) *UserManag
 	return nil
 }
 
+// RehashPassword replaces the stored hash of a user that just authenticated with one
+// from the default password hasher, the password rules are not checked again as the
+// password itself does not change
+func (um *UserManager) RehashPassword(user *models.User, password string) *UserManagerError {
+	hashedPassword := user.HashPassword(password)
+	if hashedPassword == "" {
+		returnErr := NewUserManagerError(PasswordHashingError, fmt.Errorf("there was an error hashing the password for user %v", user.ID))
+		return &returnErr
+	}
+
+	if err := um.UserContext.UpdateUserPassword(user.ID, hashedPassword); err != nil {
+		returnErr := NewUserManagerError(DatabaseError, err)
+		return &returnErr
+	}
+
+	user.Password = hashedPassword
+	return nil
+}
+
 func (um *UserManager) UpdateRecoveryToken(userID string) (*models.User, *UserManagerError) {
 	var user *dto.UserDTO
 
@@ -0,0 +1,128 @@
+package user_manager
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cjlapao/common-go-identity/database/dto"
+)
+
+const (
+	recoveryCodeCount    = 10
+	recoveryCodeLength   = 10
+	recoveryCodeAlphabet = "abcdefghijklmnopqrstuvwxyz234567"
+)
+
+// GenerateRecoveryCodes replaces the recovery codes of the user with a new set, the codes
+// are only returned here as only their hashes are stored
+func (um *UserManager) GenerateRecoveryCodes(userId string) ([]string, *UserManagerError) {
+	if !um.IsTotpEnabled(userId) {
+		err := NewUserManagerError(MfaNotEnrolledError, fmt.Errorf("user %v does not have totp enabled", userId))
+		return nil, &err
+	}
+
+	codes := make([]string, 0)
+	dtos := make([]dto.UserRecoveryCodeDTO, 0)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := newRecoveryCode()
+		if err != nil {
+			resultErr := NewUserManagerError(UnknownError, err)
+			return nil, &resultErr
+		}
+
+		codes = append(codes, code)
+		dtos = append(dtos, dto.UserRecoveryCodeDTO{
+			ID:        hashRecoveryCode(userId, code),
+			UserID:    userId,
+			Used:      false,
+			CreatedAt: time.Now().UTC(),
+		})
+	}
+
+	if err := um.UserContext.ReplaceUserRecoveryCodes(userId, dtos); err != nil {
+		resultErr := NewUserManagerError(DatabaseError, err)
+		return nil, &resultErr
+	}
+
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user, a valid totp or recovery
+// code is required to regenerate them
+func (um *UserManager) RegenerateRecoveryCodes(userId string, code string) ([]string, *UserManagerError) {
+	if _, err := um.ValidateMfaCode(userId, code); err != nil {
+		return nil, err
+	}
+
+	return um.GenerateRecoveryCodes(userId)
+}
+
+// CountRecoveryCodes returns how many recovery codes the user has not used yet
+func (um *UserManager) CountRecoveryCodes(userId string) int {
+	return um.UserContext.CountUserRecoveryCodes(userId)
+}
+
+// ValidateRecoveryCode checks a recovery code of a user with totp enabled, each code is
+// only accepted once
+func (um *UserManager) ValidateRecoveryCode(userId string, code string) *UserManagerError {
+	if !um.IsTotpEnabled(userId) {
+		err := NewUserManagerError(MfaNotEnrolledError, fmt.Errorf("user %v does not have totp enabled", userId))
+		return &err
+	}
+
+	if code == "" || !um.UserContext.UseUserRecoveryCode(userId, hashRecoveryCode(userId, code)) {
+		err := NewUserManagerError(InvalidMfaCodeError, fmt.Errorf("recovery code for user %v is not valid", userId))
+		return &err
+	}
+
+	return nil
+}
+
+// ValidateMfaCode accepts either a totp code or a recovery code as the second factor of the
+// user, it returns true if a recovery code was used
+func (um *UserManager) ValidateMfaCode(userId string, code string) (bool, *UserManagerError) {
+	err := um.ValidateTotp(userId, code)
+	if err == nil {
+		return false, nil
+	}
+
+	if err.Error != InvalidMfaCodeError {
+		return false, err
+	}
+
+	if recoveryErr := um.ValidateRecoveryCode(userId, code); recoveryErr != nil {
+		return false, recoveryErr
+	}
+
+	return true, nil
+}
+
+func newRecoveryCode() (string, error) {
+	randomBytes := make([]byte, recoveryCodeLength)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", err
+	}
+
+	// the alphabet has 32 characters so the modulo does not bias the code
+	var code strings.Builder
+	for i, b := range randomBytes {
+		if i == recoveryCodeLength/2 {
+			code.WriteString("-")
+		}
+		code.WriteByte(recoveryCodeAlphabet[int(b)%len(recoveryCodeAlphabet)])
+	}
+
+	return code.String(), nil
+}
+
+// hashRecoveryCode normalizes the code the way users tend to type it and salts it with the
+// user id so the same code of two users has different hashes
+func hashRecoveryCode(userId string, code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	hash := sha256.Sum256([]byte(strings.ToLower(userId) + ":" + normalized))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,198 @@
+package user_manager
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/cjlapao/common-go-identity-otp/otp"
+	"github.com/cjlapao/common-go-identity-otp/totp"
+	"github.com/cjlapao/common-go-identity/data_protection"
+	"github.com/cjlapao/common-go-identity/database/dto"
+)
+
+const (
+	totpPeriod     = 30
+	totpSkew       = 1
+	totpSecretSize = 20
+)
+
+// EnrollTotp starts a totp enrollment with a new random secret, the enrollment only becomes
+// active once it is confirmed with a code, starting again replaces a pending enrollment
+func (um *UserManager) EnrollTotp(userId string) (*otp.OtpKey, *UserManagerError) {
+	user := um.GetUserById(userId)
+	if user == nil || user.ID == "" {
+		err := NewUserManagerError(InvalidModelError, fmt.Errorf("user %v was not found", userId))
+		return nil, &err
+	}
+
+	if current := um.UserContext.GetUserTotp(user.ID); current != nil && current.Enabled {
+		err := NewUserManagerError(MfaAlreadyEnabledError, fmt.Errorf("user %v already has totp enabled", user.ID))
+		return nil, &err
+	}
+
+	secretBytes := make([]byte, totpSecretSize)
+	if _, err := rand.Read(secretBytes); err != nil {
+		resultErr := NewUserManagerError(UnknownError, err)
+		return nil, &resultErr
+	}
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(secretBytes)
+
+	accountName := user.Email
+	if accountName == "" {
+		accountName = user.Username
+	}
+
+	key, err := totp.GenerateKey(&otp.OtpKeyOptions{
+		Issuer:  um.getTotpIssuer(),
+		UserId:  accountName,
+		Secret:  otp.NewSecret(secret),
+		Options: otp.NewDefaultOtpOptions(),
+	})
+	if err != nil {
+		resultErr := NewUserManagerError(UnknownError, err)
+		return nil, &resultErr
+	}
+
+	protector, err := data_protection.NewProtector(um.AuthorizationContext.Options.EncryptionKey)
+	if err != nil {
+		resultErr := NewUserManagerError(InvalidKeyError, err)
+		return nil, &resultErr
+	}
+
+	protectedSecret, err := protector.ProtectString(secret)
+	if err != nil {
+		resultErr := NewUserManagerError(InvalidKeyError, err)
+		return nil, &resultErr
+	}
+
+	if err := um.UserContext.UpsertUserTotp(dto.UserTotpDTO{
+		UserID:    user.ID,
+		Secret:    protectedSecret,
+		Enabled:   false,
+		CreatedAt: time.Now().UTC(),
+	}); err != nil {
+		resultErr := NewUserManagerError(DatabaseError, err)
+		return nil, &resultErr
+	}
+
+	return key, nil
+}
+
+// ConfirmTotp enables a pending totp enrollment if the code was generated with its secret,
+// it returns the first set of recovery codes of the user
+func (um *UserManager) ConfirmTotp(userId string, code string) ([]string, *UserManagerError) {
+	current := um.UserContext.GetUserTotp(userId)
+	if current == nil {
+		err := NewUserManagerError(MfaNotEnrolledError, fmt.Errorf("user %v has no totp enrollment", userId))
+		return nil, &err
+	}
+
+	if current.Enabled {
+		err := NewUserManagerError(MfaAlreadyEnabledError, fmt.Errorf("user %v already has totp enabled", userId))
+		return nil, &err
+	}
+
+	step, err := um.matchTotpStep(*current, code)
+	if err != nil {
+		return nil, err
+	}
+
+	current.Enabled = true
+	current.LastUsedStep = step
+	if err := um.UserContext.UpsertUserTotp(*current); err != nil {
+		resultErr := NewUserManagerError(DatabaseError, err)
+		return nil, &resultErr
+	}
+
+	return um.GenerateRecoveryCodes(userId)
+}
+
+// ValidateTotp checks a code against the enabled totp of the user, each code is only
+// accepted once
+func (um *UserManager) ValidateTotp(userId string, code string) *UserManagerError {
+	current := um.UserContext.GetUserTotp(userId)
+	if current == nil || !current.Enabled {
+		err := NewUserManagerError(MfaNotEnrolledError, fmt.Errorf("user %v does not have totp enabled", userId))
+		return &err
+	}
+
+	step, err := um.matchTotpStep(*current, code)
+	if err != nil {
+		return err
+	}
+
+	if !um.UserContext.UpdateUserTotpLastUsedStep(userId, step) {
+		err := NewUserManagerError(InvalidMfaCodeError, fmt.Errorf("totp code for user %v was already used", userId))
+		return &err
+	}
+
+	return nil
+}
+
+// DisableTotp removes the totp and the recovery codes of the user, a valid totp or recovery
+// code is required to disable it
+func (um *UserManager) DisableTotp(userId string, code string) *UserManagerError {
+	if _, err := um.ValidateMfaCode(userId, code); err != nil {
+		return err
+	}
+
+	if err := um.UserContext.RemoveUserTotp(userId); err != nil {
+		resultErr := NewUserManagerError(DatabaseError, err)
+		return &resultErr
+	}
+
+	if err := um.UserContext.RemoveUserRecoveryCodes(userId); err != nil {
+		resultErr := NewUserManagerError(DatabaseError, err)
+		return &resultErr
+	}
+
+	return nil
+}
+
+// IsTotpEnabled returns true if the user has a confirmed totp enrollment
+func (um *UserManager) IsTotpEnabled(userId string) bool {
+	current := um.UserContext.GetUserTotp(userId)
+	return current != nil && current.Enabled
+}
+
+// matchTotpStep returns the time step the code was generated for, the steps next to the
+// current one are also accepted to allow for clock drift
+func (um *UserManager) matchTotpStep(current dto.UserTotpDTO, code string) (int64, *UserManagerError) {
+	protector, err := data_protection.NewProtector(um.AuthorizationContext.Options.EncryptionKey)
+	if err != nil {
+		resultErr := NewUserManagerError(InvalidKeyError, err)
+		return 0, &resultErr
+	}
+
+	secret, err := protector.UnprotectString(current.Secret)
+	if err != nil {
+		resultErr := NewUserManagerError(InvalidKeyError, fmt.Errorf("totp secret for user %v could not be decrypted", current.UserID), err)
+		return 0, &resultErr
+	}
+
+	currentStep := time.Now().UTC().Unix() / totpPeriod
+	for _, step := range []int64{currentStep, currentStep - totpSkew, currentStep + totpSkew} {
+		valid, err := otp.ValidateCode(code, uint64(step), secret, otp.NewDefaultOtpOptions())
+		if err == nil && valid {
+			return step, nil
+		}
+	}
+
+	resultErr := NewUserManagerError(InvalidMfaCodeError, fmt.Errorf("totp code for user %v is not valid", current.UserID))
+	return 0, &resultErr
+}
+
+func (um *UserManager) getTotpIssuer() string {
+	if um.AuthorizationContext.Options.TotpIssuer != "" {
+		return um.AuthorizationContext.Options.TotpIssuer
+	}
+
+	if issuerUrl, err := url.Parse(um.AuthorizationContext.Issuer); err == nil && issuerUrl.Hostname() != "" {
+		return issuerUrl.Hostname()
+	}
+
+	return um.AuthorizationContext.Issuer
+}
@@ -0,0 +1,298 @@
+package user_manager
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cjlapao/common-go-identity/constants"
+	"github.com/cjlapao/common-go-identity/database/dto"
+	"github.com/cjlapao/common-go-identity/jwt"
+	"github.com/cjlapao/common-go-identity/mappers"
+	"github.com/cjlapao/common-go-identity/models"
+	"github.com/cjlapao/common-go-identity/webauthn"
+	"github.com/cjlapao/common-go/security"
+)
+
+// BeginWebAuthnRegistration returns the options to create a new credential for the user and
+// the session that carries the challenge to the second step
+func (um *UserManager) BeginWebAuthnRegistration(userId string) (*webauthn.CredentialCreationOptions, string, *UserManagerError) {
+	user := um.GetUserById(userId)
+	if user == nil || user.ID == "" {
+		err := NewUserManagerError(InvalidModelError, fmt.Errorf("user %v was not found", userId))
+		return nil, "", &err
+	}
+
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		resultErr := NewUserManagerError(UnknownError, err)
+		return nil, "", &resultErr
+	}
+
+	exclude := make([][]byte, 0)
+	for _, credential := range um.UserContext.GetUserWebAuthnCredentials(user.ID) {
+		if id, err := webauthn.DecodeBase64URL(credential.ID); err == nil {
+			exclude = append(exclude, id)
+		}
+	}
+
+	name := user.Email
+	if name == "" {
+		name = user.Username
+	}
+	displayName := user.DisplayName
+	if displayName == "" {
+		displayName = name
+	}
+
+	relyingParty := um.getRelyingParty()
+	options, err := relyingParty.NewCreationOptions(webauthn.User{
+		ID:          []byte(user.ID),
+		Name:        name,
+		DisplayName: displayName,
+	}, challenge, um.getWebAuthnTimeout(), exclude)
+	if err != nil {
+		resultErr := NewUserManagerError(UnknownError, err)
+		return nil, "", &resultErr
+	}
+
+	session := jwt.GenerateWebAuthnSessionToken(um.AuthorizationContext.Options.KeyId, user.ID, "", challenge, constants.WebAuthnRegistrationScope)
+	if session == "" {
+		resultErr := NewUserManagerError(InvalidTokenError, fmt.Errorf("there was an error generating the webauthn session for user %v", user.ID))
+		return nil, "", &resultErr
+	}
+	if err := um.addWebAuthnSession(user.ID, challenge); err != nil {
+		return nil, "", err
+	}
+
+	return options, session, nil
+}
+
+// FinishWebAuthnRegistration verifies the new credential against the session challenge and
+// stores it for the user
+func (um *UserManager) FinishWebAuthnRegistration(userId string, session string, name string, response webauthn.RegistrationResponse) (*models.WebAuthnCredential, *UserManagerError) {
+	sessionToken, err := jwt.ValidateTokenByScope(session, userId, constants.WebAuthnRegistrationScope)
+	if err != nil {
+		resultErr := NewUserManagerError(InvalidTokenError, fmt.Errorf("webauthn session for user %v is not valid, %v", userId, err.Error()))
+		return nil, &resultErr
+	}
+
+	relyingParty := um.getRelyingParty()
+	credential, err := relyingParty.VerifyRegistration(response, sessionToken.Nonce)
+	if err != nil {
+		resultErr := NewUserManagerError(InvalidWebAuthnResponseError, fmt.Errorf("webauthn registration for user %v is not valid, %v", userId, err.Error()))
+		return nil, &resultErr
+	}
+
+	if !um.useWebAuthnSession(sessionToken.Nonce) {
+		resultErr := NewUserManagerError(InvalidTokenError, fmt.Errorf("webauthn session for user %v was already used", userId))
+		return nil, &resultErr
+	}
+
+	credentialId := base64.RawURLEncoding.EncodeToString(credential.ID)
+	if existing := um.UserContext.GetWebAuthnCredential(credentialId); existing != nil {
+		resultErr := NewUserManagerError(InvalidWebAuthnResponseError, fmt.Errorf("webauthn credential %v is already registered", credentialId))
+		return nil, &resultErr
+	}
+
+	if name == "" {
+		name = "Passkey"
+	}
+
+	now := time.Now().UTC()
+	result := models.WebAuthnCredential{
+		ID:              credentialId,
+		UserID:          userId,
+		Name:            name,
+		PublicKey:       base64.RawURLEncoding.EncodeToString(credential.PublicKey),
+		AttestationType: credential.AttestationType,
+		AAGUID:          hex.EncodeToString(credential.AAGUID),
+		SignCount:       int64(credential.SignCount),
+		CreatedAt:       now,
+		LastUsedAt:      now,
+	}
+
+	if err := um.UserContext.UpsertWebAuthnCredential(mappers.ToWebAuthnCredentialDTO(result)); err != nil {
+		resultErr := NewUserManagerError(DatabaseError, err)
+		return nil, &resultErr
+	}
+
+	return &result, nil
+}
+
+// BeginWebAuthnLogin returns the options to sign in with a credential, if the username is
+// known only its credentials are allowed, otherwise any discoverable credential can be used
+func (um *UserManager) BeginWebAuthnLogin(username string, clientId string) (*webauthn.CredentialRequestOptions, string, *UserManagerError) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		resultErr := NewUserManagerError(UnknownError, err)
+		return nil, "", &resultErr
+	}
+
+	userId := ""
+	allow := make([][]byte, 0)
+	if username != "" {
+		if user := um.GetUser(username); user != nil && user.ID != "" {
+			for _, credential := range um.UserContext.GetUserWebAuthnCredentials(user.ID) {
+				if id, err := webauthn.DecodeBase64URL(credential.ID); err == nil {
+					allow = append(allow, id)
+				}
+			}
+
+			if len(allow) > 0 {
+				userId = user.ID
+			}
+		}
+	}
+
+	relyingParty := um.getRelyingParty()
+	options, err := relyingParty.NewRequestOptions(challenge, um.getWebAuthnTimeout(), allow)
+	if err != nil {
+		resultErr := NewUserManagerError(UnknownError, err)
+		return nil, "", &resultErr
+	}
+
+	session := jwt.GenerateWebAuthnSessionToken(um.AuthorizationContext.Options.KeyId, userId, clientId, challenge, constants.WebAuthnLoginScope)
+	if session == "" {
+		resultErr := NewUserManagerError(InvalidTokenError, fmt.Errorf("there was an error generating the webauthn login session"))
+		return nil, "", &resultErr
+	}
+	if err := um.addWebAuthnSession(userId, challenge); err != nil {
+		return nil, "", err
+	}
+
+	return options, session, nil
+}
+
+// ValidateWebAuthnAssertion verifies the assertion against the stored credential and returns
+// the user that owns it, if the user id is set the credential must belong to that user. The
+// challenge is used up once the assertion is valid so the same assertion cannot be replayed
+func (um *UserManager) ValidateWebAuthnAssertion(challenge string, userId string, response webauthn.AssertionResponse) (*models.User, *UserManagerError) {
+	credentialId := base64.RawURLEncoding.EncodeToString(response.RawID)
+	stored := um.UserContext.GetWebAuthnCredential(credentialId)
+	if stored == nil {
+		err := NewUserManagerError(WebAuthnCredentialNotFoundError, fmt.Errorf("webauthn credential %v was not found", credentialId))
+		return nil, &err
+	}
+
+	if userId != "" && !strings.EqualFold(stored.UserID, userId) {
+		err := NewUserManagerError(InvalidWebAuthnResponseError, fmt.Errorf("webauthn credential %v does not belong to user %v", credentialId, userId))
+		return nil, &err
+	}
+
+	if len(response.Response.UserHandle) > 0 && !strings.EqualFold(string(response.Response.UserHandle), stored.UserID) {
+		err := NewUserManagerError(InvalidWebAuthnResponseError, fmt.Errorf("webauthn user handle does not match the owner of credential %v", credentialId))
+		return nil, &err
+	}
+
+	publicKey, err := webauthn.DecodeBase64URL(stored.PublicKey)
+	if err != nil {
+		resultErr := NewUserManagerError(InvalidWebAuthnResponseError, fmt.Errorf("webauthn credential %v has an invalid public key", credentialId))
+		return nil, &resultErr
+	}
+
+	relyingParty := um.getRelyingParty()
+	signCount, err := relyingParty.VerifyAssertion(response, challenge, webauthn.Credential{
+		ID:        response.RawID,
+		PublicKey: publicKey,
+		SignCount: uint32(stored.SignCount),
+	})
+	if err != nil {
+		resultErr := NewUserManagerError(InvalidWebAuthnResponseError, fmt.Errorf("webauthn assertion for credential %v is not valid, %v", credentialId, err.Error()))
+		return nil, &resultErr
+	}
+
+	if !um.useWebAuthnSession(challenge) {
+		resultErr := NewUserManagerError(InvalidTokenError, fmt.Errorf("webauthn login session was already used"))
+		return nil, &resultErr
+	}
+
+	// A concurrent login with the same counter means the assertion was replayed
+	if !um.UserContext.UpdateWebAuthnCredentialSignCount(credentialId, int64(signCount), time.Now().UTC()) {
+		resultErr := NewUserManagerError(InvalidWebAuthnResponseError, fmt.Errorf("webauthn signature counter of credential %v was already used", credentialId))
+		return nil, &resultErr
+	}
+
+	user := um.GetUserById(stored.UserID)
+	if user == nil || user.ID == "" {
+		resultErr := NewUserManagerError(InvalidModelError, fmt.Errorf("user %v was not found", stored.UserID))
+		return nil, &resultErr
+	}
+
+	return user, nil
+}
+
+// GetWebAuthnCredentials returns the credentials registered by the user
+func (um *UserManager) GetWebAuthnCredentials(userId string) []models.WebAuthnCredential {
+	result := make([]models.WebAuthnCredential, 0)
+	for _, credential := range um.UserContext.GetUserWebAuthnCredentials(userId) {
+		result = append(result, mappers.ToWebAuthnCredential(credential))
+	}
+
+	return result
+}
+
+// RemoveWebAuthnCredential removes a credential of the user
+func (um *UserManager) RemoveWebAuthnCredential(userId string, credentialId string) *UserManagerError {
+	if err := um.UserContext.RemoveWebAuthnCredential(userId, credentialId); err != nil {
+		resultErr := NewUserManagerError(WebAuthnCredentialNotFoundError, fmt.Errorf("webauthn credential %v was not found for user %v", credentialId, userId), err)
+		return &resultErr
+	}
+
+	return nil
+}
+
+// addWebAuthnSession stores the challenge of a ceremony so it can only be completed once, the
+// session expires with the session token
+func (um *UserManager) addWebAuthnSession(userId string, challenge string) *UserManagerError {
+	err := um.UserContext.AddWebAuthnSession(dto.WebAuthnSessionDTO{
+		ID:        security.SHA256Encode(challenge),
+		UserID:    userId,
+		ExpiresAt: time.Now().Add(time.Minute * time.Duration(um.AuthorizationContext.Options.MfaTokenDuration)),
+	})
+	if err != nil {
+		resultErr := NewUserManagerError(DatabaseError, err)
+		return &resultErr
+	}
+
+	return nil
+}
+
+// useWebAuthnSession removes the pending ceremony of the challenge once it was verified, it
+// returns false if the ceremony was already completed or has expired
+func (um *UserManager) useWebAuthnSession(challenge string) bool {
+	return challenge != "" && um.UserContext.RemoveWebAuthnSession(security.SHA256Encode(challenge))
+}
+
+// getRelyingParty builds the relying party from the options, by default the credentials are
+// scoped to the issuer host
+func (um *UserManager) getRelyingParty() webauthn.RelyingParty {
+	options := um.AuthorizationContext.Options
+	relyingParty := webauthn.RelyingParty{
+		ID:                      options.WebAuthnRelyingPartyId,
+		Name:                    options.WebAuthnRelyingPartyName,
+		Origins:                 options.WebAuthnOrigins,
+		RequireUserVerification: true,
+	}
+
+	issuerUrl, err := url.Parse(um.AuthorizationContext.Issuer)
+	if relyingParty.ID == "" && err == nil {
+		relyingParty.ID = issuerUrl.Hostname()
+	}
+	if len(relyingParty.Origins) == 0 && err == nil && issuerUrl.Host != "" {
+		relyingParty.Origins = []string{issuerUrl.Scheme + "://" + issuerUrl.Host}
+	}
+	if relyingParty.Name == "" {
+		relyingParty.Name = relyingParty.ID
+	}
+
+	return relyingParty
+}
+
+// getWebAuthnTimeout returns the ceremony timeout in milliseconds, it matches the session
+func (um *UserManager) getWebAuthnTimeout() int {
+	return um.AuthorizationContext.Options.MfaTokenDuration * 60 * 1000
+}
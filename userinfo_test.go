@@ -0,0 +1,92 @@
+package identity
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestUserInfo(t *testing.T) {
+	status, body := requestToken(t, url.Values{
+		"grant_type": {"password"},
+		"client_id":  {"spa"},
+		"username":   {testAdminUsername},
+		"password":   {testAdminPassword},
+		"scope":      {"openid profile"},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("expected a login, got %v %v", status, body)
+	}
+	accessToken := body["access_token"].(string)
+
+	var userInfo map[string]interface{}
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		userInfo = nil
+		if status := sendJsonRequest(t, method, "userinfo", accessToken, nil, &userInfo); status != http.StatusOK {
+			t.Fatalf("expected status %v on %v, got %v", http.StatusOK, method, status)
+		}
+		if userInfo["sub"] != testAdminUserId || userInfo["given_name"] == nil {
+			t.Errorf("expected the subject and the profile claims, got %v", userInfo)
+		}
+		if userInfo["email"] != nil || userInfo["roles"] != nil {
+			t.Errorf("expected only the claims of the granted scopes, got %v", userInfo)
+		}
+	}
+
+	// the scopes granted at login survive the refresh
+	status, body = requestToken(t, url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {"spa"},
+		"refresh_token": {body["refresh_token"].(string)},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("expected a refresh, got %v %v", status, body)
+	}
+	userInfo = nil
+	if status := sendJsonRequest(t, http.MethodGet, "userinfo", body["access_token"].(string), nil, &userInfo); status != http.StatusOK || userInfo["given_name"] == nil {
+		t.Fatalf("expected the profile claims after the refresh, got %v %v", status, userInfo)
+	}
+
+	// signed response
+	request, _ := http.NewRequest(http.MethodGet, getTestServer(t).URL+"/auth/userinfo", nil)
+	request.Header.Set("Authorization", "Bearer "+accessToken)
+	request.Header.Set("Accept", "application/jwt")
+	response, err := newTestClient().Do(request)
+	if err != nil {
+		t.Fatalf("request failed, %v", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK || response.Header.Get("Content-Type") != "application/jwt" {
+		t.Fatalf("expected a jwt response, got %v %v", response.StatusCode, response.Header.Get("Content-Type"))
+	}
+	content, _ := io.ReadAll(response.Body)
+	claims := decodeTestToken(t, string(content))
+	if claims["sub"] != testAdminUserId || claims["given_name"] == nil {
+		t.Errorf("expected the signed user claims, got %v", claims)
+	}
+	if aud := toStrings(claims["aud"]); len(aud) != 1 || aud[0] != "spa" {
+		t.Errorf("expected the client as audience, got %v", claims["aud"])
+	}
+
+	if status := sendJsonRequest(t, http.MethodGet, "userinfo", "", nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("expected status %v without a token, got %v", http.StatusUnauthorized, status)
+	}
+}
+
+func TestUserInfoRequiresOpenIdScope(t *testing.T) {
+	status, body := requestToken(t, url.Values{
+		"grant_type": {"password"},
+		"client_id":  {"spa"},
+		"username":   {testAdminUsername},
+		"password":   {testAdminPassword},
+		"scope":      {"email"},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("expected a login, got %v %v", status, body)
+	}
+
+	if status := sendJsonRequest(t, http.MethodGet, "userinfo", body["access_token"].(string), nil, nil); status != http.StatusForbidden {
+		t.Errorf("expected status %v without the openid scope, got %v", http.StatusForbidden, status)
+	}
+}
@@ -0,0 +1,159 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+)
+
+// Attestation formats and the type of attestation they provide
+const (
+	AttestationFormatNone   = "none"
+	AttestationFormatPacked = "packed"
+
+	AttestationTypeNone  = "none"
+	AttestationTypeSelf  = "self"
+	AttestationTypeBasic = "basic"
+)
+
+var (
+	ErrUnsupportedAttestationFormat = errors.New("unsupported attestation format")
+	ErrInvalidAttestation           = errors.New("invalid attestation statement")
+)
+
+// id-fido-gen-ce-aaguid, the aaguid of the authenticator model in the attestation certificate
+var oidFidoAaguid = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 45724, 1, 1, 4}
+
+type attestationObject struct {
+	Format               string
+	AttestationStatement map[interface{}]interface{}
+	AuthData             []byte
+}
+
+func parseAttestationObject(data []byte) (*attestationObject, error) {
+	decoded, length, err := decodeCBOR(data)
+	if err != nil {
+		return nil, err
+	}
+	if length != len(data) {
+		return nil, ErrInvalidAttestation
+	}
+
+	values, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, ErrInvalidAttestation
+	}
+
+	result := attestationObject{}
+	result.Format, _ = values["fmt"].(string)
+	result.AttestationStatement, _ = values["attStmt"].(map[interface{}]interface{})
+	result.AuthData, _ = values["authData"].([]byte)
+	if result.Format == "" || result.AttestationStatement == nil || len(result.AuthData) == 0 {
+		return nil, ErrInvalidAttestation
+	}
+
+	return &result, nil
+}
+
+// verifyAttestation checks the attestation statement and returns the attestation type
+func verifyAttestation(attestation attestationObject, authData AuthenticatorData, credentialKey PublicKey, clientDataHash []byte) (string, error) {
+	switch attestation.Format {
+	case AttestationFormatNone:
+		if len(attestation.AttestationStatement) != 0 {
+			return "", ErrInvalidAttestation
+		}
+		return AttestationTypeNone, nil
+	case AttestationFormatPacked:
+		return verifyPackedAttestation(attestation, authData, credentialKey, clientDataHash)
+	}
+
+	return "", fmt.Errorf("%w %v", ErrUnsupportedAttestationFormat, attestation.Format)
+}
+
+// verifyPackedAttestation implements https://www.w3.org/TR/webauthn-2/#sctn-packed-attestation
+func verifyPackedAttestation(attestation attestationObject, authData AuthenticatorData, credentialKey PublicKey, clientDataHash []byte) (string, error) {
+	statement := attestation.AttestationStatement
+	algorithm, ok := statement["alg"].(int64)
+	if !ok {
+		return "", ErrInvalidAttestation
+	}
+	signature, ok := statement["sig"].([]byte)
+	if !ok {
+		return "", ErrInvalidAttestation
+	}
+	if _, ok := statement["ecdaaKeyId"]; ok {
+		return "", fmt.Errorf("%w, ecdaa is not supported", ErrUnsupportedAttestationFormat)
+	}
+
+	signedData := append(append([]byte{}, attestation.AuthData...), clientDataHash...)
+
+	chain, hasChain := statement["x5c"].([]interface{})
+	if !hasChain {
+		// self attestation is signed with the credential key itself
+		if algorithm != credentialKey.Algorithm {
+			return "", ErrInvalidAttestation
+		}
+		if err := credentialKey.Verify(signedData, signature); err != nil {
+			return "", err
+		}
+		return AttestationTypeSelf, nil
+	}
+
+	if len(chain) == 0 {
+		return "", ErrInvalidAttestation
+	}
+	rawCertificate, ok := chain[0].([]byte)
+	if !ok {
+		return "", ErrInvalidAttestation
+	}
+	certificate, err := x509.ParseCertificate(rawCertificate)
+	if err != nil {
+		return "", fmt.Errorf("%w, %v", ErrInvalidAttestation, err.Error())
+	}
+
+	if err := verifySignature(algorithm, certificate.PublicKey, signedData, signature); err != nil {
+		return "", err
+	}
+
+	if err := verifyPackedCertificate(certificate, authData); err != nil {
+		return "", err
+	}
+
+	return AttestationTypeBasic, nil
+}
+
+// verifyPackedCertificate checks the requirements of the packed attestation certificates
+func verifyPackedCertificate(certificate *x509.Certificate, authData AuthenticatorData) error {
+	if certificate.Version != 3 {
+		return fmt.Errorf("%w, the certificate version must be 3", ErrInvalidAttestation)
+	}
+
+	subject := certificate.Subject
+	if len(subject.Country) == 0 || len(subject.Organization) == 0 || subject.CommonName == "" {
+		return fmt.Errorf("%w, the certificate subject is incomplete", ErrInvalidAttestation)
+	}
+	if len(subject.OrganizationalUnit) != 1 || subject.OrganizationalUnit[0] != "Authenticator Attestation" {
+		return fmt.Errorf("%w, the certificate organizational unit is not valid", ErrInvalidAttestation)
+	}
+
+	if certificate.BasicConstraintsValid && certificate.IsCA {
+		return fmt.Errorf("%w, the certificate cannot be a CA", ErrInvalidAttestation)
+	}
+
+	for _, extension := range certificate.Extensions {
+		if !extension.Id.Equal(oidFidoAaguid) {
+			continue
+		}
+		if extension.Critical {
+			return fmt.Errorf("%w, the aaguid extension cannot be critical", ErrInvalidAttestation)
+		}
+		var aaguid []byte
+		if _, err := asn1.Unmarshal(extension.Value, &aaguid); err != nil || !bytes.Equal(aaguid, authData.AAGUID) {
+			return fmt.Errorf("%w, the certificate aaguid does not match the authenticator", ErrInvalidAttestation)
+		}
+	}
+
+	return nil
+}
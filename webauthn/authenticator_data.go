@@ -0,0 +1,90 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Authenticator data flags
+const (
+	FlagUserPresent            byte = 0x01
+	FlagUserVerified           byte = 0x04
+	FlagBackupEligible         byte = 0x08
+	FlagBackupState            byte = 0x10
+	FlagAttestedCredentialData byte = 0x40
+	FlagExtensionData          byte = 0x80
+)
+
+const (
+	rpIdHashLength     = 32
+	aaguidLength       = 16
+	minAuthDataLength  = rpIdHashLength + 1 + 4
+	maxCredentialIdLen = 1023
+)
+
+var ErrInvalidAuthenticatorData = errors.New("invalid authenticator data")
+
+// AuthenticatorData is the data the authenticator signs in both ceremonies
+type AuthenticatorData struct {
+	RPIDHash            []byte
+	Flags               byte
+	SignCount           uint32
+	AAGUID              []byte
+	CredentialID        []byte
+	CredentialPublicKey []byte
+}
+
+// HasFlag returns true if the authenticator set the flag
+func (a AuthenticatorData) HasFlag(flag byte) bool {
+	return a.Flags&flag == flag
+}
+
+// ParseAuthenticatorData parses the raw authenticator data, the attested credential data is
+// only present during the registration
+func ParseAuthenticatorData(data []byte) (*AuthenticatorData, error) {
+	if len(data) < minAuthDataLength {
+		return nil, ErrInvalidAuthenticatorData
+	}
+
+	result := AuthenticatorData{
+		RPIDHash:  data[:rpIdHashLength],
+		Flags:     data[rpIdHashLength],
+		SignCount: binary.BigEndian.Uint32(data[rpIdHashLength+1 : minAuthDataLength]),
+	}
+	remaining := data[minAuthDataLength:]
+
+	if result.HasFlag(FlagAttestedCredentialData) {
+		if len(remaining) < aaguidLength+2 {
+			return nil, ErrInvalidAuthenticatorData
+		}
+		result.AAGUID = remaining[:aaguidLength]
+		credentialIdLength := int(binary.BigEndian.Uint16(remaining[aaguidLength : aaguidLength+2]))
+		remaining = remaining[aaguidLength+2:]
+		if credentialIdLength == 0 || credentialIdLength > maxCredentialIdLen || len(remaining) < credentialIdLength {
+			return nil, ErrInvalidAuthenticatorData
+		}
+		result.CredentialID = remaining[:credentialIdLength]
+		remaining = remaining[credentialIdLength:]
+
+		_, keyLength, err := decodeCBOR(remaining)
+		if err != nil {
+			return nil, ErrInvalidAuthenticatorData
+		}
+		result.CredentialPublicKey = remaining[:keyLength]
+		remaining = remaining[keyLength:]
+	}
+
+	if result.HasFlag(FlagExtensionData) {
+		_, extensionsLength, err := decodeCBOR(remaining)
+		if err != nil {
+			return nil, ErrInvalidAuthenticatorData
+		}
+		remaining = remaining[extensionsLength:]
+	}
+
+	if len(remaining) > 0 {
+		return nil, ErrInvalidAuthenticatorData
+	}
+
+	return &result, nil
+}
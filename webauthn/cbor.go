@@ -0,0 +1,224 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// The authenticators encode their data with the CTAP2 canonical CBOR encoding, this decoder
+// only supports the definite length items that encoding allows
+
+const cborMaxDepth = 16
+
+var ErrInvalidCBOR = errors.New("invalid cbor data")
+
+// decodeCBOR decodes the first item of the data, it returns the item and the number of bytes
+// it used so the caller can continue with the data that follows it
+// Integers are decoded as int64, byte strings as []byte, text strings as string, arrays as
+// []interface{} and maps as map[interface{}]interface{}
+func decodeCBOR(data []byte) (interface{}, int, error) {
+	decoder := cborDecoder{data: data}
+	value, err := decoder.decode(0)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return value, decoder.offset, nil
+}
+
+type cborDecoder struct {
+	data   []byte
+	offset int
+}
+
+func (d *cborDecoder) decode(depth int) (interface{}, error) {
+	if depth > cborMaxDepth {
+		return nil, ErrInvalidCBOR
+	}
+
+	if d.offset >= len(d.data) {
+		return nil, ErrInvalidCBOR
+	}
+
+	initial := d.data[d.offset]
+	d.offset++
+	majorType := initial >> 5
+	info := initial & 0x1f
+
+	if majorType == 7 {
+		return d.decodeSimple(info)
+	}
+
+	argument, err := d.readArgument(info)
+	if err != nil {
+		return nil, err
+	}
+
+	switch majorType {
+	case 0:
+		if argument > math.MaxInt64 {
+			return nil, ErrInvalidCBOR
+		}
+		return int64(argument), nil
+	case 1:
+		if argument > math.MaxInt64 {
+			return nil, ErrInvalidCBOR
+		}
+		return -1 - int64(argument), nil
+	case 2:
+		value, err := d.readBytes(argument)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{}, value...), nil
+	case 3:
+		value, err := d.readBytes(argument)
+		if err != nil {
+			return nil, err
+		}
+		return string(value), nil
+	case 4:
+		// every item needs at least one byte, this stops bogus lengths before allocating
+		if argument > uint64(len(d.data)-d.offset) {
+			return nil, ErrInvalidCBOR
+		}
+		items := make([]interface{}, 0, argument)
+		for i := uint64(0); i < argument; i++ {
+			item, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case 5:
+		if argument > uint64(len(d.data)-d.offset) {
+			return nil, ErrInvalidCBOR
+		}
+		items := make(map[interface{}]interface{}, argument)
+		for i := uint64(0); i < argument; i++ {
+			key, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, ErrInvalidCBOR
+			}
+			if _, exists := items[key]; exists {
+				return nil, ErrInvalidCBOR
+			}
+			value, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			items[key] = value
+		}
+		return items, nil
+	case 6:
+		// tags are not used by webauthn, the tagged item is returned as is
+		return d.decode(depth + 1)
+	}
+
+	return nil, ErrInvalidCBOR
+}
+
+func (d *cborDecoder) decodeSimple(info byte) (interface{}, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		return nil, nil
+	case 25:
+		value, err := d.readBytes(2)
+		if err != nil {
+			return nil, err
+		}
+		return float64(halfToFloat(binary.BigEndian.Uint16(value))), nil
+	case 26:
+		value, err := d.readBytes(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(value))), nil
+	case 27:
+		value, err := d.readBytes(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(value)), nil
+	}
+
+	return nil, ErrInvalidCBOR
+}
+
+func (d *cborDecoder) readArgument(info byte) (uint64, error) {
+	switch {
+	case info < 24:
+		return uint64(info), nil
+	case info == 24:
+		value, err := d.readBytes(1)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(value[0]), nil
+	case info == 25:
+		value, err := d.readBytes(2)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(binary.BigEndian.Uint16(value)), nil
+	case info == 26:
+		value, err := d.readBytes(4)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(binary.BigEndian.Uint32(value)), nil
+	case info == 27:
+		value, err := d.readBytes(8)
+		if err != nil {
+			return 0, err
+		}
+		return binary.BigEndian.Uint64(value), nil
+	}
+
+	// indefinite lengths are not allowed in the canonical encoding
+	return 0, ErrInvalidCBOR
+}
+
+func (d *cborDecoder) readBytes(length uint64) ([]byte, error) {
+	if length > uint64(len(d.data)-d.offset) {
+		return nil, ErrInvalidCBOR
+	}
+
+	value := d.data[d.offset : d.offset+int(length)]
+	d.offset += int(length)
+	return value, nil
+}
+
+func halfToFloat(half uint16) float32 {
+	sign := uint32(half>>15) << 31
+	exponent := uint32(half>>10) & 0x1f
+	mantissa := uint32(half & 0x3ff)
+
+	switch exponent {
+	case 0:
+		return math.Float32frombits(sign) + float32(mantissa)*float32(math.Pow(2, -24))*signFactor(sign)
+	case 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mantissa<<13)
+	}
+
+	return math.Float32frombits(sign | (exponent+112)<<23 | mantissa<<13)
+}
+
+func signFactor(sign uint32) float32 {
+	if sign != 0 {
+		return -1
+	}
+
+	return 1
+}
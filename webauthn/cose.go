@@ -0,0 +1,213 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// COSE algorithm identifiers, https://www.iana.org/assignments/cose/cose.xhtml#algorithms
+const (
+	AlgES256 int64 = -7
+	AlgEdDSA int64 = -8
+	AlgES384 int64 = -35
+	AlgES512 int64 = -36
+	AlgPS256 int64 = -37
+	AlgRS256 int64 = -257
+)
+
+// SupportedAlgorithms are the algorithms offered to the authenticators in order of preference
+var SupportedAlgorithms = []int64{AlgES256, AlgEdDSA, AlgRS256, AlgPS256, AlgES384, AlgES512}
+
+const (
+	coseKeyTypeOKP int64 = 1
+	coseKeyTypeEC2 int64 = 2
+	coseKeyTypeRSA int64 = 3
+
+	coseCurveP256    int64 = 1
+	coseCurveP384    int64 = 2
+	coseCurveP521    int64 = 3
+	coseCurveEd25519 int64 = 6
+
+	coseLabelKeyType   int64 = 1
+	coseLabelAlgorithm int64 = 3
+)
+
+var (
+	ErrUnsupportedAlgorithm = errors.New("unsupported algorithm")
+	ErrInvalidPublicKey     = errors.New("invalid public key")
+	ErrInvalidSignature     = errors.New("invalid signature")
+)
+
+// PublicKey is a credential public key parsed from its COSE encoding
+type PublicKey struct {
+	Algorithm int64
+	Key       crypto.PublicKey
+}
+
+// ParsePublicKey parses a COSE_Key as stored in the attested credential data
+func ParsePublicKey(coseKey []byte) (*PublicKey, error) {
+	decoded, length, err := decodeCBOR(coseKey)
+	if err != nil {
+		return nil, err
+	}
+	if length != len(coseKey) {
+		return nil, ErrInvalidPublicKey
+	}
+
+	values, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, ErrInvalidPublicKey
+	}
+
+	keyType, _ := values[coseLabelKeyType].(int64)
+	algorithm, ok := values[coseLabelAlgorithm].(int64)
+	if !ok {
+		return nil, ErrInvalidPublicKey
+	}
+
+	switch keyType {
+	case coseKeyTypeEC2:
+		curveId, _ := values[int64(-1)].(int64)
+		x, _ := values[int64(-2)].([]byte)
+		y, _ := values[int64(-3)].([]byte)
+		key, err := newEcdsaPublicKey(algorithm, curveId, x, y)
+		if err != nil {
+			return nil, err
+		}
+		return &PublicKey{Algorithm: algorithm, Key: key}, nil
+	case coseKeyTypeRSA:
+		if algorithm != AlgRS256 && algorithm != AlgPS256 {
+			return nil, ErrUnsupportedAlgorithm
+		}
+		n, _ := values[int64(-1)].([]byte)
+		e, _ := values[int64(-2)].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, ErrInvalidPublicKey
+		}
+		exponent := 0
+		for _, b := range e {
+			exponent = exponent<<8 | int(b)
+		}
+		return &PublicKey{Algorithm: algorithm, Key: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exponent}}, nil
+	case coseKeyTypeOKP:
+		curveId, _ := values[int64(-1)].(int64)
+		x, _ := values[int64(-2)].([]byte)
+		if algorithm != AlgEdDSA || curveId != coseCurveEd25519 {
+			return nil, ErrUnsupportedAlgorithm
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, ErrInvalidPublicKey
+		}
+		return &PublicKey{Algorithm: algorithm, Key: ed25519.PublicKey(x)}, nil
+	}
+
+	return nil, ErrUnsupportedAlgorithm
+}
+
+// Verify checks the signature of the data with the public key
+func (k PublicKey) Verify(data []byte, signature []byte) error {
+	return verifySignature(k.Algorithm, k.Key, data, signature)
+}
+
+func verifySignature(algorithm int64, key crypto.PublicKey, data []byte, signature []byte) error {
+	switch algorithm {
+	case AlgES256, AlgES384, AlgES512:
+		ecdsaKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return ErrInvalidPublicKey
+		}
+		if !ecdsa.VerifyASN1(ecdsaKey, hashData(algorithm, data), signature) {
+			return ErrInvalidSignature
+		}
+		return nil
+	case AlgRS256:
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return ErrInvalidPublicKey
+		}
+		if rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, hashData(algorithm, data), signature) != nil {
+			return ErrInvalidSignature
+		}
+		return nil
+	case AlgPS256:
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return ErrInvalidPublicKey
+		}
+		if rsa.VerifyPSS(rsaKey, crypto.SHA256, hashData(algorithm, data), signature, nil) != nil {
+			return ErrInvalidSignature
+		}
+		return nil
+	case AlgEdDSA:
+		edKey, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return ErrInvalidPublicKey
+		}
+		if !ed25519.Verify(edKey, data, signature) {
+			return ErrInvalidSignature
+		}
+		return nil
+	}
+
+	return ErrUnsupportedAlgorithm
+}
+
+func hashData(algorithm int64, data []byte) []byte {
+	switch algorithm {
+	case AlgES384:
+		hash := sha512.Sum384(data)
+		return hash[:]
+	case AlgES512:
+		hash := sha512.Sum512(data)
+		return hash[:]
+	}
+
+	hash := sha256.Sum256(data)
+	return hash[:]
+}
+
+func newEcdsaPublicKey(algorithm int64, curveId int64, x []byte, y []byte) (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	var ecdhCurve ecdh.Curve
+	var expectedAlgorithm int64
+	switch curveId {
+	case coseCurveP256:
+		curve, ecdhCurve, expectedAlgorithm = elliptic.P256(), ecdh.P256(), AlgES256
+	case coseCurveP384:
+		curve, ecdhCurve, expectedAlgorithm = elliptic.P384(), ecdh.P384(), AlgES384
+	case coseCurveP521:
+		curve, ecdhCurve, expectedAlgorithm = elliptic.P521(), ecdh.P521(), AlgES512
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+
+	if algorithm != expectedAlgorithm {
+		return nil, fmt.Errorf("%w, algorithm %v does not match the curve", ErrUnsupportedAlgorithm, algorithm)
+	}
+
+	size := (curve.Params().BitSize + 7) / 8
+	if len(x) != size || len(y) != size {
+		return nil, ErrInvalidPublicKey
+	}
+
+	// the ecdh parser rejects points that are not on the curve
+	point := append(append([]byte{0x04}, x...), y...)
+	if _, err := ecdhCurve.NewPublicKey(point); err != nil {
+		return nil, ErrInvalidPublicKey
+	}
+
+	return &ecdsa.PublicKey{
+		Curve: curve,
+		X:     new(big.Int).SetBytes(x),
+		Y:     new(big.Int).SetBytes(y),
+	}, nil
+}
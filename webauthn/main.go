@@ -0,0 +1,403 @@
+// Package webauthn implements the relying party side of the WebAuthn registration and
+// authentication ceremonies, https://www.w3.org/TR/webauthn-2/
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	challengeSize = 32
+
+	ceremonyCreate = "webauthn.create"
+	ceremonyGet    = "webauthn.get"
+
+	publicKeyCredentialType = "public-key"
+
+	UserVerificationRequired  = "required"
+	UserVerificationPreferred = "preferred"
+	UserVerificationNone      = "discouraged"
+)
+
+var (
+	ErrInvalidClientData    = errors.New("invalid client data")
+	ErrChallengeMismatch    = errors.New("the challenge does not match")
+	ErrOriginNotAllowed     = errors.New("the origin is not allowed")
+	ErrRelyingPartyMismatch = errors.New("the relying party id does not match")
+	ErrUserNotPresent       = errors.New("the user was not present")
+	ErrUserNotVerified      = errors.New("the user was not verified")
+	ErrCredentialMismatch   = errors.New("the credential does not match")
+	ErrSignCountMismatch    = errors.New("the signature counter did not increase, the authenticator may have been cloned")
+)
+
+// Base64URL is a byte array encoded as base64url in json like the browsers serialize the
+// credentials, the decoding also accepts padding and the standard alphabet
+type Base64URL []byte
+
+func (b Base64URL) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+func (b *Base64URL) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	decoded, err := DecodeBase64URL(value)
+	if err != nil {
+		return err
+	}
+
+	*b = decoded
+	return nil
+}
+
+func (b Base64URL) String() string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeBase64URL decodes base64url values with or without padding
+func DecodeBase64URL(value string) ([]byte, error) {
+	value = strings.TrimRight(value, "=")
+	value = strings.NewReplacer("+", "-", "/", "_").Replace(value)
+	return base64.RawURLEncoding.DecodeString(value)
+}
+
+// RelyingParty is the identity of the server the credentials are scoped to
+type RelyingParty struct {
+	ID      string
+	Name    string
+	Origins []string
+	// RequireUserVerification requires the authenticator to verify the user with a pin or
+	// biometrics, this is what makes a credential enough to sign in without a password
+	RequireUserVerification bool
+}
+
+// User is the account a credential is created for
+type User struct {
+	ID          []byte
+	Name        string
+	DisplayName string
+}
+
+type RelyingPartyEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserEntity struct {
+	ID          Base64URL `json:"id"`
+	Name        string    `json:"name"`
+	DisplayName string    `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type      string `json:"type"`
+	Algorithm int64  `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type       string    `json:"type"`
+	ID         Base64URL `json:"id"`
+	Transports []string  `json:"transports,omitempty"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey,omitempty"`
+	UserVerification string `json:"userVerification,omitempty"`
+}
+
+// CredentialCreationOptions are the options passed to navigator.credentials.create
+type CredentialCreationOptions struct {
+	RelyingParty           RelyingPartyEntity     `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	Challenge              Base64URL              `json:"challenge"`
+	Parameters             []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int                    `json:"timeout,omitempty"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials,omitempty"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation,omitempty"`
+}
+
+// CredentialRequestOptions are the options passed to navigator.credentials.get
+type CredentialRequestOptions struct {
+	Challenge        Base64URL              `json:"challenge"`
+	Timeout          int                    `json:"timeout,omitempty"`
+	RelyingPartyID   string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials,omitempty"`
+	UserVerification string                 `json:"userVerification,omitempty"`
+}
+
+type AuthenticatorAttestationResponse struct {
+	ClientDataJSON    Base64URL `json:"clientDataJSON"`
+	AttestationObject Base64URL `json:"attestationObject"`
+	Transports        []string  `json:"transports,omitempty"`
+}
+
+// RegistrationResponse is the credential returned by navigator.credentials.create
+type RegistrationResponse struct {
+	ID       string                           `json:"id"`
+	RawID    Base64URL                        `json:"rawId"`
+	Type     string                           `json:"type"`
+	Response AuthenticatorAttestationResponse `json:"response"`
+}
+
+type AuthenticatorAssertionResponse struct {
+	ClientDataJSON    Base64URL `json:"clientDataJSON"`
+	AuthenticatorData Base64URL `json:"authenticatorData"`
+	Signature         Base64URL `json:"signature"`
+	UserHandle        Base64URL `json:"userHandle,omitempty"`
+}
+
+// AssertionResponse is the credential returned by navigator.credentials.get
+type AssertionResponse struct {
+	ID       string                         `json:"id"`
+	RawID    Base64URL                      `json:"rawId"`
+	Type     string                         `json:"type"`
+	Response AuthenticatorAssertionResponse `json:"response"`
+}
+
+// Credential is a verified credential ready to be stored with its owner
+type Credential struct {
+	ID              []byte
+	PublicKey       []byte
+	AttestationType string
+	AAGUID          []byte
+	SignCount       uint32
+	BackupEligible  bool
+	Transports      []string
+}
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+// NewChallenge returns a new random challenge encoded as base64url
+func NewChallenge() (string, error) {
+	challenge := make([]byte, challengeSize)
+	if _, err := rand.Read(challenge); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(challenge), nil
+}
+
+// NewCreationOptions returns the options to register a new credential for the user, the
+// existing credentials are excluded so the same authenticator is not registered twice
+func (rp RelyingParty) NewCreationOptions(user User, challenge string, timeout int, exclude [][]byte) (*CredentialCreationOptions, error) {
+	decodedChallenge, err := DecodeBase64URL(challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	options := CredentialCreationOptions{
+		RelyingParty: RelyingPartyEntity{ID: rp.ID, Name: rp.Name},
+		User: UserEntity{
+			ID:          user.ID,
+			Name:        user.Name,
+			DisplayName: user.DisplayName,
+		},
+		Challenge:  decodedChallenge,
+		Parameters: make([]CredentialParameter, 0),
+		Timeout:    timeout,
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: rp.userVerification(),
+		},
+		Attestation: "none",
+	}
+
+	for _, algorithm := range SupportedAlgorithms {
+		options.Parameters = append(options.Parameters, CredentialParameter{Type: publicKeyCredentialType, Algorithm: algorithm})
+	}
+
+	for _, id := range exclude {
+		options.ExcludeCredentials = append(options.ExcludeCredentials, CredentialDescriptor{Type: publicKeyCredentialType, ID: id})
+	}
+
+	return &options, nil
+}
+
+// NewRequestOptions returns the options to authenticate with one of the allowed credentials,
+// without allowed credentials the authenticator can use any discoverable credential
+func (rp RelyingParty) NewRequestOptions(challenge string, timeout int, allow [][]byte) (*CredentialRequestOptions, error) {
+	decodedChallenge, err := DecodeBase64URL(challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	options := CredentialRequestOptions{
+		Challenge:        decodedChallenge,
+		Timeout:          timeout,
+		RelyingPartyID:   rp.ID,
+		UserVerification: rp.userVerification(),
+	}
+
+	for _, id := range allow {
+		options.AllowCredentials = append(options.AllowCredentials, CredentialDescriptor{Type: publicKeyCredentialType, ID: id})
+	}
+
+	return &options, nil
+}
+
+// VerifyRegistration runs the registration ceremony checks, the challenge is the one sent in
+// the creation options
+func (rp RelyingParty) VerifyRegistration(response RegistrationResponse, challenge string) (*Credential, error) {
+	if response.Type != publicKeyCredentialType {
+		return nil, fmt.Errorf("%w, type %v is not supported", ErrInvalidClientData, response.Type)
+	}
+
+	if err := rp.verifyClientData(response.Response.ClientDataJSON, ceremonyCreate, challenge); err != nil {
+		return nil, err
+	}
+
+	attestation, err := parseAttestationObject(response.Response.AttestationObject)
+	if err != nil {
+		return nil, err
+	}
+
+	authData, err := ParseAuthenticatorData(attestation.AuthData)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rp.verifyAuthenticatorData(*authData); err != nil {
+		return nil, err
+	}
+
+	if !authData.HasFlag(FlagAttestedCredentialData) {
+		return nil, fmt.Errorf("%w, no attested credential data", ErrInvalidAuthenticatorData)
+	}
+
+	if len(response.RawID) > 0 && !bytes.Equal(response.RawID, authData.CredentialID) {
+		return nil, ErrCredentialMismatch
+	}
+
+	publicKey, err := ParsePublicKey(authData.CredentialPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	clientDataHash := sha256.Sum256(response.Response.ClientDataJSON)
+	attestationType, err := verifyAttestation(*attestation, *authData, *publicKey, clientDataHash[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:              append([]byte{}, authData.CredentialID...),
+		PublicKey:       append([]byte{}, authData.CredentialPublicKey...),
+		AttestationType: attestationType,
+		AAGUID:          append([]byte{}, authData.AAGUID...),
+		SignCount:       authData.SignCount,
+		BackupEligible:  authData.HasFlag(FlagBackupEligible),
+		Transports:      response.Response.Transports,
+	}, nil
+}
+
+// VerifyAssertion runs the authentication ceremony checks against the stored credential, it
+// returns the new signature counter that needs to be stored
+func (rp RelyingParty) VerifyAssertion(response AssertionResponse, challenge string, credential Credential) (uint32, error) {
+	if response.Type != publicKeyCredentialType {
+		return 0, fmt.Errorf("%w, type %v is not supported", ErrInvalidClientData, response.Type)
+	}
+
+	if !bytes.Equal(response.RawID, credential.ID) {
+		return 0, ErrCredentialMismatch
+	}
+
+	if err := rp.verifyClientData(response.Response.ClientDataJSON, ceremonyGet, challenge); err != nil {
+		return 0, err
+	}
+
+	authData, err := ParseAuthenticatorData(response.Response.AuthenticatorData)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := rp.verifyAuthenticatorData(*authData); err != nil {
+		return 0, err
+	}
+
+	publicKey, err := ParsePublicKey(credential.PublicKey)
+	if err != nil {
+		return 0, err
+	}
+
+	clientDataHash := sha256.Sum256(response.Response.ClientDataJSON)
+	signedData := append(append([]byte{}, response.Response.AuthenticatorData...), clientDataHash[:]...)
+	if err := publicKey.Verify(signedData, response.Response.Signature); err != nil {
+		return 0, err
+	}
+
+	// authenticators that do not implement the counter always return zero
+	if (authData.SignCount != 0 || credential.SignCount != 0) && authData.SignCount <= credential.SignCount {
+		return 0, ErrSignCountMismatch
+	}
+
+	return authData.SignCount, nil
+}
+
+func (rp RelyingParty) verifyClientData(rawClientData []byte, ceremony string, challenge string) error {
+	var data clientData
+	if err := json.Unmarshal(rawClientData, &data); err != nil {
+		return fmt.Errorf("%w, %v", ErrInvalidClientData, err.Error())
+	}
+
+	if data.Type != ceremony {
+		return fmt.Errorf("%w, expected type %v", ErrInvalidClientData, ceremony)
+	}
+
+	receivedChallenge, err := DecodeBase64URL(data.Challenge)
+	if err != nil {
+		return ErrChallengeMismatch
+	}
+	expectedChallenge, err := DecodeBase64URL(challenge)
+	if err != nil || len(expectedChallenge) == 0 || subtle.ConstantTimeCompare(receivedChallenge, expectedChallenge) != 1 {
+		return ErrChallengeMismatch
+	}
+
+	for _, origin := range rp.Origins {
+		if strings.EqualFold(strings.TrimRight(origin, "/"), data.Origin) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w, %v", ErrOriginNotAllowed, data.Origin)
+}
+
+func (rp RelyingParty) verifyAuthenticatorData(authData AuthenticatorData) error {
+	rpIdHash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(authData.RPIDHash, rpIdHash[:]) {
+		return ErrRelyingPartyMismatch
+	}
+
+	if !authData.HasFlag(FlagUserPresent) {
+		return ErrUserNotPresent
+	}
+
+	if rp.RequireUserVerification && !authData.HasFlag(FlagUserVerified) {
+		return ErrUserNotVerified
+	}
+
+	return nil
+}
+
+func (rp RelyingParty) userVerification() string {
+	if rp.RequireUserVerification {
+		return UserVerificationRequired
+	}
+
+	return UserVerificationPreferred
+}
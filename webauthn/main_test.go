@@ -0,0 +1,421 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"sort"
+	"testing"
+	"time"
+)
+
+const (
+	testRpId   = "localhost"
+	testOrigin = "http://localhost:5000"
+)
+
+var testRelyingParty = RelyingParty{ID: testRpId, Name: "Test", Origins: []string{testOrigin}, RequireUserVerification: true}
+
+// encodeCBOR is a small canonical encoder used to emulate the authenticators
+func encodeCBOR(value interface{}) []byte {
+	header := func(majorType byte, argument uint64) []byte {
+		switch {
+		case argument < 24:
+			return []byte{majorType<<5 | byte(argument)}
+		case argument <= 0xff:
+			return []byte{majorType<<5 | 24, byte(argument)}
+		case argument <= 0xffff:
+			result := []byte{majorType<<5 | 25, 0, 0}
+			binary.BigEndian.PutUint16(result[1:], uint16(argument))
+			return result
+		default:
+			result := []byte{majorType<<5 | 26, 0, 0, 0, 0}
+			binary.BigEndian.PutUint32(result[1:], uint32(argument))
+			return result
+		}
+	}
+
+	switch v := value.(type) {
+	case int:
+		return encodeCBOR(int64(v))
+	case int64:
+		if v < 0 {
+			return header(1, uint64(-1-v))
+		}
+		return header(0, uint64(v))
+	case []byte:
+		return append(header(2, uint64(len(v))), v...)
+	case string:
+		return append(header(3, uint64(len(v))), v...)
+	case bool:
+		if v {
+			return []byte{0xf5}
+		}
+		return []byte{0xf4}
+	case []interface{}:
+		result := header(4, uint64(len(v)))
+		for _, item := range v {
+			result = append(result, encodeCBOR(item)...)
+		}
+		return result
+	case map[interface{}]interface{}:
+		keys := make([][]byte, 0)
+		encoded := make(map[string][]byte)
+		for key, item := range v {
+			encodedKey := encodeCBOR(key)
+			keys = append(keys, encodedKey)
+			encoded[string(encodedKey)] = encodeCBOR(item)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if len(keys[i]) != len(keys[j]) {
+				return len(keys[i]) < len(keys[j])
+			}
+			return string(keys[i]) < string(keys[j])
+		})
+		result := header(5, uint64(len(v)))
+		for _, key := range keys {
+			result = append(result, key...)
+			result = append(result, encoded[string(key)]...)
+		}
+		return result
+	}
+
+	panic("unsupported cbor type")
+}
+
+type softAuthenticator struct {
+	credentialId []byte
+	algorithm    int64
+	signer       crypto.Signer
+	signCount    uint32
+	aaguid       []byte
+	rpId         string
+	origin       string
+}
+
+func newSoftAuthenticator(t *testing.T, algorithm int64) *softAuthenticator {
+	authenticator := softAuthenticator{
+		credentialId: make([]byte, 32),
+		algorithm:    algorithm,
+		aaguid:       make([]byte, 16),
+		rpId:         testRpId,
+		origin:       testOrigin,
+	}
+	rand.Read(authenticator.credentialId)
+	rand.Read(authenticator.aaguid)
+
+	var err error
+	switch algorithm {
+	case AlgES256:
+		authenticator.signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgEdDSA:
+		_, authenticator.signer, err = ed25519.GenerateKey(rand.Reader)
+	}
+	if err != nil {
+		t.Fatalf("failed to generate the authenticator key, %v", err)
+	}
+
+	return &authenticator
+}
+
+func (a *softAuthenticator) coseKey() []byte {
+	switch key := a.signer.Public().(type) {
+	case *ecdsa.PublicKey:
+		return encodeCBOR(map[interface{}]interface{}{
+			1: 2, 3: int(a.algorithm), -1: 1,
+			-2: key.X.FillBytes(make([]byte, 32)),
+			-3: key.Y.FillBytes(make([]byte, 32)),
+		})
+	case ed25519.PublicKey:
+		return encodeCBOR(map[interface{}]interface{}{
+			1: 1, 3: int(a.algorithm), -1: 6, -2: []byte(key),
+		})
+	}
+
+	return nil
+}
+
+func (a *softAuthenticator) authenticatorData(flags byte, attested bool) []byte {
+	rpIdHash := sha256.Sum256([]byte(a.rpId))
+	data := append([]byte{}, rpIdHash[:]...)
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	if attested {
+		data = append(data, a.aaguid...)
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.credentialId)))
+		data = append(data, a.credentialId...)
+		data = append(data, a.coseKey()...)
+	}
+
+	return data
+}
+
+func (a *softAuthenticator) clientData(ceremony string, challenge string) []byte {
+	data, _ := json.Marshal(map[string]interface{}{
+		"type":      ceremony,
+		"challenge": challenge,
+		"origin":    a.origin,
+	})
+	return data
+}
+
+func (a *softAuthenticator) sign(signer crypto.Signer, algorithm int64, data []byte) []byte {
+	var signature []byte
+	if algorithm == AlgEdDSA {
+		signature, _ = signer.Sign(rand.Reader, data, crypto.Hash(0))
+	} else {
+		hash := sha256.Sum256(data)
+		signature, _ = signer.Sign(rand.Reader, hash[:], crypto.SHA256)
+	}
+
+	return signature
+}
+
+func (a *softAuthenticator) register(challenge string, format string, statement map[interface{}]interface{}) RegistrationResponse {
+	clientData := a.clientData(ceremonyCreate, challenge)
+	authData := a.authenticatorData(FlagUserPresent|FlagUserVerified|FlagAttestedCredentialData, true)
+
+	if format == AttestationFormatPacked && statement == nil {
+		clientDataHash := sha256.Sum256(clientData)
+		statement = map[interface{}]interface{}{
+			"alg": int(a.algorithm),
+			"sig": a.sign(a.signer, a.algorithm, append(append([]byte{}, authData...), clientDataHash[:]...)),
+		}
+	}
+	if statement == nil {
+		statement = map[interface{}]interface{}{}
+	}
+
+	return RegistrationResponse{
+		ID:    base64.RawURLEncoding.EncodeToString(a.credentialId),
+		RawID: a.credentialId,
+		Type:  publicKeyCredentialType,
+		Response: AuthenticatorAttestationResponse{
+			ClientDataJSON: clientData,
+			AttestationObject: encodeCBOR(map[interface{}]interface{}{
+				"fmt":      format,
+				"attStmt":  statement,
+				"authData": authData,
+			}),
+		},
+	}
+}
+
+func (a *softAuthenticator) login(challenge string) AssertionResponse {
+	a.signCount++
+	clientData := a.clientData(ceremonyGet, challenge)
+	authData := a.authenticatorData(FlagUserPresent|FlagUserVerified, false)
+	clientDataHash := sha256.Sum256(clientData)
+
+	return AssertionResponse{
+		ID:    base64.RawURLEncoding.EncodeToString(a.credentialId),
+		RawID: a.credentialId,
+		Type:  publicKeyCredentialType,
+		Response: AuthenticatorAssertionResponse{
+			ClientDataJSON:    clientData,
+			AuthenticatorData: authData,
+			Signature:         a.sign(a.signer, a.algorithm, append(append([]byte{}, authData...), clientDataHash[:]...)),
+		},
+	}
+}
+
+func newTestChallenge(t *testing.T) string {
+	challenge, err := NewChallenge()
+	if err != nil {
+		t.Fatalf("failed to generate the challenge, %v", err)
+	}
+
+	return challenge
+}
+
+func TestRegistrationWithNoneAttestation(t *testing.T) {
+	authenticator := newSoftAuthenticator(t, AlgES256)
+	challenge := newTestChallenge(t)
+
+	credential, err := testRelyingParty.VerifyRegistration(authenticator.register(challenge, AttestationFormatNone, nil), challenge)
+	if err != nil {
+		t.Fatalf("expected the registration to be valid, got %v", err)
+	}
+
+	if string(credential.ID) != string(authenticator.credentialId) || credential.AttestationType != AttestationTypeNone {
+		t.Errorf("unexpected credential %+v", credential)
+	}
+
+	if _, err := ParsePublicKey(credential.PublicKey); err != nil {
+		t.Errorf("expected the stored public key to be valid, got %v", err)
+	}
+}
+
+func TestRegistrationWithPackedSelfAttestation(t *testing.T) {
+	authenticator := newSoftAuthenticator(t, AlgEdDSA)
+	challenge := newTestChallenge(t)
+
+	credential, err := testRelyingParty.VerifyRegistration(authenticator.register(challenge, AttestationFormatPacked, nil), challenge)
+	if err != nil {
+		t.Fatalf("expected the registration to be valid, got %v", err)
+	}
+
+	if credential.AttestationType != AttestationTypeSelf {
+		t.Errorf("expected self attestation, got %v", credential.AttestationType)
+	}
+}
+
+func TestRegistrationWithPackedBasicAttestation(t *testing.T) {
+	authenticator := newSoftAuthenticator(t, AlgES256)
+	attestationKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	aaguidExtension, _ := asn1.Marshal(authenticator.aaguid)
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{
+			Country:            []string{"PT"},
+			Organization:       []string{"Test Authenticators"},
+			OrganizationalUnit: []string{"Authenticator Attestation"},
+			CommonName:         "Test Authenticator",
+		},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		ExtraExtensions:       []pkix.Extension{{Id: oidFidoAaguid, Value: aaguidExtension}},
+	}
+	certificate, err := x509.CreateCertificate(rand.Reader, &template, &template, attestationKey.Public(), attestationKey)
+	if err != nil {
+		t.Fatalf("failed to create the attestation certificate, %v", err)
+	}
+
+	challenge := newTestChallenge(t)
+	clientData := authenticator.clientData(ceremonyCreate, challenge)
+	clientDataHash := sha256.Sum256(clientData)
+	authData := authenticator.authenticatorData(FlagUserPresent|FlagAttestedCredentialData, true)
+	statement := map[interface{}]interface{}{
+		"alg": int(AlgES256),
+		"sig": authenticator.sign(attestationKey, AlgES256, append(append([]byte{}, authData...), clientDataHash[:]...)),
+		"x5c": []interface{}{certificate},
+	}
+	response := authenticator.register(challenge, AttestationFormatPacked, statement)
+	response.Response.ClientDataJSON = clientData
+	response.Response.AttestationObject = encodeCBOR(map[interface{}]interface{}{
+		"fmt":      AttestationFormatPacked,
+		"attStmt":  statement,
+		"authData": authData,
+	})
+
+	withoutUserVerification := RelyingParty{ID: testRpId, Origins: []string{testOrigin}}
+	credential, err := withoutUserVerification.VerifyRegistration(response, challenge)
+	if err != nil {
+		t.Fatalf("expected the registration to be valid, got %v", err)
+	}
+	if credential.AttestationType != AttestationTypeBasic {
+		t.Errorf("expected basic attestation, got %v", credential.AttestationType)
+	}
+
+	// the authenticator did not verify the user
+	if _, err := testRelyingParty.VerifyRegistration(response, challenge); !errors.Is(err, ErrUserNotVerified) {
+		t.Errorf("expected %v, got %v", ErrUserNotVerified, err)
+	}
+
+	// a signature of another key must not be accepted
+	statement["sig"] = authenticator.sign(authenticator.signer, AlgES256, append(append([]byte{}, authData...), clientDataHash[:]...))
+	response.Response.AttestationObject = encodeCBOR(map[interface{}]interface{}{
+		"fmt":      AttestationFormatPacked,
+		"attStmt":  statement,
+		"authData": authData,
+	})
+	if _, err := withoutUserVerification.VerifyRegistration(response, challenge); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected %v, got %v", ErrInvalidSignature, err)
+	}
+}
+
+func TestRegistrationRejectsInvalidCeremonies(t *testing.T) {
+	authenticator := newSoftAuthenticator(t, AlgES256)
+	challenge := newTestChallenge(t)
+	response := authenticator.register(challenge, AttestationFormatNone, nil)
+
+	if _, err := testRelyingParty.VerifyRegistration(response, newTestChallenge(t)); !errors.Is(err, ErrChallengeMismatch) {
+		t.Errorf("expected %v, got %v", ErrChallengeMismatch, err)
+	}
+
+	otherOrigin := RelyingParty{ID: testRpId, Origins: []string{"https://example.com"}}
+	if _, err := otherOrigin.VerifyRegistration(response, challenge); !errors.Is(err, ErrOriginNotAllowed) {
+		t.Errorf("expected %v, got %v", ErrOriginNotAllowed, err)
+	}
+
+	otherRelyingParty := RelyingParty{ID: "example.com", Origins: []string{testOrigin}}
+	if _, err := otherRelyingParty.VerifyRegistration(response, challenge); !errors.Is(err, ErrRelyingPartyMismatch) {
+		t.Errorf("expected %v, got %v", ErrRelyingPartyMismatch, err)
+	}
+
+	if _, err := testRelyingParty.VerifyRegistration(authenticator.register(challenge, "fido-u2f", nil), challenge); !errors.Is(err, ErrUnsupportedAttestationFormat) {
+		t.Errorf("expected %v, got %v", ErrUnsupportedAttestationFormat, err)
+	}
+
+	// an assertion cannot be used as a registration
+	response.Response.ClientDataJSON = authenticator.clientData(ceremonyGet, challenge)
+	if _, err := testRelyingParty.VerifyRegistration(response, challenge); !errors.Is(err, ErrInvalidClientData) {
+		t.Errorf("expected %v, got %v", ErrInvalidClientData, err)
+	}
+}
+
+func TestAssertion(t *testing.T) {
+	for _, algorithm := range []int64{AlgES256, AlgEdDSA} {
+		authenticator := newSoftAuthenticator(t, algorithm)
+		challenge := newTestChallenge(t)
+		credential, err := testRelyingParty.VerifyRegistration(authenticator.register(challenge, AttestationFormatNone, nil), challenge)
+		if err != nil {
+			t.Fatalf("expected the registration to be valid, got %v", err)
+		}
+
+		challenge = newTestChallenge(t)
+		response := authenticator.login(challenge)
+		signCount, err := testRelyingParty.VerifyAssertion(response, challenge, *credential)
+		if err != nil {
+			t.Fatalf("expected the assertion to be valid, got %v", err)
+		}
+		if signCount != 1 {
+			t.Errorf("expected the signature counter to be 1, got %v", signCount)
+		}
+		credential.SignCount = signCount
+
+		// replaying the same assertion does not increase the counter
+		if _, err := testRelyingParty.VerifyAssertion(response, challenge, *credential); !errors.Is(err, ErrSignCountMismatch) {
+			t.Errorf("expected %v, got %v", ErrSignCountMismatch, err)
+		}
+
+		response = authenticator.login(challenge)
+		response.Response.Signature[len(response.Response.Signature)-1] ^= 0xff
+		if _, err := testRelyingParty.VerifyAssertion(response, challenge, *credential); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("expected %v, got %v", ErrInvalidSignature, err)
+		}
+
+		response = newSoftAuthenticator(t, algorithm).login(challenge)
+		if _, err := testRelyingParty.VerifyAssertion(response, challenge, *credential); !errors.Is(err, ErrCredentialMismatch) {
+			t.Errorf("expected %v, got %v", ErrCredentialMismatch, err)
+		}
+	}
+}
+
+func TestDecodeCBORRejectsInvalidData(t *testing.T) {
+	invalid := [][]byte{
+		{},
+		{0x5f, 0x41, 0x00, 0xff}, // indefinite length byte string
+		{0x58, 0x10, 0x00},       // byte string longer than the data
+		{0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, // huge array
+		{0xa2, 0x01, 0x01, 0x01, 0x02},                         // duplicated map key
+	}
+
+	for _, data := range invalid {
+		if _, _, err := decodeCBOR(data); err == nil {
+			t.Errorf("expected %x to be rejected", data)
+		}
+	}
+}
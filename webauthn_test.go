@@ -0,0 +1,322 @@
+package identity
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/cjlapao/common-go-identity/authorization_context"
+	"github.com/cjlapao/common-go-identity/database/dto"
+	"github.com/cjlapao/common-go-identity/models"
+	"github.com/cjlapao/common-go-identity/webauthn"
+)
+
+const (
+	testWebAuthnUserId   = "webauthn-test-user"
+	testWebAuthnUsername = testWebAuthnUserId + "@localhost.com"
+	testWebAuthnRpId     = "localhost"
+	testWebAuthnOrigin   = "https://localhost"
+
+	testWebAuthnReplayUserId   = "webauthn-replay-test-user"
+	testWebAuthnReplayUsername = testWebAuthnReplayUserId + "@localhost.com"
+
+	testWebAuthnStateUserId   = "webauthn-state-test-user"
+	testWebAuthnStateUsername = testWebAuthnStateUserId + "@localhost.com"
+)
+
+// testPasskey emulates a platform authenticator with an ES256 key and no attestation, synced
+// passkeys have no signature counter and always send zero
+type testPasskey struct {
+	id        []byte
+	key       *ecdsa.PrivateKey
+	signCount uint32
+	synced    bool
+}
+
+func newTestPasskey(t *testing.T) *testPasskey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate the passkey, %v", err)
+	}
+	passkey := testPasskey{id: make([]byte, 16), key: key}
+	rand.Read(passkey.id)
+	return &passkey
+}
+
+func (p *testPasskey) authenticatorData(attested bool) []byte {
+	rpIdHash := sha256.Sum256([]byte(testWebAuthnRpId))
+	flags := webauthn.FlagUserPresent | webauthn.FlagUserVerified
+	if attested {
+		flags |= webauthn.FlagAttestedCredentialData
+	}
+	data := append(rpIdHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, p.signCount)
+	if attested {
+		data = append(data, make([]byte, 16)...)
+		data = binary.BigEndian.AppendUint16(data, uint16(len(p.id)))
+		data = append(data, p.id...)
+		// COSE key map {1: 2, 3: -7, -1: 1, -2: x, -3: y}
+		data = append(data, 0xa5, 0x01, 0x02, 0x03, 0x26, 0x20, 0x01, 0x21, 0x58, 0x20)
+		data = append(data, p.key.X.FillBytes(make([]byte, 32))...)
+		data = append(data, 0x22, 0x58, 0x20)
+		data = append(data, p.key.Y.FillBytes(make([]byte, 32))...)
+	}
+
+	return data
+}
+
+func (p *testPasskey) clientData(ceremony string, challenge string) []byte {
+	data, _ := json.Marshal(map[string]string{"type": ceremony, "challenge": challenge, "origin": testWebAuthnOrigin})
+	return data
+}
+
+func (p *testPasskey) create(challenge string) webauthn.RegistrationResponse {
+	authData := p.authenticatorData(true)
+	// attestation object map {"fmt": "none", "attStmt": {}, "authData": authData}
+	attestation := []byte{0xa3, 0x63, 'f', 'm', 't', 0x64, 'n', 'o', 'n', 'e', 0x67, 'a', 't', 't', 'S', 't', 'm', 't', 0xa0}
+	attestation = append(attestation, 0x68, 'a', 'u', 't', 'h', 'D', 'a', 't', 'a', 0x58, byte(len(authData)))
+	attestation = append(attestation, authData...)
+
+	return webauthn.RegistrationResponse{
+		ID:    base64.RawURLEncoding.EncodeToString(p.id),
+		RawID: p.id,
+		Type:  "public-key",
+		Response: webauthn.AuthenticatorAttestationResponse{
+			ClientDataJSON:    p.clientData("webauthn.create", challenge),
+			AttestationObject: attestation,
+		},
+	}
+}
+
+func (p *testPasskey) get(t *testing.T, challenge string, userHandle string) string {
+	if !p.synced {
+		p.signCount++
+	}
+	authData := p.authenticatorData(false)
+	clientData := p.clientData("webauthn.get", challenge)
+	clientDataHash := sha256.Sum256(clientData)
+	hash := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, _ := p.key.Sign(rand.Reader, hash[:], crypto.SHA256)
+
+	response, err := json.Marshal(webauthn.AssertionResponse{
+		ID:    base64.RawURLEncoding.EncodeToString(p.id),
+		RawID: p.id,
+		Type:  "public-key",
+		Response: webauthn.AuthenticatorAssertionResponse{
+			ClientDataJSON:    clientData,
+			AuthenticatorData: authData,
+			Signature:         signature,
+			UserHandle:        []byte(userHandle),
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to serialize the assertion, %v", err)
+	}
+
+	return string(response)
+}
+
+func sendJsonRequest(t *testing.T, method string, path string, accessToken string, body interface{}, result interface{}) int {
+	t.Helper()
+	server := getTestServer(t)
+	content, _ := json.Marshal(body)
+	request, _ := http.NewRequest(method, server.URL+"/auth/"+path, bytes.NewReader(content))
+	request.Header.Set("Content-Type", "application/json")
+	if accessToken != "" {
+		request.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	response, err := newTestClient().Do(request)
+	if err != nil {
+		t.Fatalf("request failed, %v", err)
+	}
+	defer response.Body.Close()
+
+	if result != nil {
+		json.NewDecoder(response.Body).Decode(result)
+	}
+	return response.StatusCode
+}
+
+type testWebAuthnBeginResponse struct {
+	Session   string `json:"session"`
+	PublicKey struct {
+		Challenge        string `json:"challenge"`
+		AllowCredentials []struct {
+			ID string `json:"id"`
+		} `json:"allowCredentials"`
+		User struct {
+			ID string `json:"id"`
+		} `json:"user"`
+	} `json:"publicKey"`
+}
+
+func webAuthnLogin(t *testing.T, passkey *testPasskey, username string, userHandle string) (int, map[string]interface{}) {
+	t.Helper()
+	var begin testWebAuthnBeginResponse
+	if status := sendJsonRequest(t, http.MethodPost, "webauthn/login/begin", "", map[string]string{"username": username, "client_id": "spa"}, &begin); status != http.StatusOK {
+		t.Fatalf("expected status %v, got %v", http.StatusOK, status)
+	}
+
+	return requestToken(t, url.Values{
+		"grant_type":        {"webauthn"},
+		"client_id":         {"spa"},
+		"webauthn_session":  {begin.Session},
+		"webauthn_response": {passkey.get(t, begin.PublicKey.Challenge, userHandle)},
+	})
+}
+
+func TestWebAuthnRegistrationAndLogin(t *testing.T) {
+	addTestUser(t, testWebAuthnUserId, testMfaPassword)
+	options := authorization_context.GetBaseContext().Options
+	options.WebAuthnRelyingPartyId = testWebAuthnRpId
+	options.WebAuthnOrigins = []string{testWebAuthnOrigin}
+
+	_, body := passwordLogin(t, testWebAuthnUsername, testMfaPassword, nil)
+	accessToken := body["access_token"].(string)
+
+	var begin testWebAuthnBeginResponse
+	if status := sendJsonRequest(t, http.MethodPost, "webauthn/register/begin", accessToken, nil, &begin); status != http.StatusOK {
+		t.Fatalf("expected status %v, got %v", http.StatusOK, status)
+	}
+	if begin.Session == "" || begin.PublicKey.User.ID != base64.RawURLEncoding.EncodeToString([]byte(testWebAuthnUserId)) {
+		t.Fatalf("expected the creation options for the user, got %+v", begin)
+	}
+
+	passkey := newTestPasskey(t)
+	registration := map[string]interface{}{"session": begin.Session, "name": "Laptop", "credential": passkey.create(begin.PublicKey.Challenge)}
+
+	// the challenge of the session must be the one the authenticator signed
+	wrongChallenge := map[string]interface{}{"session": begin.Session, "credential": passkey.create(base64.RawURLEncoding.EncodeToString([]byte("another challenge")))}
+	if status := sendJsonRequest(t, http.MethodPost, "webauthn/register/finish", accessToken, wrongChallenge, nil); status != http.StatusBadRequest {
+		t.Fatalf("expected a wrong challenge to be rejected, got %v", status)
+	}
+
+	var credential map[string]interface{}
+	if status := sendJsonRequest(t, http.MethodPost, "webauthn/register/finish", accessToken, registration, &credential); status != http.StatusCreated {
+		t.Fatalf("expected status %v, got %v %v", http.StatusCreated, status, credential)
+	}
+	credentialId := base64.RawURLEncoding.EncodeToString(passkey.id)
+	if credential["id"] != credentialId || credential["name"] != "Laptop" {
+		t.Fatalf("unexpected credential %v", credential)
+	}
+
+	var credentials []map[string]interface{}
+	sendJsonRequest(t, http.MethodGet, "webauthn/credentials", accessToken, nil, &credentials)
+	if len(credentials) != 1 {
+		t.Fatalf("expected one credential, got %v", credentials)
+	}
+
+	status, body := webAuthnLogin(t, passkey, testWebAuthnUsername, "")
+	if status != http.StatusOK || body["access_token"] == nil {
+		t.Fatalf("expected the passkey login to succeed, got %v %v", status, body)
+	}
+
+	// discoverable credentials identify the user with the user handle
+	status, body = webAuthnLogin(t, passkey, "", testWebAuthnUserId)
+	if status != http.StatusOK || body["access_token"] == nil {
+		t.Fatalf("expected the discoverable login to succeed, got %v %v", status, body)
+	}
+
+	// an assertion with a counter that did not increase is rejected
+	passkey.signCount = 0
+	if status, body = webAuthnLogin(t, passkey, testWebAuthnUsername, ""); status != http.StatusBadRequest {
+		t.Fatalf("expected a cloned authenticator to be rejected, got %v %v", status, body)
+	}
+
+	passkey.signCount = 10
+	if status = sendJsonRequest(t, http.MethodDelete, "webauthn/credentials/"+credentialId, accessToken, nil, nil); status != http.StatusNoContent {
+		t.Fatalf("expected status %v, got %v", http.StatusNoContent, status)
+	}
+	if status, body = webAuthnLogin(t, passkey, "", testWebAuthnUserId); status != http.StatusBadRequest {
+		t.Fatalf("expected a removed credential to be rejected, got %v %v", status, body)
+	}
+}
+
+func TestWebAuthnAssertionReplay(t *testing.T) {
+	addTestUser(t, testWebAuthnReplayUserId, testMfaPassword)
+	options := authorization_context.GetBaseContext().Options
+	options.WebAuthnRelyingPartyId = testWebAuthnRpId
+	options.WebAuthnOrigins = []string{testWebAuthnOrigin}
+
+	_, body := passwordLogin(t, testWebAuthnReplayUsername, testMfaPassword, nil)
+	accessToken := body["access_token"].(string)
+
+	var begin testWebAuthnBeginResponse
+	sendJsonRequest(t, http.MethodPost, "webauthn/register/begin", accessToken, nil, &begin)
+	passkey := newTestPasskey(t)
+	passkey.synced = true
+	registration := map[string]interface{}{"session": begin.Session, "credential": passkey.create(begin.PublicKey.Challenge)}
+	if status := sendJsonRequest(t, http.MethodPost, "webauthn/register/finish", accessToken, registration, nil); status != http.StatusCreated {
+		t.Fatalf("expected status %v, got %v", http.StatusCreated, status)
+	}
+	// the registration session cannot be used again, even for another credential
+	registration["credential"] = newTestPasskey(t).create(begin.PublicKey.Challenge)
+	if status := sendJsonRequest(t, http.MethodPost, "webauthn/register/finish", accessToken, registration, nil); status != http.StatusBadRequest {
+		t.Fatalf("expected the registration session to be used up, got %v", status)
+	}
+
+	sendJsonRequest(t, http.MethodPost, "webauthn/login/begin", "", map[string]string{"username": testWebAuthnReplayUsername, "client_id": "spa"}, &begin)
+	login := url.Values{
+		"grant_type":        {"webauthn"},
+		"client_id":         {"spa"},
+		"webauthn_session":  {begin.Session},
+		"webauthn_response": {passkey.get(t, begin.PublicKey.Challenge, "")},
+	}
+	login.Set("client_id", "unregistered-app")
+	if status, body := requestToken(t, login); status != http.StatusUnauthorized || body["error"] != "invalid_client" {
+		t.Fatalf("expected an unregistered client to be rejected, got %v %v", status, body)
+	}
+	login.Set("client_id", "spa")
+	if status, body := requestToken(t, login); status != http.StatusOK {
+		t.Fatalf("expected the passkey login to succeed, got %v %v", status, body)
+	}
+
+	// the counter of a synced passkey does not change so only the session stops the replay
+	if status, body := requestToken(t, login); status != http.StatusBadRequest || body["access_token"] != nil {
+		t.Fatalf("expected the replayed assertion to be rejected, got %v %v", status, body)
+	}
+
+	if status, body := webAuthnLogin(t, passkey, testWebAuthnReplayUsername, ""); status != http.StatusOK {
+		t.Fatalf("expected a new ceremony to succeed, got %v %v", status, body)
+	}
+}
+
+func TestWebAuthnLoginUserState(t *testing.T) {
+	addTestUser(t, testWebAuthnStateUserId, testMfaPassword)
+	authCtx := authorization_context.GetBaseContext()
+	authCtx.Options.WebAuthnRelyingPartyId = testWebAuthnRpId
+	authCtx.Options.WebAuthnOrigins = []string{testWebAuthnOrigin}
+
+	_, body := passwordLogin(t, testWebAuthnStateUsername, testMfaPassword, nil)
+	accessToken := body["access_token"].(string)
+	var begin testWebAuthnBeginResponse
+	sendJsonRequest(t, http.MethodPost, "webauthn/register/begin", accessToken, nil, &begin)
+	passkey := newTestPasskey(t)
+	registration := map[string]interface{}{"session": begin.Session, "credential": passkey.create(begin.PublicKey.Challenge)}
+	if status := sendJsonRequest(t, http.MethodPost, "webauthn/register/finish", accessToken, registration, nil); status != http.StatusCreated {
+		t.Fatalf("expected status %v, got %v", http.StatusCreated, status)
+	}
+
+	// the passkey proves who the user is but the user still needs to be allowed to login
+	authCtx.ValidationOptions.VerifiedEmail = true
+	updateTestUser(t, testWebAuthnStateUserId, func(user *dto.UserDTO) { user.EmailVerified = false })
+	status, body := webAuthnLogin(t, passkey, testWebAuthnStateUsername, "")
+	authCtx.ValidationOptions.VerifiedEmail = false
+	if status != http.StatusBadRequest || body["error"] != models.OAuthEmailNotVerified.String() {
+		t.Fatalf("expected an unverified email to be rejected, got %v %v", status, body)
+	}
+
+	updateTestUser(t, testWebAuthnStateUserId, func(user *dto.UserDTO) { user.Blocked = true })
+	if status, body := webAuthnLogin(t, passkey, testWebAuthnStateUsername, ""); status != http.StatusBadRequest || body["error"] != models.OAuthUserBlocked.String() {
+		t.Fatalf("expected a blocked user to be rejected, got %v %v", status, body)
+	}
+}